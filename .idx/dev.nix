@@ -8,7 +8,6 @@
   packages = [
     pkgs.go
     pkgs.sqlc
-    pkgs.go-migrate
     # pkgs.python311
     # pkgs.python311Packages.pip
     # pkgs.nodejs_20
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"context"
+	"errors"
+
+	"github.com/rs/zerolog/log"
+	"gosuda.org/jimin/database"
+	"gosuda.org/jimin/internal/api"
+	"gosuda.org/jimin/internal/auth"
+	"gosuda.org/jimin/internal/feed"
+	"gosuda.org/jimin/internal/linkcheck"
+	"gosuda.org/jimin/internal/recrawl"
+	"gosuda.org/jimin/internal/reprocess"
+	"gosuda.org/jimin/internal/search"
+)
+
+// documentAdder adds the documents created through the API to the recrawl,
+// with the source of their host if none is given.
+type documentAdder struct {
+	cfg *Config
+	r   *recrawl.Recrawler
+}
+
+func (d documentAdder) Add(ctx context.Context, wsID int64, source, url string) (database.Document, error) {
+	if source == "" {
+		s, _ := d.cfg.Source(url)
+		source = s.Name
+	}
+	return d.r.Add(ctx, wsID, source, url)
+}
+
+// NewAPI returns the /v1 API authenticated by au. Answering is disabled if the generator is not configured,
+// reprocessing if r is nil, and serving the captured images if image capture is disabled.
+func NewAPI(c *Config, db api.DB, ids api.IDGenerator, au *auth.Auth, rc *recrawl.Recrawler, links *linkcheck.Checker, r *reprocess.Reprocessor) (*api.API, error) {
+	mode, err := search.ParseMode(c.Search.Mode)
+	if err != nil {
+		return nil, err
+	}
+	searcher, err := NewSearcher(c, db, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	cfg := api.Config{
+		DB:       db,
+		IDs:      ids,
+		Auth:     au,
+		Crawler:  documentAdder{cfg: c, r: rc},
+		Searcher: searcher,
+		Links:    links,
+		Feeds:    feed.NewRegistry(db, ids),
+		Mode:     mode,
+	}
+	for _, s := range c.Sources {
+		cfg.Sources = append(cfg.Sources, api.SourceInfo{Name: s.Name, Hosts: s.Hosts})
+	}
+	if gen, err := c.NewAnswerer(); errors.Is(err, ErrUnknownModelRole) {
+		log.Info().Err(err).Msg("Answering is disabled without a generator")
+	} else if err != nil {
+		return nil, err
+	} else {
+		cfg.Generator = gen
+	}
+	if r != nil {
+		cfg.Reprocess = r
+	}
+	if c.Assets.Enabled {
+		store, err := c.BlobStore()
+		if err != nil {
+			return nil, err
+		}
+		cfg.Blobs = store
+	}
+	return api.New(cfg), nil
+}
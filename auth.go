@@ -0,0 +1,113 @@
+package main
+
+import (
+	"encoding/hex"
+	"errors"
+	"regexp"
+	"strings"
+	"time"
+
+	"gosuda.org/jimin/internal/auth"
+	"gosuda.org/jimin/internal/mail"
+	"gosuda.org/jimin/internal/oidc"
+)
+
+type AuthConfig struct {
+	// SessionTTL is the time in seconds a session stays signed in without being used, 14 days if 0.
+	SessionTTL int `json:"session_ttl,omitempty"`
+	// SessionMaxAge is the time in seconds after which a session is signed out even if used, 90 days if 0.
+	SessionMaxAge int    `json:"session_max_age,omitempty"`
+	CookieName    string `json:"cookie_name,omitempty"`
+	// InsecureCookies sends the session cookie over plain HTTP. Only for local development.
+	InsecureCookies bool `json:"insecure_cookies,omitempty"`
+	// BaseURL is the public URL of the server, which the OpenID Connect providers redirect back to.
+	BaseURL string `json:"base_url,omitempty"`
+	// OIDC are the OpenID Connect providers users sign in with.
+	OIDC []OIDCConfig `json:"oidc,omitempty"`
+	// TokenSecret is the hex encoded key of at least 32 bytes signing the tokens of the emails.
+	// A random key is used if empty, and the emails sent before a restart stop working.
+	TokenSecret string `json:"token_secret,omitempty"`
+	// VerifyEmailURL is the page verification emails link to, base_url + /verify-email if empty.
+	VerifyEmailURL string `json:"verify_email_url,omitempty"`
+	// ResetPasswordURL is the page password reset emails link to, base_url + /reset-password if empty.
+	ResetPasswordURL string `json:"reset_password_url,omitempty"`
+}
+
+type OIDCConfig struct {
+	// ID is the users_auth provider id of the provider, greater than 1 and never changed once users signed in.
+	ID int64 `json:"id"`
+	// Name identifies the provider in the login URLs.
+	Name         string `json:"name"`
+	Issuer       string `json:"issuer"`
+	ClientID     string `json:"client_id"`
+	ClientSecret string `json:"client_secret,omitempty"`
+	// RedirectURL is the callback registered with the provider,
+	// base_url + /v1/auth/oidc/<name>/callback if empty.
+	RedirectURL string   `json:"redirect_url,omitempty"`
+	Scopes      []string `json:"scopes,omitempty"`
+}
+
+var oidcNamePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]*$`)
+
+var ErrInvalidTokenSecret = errors.New("auth.token_secret must be at least 32 bytes in hex")
+
+// page returns the URL of a page, url if set or path of the base URL.
+func (c *AuthConfig) page(url, path string) string {
+	if url != "" || c.BaseURL == "" {
+		return url
+	}
+	return strings.TrimSuffix(c.BaseURL, "/") + path
+}
+
+func (c *AuthConfig) tokenSecret() ([]byte, error) {
+	if c.TokenSecret == "" {
+		return nil, nil
+	}
+	secret, err := hex.DecodeString(c.TokenSecret)
+	if err != nil || len(secret) < 32 {
+		return nil, ErrInvalidTokenSecret
+	}
+	return secret, nil
+}
+
+func (c *AuthConfig) redirectURL(p OIDCConfig) string {
+	return c.page(p.RedirectURL, "/v1/auth/oidc/"+p.Name+"/callback")
+}
+
+// NewAuth returns the password and OpenID Connect authentication and the sessions of the API.
+// The verification and password reset emails are sent with m, and disabled if it is nil.
+func NewAuth(c *Config, db auth.DB, ids auth.IDGenerator, m mail.Mailer) (*auth.Auth, error) {
+	secret, err := c.Auth.tokenSecret()
+	if err != nil {
+		return nil, err
+	}
+
+	providers := make([]auth.Provider, len(c.Auth.OIDC))
+	for i, p := range c.Auth.OIDC {
+		providers[i] = auth.Provider{
+			ID:   p.ID,
+			Name: p.Name,
+			OIDC: oidc.New(oidc.Config{
+				Issuer:       p.Issuer,
+				ClientID:     p.ClientID,
+				ClientSecret: p.ClientSecret,
+				RedirectURL:  c.Auth.redirectURL(p),
+				Scopes:       p.Scopes,
+			}),
+		}
+	}
+
+	return auth.New(auth.Config{
+		DB:               db,
+		IDs:              ids,
+		SessionTTL:       time.Duration(c.Auth.SessionTTL) * time.Second,
+		SessionMaxAge:    time.Duration(c.Auth.SessionMaxAge) * time.Second,
+		CookieName:       c.Auth.CookieName,
+		InsecureCookies:  c.Auth.InsecureCookies,
+		Providers:        providers,
+		Mailer:           m,
+		TokenSecret:      secret,
+		VerifyEmailURL:   c.Auth.page(c.Auth.VerifyEmailURL, "/verify-email"),
+		ResetPasswordURL: c.Auth.page(c.Auth.ResetPasswordURL, "/reset-password"),
+	}), nil
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/lemon-mint/coord/llm"
+	"gosuda.org/jimin/internal/asset"
+	"gosuda.org/jimin/internal/crawler"
+)
+
+const _DEFAULT_ASSET_BASE_URL = "/v1/assets/"
+
+const captionInstruction = "Write alt text for the image in one or two sentences. " +
+	"Describe what it shows and transcribe any important text. Reply with the alt text only."
+
+type llmCaptioner struct {
+	model llm.Model
+}
+
+func (g *llmCaptioner) Caption(ctx context.Context, image []byte, contentType string) (string, error) {
+	out := g.model.GenerateStream(
+		ctx,
+		&llm.ChatContext{SystemInstruction: captionInstruction},
+		&llm.Content{
+			Role: llm.RoleUser,
+			Parts: []llm.Segment{
+				&llm.InlineData{MIMEType: contentType, Data: image},
+				llm.Text("Describe this image."),
+			},
+		},
+	)
+
+	var sb strings.Builder
+	for segment := range out.Stream {
+		if text, ok := segment.(llm.Text); ok {
+			sb.WriteString(string(text))
+		}
+	}
+	if out.Err != nil {
+		return "", out.Err
+	}
+
+	return strings.TrimSpace(sb.String()), nil
+}
+
+// NewAssetCapturer returns an image capturer for the asset config,
+// or nil if image capture is disabled.
+// Images are fetched with the profiles and proxies of cr if it is not nil.
+func NewAssetCapturer(c *Config, cr *crawler.Crawler) (*asset.Capturer, error) {
+	if !c.Assets.Enabled {
+		return nil, nil
+	}
+
+	store, err := c.BlobStore()
+	if err != nil {
+		return nil, err
+	}
+
+	client := &http.Client{Timeout: time.Second * 30}
+	if cr != nil {
+		client.Transport = cr.Transport(nil)
+	}
+	baseURL := c.Assets.BaseURL
+	if baseURL == "" {
+		baseURL = _DEFAULT_ASSET_BASE_URL
+	}
+
+	cfg := asset.Config{
+		Store:     store,
+		Client:    client,
+		BaseURL:   baseURL,
+		MaxBytes:  c.Assets.MaxBytes,
+		MaxImages: c.Assets.MaxImages,
+	}
+
+	if c.ModelConfigs.ImageCaptioner != nil {
+		model, err := c.NewModel(*c.ModelConfigs.ImageCaptioner)
+		if err != nil {
+			return nil, err
+		}
+		cfg.Captioner = &llmCaptioner{model: model}
+	}
+
+	return asset.NewCapturer(cfg), nil
+}
@@ -0,0 +1,205 @@
+package main
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	netmail "net/mail"
+	"net/url"
+
+	"gosuda.org/jimin/internal/auth"
+	"gosuda.org/jimin/internal/crawler"
+	"gosuda.org/jimin/internal/mail"
+	"gosuda.org/jimin/internal/pipeline"
+	"gosuda.org/jimin/internal/recrawl"
+	"gosuda.org/jimin/internal/search"
+)
+
+var ErrInvalidConfig = errors.New("invalid config")
+
+var providerTypes = map[string]bool{"aistudio": true, "anthropic": true, "openai": true, "vertexai": true}
+
+var mailTypes = map[string]bool{"smtp": true, "file": true, "log": true}
+
+// Check validates the config without connecting to the database or the providers.
+// It returns every problem found.
+func (c *Config) Check() []error {
+	var errs []error
+	add := func(format string, args ...any) {
+		errs = append(errs, fmt.Errorf(format, args...))
+	}
+
+	if c.Database.URL == "" {
+		add("database.url: %w", ErrNoDatabase)
+	}
+	if secret, err := hex.DecodeString(c.Database.IDSecret); err != nil || len(secret) != 16 {
+		add("database.id_secret: %w", ErrInvalidIDSecret)
+	}
+
+	providers := make(map[string]bool, len(c.Providers))
+	for i, p := range c.Providers {
+		if providers[p.Name] {
+			add("providers[%d]: duplicate provider %q", i, p.Name)
+		}
+		providers[p.Name] = true
+		if !providerTypes[p.Type] {
+			add("providers[%d]: unknown provider type %q", i, p.Type)
+		}
+	}
+
+	roles := map[string]ModelConfig{"chunk_generator": c.ModelConfigs.ChunkGenerator}
+	if c.ModelConfigs.ImageCaptioner != nil {
+		roles["image_captioner"] = *c.ModelConfigs.ImageCaptioner
+	}
+	for name, mc := range c.ModelConfigs.Roles {
+		roles[name] = mc
+	}
+	for name, mc := range roles {
+		if mc.Model != "" && !providers[mc.Provider] {
+			add("model_configs.%s: %w %q", name, ErrUnknownProvider, mc.Provider)
+		}
+	}
+
+	if c.Assets.Enabled && c.Blobs.Dir == "" {
+		add("assets.enabled: %w", ErrNoBlobStore)
+	}
+
+	// the stages are built with models that are never connected
+	env := pipeline.Env{
+		Generator: func(role string) (pipeline.Generator, error) {
+			if _, ok := c.ModelConfigs.Role(role); !ok {
+				return nil, fmt.Errorf("%w: %s", ErrUnknownModelRole, role)
+			}
+			return nil, nil
+		},
+		Embedder: func(role string) (pipeline.Embedder, error) {
+			if _, ok := c.ModelConfigs.Role(role); !ok {
+				return nil, fmt.Errorf("%w: %s", ErrUnknownModelRole, role)
+			}
+			return nil, nil
+		},
+	}
+	for name, pc := range c.Pipelines {
+		if _, err := pipeline.New(pc, env); err != nil {
+			add("pipelines.%s: %w", name, err)
+		}
+	}
+
+	for i, s := range c.Sources {
+		if s.Recrawl.Cron != "" {
+			if _, err := recrawl.ParseCron(s.Recrawl.Cron); err != nil {
+				add("sources[%d].recrawl.cron: %w", i, err)
+			}
+		}
+	}
+	if _, err := crawler.LoadProfiles(c.Profiles); err != nil {
+		add("profiles: %w", err)
+	}
+	if _, err := crawler.NewProxyRouter(c.Crawler.Proxies); err != nil {
+		add("crawler.proxies: %w", err)
+	}
+
+	if _, err := search.ParseMode(c.Search.Mode); err != nil {
+		add("search.mode: %w", err)
+	}
+	for _, role := range []string{c.Search.Embedder, c.Search.Generator} {
+		if _, ok := c.ModelConfigs.Role(role); role != "" && !ok {
+			add("search: %w: %s", ErrUnknownModelRole, role)
+		}
+	}
+
+	if _, err := c.Auth.tokenSecret(); err != nil {
+		add("%w", err)
+	}
+	if c.Mail.Type != "" {
+		if !mailTypes[c.Mail.Type] {
+			add("mail.type: unknown mail type %q", c.Mail.Type)
+		}
+		if _, err := netmail.ParseAddress(c.Mail.From); c.Mail.Type != "log" && err != nil {
+			add("mail.from: invalid address %q", c.Mail.From)
+		}
+		switch c.Mail.Type {
+		case "smtp":
+			if _, _, err := net.SplitHostPort(c.Mail.SMTP.Addr); err != nil {
+				add("mail.smtp.addr: invalid address %q", c.Mail.SMTP.Addr)
+			}
+			if tls := c.Mail.SMTP.TLS; tls != "" && tls != mail.TLSStartTLS && tls != mail.TLSImplicit && tls != mail.TLSNone {
+				add("mail.smtp.tls: unknown TLS mode %q", tls)
+			}
+		case "file":
+			if c.Mail.Dir == "" {
+				add("mail.dir: required for the file mailer")
+			}
+		}
+		if c.Auth.BaseURL == "" && (c.Auth.VerifyEmailURL == "" || c.Auth.ResetPasswordURL == "") {
+			add("auth.base_url: required for the links of the emails without verify_email_url and reset_password_url")
+		}
+	}
+
+	ids := make(map[int64]bool, len(c.Auth.OIDC))
+	names := make(map[string]bool, len(c.Auth.OIDC))
+	for i, p := range c.Auth.OIDC {
+		switch {
+		case p.ID <= auth.PasswordProvider:
+			add("auth.oidc[%d].id: must be greater than %d", i, auth.PasswordProvider)
+		case ids[p.ID]:
+			add("auth.oidc[%d].id: duplicate id %d", i, p.ID)
+		}
+		ids[p.ID] = true
+		switch {
+		case !oidcNamePattern.MatchString(p.Name) || p.Name == auth.PasswordProviderName:
+			add("auth.oidc[%d].name: invalid name %q", i, p.Name)
+		case names[p.Name]:
+			add("auth.oidc[%d].name: duplicate name %q", i, p.Name)
+		}
+		names[p.Name] = true
+		if u, err := url.Parse(p.Issuer); err != nil || u.Scheme != "https" && u.Scheme != "http" || u.Host == "" {
+			add("auth.oidc[%d].issuer: invalid URL %q", i, p.Issuer)
+		}
+		if p.ClientID == "" {
+			add("auth.oidc[%d].client_id: required", i)
+		}
+		if c.Auth.redirectURL(p) == "" {
+			add("auth.oidc[%d].redirect_url: required without auth.base_url", i)
+		}
+	}
+
+	return errs
+}
+
+func (c *cli) checkConfig(ctx context.Context, args []string) error {
+	fs := c.flags("config check", "")
+	if _, err := c.parse(fs, args, 0); err != nil {
+		return err
+	}
+
+	cfg, err := c.loadConfig()
+	if err != nil {
+		return err
+	}
+
+	errs := cfg.Check()
+	problems := make([]string, len(errs))
+	for i, err := range errs {
+		problems[i] = err.Error()
+	}
+	err = c.print(map[string]any{"file": c.configFile, "valid": len(errs) == 0, "errors": problems}, func(w io.Writer) {
+		for _, p := range problems {
+			fmt.Fprintln(w, p)
+		}
+		if len(errs) == 0 {
+			fmt.Fprintf(w, "%s is valid\n", c.configFile)
+		}
+	})
+	if err != nil {
+		return err
+	}
+
+	if len(errs) > 0 {
+		return withCode(_EXIT_CONFIG, fmt.Errorf("%w: %s has %d errors", ErrInvalidConfig, c.configFile, len(errs)))
+	}
+	return nil
+}
@@ -0,0 +1,308 @@
+package main
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/rs/zerolog/log"
+	"gosuda.org/jimin/internal/randflake"
+)
+
+const _DEFAULT_CONFIG_FILE = "config.jsonnet"
+
+// Exit codes of the commands.
+const (
+	_EXIT_OK          = 0
+	_EXIT_FAILURE     = 1
+	_EXIT_USAGE       = 2
+	_EXIT_CONFIG      = 3
+	_EXIT_UNAVAILABLE = 4
+	_EXIT_NOT_FOUND   = 5
+	_EXIT_CONFLICT    = 6
+)
+
+// exitError is an error with the exit code it is reported with.
+type exitError struct {
+	code int
+	err  error
+}
+
+func (e *exitError) Error() string { return e.err.Error() }
+func (e *exitError) Unwrap() error { return e.err }
+
+func withCode(code int, err error) error {
+	if err == nil {
+		return nil
+	}
+	return &exitError{code: code, err: err}
+}
+
+func usageErrorf(format string, args ...any) error {
+	return withCode(_EXIT_USAGE, fmt.Errorf(format, args...))
+}
+
+// exitCode returns the exit code err is reported with.
+func exitCode(err error) int {
+	var ee *exitError
+	var pgErr *pgconn.PgError
+	switch {
+	case err == nil:
+		return _EXIT_OK
+	case errors.As(err, &ee):
+		return ee.code
+	case errors.Is(err, pgx.ErrNoRows):
+		return _EXIT_NOT_FOUND
+	case errors.As(err, &pgErr) && pgErr.Code == "23505":
+		return _EXIT_CONFLICT
+	}
+	return _EXIT_FAILURE
+}
+
+type command struct {
+	name    string
+	args    string
+	summary string
+	run     func(c *cli, ctx context.Context, args []string) error
+}
+
+var commands = []command{
+	{"serve", "", "Run the API server and the background workers", (*cli).serve},
+	{"migrate up", "", "Apply the pending database migrations", (*cli).migrateUp},
+	{"migrate down", "<n>", "Revert the last n applied migrations", (*cli).migrateDown},
+	{"migrate status", "", "List the migrations and the version of the database", (*cli).migrateStatus},
+	{"migrate force", "<version>", "Record a version as applied to recover a dirty database", (*cli).migrateForce},
+	{"crawl", "<url>", "Crawl and convert a page, storing it with -ws", (*cli).crawl},
+	{"ingest", "<path>", "Import files, directories and web archives into a workspace", (*cli).ingest},
+	{"search", "<query>", "Search the documents of a workspace", (*cli).search},
+	{"ask", "<question>", "Answer a question from the documents of a workspace", (*cli).ask},
+	{"user create", "", "Create a user", (*cli).createUser},
+	{"user admin", "<user_id>", "Let a user administer the server, or stop it with -revoke", (*cli).setUserAdmin},
+	{"workspace create", "", "Create a workspace", (*cli).createWorkspace},
+	{"config check", "", "Validate the config file", (*cli).checkConfig},
+}
+
+// cli holds the common flags and the outputs of a command.
+type cli struct {
+	stdout, stderr io.Writer
+
+	configFile string
+	json       bool
+}
+
+// run runs the command of args and returns its exit code.
+func run(ctx context.Context, args []string, stdout, stderr io.Writer) int {
+	c := &cli{stdout: stdout, stderr: stderr, configFile: _DEFAULT_CONFIG_FILE}
+
+	if len(args) == 0 {
+		c.usage()
+		return _EXIT_USAGE
+	}
+	if args[0] == "help" || args[0] == "-h" || args[0] == "-help" || args[0] == "--help" {
+		c.usage()
+		return _EXIT_OK
+	}
+
+	cmd, rest, ok := lookup(args)
+	if !ok {
+		c.fail(usageErrorf("unknown command %q", strings.Join(args[:min(len(args), 2)], " ")))
+		c.usage()
+		return _EXIT_USAGE
+	}
+
+	err := cmd.run(c, ctx, rest)
+	if errors.Is(err, flag.ErrHelp) {
+		return _EXIT_OK
+	}
+	if err != nil {
+		c.fail(err)
+	}
+	return exitCode(err)
+}
+
+// lookup returns the command named by the first one or two words of args and the remaining args.
+func lookup(args []string) (command, []string, bool) {
+	for _, cmd := range commands {
+		words := strings.Fields(cmd.name)
+		if len(args) >= len(words) && strings.Join(args[:len(words)], " ") == cmd.name {
+			return cmd, args[len(words):], true
+		}
+	}
+	return command{}, nil, false
+}
+
+func (c *cli) usage() {
+	fmt.Fprintln(c.stderr, "Usage: jimin <command> [flags] [args]")
+	fmt.Fprintln(c.stderr)
+	fmt.Fprintln(c.stderr, "Commands:")
+	for _, cmd := range commands {
+		fmt.Fprintf(c.stderr, "  %-26s %s\n", strings.TrimSpace(cmd.name+" "+cmd.args), cmd.summary)
+	}
+	fmt.Fprintln(c.stderr)
+	fmt.Fprintln(c.stderr, "Run 'jimin <command> -h' for the flags of a command.")
+}
+
+// flags returns the flag set of a command with the common flags.
+func (c *cli) flags(name, args string) *flag.FlagSet {
+	fs := flag.NewFlagSet("jimin "+name, flag.ContinueOnError)
+	fs.SetOutput(c.stderr)
+	fs.StringVar(&c.configFile, "config", _DEFAULT_CONFIG_FILE, "config file")
+	fs.BoolVar(&c.json, "json", false, "print the output as JSON")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: jimin %s [flags] %s\n\nFlags:\n", name, args)
+		fs.PrintDefaults()
+	}
+	return fs
+}
+
+// parse parses the flags of args, which may follow the positional arguments,
+// and checks that there are n positional arguments.
+func (c *cli) parse(fs *flag.FlagSet, args []string, n int) ([]string, error) {
+	var positional []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			if errors.Is(err, flag.ErrHelp) {
+				return nil, err
+			}
+			return nil, withCode(_EXIT_USAGE, err)
+		}
+		args = fs.Args()
+		if len(args) == 0 {
+			break
+		}
+		positional = append(positional, args[0])
+		args = args[1:]
+	}
+
+	if len(positional) != n {
+		fs.Usage()
+		return nil, usageErrorf("%s: expected %d arguments, got %d", fs.Name(), n, len(positional))
+	}
+	return positional, nil
+}
+
+func (c *cli) loadConfig() (*Config, error) {
+	cfg, err := LoadConfig(c.configFile)
+	if err != nil {
+		return nil, withCode(_EXIT_CONFIG, fmt.Errorf("failed to load config %s: %w", c.configFile, err))
+	}
+	return cfg, nil
+}
+
+// print writes v as JSON with -json, and with text otherwise.
+func (c *cli) print(v any, text func(w io.Writer)) error {
+	if c.json {
+		enc := json.NewEncoder(c.stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(v)
+	}
+	text(c.stdout)
+	return nil
+}
+
+// fail reports err on stderr, as a JSON object with -json.
+func (c *cli) fail(err error) {
+	if c.json {
+		json.NewEncoder(c.stderr).Encode(map[string]any{"error": err.Error(), "code": exitCode(err)})
+		return
+	}
+	fmt.Fprintf(c.stderr, "jimin: %v\n", err)
+}
+
+// app holds the connections shared by the commands using the database.
+type app struct {
+	cfg  *Config
+	pool *pgxpool.Pool
+	rf   *randflake.RandFlake
+	ids  *randflake.Generator
+}
+
+var (
+	ErrNoDatabase      = errors.New("database url is not configured")
+	ErrInvalidIDSecret = errors.New("database id_secret must be 16 bytes in hex")
+)
+
+const _RELEASE_TIMEOUT = time.Second * 10
+
+// connect opens a connection pool to the database of cfg.
+func connect(ctx context.Context, cfg *Config) (*pgxpool.Pool, error) {
+	if cfg.Database.URL == "" {
+		return nil, withCode(_EXIT_CONFIG, ErrNoDatabase)
+	}
+
+	pool, err := pgxpool.New(ctx, cfg.Database.URL)
+	if err != nil {
+		return nil, withCode(_EXIT_CONFIG, err)
+	}
+	if err := pool.Ping(ctx); err != nil {
+		pool.Close()
+		return nil, withCode(_EXIT_UNAVAILABLE, fmt.Errorf("failed to connect to the database: %w", err))
+	}
+	return pool, nil
+}
+
+// startIDs starts the randflake of cfg with its lease worker.
+func startIDs(pool *pgxpool.Pool, cfg *Config) (*randflake.RandFlake, error) {
+	secret, err := hex.DecodeString(cfg.Database.IDSecret)
+	if err != nil || len(secret) != 16 {
+		return nil, withCode(_EXIT_CONFIG, ErrInvalidIDSecret)
+	}
+
+	rf, err := randflake.NewRandFlake(pool, secret)
+	if err != nil {
+		return nil, withCode(_EXIT_CONFIG, err)
+	}
+	rf.Start()
+	return rf, nil
+}
+
+// open connects to the database of cfg and starts generating ids.
+func open(ctx context.Context, cfg *Config) (*app, error) {
+	pool, err := connect(ctx, cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	rf, err := startIDs(pool, cfg)
+	if err != nil {
+		pool.Close()
+		return nil, err
+	}
+	ids, err := rf.NewGenerator(ctx)
+	if err != nil {
+		rf.Close(ctx)
+		pool.Close()
+		return nil, withCode(_EXIT_UNAVAILABLE, fmt.Errorf("failed to lease an id range: %w", err))
+	}
+
+	return &app{cfg: cfg, pool: pool, rf: rf, ids: ids}, nil
+}
+
+func (a *app) Close() {
+	a.ids.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), _RELEASE_TIMEOUT)
+	defer cancel()
+	if err := a.rf.Close(ctx); err != nil {
+		log.Warn().Err(err).Msg("Failed to release the id leases")
+	}
+	a.pool.Close()
+}
+
+// open loads the config and connects to its database.
+func (c *cli) open(ctx context.Context) (*app, error) {
+	cfg, err := c.loadConfig()
+	if err != nil {
+		return nil, err
+	}
+	return open(ctx, cfg)
+}
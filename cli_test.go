@@ -0,0 +1,260 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+func TestExitCode(t *testing.T) {
+	tests := []struct {
+		err  error
+		want int
+	}{
+		{nil, _EXIT_OK},
+		{errors.New("boom"), _EXIT_FAILURE},
+		{usageErrorf("bad"), _EXIT_USAGE},
+		{withCode(_EXIT_CONFIG, errors.New("bad config")), _EXIT_CONFIG},
+		{pgx.ErrNoRows, _EXIT_NOT_FOUND},
+		{&pgconn.PgError{Code: "23505"}, _EXIT_CONFLICT},
+	}
+	for _, tt := range tests {
+		if got := exitCode(tt.err); got != tt.want {
+			t.Errorf("exitCode(%v) = %d, want %d", tt.err, got, tt.want)
+		}
+	}
+}
+
+func TestLookup(t *testing.T) {
+	cmd, rest, ok := lookup([]string{"user", "create", "-name", "a"})
+	if !ok || cmd.name != "user create" || strings.Join(rest, " ") != "-name a" {
+		t.Errorf("lookup(user create) = %q, %v, %v", cmd.name, rest, ok)
+	}
+	if _, _, ok := lookup([]string{"user"}); ok {
+		t.Error("lookup(user) found a command")
+	}
+}
+
+func TestParseInterspersed(t *testing.T) {
+	c := &cli{stderr: new(bytes.Buffer)}
+	fs := c.flags("search", "<query>")
+	ws := fs.Int64("ws", 0, "")
+
+	pos, err := c.parse(fs, []string{"hello world", "-ws", "7", "-json"}, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pos[0] != "hello world" || *ws != 7 || !c.json {
+		t.Errorf("parse() = %v, ws %d, json %v", pos, *ws, c.json)
+	}
+
+	if _, err := c.parse(fs, []string{"a", "b"}, 1); exitCode(err) != _EXIT_USAGE {
+		t.Errorf("parse() with extra arguments error = %v, want a usage error", err)
+	}
+}
+
+func TestRun(t *testing.T) {
+	dir := t.TempDir()
+	valid := filepath.Join(dir, "valid.jsonnet")
+	os.WriteFile(valid, []byte(`{
+		database: { url: "postgres://localhost/jimin", id_secret: "000102030405060708090a0b0c0d0e0f" },
+		providers: [{ name: "openai", type: "openai", api_key: "key" }],
+		model_configs: { chunk_generator: { model: "gpt", provider: "openai" } },
+		auth: {
+			base_url: "https://jimin.example",
+			oidc: [{ id: 2, name: "google", issuer: "https://accounts.google.com", client_id: "client" }],
+		},
+	}`), 0o644)
+	invalid := filepath.Join(dir, "invalid.jsonnet")
+	os.WriteFile(invalid, []byte(`{
+		providers: [{ name: "x", type: "unknown" }],
+		sources: [{ name: "blog", hosts: ["example.com"], recrawl: { cron: "every day" } }],
+	}`), 0o644)
+
+	tests := []struct {
+		name string
+		args []string
+		want int
+	}{
+		{"no command", nil, _EXIT_USAGE},
+		{"help", []string{"help"}, _EXIT_OK},
+		{"unknown command", []string{"frobnicate"}, _EXIT_USAGE},
+		{"command help", []string{"search", "-h"}, _EXIT_OK},
+		{"unknown flag", []string{"config", "check", "-nope"}, _EXIT_USAGE},
+		{"missing argument", []string{"search", "-ws", "1"}, _EXIT_USAGE},
+		{"missing workspace", []string{"search", "query"}, _EXIT_USAGE},
+		{"missing config", []string{"config", "check", "-config", filepath.Join(dir, "none.jsonnet")}, _EXIT_CONFIG},
+		{"valid config", []string{"config", "check", "-config", valid}, _EXIT_OK},
+		{"invalid config", []string{"config", "check", "-config", invalid}, _EXIT_CONFIG},
+		{"serve without database", []string{"serve", "-config", invalid}, _EXIT_CONFIG},
+		{"migrate without subcommand", []string{"migrate"}, _EXIT_USAGE},
+		{"migrate down without count", []string{"migrate", "down", "-config", valid, "none"}, _EXIT_USAGE},
+		{"migrate force negative", []string{"migrate", "force", "-config", valid, "-1"}, _EXIT_USAGE},
+		{"user admin without id", []string{"user", "admin", "-config", valid}, _EXIT_USAGE},
+		{"user admin invalid id", []string{"user", "admin", "-config", valid, "me"}, _EXIT_USAGE},
+		{"migrate missing dir", []string{"migrate", "up", "-config", valid, "-dir", filepath.Join(dir, "none")}, _EXIT_CONFIG},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var stdout, stderr bytes.Buffer
+			if got := run(context.Background(), tt.args, &stdout, &stderr); got != tt.want {
+				t.Errorf("run(%q) = %d, want %d\nstdout: %s\nstderr: %s", tt.args, got, tt.want, stdout.String(), stderr.String())
+			}
+		})
+	}
+}
+
+func TestRunJSON(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "config.jsonnet")
+	os.WriteFile(file, []byte(`{ providers: [{ name: "x", type: "unknown" }] }`), 0o644)
+
+	var stdout, stderr bytes.Buffer
+	if code := run(context.Background(), []string{"config", "check", "-json", "-config", file}, &stdout, &stderr); code != _EXIT_CONFIG {
+		t.Fatalf("run() = %d, want %d", code, _EXIT_CONFIG)
+	}
+
+	var out struct {
+		Valid  bool     `json:"valid"`
+		Errors []string `json:"errors"`
+	}
+	if err := json.Unmarshal(stdout.Bytes(), &out); err != nil {
+		t.Fatalf("stdout is not JSON: %v\n%s", err, stdout.String())
+	}
+	if out.Valid || len(out.Errors) == 0 {
+		t.Errorf("config check = %+v, want errors", out)
+	}
+
+	var fail struct {
+		Error string `json:"error"`
+		Code  int    `json:"code"`
+	}
+	if err := json.Unmarshal(stderr.Bytes(), &fail); err != nil || fail.Code != _EXIT_CONFIG {
+		t.Errorf("stderr = %s, want a JSON error with code %d", stderr.String(), _EXIT_CONFIG)
+	}
+}
+
+func TestEmbeddedMigrations(t *testing.T) {
+	ms, err := loadMigrations("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ms) == 0 || ms[0].Version != 241105 {
+		t.Fatalf("loadMigrations() = %d migrations", len(ms))
+	}
+	for _, m := range ms {
+		if m.Up == "" || m.Down == "" {
+			t.Errorf("migration %d_%s is missing a script", m.Version, m.Name)
+		}
+	}
+}
+
+func TestMarkdownTitle(t *testing.T) {
+	if got := markdownTitle("intro\n\n#  Getting started \n\n## Install", "file.md"); got != "Getting started" {
+		t.Errorf("markdownTitle() = %q", got)
+	}
+	if got := markdownTitle("## Install", "file.md"); got != "file.md" {
+		t.Errorf("markdownTitle() without a title = %q", got)
+	}
+}
+
+func TestCheckOIDC(t *testing.T) {
+	c := Config{Auth: AuthConfig{OIDC: []OIDCConfig{
+		{ID: 2, Name: "google", Issuer: "https://accounts.google.com", ClientID: "client", RedirectURL: "https://jimin.example/callback"},
+		{ID: 2, Name: "google", Issuer: "https://accounts.google.com", ClientID: "client", RedirectURL: "https://jimin.example/callback"},
+		{ID: 1, Name: "password", Issuer: "accounts.google.com"},
+	}}}
+
+	var problems []string
+	for _, err := range c.Check() {
+		if strings.HasPrefix(err.Error(), "auth.") {
+			problems = append(problems, err.Error())
+		}
+	}
+	want := []string{
+		"auth.oidc[1].id: duplicate id 2",
+		`auth.oidc[1].name: duplicate name "google"`,
+		"auth.oidc[2].id: must be greater than 1",
+		`auth.oidc[2].name: invalid name "password"`,
+		`auth.oidc[2].issuer: invalid URL "accounts.google.com"`,
+		"auth.oidc[2].client_id: required",
+		"auth.oidc[2].redirect_url: required without auth.base_url",
+	}
+	if !slices.Equal(problems, want) {
+		t.Errorf("Check() = %q, want %q", problems, want)
+	}
+
+	c.Auth.BaseURL = "https://jimin.example/"
+	if got := c.Auth.redirectURL(OIDCConfig{Name: "google"}); got != "https://jimin.example/v1/auth/oidc/google/callback" {
+		t.Errorf("redirect URL = %q", got)
+	}
+}
+
+func TestCheckMail(t *testing.T) {
+	c := Config{
+		Auth: AuthConfig{TokenSecret: "00ff"},
+		Mail: MailConfig{Type: "smtp", From: "noreply", SMTP: SMTPMailConfig{Addr: "smtp.example.com", TLS: "ssl"}},
+	}
+
+	var problems []string
+	for _, err := range c.Check() {
+		if s := err.Error(); strings.HasPrefix(s, "auth.") || strings.HasPrefix(s, "mail.") {
+			problems = append(problems, s)
+		}
+	}
+	want := []string{
+		ErrInvalidTokenSecret.Error(),
+		`mail.from: invalid address "noreply"`,
+		`mail.smtp.addr: invalid address "smtp.example.com"`,
+		`mail.smtp.tls: unknown TLS mode "ssl"`,
+		"auth.base_url: required for the links of the emails without verify_email_url and reset_password_url",
+	}
+	if !slices.Equal(problems, want) {
+		t.Errorf("Check() = %q, want %q", problems, want)
+	}
+
+	if _, err := (&Config{}).Mailer(); !errors.Is(err, ErrNoMailer) {
+		t.Errorf("Mailer() without a type error = %v, want %v", err, ErrNoMailer)
+	}
+	c = Config{Mail: MailConfig{Type: "file", From: "noreply@jimin.example", Dir: t.TempDir()}, Auth: AuthConfig{BaseURL: "https://jimin.example"}}
+	if errs := c.Check(); slices.ContainsFunc(errs, func(err error) bool { return strings.HasPrefix(err.Error(), "mail.") }) {
+		t.Errorf("Check() = %v", errs)
+	}
+	if _, err := c.Mailer(); err != nil {
+		t.Errorf("Mailer() = %v", err)
+	}
+	if got := c.Auth.page("", "/verify-email"); got != "https://jimin.example/verify-email" {
+		t.Errorf("verify email URL = %q", got)
+	}
+}
+
+func TestBlobStoreErrors(t *testing.T) {
+	if _, _, err := NewCrawler(&Config{}); err != nil {
+		t.Errorf("NewCrawler() without a blob store error = %v", err)
+	}
+	if _, err := newRecrawler(&Config{}, nil, nil, nil, nil, nil); err != nil {
+		t.Errorf("newRecrawler() without a blob store error = %v", err)
+	}
+
+	// the blob directory can not be created under a file
+	file := filepath.Join(t.TempDir(), "file")
+	if err := os.WriteFile(file, nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	c := &Config{Blobs: BlobConfig{Dir: filepath.Join(file, "blobs")}}
+	if _, _, err := NewCrawler(c); err == nil {
+		t.Error("NewCrawler() with an unusable blob store succeeded")
+	}
+	if _, err := newRecrawler(c, nil, nil, nil, nil, nil); err == nil {
+		t.Error("newRecrawler() with an unusable blob store succeeded")
+	}
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"time"
+
+	"gosuda.org/jimin/internal/crawler"
+	"gosuda.org/jimin/internal/warc"
+)
+
+type CrawlerConfig struct {
+	Concurrency int `json:"concurrency,omitempty"`
+	// Timeout is the page timeout in seconds.
+	Timeout int `json:"timeout,omitempty"`
+	// WARCDir enables writing the crawled responses to WARC files in the directory.
+	WARCDir string `json:"warc_dir,omitempty"`
+	// Proxies route the crawls of matching hosts through a proxy. Other hosts are crawled directly.
+	Proxies []crawler.Proxy `json:"proxies,omitempty"`
+}
+
+// NewCrawler returns the crawler for the config and a function that releases its resources.
+func NewCrawler(c *Config) (*crawler.Crawler, func() error, error) {
+	concurrency := c.Crawler.Concurrency
+	if concurrency <= 0 {
+		concurrency = 4
+	}
+	timeout := time.Duration(c.Crawler.Timeout) * time.Second
+	if timeout <= 0 {
+		timeout = time.Minute
+	}
+
+	profiles, err := crawler.LoadProfiles(c.Profiles)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	proxies, err := crawler.NewProxyRouter(c.Crawler.Proxies)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	opts := []crawler.CrawlerOption{crawler.WithProfiles(profiles), crawler.WithProxies(proxies)}
+	closer := func() error { return nil }
+
+	store, err := c.BlobStore()
+	switch {
+	case err == nil:
+		opts = append(opts, crawler.WithSnapshotStore(store))
+	case !errors.Is(err, ErrNoBlobStore):
+		return nil, nil, err
+	}
+
+	if c.Crawler.WARCDir != "" {
+		if err := os.MkdirAll(c.Crawler.WARCDir, 0o755); err != nil {
+			return nil, nil, err
+		}
+
+		name := "jimin-" + time.Now().UTC().Format("20060102150405") + ".warc.gz"
+		f, err := os.Create(filepath.Join(c.Crawler.WARCDir, name))
+		if err != nil {
+			return nil, nil, err
+		}
+
+		w := warc.NewWriter(f, true)
+		if _, err := w.WriteInfo(name, map[string]string{"software": "jimin", "format": "WARC File Format 1.1"}); err != nil {
+			f.Close()
+			return nil, nil, err
+		}
+		opts = append(opts, crawler.WithWARC(w))
+		closer = f.Close
+	}
+
+	cr := crawler.NewCrawler(concurrency, timeout, opts...)
+	return cr, func() error {
+		cr.Close()
+		return closer()
+	}, nil
+}
@@ -0,0 +1,12 @@
+-- name: CreateDocumentAsset :exec
+INSERT INTO document_assets (id, ws_id, document_id, version_id, source_url, blob_key, content_type, size, caption)
+    VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9);
+
+-- name: ListDocumentVersionAssets :many
+SELECT * FROM document_assets WHERE version_id = $1 ORDER BY id ASC;
+
+-- name: GetAssetByKey :one
+SELECT * FROM document_assets WHERE blob_key = $1 AND ws_id = $2 LIMIT 1;
+
+-- name: ListAssetWorkspaces :many
+SELECT DISTINCT ws_id FROM document_assets WHERE blob_key = $1 ORDER BY ws_id ASC;
@@ -0,0 +1,115 @@
+-- name: CreateUserAuth :one
+INSERT INTO users_auth (id, user_id, provider_id, provider_subject, associated_data) VALUES ($1, $2, $3, $4, $5) RETURNING *;
+
+-- name: GetUserAuth :one
+SELECT * FROM users_auth WHERE user_id = $1 AND provider_id = $2;
+
+-- name: UpdateUserAuthData :execrows
+UPDATE users_auth
+    SET associated_data = sqlc.arg(associated_data), updated_at = NOW()
+    WHERE
+        user_id = sqlc.arg(user_id)
+        AND provider_id = sqlc.arg(provider_id)
+        AND associated_data = sqlc.arg(old_associated_data);
+
+-- name: CreateSession :one
+INSERT INTO sessions (id, user_id, token_hash, user_agent, ip_address, expires_at) VALUES ($1, $2, $3, $4, $5, $6) RETURNING *;
+
+-- name: GetSessionByTokenHash :one
+SELECT * FROM sessions WHERE token_hash = $1;
+
+-- name: TouchSession :exec
+UPDATE sessions SET expires_at = $2, last_seen_at = NOW() WHERE id = $1 AND revoked_at IS NULL;
+
+-- name: ListUserSessions :many
+SELECT * FROM sessions WHERE user_id = $1 AND revoked_at IS NULL AND expires_at > NOW() ORDER BY id DESC;
+
+-- name: RevokeSession :execrows
+UPDATE sessions SET revoked_at = NOW() WHERE id = $1 AND user_id = $2 AND revoked_at IS NULL;
+
+-- name: RevokeUserSessions :exec
+UPDATE sessions
+    SET revoked_at = NOW()
+    WHERE
+        user_id = sqlc.arg(user_id)
+        AND id <> sqlc.arg(except_id)
+        AND revoked_at IS NULL;
+
+-- name: DeleteExpiredSessions :execrows
+DELETE FROM sessions WHERE expires_at < $1 OR revoked_at < $1;
+
+-- name: GetUserAuthBySubject :one
+SELECT * FROM users_auth WHERE provider_id = $1 AND provider_subject = $2;
+
+-- name: ListUserAuths :many
+SELECT * FROM users_auth WHERE user_id = $1 ORDER BY provider_id ASC;
+
+-- name: LockUserAuths :many
+SELECT provider_id FROM users_auth WHERE user_id = $1 ORDER BY provider_id ASC FOR UPDATE;
+
+-- name: DeleteUserAuth :execrows
+DELETE FROM users_auth WHERE user_id = $1 AND provider_id = $2;
+
+-- name: DeleteUserAuths :exec
+DELETE FROM users_auth WHERE user_id = $1;
+
+-- name: CreateOIDCLogin :exec
+INSERT INTO oidc_logins (id, state_hash, provider_id, nonce, code_verifier, link_user_id, return_to, expires_at) VALUES ($1, $2, $3, $4, $5, $6, $7, $8);
+
+-- name: TakeOIDCLogin :one
+DELETE FROM oidc_logins WHERE state_hash = $1 RETURNING *;
+
+-- name: DeleteExpiredOIDCLogins :execrows
+DELETE FROM oidc_logins WHERE expires_at < $1;
+
+-- name: UpsertUserAuth :exec
+INSERT INTO users_auth (id, user_id, provider_id, provider_subject, associated_data) VALUES ($1, $2, $3, $4, $5)
+ON CONFLICT (user_id, provider_id) DO UPDATE SET associated_data = EXCLUDED.associated_data, updated_at = NOW();
+
+-- name: CreateEmailToken :one
+INSERT INTO email_tokens (id, user_id, purpose, email, expires_at) VALUES ($1, $2, $3, $4, $5) RETURNING *;
+
+-- name: CountRecentEmailTokens :one
+SELECT
+    COUNT(*) AS sent,
+    COALESCE(MAX(created_at), '-infinity')::TIMESTAMPTZ AS last_sent_at
+FROM email_tokens
+WHERE user_id = $1 AND purpose = $2 AND created_at > $3;
+
+-- name: UseEmailToken :one
+UPDATE email_tokens SET used_at = NOW()
+WHERE id = $1 AND purpose = $2 AND used_at IS NULL AND expires_at > NOW()
+RETURNING *;
+
+-- name: ExpireEmailTokens :exec
+UPDATE email_tokens SET used_at = NOW() WHERE user_id = $1 AND purpose = $2 AND used_at IS NULL;
+
+-- name: DeleteExpiredEmailTokens :execrows
+DELETE FROM email_tokens WHERE expires_at < $1;
+
+-- name: CreateAPIToken :one
+INSERT INTO api_tokens (id, user_id, ws_id, name, token_hash, prefix, scopes, expires_at) VALUES ($1, $2, $3, $4, $5, $6, $7, $8) RETURNING *;
+
+-- name: GetAPITokenByHash :one
+SELECT * FROM api_tokens WHERE token_hash = $1;
+
+-- name: TouchAPIToken :exec
+UPDATE api_tokens SET last_used_at = NOW() WHERE id = $1;
+
+-- name: ListUserAPITokens :many
+SELECT * FROM api_tokens WHERE user_id = $1 AND ws_id = 0 AND revoked_at IS NULL ORDER BY id DESC;
+
+-- name: ListWorkspaceAPITokens :many
+SELECT * FROM api_tokens WHERE ws_id = $1 AND revoked_at IS NULL ORDER BY id DESC;
+
+-- name: RevokeUserAPIToken :execrows
+UPDATE api_tokens SET revoked_at = NOW() WHERE id = $1 AND user_id = $2 AND ws_id = 0 AND revoked_at IS NULL;
+
+-- name: RevokeUserAPITokens :exec
+UPDATE api_tokens SET revoked_at = NOW() WHERE user_id = $1 AND ws_id = 0 AND revoked_at IS NULL;
+
+-- name: RevokeWorkspaceAPIToken :execrows
+UPDATE api_tokens SET revoked_at = NOW() WHERE id = $1 AND ws_id = $2 AND ws_id <> 0 AND revoked_at IS NULL;
+
+-- name: DeleteExpiredAPITokens :execrows
+DELETE FROM api_tokens WHERE expires_at < $1 OR revoked_at < $1;
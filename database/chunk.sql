@@ -0,0 +1,18 @@
+-- name: CreateDocumentChunk :exec
+INSERT INTO document_chunks (id, ws_id, document_id, version_id, seq, heading, content, start_offset, end_offset, embedding)
+    VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10);
+
+-- name: DeleteDocumentChunks :exec
+DELETE FROM document_chunks WHERE document_id = $1;
+
+-- name: ListDocumentChunks :many
+SELECT * FROM document_chunks WHERE document_id = $1 ORDER BY seq ASC;
+
+-- name: SetDocumentVersionSummary :exec
+UPDATE document_versions SET summary = $2, tags = $3 WHERE id = $1;
+
+-- name: ListDocumentChunksAfter :many
+SELECT * FROM document_chunks
+    WHERE document_id = sqlc.arg(document_id) AND seq > sqlc.arg(after_seq)
+    ORDER BY seq ASC
+    LIMIT sqlc.arg(page_size);
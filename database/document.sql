@@ -0,0 +1,79 @@
+-- name: CreateDocument :one
+INSERT INTO documents (id, ws_id, source, url, title, recrawl_interval, next_crawl_at) VALUES ($1, $2, $3, $4, $5, $6, $7) RETURNING *;
+
+-- name: GetDocument :one
+SELECT * FROM documents WHERE id = $1;
+
+-- name: GetDocumentByURL :one
+SELECT * FROM documents WHERE ws_id = $1 AND url = $2;
+
+-- name: ClaimDueDocuments :many
+UPDATE documents
+    SET
+        next_crawl_at = sqlc.arg(lease_until),
+        updated_at = NOW()
+    WHERE id IN (
+        SELECT id FROM documents
+            WHERE next_crawl_at <= sqlc.arg(next_crawl_at)
+            ORDER BY next_crawl_at ASC
+            LIMIT sqlc.arg(page_size)
+            FOR UPDATE SKIP LOCKED
+    )
+    RETURNING *;
+
+-- name: UpdateDocumentCrawl :exec
+UPDATE documents
+    SET
+        etag = $2,
+        last_modified = $3,
+        recrawl_interval = $4,
+        unchanged_count = $5,
+        last_crawled_at = $6,
+        next_crawl_at = $7,
+        updated_at = NOW()
+    WHERE id = $1;
+
+-- name: SetDocumentVersion :exec
+UPDATE documents
+    SET
+        current_version_id = $2,
+        title = $3,
+        content_hash = $4,
+        last_changed_at = $5,
+        updated_at = NOW()
+    WHERE id = $1;
+
+-- name: CreateDocumentVersion :one
+INSERT INTO document_versions (
+        id, document_id, version, title, content, content_hash, etag, last_modified, fetched_at,
+        raw_key, raw_content_type, converter_version, chunker_version
+    )
+    SELECT $1, $2, COALESCE(MAX(version), 0) + 1, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12
+        FROM document_versions WHERE document_id = $2
+RETURNING *;
+
+-- name: SetDocumentVersionProcessors :exec
+UPDATE document_versions SET converter_version = $2, chunker_version = $3 WHERE id = $1;
+
+-- name: GetDocumentVersion :one
+SELECT * FROM document_versions WHERE document_id = $1 AND version = $2;
+
+-- name: GetDocumentVersionByID :one
+SELECT * FROM document_versions WHERE id = $1;
+
+-- name: ListDocumentVersions :many
+SELECT id, document_id, version, title, content_hash, fetched_at, created_at FROM document_versions
+    WHERE document_id = $1
+    ORDER BY version DESC;
+
+-- name: ListDocuments :many
+SELECT * FROM documents
+    WHERE
+        ws_id = sqlc.arg(ws_id)
+        AND id > sqlc.arg(after_id)
+        AND (sqlc.arg(source)::TEXT = '' OR source = sqlc.arg(source))
+    ORDER BY id ASC
+    LIMIT sqlc.arg(page_size);
+
+-- name: CountDocumentsBySource :many
+SELECT source, COUNT(*) AS documents FROM documents WHERE ws_id = $1 GROUP BY source ORDER BY source ASC;
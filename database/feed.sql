@@ -0,0 +1,41 @@
+-- name: CreateFeed :one
+INSERT INTO feeds (id, ws_id, url, title, type, status, discovered_from) VALUES ($1, $2, $3, $4, $5, $6, $7)
+    ON CONFLICT (ws_id, url) DO NOTHING
+RETURNING *;
+
+-- name: GetFeed :one
+SELECT * FROM feeds WHERE id = $1;
+
+-- name: GetFeedByURL :one
+SELECT * FROM feeds WHERE ws_id = $1 AND url = $2;
+
+-- name: ListFeeds :many
+SELECT * FROM feeds WHERE ws_id = $1 AND status = $2 ORDER BY id ASC;
+
+-- name: SetFeedStatus :one
+UPDATE feeds SET status = $2, subscribed_by = $3, next_fetch_at = NOW(), updated_at = NOW() WHERE id = $1 RETURNING *;
+
+-- name: ClaimDueFeeds :many
+UPDATE feeds
+    SET
+        next_fetch_at = sqlc.arg(lease_until),
+        updated_at = NOW()
+    WHERE id IN (
+        SELECT id FROM feeds
+            WHERE status = 'SUBSCRIBED' AND next_fetch_at <= sqlc.arg(next_fetch_at)
+            ORDER BY next_fetch_at ASC
+            LIMIT sqlc.arg(page_size)
+            FOR UPDATE SKIP LOCKED
+    )
+    RETURNING *;
+
+-- name: UpdateFeedFetch :exec
+UPDATE feeds
+    SET
+        etag = $2,
+        last_modified = $3,
+        fetch_error = $4,
+        last_fetched_at = $5,
+        next_fetch_at = $6,
+        updated_at = NOW()
+    WHERE id = $1;
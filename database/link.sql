@@ -0,0 +1,48 @@
+-- name: CreateLink :exec
+INSERT INTO links (id, ws_id, document_id, url, kind, next_check_at) VALUES ($1, $2, $3, $4, $5, $6)
+    ON CONFLICT (document_id, url) DO NOTHING;
+
+-- name: DeleteStaleOutlinks :exec
+DELETE FROM links WHERE document_id = sqlc.arg(document_id) AND kind = 'OUTLINK' AND NOT (url = ANY(sqlc.arg(urls)::TEXT[]));
+
+-- name: ClaimDueLinks :many
+UPDATE links
+    SET
+        next_check_at = sqlc.arg(lease_until),
+        updated_at = NOW()
+    WHERE id IN (
+        SELECT id FROM links
+            WHERE next_check_at <= sqlc.arg(next_check_at)
+            ORDER BY next_check_at ASC
+            LIMIT sqlc.arg(page_size)
+            FOR UPDATE SKIP LOCKED
+    )
+    RETURNING *;
+
+-- name: UpdateLinkStatus :exec
+UPDATE links
+    SET
+        status = $2,
+        status_code = $3,
+        failures = $4,
+        last_checked_at = $5,
+        next_check_at = $6,
+        updated_at = NOW()
+    WHERE id = $1;
+
+-- name: CreateLinkCheck :exec
+INSERT INTO link_checks (id, link_id, status, status_code, error, checked_at) VALUES ($1, $2, $3, $4, $5, $6);
+
+-- name: ListLinkChecks :many
+SELECT * FROM link_checks WHERE link_id = $1 ORDER BY checked_at DESC LIMIT $2;
+
+-- name: ListBrokenLinks :many
+SELECT * FROM links
+    WHERE
+        ws_id = $1
+        AND status IN ('BROKEN', 'PARKED')
+        AND id > $2
+    ORDER BY id ASC LIMIT $3;
+
+-- name: SetDocumentLinkStatus :exec
+UPDATE documents SET link_status = $2, updated_at = NOW() WHERE id = $1;
@@ -11,12 +11,185 @@ import (
 	"github.com/jackc/pgx/v5/pgtype"
 )
 
+type EmailTokenPurpose string
+
+const (
+	EmailTokenPurposeVERIFYEMAIL   EmailTokenPurpose = "VERIFY_EMAIL"
+	EmailTokenPurposeRESETPASSWORD EmailTokenPurpose = "RESET_PASSWORD"
+)
+
+func (e *EmailTokenPurpose) Scan(src interface{}) error {
+	switch s := src.(type) {
+	case []byte:
+		*e = EmailTokenPurpose(s)
+	case string:
+		*e = EmailTokenPurpose(s)
+	default:
+		return fmt.Errorf("unsupported scan type for EmailTokenPurpose: %T", src)
+	}
+	return nil
+}
+
+type NullEmailTokenPurpose struct {
+	EmailTokenPurpose EmailTokenPurpose `json:"email_token_purpose"`
+	Valid             bool              `json:"valid"` // Valid is true if EmailTokenPurpose is not NULL
+}
+
+// Scan implements the Scanner interface.
+func (ns *NullEmailTokenPurpose) Scan(value interface{}) error {
+	if value == nil {
+		ns.EmailTokenPurpose, ns.Valid = "", false
+		return nil
+	}
+	ns.Valid = true
+	return ns.EmailTokenPurpose.Scan(value)
+}
+
+// Value implements the driver Valuer interface.
+func (ns NullEmailTokenPurpose) Value() (driver.Value, error) {
+	if !ns.Valid {
+		return nil, nil
+	}
+	return string(ns.EmailTokenPurpose), nil
+}
+
+type FeedStatus string
+
+const (
+	FeedStatusSUGGESTED  FeedStatus = "SUGGESTED"
+	FeedStatusSUBSCRIBED FeedStatus = "SUBSCRIBED"
+	FeedStatusDISMISSED  FeedStatus = "DISMISSED"
+)
+
+func (e *FeedStatus) Scan(src interface{}) error {
+	switch s := src.(type) {
+	case []byte:
+		*e = FeedStatus(s)
+	case string:
+		*e = FeedStatus(s)
+	default:
+		return fmt.Errorf("unsupported scan type for FeedStatus: %T", src)
+	}
+	return nil
+}
+
+type NullFeedStatus struct {
+	FeedStatus FeedStatus `json:"feed_status"`
+	Valid      bool       `json:"valid"` // Valid is true if FeedStatus is not NULL
+}
+
+// Scan implements the Scanner interface.
+func (ns *NullFeedStatus) Scan(value interface{}) error {
+	if value == nil {
+		ns.FeedStatus, ns.Valid = "", false
+		return nil
+	}
+	ns.Valid = true
+	return ns.FeedStatus.Scan(value)
+}
+
+// Value implements the driver Valuer interface.
+func (ns NullFeedStatus) Value() (driver.Value, error) {
+	if !ns.Valid {
+		return nil, nil
+	}
+	return string(ns.FeedStatus), nil
+}
+
+type LinkKind string
+
+const (
+	LinkKindSOURCE  LinkKind = "SOURCE"
+	LinkKindOUTLINK LinkKind = "OUTLINK"
+)
+
+func (e *LinkKind) Scan(src interface{}) error {
+	switch s := src.(type) {
+	case []byte:
+		*e = LinkKind(s)
+	case string:
+		*e = LinkKind(s)
+	default:
+		return fmt.Errorf("unsupported scan type for LinkKind: %T", src)
+	}
+	return nil
+}
+
+type NullLinkKind struct {
+	LinkKind LinkKind `json:"link_kind"`
+	Valid    bool     `json:"valid"` // Valid is true if LinkKind is not NULL
+}
+
+// Scan implements the Scanner interface.
+func (ns *NullLinkKind) Scan(value interface{}) error {
+	if value == nil {
+		ns.LinkKind, ns.Valid = "", false
+		return nil
+	}
+	ns.Valid = true
+	return ns.LinkKind.Scan(value)
+}
+
+// Value implements the driver Valuer interface.
+func (ns NullLinkKind) Value() (driver.Value, error) {
+	if !ns.Valid {
+		return nil, nil
+	}
+	return string(ns.LinkKind), nil
+}
+
+type LinkStatus string
+
+const (
+	LinkStatusUNKNOWN LinkStatus = "UNKNOWN"
+	LinkStatusOK      LinkStatus = "OK"
+	LinkStatusBROKEN  LinkStatus = "BROKEN"
+	LinkStatusPARKED  LinkStatus = "PARKED"
+	LinkStatusERROR   LinkStatus = "ERROR"
+)
+
+func (e *LinkStatus) Scan(src interface{}) error {
+	switch s := src.(type) {
+	case []byte:
+		*e = LinkStatus(s)
+	case string:
+		*e = LinkStatus(s)
+	default:
+		return fmt.Errorf("unsupported scan type for LinkStatus: %T", src)
+	}
+	return nil
+}
+
+type NullLinkStatus struct {
+	LinkStatus LinkStatus `json:"link_status"`
+	Valid      bool       `json:"valid"` // Valid is true if LinkStatus is not NULL
+}
+
+// Scan implements the Scanner interface.
+func (ns *NullLinkStatus) Scan(value interface{}) error {
+	if value == nil {
+		ns.LinkStatus, ns.Valid = "", false
+		return nil
+	}
+	ns.Valid = true
+	return ns.LinkStatus.Scan(value)
+}
+
+// Value implements the driver Valuer interface.
+func (ns NullLinkStatus) Value() (driver.Value, error) {
+	if !ns.Valid {
+		return nil, nil
+	}
+	return string(ns.LinkStatus), nil
+}
+
 type RelationType string
 
 const (
-	RelationTypeINCLUDE RelationType = "INCLUDE"
-	RelationTypeREWRITE RelationType = "REWRITE"
-	RelationTypeOTHER   RelationType = "OTHER"
+	RelationTypeINCLUDE   RelationType = "INCLUDE"
+	RelationTypeREWRITE   RelationType = "REWRITE"
+	RelationTypeOTHER     RelationType = "OTHER"
+	RelationTypeDUPLICATE RelationType = "DUPLICATE"
 )
 
 func (e *RelationType) Scan(src interface{}) error {
@@ -54,6 +227,256 @@ func (ns NullRelationType) Value() (driver.Value, error) {
 	return string(ns.RelationType), nil
 }
 
+type ReprocessStatus string
+
+const (
+	ReprocessStatusPENDING  ReprocessStatus = "PENDING"
+	ReprocessStatusRUNNING  ReprocessStatus = "RUNNING"
+	ReprocessStatusDONE     ReprocessStatus = "DONE"
+	ReprocessStatusFAILED   ReprocessStatus = "FAILED"
+	ReprocessStatusCANCELED ReprocessStatus = "CANCELED"
+)
+
+func (e *ReprocessStatus) Scan(src interface{}) error {
+	switch s := src.(type) {
+	case []byte:
+		*e = ReprocessStatus(s)
+	case string:
+		*e = ReprocessStatus(s)
+	default:
+		return fmt.Errorf("unsupported scan type for ReprocessStatus: %T", src)
+	}
+	return nil
+}
+
+type NullReprocessStatus struct {
+	ReprocessStatus ReprocessStatus `json:"reprocess_status"`
+	Valid           bool            `json:"valid"` // Valid is true if ReprocessStatus is not NULL
+}
+
+// Scan implements the Scanner interface.
+func (ns *NullReprocessStatus) Scan(value interface{}) error {
+	if value == nil {
+		ns.ReprocessStatus, ns.Valid = "", false
+		return nil
+	}
+	ns.Valid = true
+	return ns.ReprocessStatus.Scan(value)
+}
+
+// Value implements the driver Valuer interface.
+func (ns NullReprocessStatus) Value() (driver.Value, error) {
+	if !ns.Valid {
+		return nil, nil
+	}
+	return string(ns.ReprocessStatus), nil
+}
+
+type WsMemberRole string
+
+const (
+	WsMemberRoleOWNER  WsMemberRole = "OWNER"
+	WsMemberRoleADMIN  WsMemberRole = "ADMIN"
+	WsMemberRoleEDITOR WsMemberRole = "EDITOR"
+	WsMemberRoleVIEWER WsMemberRole = "VIEWER"
+)
+
+func (e *WsMemberRole) Scan(src interface{}) error {
+	switch s := src.(type) {
+	case []byte:
+		*e = WsMemberRole(s)
+	case string:
+		*e = WsMemberRole(s)
+	default:
+		return fmt.Errorf("unsupported scan type for WsMemberRole: %T", src)
+	}
+	return nil
+}
+
+type NullWsMemberRole struct {
+	WsMemberRole WsMemberRole `json:"ws_member_role"`
+	Valid        bool         `json:"valid"` // Valid is true if WsMemberRole is not NULL
+}
+
+// Scan implements the Scanner interface.
+func (ns *NullWsMemberRole) Scan(value interface{}) error {
+	if value == nil {
+		ns.WsMemberRole, ns.Valid = "", false
+		return nil
+	}
+	ns.Valid = true
+	return ns.WsMemberRole.Scan(value)
+}
+
+// Value implements the driver Valuer interface.
+func (ns NullWsMemberRole) Value() (driver.Value, error) {
+	if !ns.Valid {
+		return nil, nil
+	}
+	return string(ns.WsMemberRole), nil
+}
+
+type ApiToken struct {
+	ID         int64              `json:"id"`
+	UserID     int64              `json:"user_id"`
+	WsID       int64              `json:"ws_id"`
+	Name       string             `json:"name"`
+	TokenHash  []byte             `json:"token_hash"`
+	Prefix     string             `json:"prefix"`
+	Scopes     []string           `json:"scopes"`
+	ExpiresAt  pgtype.Timestamptz `json:"expires_at"`
+	LastUsedAt pgtype.Timestamptz `json:"last_used_at"`
+	RevokedAt  pgtype.Timestamptz `json:"revoked_at"`
+	CreatedAt  pgtype.Timestamptz `json:"created_at"`
+}
+
+type Document struct {
+	ID               int64              `json:"id"`
+	WsID             int64              `json:"ws_id"`
+	Source           string             `json:"source"`
+	Url              string             `json:"url"`
+	Title            string             `json:"title"`
+	CurrentVersionID int64              `json:"current_version_id"`
+	ContentHash      string             `json:"content_hash"`
+	Etag             string             `json:"etag"`
+	LastModified     string             `json:"last_modified"`
+	RecrawlInterval  int64              `json:"recrawl_interval"`
+	UnchangedCount   int32              `json:"unchanged_count"`
+	LastCrawledAt    pgtype.Timestamptz `json:"last_crawled_at"`
+	LastChangedAt    pgtype.Timestamptz `json:"last_changed_at"`
+	NextCrawlAt      pgtype.Timestamptz `json:"next_crawl_at"`
+	CreatedAt        pgtype.Timestamptz `json:"created_at"`
+	UpdatedAt        pgtype.Timestamptz `json:"updated_at"`
+	LinkStatus       LinkStatus         `json:"link_status"`
+	Simhash          int64              `json:"simhash"`
+	SimhashB0        int32              `json:"simhash_b0"`
+	SimhashB1        int32              `json:"simhash_b1"`
+	SimhashB2        int32              `json:"simhash_b2"`
+	SimhashB3        int32              `json:"simhash_b3"`
+}
+
+type DocumentAsset struct {
+	ID          int64              `json:"id"`
+	WsID        int64              `json:"ws_id"`
+	DocumentID  int64              `json:"document_id"`
+	VersionID   int64              `json:"version_id"`
+	SourceUrl   string             `json:"source_url"`
+	BlobKey     string             `json:"blob_key"`
+	ContentType string             `json:"content_type"`
+	Size        int64              `json:"size"`
+	Caption     string             `json:"caption"`
+	CreatedAt   pgtype.Timestamptz `json:"created_at"`
+}
+
+type DocumentChunk struct {
+	ID          int64              `json:"id"`
+	WsID        int64              `json:"ws_id"`
+	DocumentID  int64              `json:"document_id"`
+	VersionID   int64              `json:"version_id"`
+	Seq         int32              `json:"seq"`
+	Heading     string             `json:"heading"`
+	Content     string             `json:"content"`
+	StartOffset int32              `json:"start_offset"`
+	EndOffset   int32              `json:"end_offset"`
+	Embedding   []float32          `json:"embedding"`
+	CreatedAt   pgtype.Timestamptz `json:"created_at"`
+}
+
+type DocumentSnapshot struct {
+	ID          int64              `json:"id"`
+	WsID        int64              `json:"ws_id"`
+	DocumentID  int64              `json:"document_id"`
+	VersionID   int64              `json:"version_id"`
+	Kind        string             `json:"kind"`
+	BlobKey     string             `json:"blob_key"`
+	ContentType string             `json:"content_type"`
+	Size        int64              `json:"size"`
+	CreatedAt   pgtype.Timestamptz `json:"created_at"`
+}
+
+type DocumentVersion struct {
+	ID               int64              `json:"id"`
+	DocumentID       int64              `json:"document_id"`
+	Version          int32              `json:"version"`
+	Title            string             `json:"title"`
+	Content          string             `json:"content"`
+	ContentHash      string             `json:"content_hash"`
+	Etag             string             `json:"etag"`
+	LastModified     string             `json:"last_modified"`
+	FetchedAt        pgtype.Timestamptz `json:"fetched_at"`
+	CreatedAt        pgtype.Timestamptz `json:"created_at"`
+	RawKey           string             `json:"raw_key"`
+	RawContentType   string             `json:"raw_content_type"`
+	ConverterVersion int32              `json:"converter_version"`
+	ChunkerVersion   int32              `json:"chunker_version"`
+	Summary          string             `json:"summary"`
+	Tags             []string           `json:"tags"`
+}
+
+type EmailToken struct {
+	ID        int64              `json:"id"`
+	UserID    int64              `json:"user_id"`
+	Purpose   EmailTokenPurpose  `json:"purpose"`
+	Email     string             `json:"email"`
+	ExpiresAt pgtype.Timestamptz `json:"expires_at"`
+	UsedAt    pgtype.Timestamptz `json:"used_at"`
+	CreatedAt pgtype.Timestamptz `json:"created_at"`
+}
+
+type Feed struct {
+	ID             int64              `json:"id"`
+	WsID           int64              `json:"ws_id"`
+	Url            string             `json:"url"`
+	Title          string             `json:"title"`
+	Type           string             `json:"type"`
+	Status         FeedStatus         `json:"status"`
+	DiscoveredFrom string             `json:"discovered_from"`
+	CreatedAt      pgtype.Timestamptz `json:"created_at"`
+	UpdatedAt      pgtype.Timestamptz `json:"updated_at"`
+	Etag           string             `json:"etag"`
+	LastModified   string             `json:"last_modified"`
+	FetchError     string             `json:"fetch_error"`
+	LastFetchedAt  pgtype.Timestamptz `json:"last_fetched_at"`
+	NextFetchAt    pgtype.Timestamptz `json:"next_fetch_at"`
+	SubscribedBy   int64              `json:"subscribed_by"`
+}
+
+type Link struct {
+	ID            int64              `json:"id"`
+	WsID          int64              `json:"ws_id"`
+	DocumentID    int64              `json:"document_id"`
+	Url           string             `json:"url"`
+	Kind          LinkKind           `json:"kind"`
+	Status        LinkStatus         `json:"status"`
+	StatusCode    int32              `json:"status_code"`
+	Failures      int32              `json:"failures"`
+	LastCheckedAt pgtype.Timestamptz `json:"last_checked_at"`
+	NextCheckAt   pgtype.Timestamptz `json:"next_check_at"`
+	CreatedAt     pgtype.Timestamptz `json:"created_at"`
+	UpdatedAt     pgtype.Timestamptz `json:"updated_at"`
+}
+
+type LinkCheck struct {
+	ID         int64              `json:"id"`
+	LinkID     int64              `json:"link_id"`
+	Status     LinkStatus         `json:"status"`
+	StatusCode int32              `json:"status_code"`
+	Error      string             `json:"error"`
+	CheckedAt  pgtype.Timestamptz `json:"checked_at"`
+}
+
+type OidcLogin struct {
+	ID           int64              `json:"id"`
+	StateHash    []byte             `json:"state_hash"`
+	ProviderID   int64              `json:"provider_id"`
+	Nonce        string             `json:"nonce"`
+	CodeVerifier string             `json:"code_verifier"`
+	LinkUserID   int64              `json:"link_user_id"`
+	ReturnTo     string             `json:"return_to"`
+	ExpiresAt    pgtype.Timestamptz `json:"expires_at"`
+	CreatedAt    pgtype.Timestamptz `json:"created_at"`
+}
+
 type RandflakeNode struct {
 	ID          int64       `json:"id"`
 	RangeStart  int64       `json:"range_start"`
@@ -63,6 +486,35 @@ type RandflakeNode struct {
 	LeaseEnd    int64       `json:"lease_end"`
 }
 
+type ReprocessJob struct {
+	ID             int64              `json:"id"`
+	WsID           int64              `json:"ws_id"`
+	Source         string             `json:"source"`
+	UrlPrefix      string             `json:"url_prefix"`
+	StaleOnly      bool               `json:"stale_only"`
+	Status         ReprocessStatus    `json:"status"`
+	LastDocumentID int64              `json:"last_document_id"`
+	Processed      int32              `json:"processed"`
+	Failed         int32              `json:"failed"`
+	Error          string             `json:"error"`
+	HeartbeatAt    pgtype.Timestamptz `json:"heartbeat_at"`
+	CreatedAt      pgtype.Timestamptz `json:"created_at"`
+	UpdatedAt      pgtype.Timestamptz `json:"updated_at"`
+	CreatedBy      int64              `json:"created_by"`
+}
+
+type Session struct {
+	ID         int64              `json:"id"`
+	UserID     int64              `json:"user_id"`
+	TokenHash  []byte             `json:"token_hash"`
+	UserAgent  string             `json:"user_agent"`
+	IpAddress  string             `json:"ip_address"`
+	ExpiresAt  pgtype.Timestamptz `json:"expires_at"`
+	RevokedAt  pgtype.Timestamptz `json:"revoked_at"`
+	LastSeenAt pgtype.Timestamptz `json:"last_seen_at"`
+	CreatedAt  pgtype.Timestamptz `json:"created_at"`
+}
+
 type User struct {
 	ID            int64              `json:"id"`
 	Name          string             `json:"name"`
@@ -70,6 +522,7 @@ type User struct {
 	EmailVerified bool               `json:"email_verified"`
 	CreatedAt     pgtype.Timestamptz `json:"created_at"`
 	UpdatedAt     pgtype.Timestamptz `json:"updated_at"`
+	IsAdmin       bool               `json:"is_admin"`
 }
 
 type UsersAuth struct {
@@ -88,6 +541,7 @@ type WsMember struct {
 	UserID    int64              `json:"user_id"`
 	CreatedAt pgtype.Timestamptz `json:"created_at"`
 	UpdatedAt pgtype.Timestamptz `json:"updated_at"`
+	Role      WsMemberRole       `json:"role"`
 }
 
 type WsRelation struct {
@@ -101,11 +555,12 @@ type WsRelation struct {
 }
 
 type WsRole struct {
-	ID        int64              `json:"id"`
-	WsID      int64              `json:"ws_id"`
-	Name      string             `json:"name"`
-	CreatedAt pgtype.Timestamptz `json:"created_at"`
-	UpdatedAt pgtype.Timestamptz `json:"updated_at"`
+	ID          int64              `json:"id"`
+	WsID        int64              `json:"ws_id"`
+	Name        string             `json:"name"`
+	CreatedAt   pgtype.Timestamptz `json:"created_at"`
+	UpdatedAt   pgtype.Timestamptz `json:"updated_at"`
+	Permissions []string           `json:"permissions"`
 }
 
 type WsRoleMember struct {
@@ -9,3 +9,34 @@ UPDATE users SET email_verified = true WHERE id = $1 AND email = $2;
 
 -- name: CreateUser :one
 INSERT INTO users (id, name, email, email_verified) VALUES ($1, $2, $3, $4) RETURNING *;
+
+-- name: ListUsers :many
+SELECT * FROM users WHERE id > sqlc.arg(after_id) ORDER BY id ASC LIMIT sqlc.arg(page_size);
+
+-- name: ListVisibleUsers :many
+SELECT * FROM users
+    WHERE
+        id > sqlc.arg(after_id)
+        AND (
+            id = sqlc.arg(user_id)
+            OR id IN (
+                SELECT m.user_id FROM ws_members m
+                    JOIN ws_members mine ON mine.ws_id = m.ws_id
+                    WHERE mine.user_id = sqlc.arg(user_id)
+            )
+        )
+    ORDER BY id ASC
+    LIMIT sqlc.arg(page_size);
+
+-- name: SharesWorkspace :one
+SELECT EXISTS (
+    SELECT 1 FROM ws_members m
+        JOIN ws_members other ON other.ws_id = m.ws_id
+        WHERE m.user_id = sqlc.arg(user_id) AND other.user_id = sqlc.arg(other_id)
+);
+
+-- name: SetUserAdmin :one
+UPDATE users SET is_admin = $2, updated_at = NOW() WHERE id = $1 RETURNING *;
+
+-- name: UpdateUserName :one
+UPDATE users SET name = $2, updated_at = NOW() WHERE id = $1 RETURNING *;
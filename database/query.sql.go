@@ -10,7 +10,7 @@ import (
 )
 
 const createUser = `-- name: CreateUser :one
-INSERT INTO users (id, name, email, email_verified) VALUES ($1, $2, $3, $4) RETURNING id, name, email, email_verified, created_at, updated_at
+INSERT INTO users (id, name, email, email_verified) VALUES ($1, $2, $3, $4) RETURNING id, name, email, email_verified, created_at, updated_at, is_admin
 `
 
 type CreateUserParams struct {
@@ -35,12 +35,13 @@ func (q *Queries) CreateUser(ctx context.Context, arg CreateUserParams) (User, e
 		&i.EmailVerified,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.IsAdmin,
 	)
 	return i, err
 }
 
 const getUserByEmail = `-- name: GetUserByEmail :one
-SELECT id, name, email, email_verified, created_at, updated_at FROM users WHERE email = $1
+SELECT id, name, email, email_verified, created_at, updated_at, is_admin FROM users WHERE email = $1
 `
 
 func (q *Queries) GetUserByEmail(ctx context.Context, email string) (User, error) {
@@ -53,12 +54,13 @@ func (q *Queries) GetUserByEmail(ctx context.Context, email string) (User, error
 		&i.EmailVerified,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.IsAdmin,
 	)
 	return i, err
 }
 
 const getUserByID = `-- name: GetUserByID :one
-SELECT id, name, email, email_verified, created_at, updated_at FROM users WHERE id = $1
+SELECT id, name, email, email_verified, created_at, updated_at, is_admin FROM users WHERE id = $1
 `
 
 func (q *Queries) GetUserByID(ctx context.Context, id int64) (User, error) {
@@ -71,10 +73,98 @@ func (q *Queries) GetUserByID(ctx context.Context, id int64) (User, error) {
 		&i.EmailVerified,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.IsAdmin,
 	)
 	return i, err
 }
 
+const listUsers = `-- name: ListUsers :many
+SELECT id, name, email, email_verified, created_at, updated_at, is_admin FROM users WHERE id > $1 ORDER BY id ASC LIMIT $2
+`
+
+type ListUsersParams struct {
+	AfterID  int64 `json:"after_id"`
+	PageSize int32 `json:"page_size"`
+}
+
+func (q *Queries) ListUsers(ctx context.Context, arg ListUsersParams) ([]User, error) {
+	rows, err := q.db.Query(ctx, listUsers, arg.AfterID, arg.PageSize)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []User
+	for rows.Next() {
+		var i User
+		if err := rows.Scan(
+			&i.ID,
+			&i.Name,
+			&i.Email,
+			&i.EmailVerified,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.IsAdmin,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listVisibleUsers = `-- name: ListVisibleUsers :many
+SELECT id, name, email, email_verified, created_at, updated_at, is_admin FROM users
+    WHERE
+        id > $1
+        AND (
+            id = $2
+            OR id IN (
+                SELECT m.user_id FROM ws_members m
+                    JOIN ws_members mine ON mine.ws_id = m.ws_id
+                    WHERE mine.user_id = $2
+            )
+        )
+    ORDER BY id ASC
+    LIMIT $3
+`
+
+type ListVisibleUsersParams struct {
+	AfterID  int64 `json:"after_id"`
+	UserID   int64 `json:"user_id"`
+	PageSize int32 `json:"page_size"`
+}
+
+func (q *Queries) ListVisibleUsers(ctx context.Context, arg ListVisibleUsersParams) ([]User, error) {
+	rows, err := q.db.Query(ctx, listVisibleUsers, arg.AfterID, arg.UserID, arg.PageSize)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []User
+	for rows.Next() {
+		var i User
+		if err := rows.Scan(
+			&i.ID,
+			&i.Name,
+			&i.Email,
+			&i.EmailVerified,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.IsAdmin,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const markEmailVerified = `-- name: MarkEmailVerified :exec
 UPDATE users SET email_verified = true WHERE id = $1 AND email = $2
 `
@@ -88,3 +178,71 @@ func (q *Queries) MarkEmailVerified(ctx context.Context, arg MarkEmailVerifiedPa
 	_, err := q.db.Exec(ctx, markEmailVerified, arg.ID, arg.Email)
 	return err
 }
+
+const setUserAdmin = `-- name: SetUserAdmin :one
+UPDATE users SET is_admin = $2, updated_at = NOW() WHERE id = $1 RETURNING id, name, email, email_verified, created_at, updated_at, is_admin
+`
+
+type SetUserAdminParams struct {
+	ID      int64 `json:"id"`
+	IsAdmin bool  `json:"is_admin"`
+}
+
+func (q *Queries) SetUserAdmin(ctx context.Context, arg SetUserAdminParams) (User, error) {
+	row := q.db.QueryRow(ctx, setUserAdmin, arg.ID, arg.IsAdmin)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.Name,
+		&i.Email,
+		&i.EmailVerified,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.IsAdmin,
+	)
+	return i, err
+}
+
+const sharesWorkspace = `-- name: SharesWorkspace :one
+SELECT EXISTS (
+    SELECT 1 FROM ws_members m
+        JOIN ws_members other ON other.ws_id = m.ws_id
+        WHERE m.user_id = $1 AND other.user_id = $2
+)
+`
+
+type SharesWorkspaceParams struct {
+	UserID  int64 `json:"user_id"`
+	OtherID int64 `json:"other_id"`
+}
+
+func (q *Queries) SharesWorkspace(ctx context.Context, arg SharesWorkspaceParams) (bool, error) {
+	row := q.db.QueryRow(ctx, sharesWorkspace, arg.UserID, arg.OtherID)
+	var exists bool
+	err := row.Scan(&exists)
+	return exists, err
+}
+
+const updateUserName = `-- name: UpdateUserName :one
+UPDATE users SET name = $2, updated_at = NOW() WHERE id = $1 RETURNING id, name, email, email_verified, created_at, updated_at, is_admin
+`
+
+type UpdateUserNameParams struct {
+	ID   int64  `json:"id"`
+	Name string `json:"name"`
+}
+
+func (q *Queries) UpdateUserName(ctx context.Context, arg UpdateUserNameParams) (User, error) {
+	row := q.db.QueryRow(ctx, updateUserName, arg.ID, arg.Name)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.Name,
+		&i.Email,
+		&i.EmailVerified,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.IsAdmin,
+	)
+	return i, err
+}
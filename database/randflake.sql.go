@@ -59,6 +59,20 @@ func (q *Queries) GetRandflakeRangeLease(ctx context.Context, arg GetRandflakeRa
 	return items, nil
 }
 
+const releaseRandflakeRangeLease = `-- name: ReleaseRandflakeRangeLease :exec
+DELETE FROM randflake_nodes WHERE id = $1 AND lease_holder = $2
+`
+
+type ReleaseRandflakeRangeLeaseParams struct {
+	ID          int64       `json:"id"`
+	LeaseHolder pgtype.UUID `json:"lease_holder"`
+}
+
+func (q *Queries) ReleaseRandflakeRangeLease(ctx context.Context, arg ReleaseRandflakeRangeLeaseParams) error {
+	_, err := q.db.Exec(ctx, releaseRandflakeRangeLease, arg.ID, arg.LeaseHolder)
+	return err
+}
+
 const renewRandflakeRangeLease = `-- name: RenewRandflakeRangeLease :one
 UPDATE randflake_nodes SET lease_end = $1 WHERE id = $2 AND lease_holder = $3 AND lease_end >= $4 RETURNING id, range_start, range_end, lease_holder, lease_start, lease_end
 `
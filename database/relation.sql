@@ -0,0 +1,42 @@
+-- name: CreateRelation :exec
+INSERT INTO ws_relations (id, ws_id, object_id, relation, target_id) VALUES ($1, $2, $3, $4, $5);
+
+-- name: SetDocumentSimhash :exec
+UPDATE documents
+    SET
+        simhash = $2,
+        simhash_b0 = $3,
+        simhash_b1 = $4,
+        simhash_b2 = $5,
+        simhash_b3 = $6,
+        updated_at = NOW()
+    WHERE id = $1;
+
+-- name: ListSimhashCandidates :many
+SELECT id, url, simhash, created_at FROM documents
+    WHERE
+        ws_id = $1
+        AND id <> $2
+        AND simhash <> 0
+        AND (simhash_b0 = $3 OR simhash_b1 = $4 OR simhash_b2 = $5 OR simhash_b3 = $6);
+
+-- name: GetDuplicateTarget :one
+SELECT target_id FROM ws_relations WHERE ws_id = $1 AND object_id = $2 AND relation = 'DUPLICATE' LIMIT 1;
+
+-- name: DeleteDuplicateRelations :exec
+DELETE FROM ws_relations WHERE ws_id = $1 AND object_id = $2 AND relation = 'DUPLICATE';
+
+-- name: RetargetDuplicates :exec
+UPDATE ws_relations
+    SET target_id = sqlc.arg(canonical_id), updated_at = NOW()
+    WHERE ws_id = sqlc.arg(ws_id) AND target_id = sqlc.arg(old_target_id) AND relation = 'DUPLICATE';
+
+-- name: ListDuplicateTargets :many
+SELECT object_id, target_id FROM ws_relations
+    WHERE
+        ws_id = sqlc.arg(ws_id)
+        AND relation = 'DUPLICATE'
+        AND object_id = ANY(sqlc.arg(object_ids)::BIGINT[]);
+
+-- name: ListDuplicates :many
+SELECT object_id FROM ws_relations WHERE ws_id = $1 AND target_id = $2 AND relation = 'DUPLICATE' ORDER BY object_id ASC;
@@ -0,0 +1,63 @@
+-- name: CreateReprocessJob :one
+INSERT INTO reprocess_jobs (id, ws_id, source, url_prefix, stale_only, created_by) VALUES ($1, $2, $3, $4, $5, $6) RETURNING *;
+
+-- name: GetReprocessJob :one
+SELECT * FROM reprocess_jobs WHERE id = $1;
+
+-- name: ListReprocessJobs :many
+SELECT * FROM reprocess_jobs WHERE ws_id = $1 ORDER BY id DESC LIMIT $2;
+
+-- name: ClaimReprocessJob :one
+UPDATE reprocess_jobs
+    SET
+        status = 'RUNNING',
+        heartbeat_at = sqlc.arg(heartbeat_at),
+        updated_at = NOW()
+    WHERE id = (
+        SELECT id FROM reprocess_jobs
+            WHERE status = 'PENDING' OR (status = 'RUNNING' AND heartbeat_at < sqlc.arg(stale_before))
+            ORDER BY id ASC
+            LIMIT 1
+            FOR UPDATE SKIP LOCKED
+    )
+RETURNING *;
+
+-- name: UpdateReprocessProgress :one
+UPDATE reprocess_jobs
+    SET
+        last_document_id = $2,
+        processed = $3,
+        failed = $4,
+        heartbeat_at = $5,
+        updated_at = NOW()
+    WHERE id = $1 AND status = 'RUNNING'
+RETURNING *;
+
+-- name: FinishReprocessJob :exec
+UPDATE reprocess_jobs SET status = $2, error = $3, updated_at = NOW() WHERE id = $1 AND status = 'RUNNING';
+
+-- name: CancelReprocessJob :one
+UPDATE reprocess_jobs SET status = 'CANCELED', updated_at = NOW() WHERE id = $1 AND status IN ('PENDING', 'RUNNING') RETURNING *;
+
+-- name: ListReprocessDocuments :many
+SELECT * FROM documents
+    WHERE
+        ws_id = sqlc.arg(ws_id)
+        AND id > sqlc.arg(after_id)
+        AND current_version_id <> 0
+        AND (sqlc.arg(source)::TEXT = '' OR source = sqlc.arg(source))
+        AND (sqlc.arg(url_prefix)::TEXT = '' OR starts_with(url, sqlc.arg(url_prefix)))
+        AND (
+            NOT sqlc.arg(stale_only)::BOOLEAN
+            OR EXISTS (
+                SELECT 1 FROM document_versions
+                    WHERE
+                        document_versions.id = documents.current_version_id
+                        AND (
+                            document_versions.converter_version < sqlc.arg(converter_version)
+                            OR document_versions.chunker_version < sqlc.arg(chunker_version)
+                        )
+            )
+        )
+    ORDER BY id ASC
+    LIMIT sqlc.arg(page_size);
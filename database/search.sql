@@ -0,0 +1,33 @@
+-- name: SearchChunksText :many
+SELECT
+    document_chunks.id,
+    document_chunks.document_id,
+    document_chunks.heading,
+    document_chunks.content,
+    documents.url,
+    documents.title,
+    ts_rank(to_tsvector('simple', document_chunks.content), websearch_to_tsquery('simple', sqlc.arg(query)))::REAL AS rank
+FROM document_chunks
+    JOIN documents ON documents.id = document_chunks.document_id
+WHERE
+    document_chunks.ws_id = sqlc.arg(ws_id)
+    AND to_tsvector('simple', document_chunks.content) @@ websearch_to_tsquery('simple', sqlc.arg(query))
+ORDER BY rank DESC
+LIMIT sqlc.arg(max_results);
+
+-- name: SearchChunksVector :many
+SELECT
+    document_chunks.id,
+    document_chunks.document_id,
+    document_chunks.heading,
+    document_chunks.content,
+    documents.url,
+    documents.title,
+    (document_chunks.embedding::vector <=> sqlc.arg(embedding)::REAL[]::vector)::REAL AS distance
+FROM document_chunks
+    JOIN documents ON documents.id = document_chunks.document_id
+WHERE
+    document_chunks.ws_id = sqlc.arg(ws_id)
+    AND cardinality(document_chunks.embedding) = cardinality(sqlc.arg(embedding)::REAL[])
+ORDER BY distance ASC
+LIMIT sqlc.arg(max_results);
@@ -0,0 +1,6 @@
+-- name: CreateDocumentSnapshot :exec
+INSERT INTO document_snapshots (id, ws_id, document_id, version_id, kind, blob_key, content_type, size)
+    VALUES ($1, $2, $3, $4, $5, $6, $7, $8);
+
+-- name: ListDocumentVersionSnapshots :many
+SELECT * FROM document_snapshots WHERE version_id = $1 ORDER BY kind ASC;
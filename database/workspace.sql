@@ -0,0 +1,90 @@
+-- name: CreateWorkspace :one
+INSERT INTO wss (id, name) VALUES ($1, $2) RETURNING *;
+
+-- name: GetWorkspace :one
+SELECT * FROM wss WHERE id = $1;
+
+-- name: CreateWorkspaceMember :one
+INSERT INTO ws_members (id, ws_id, user_id, role) VALUES ($1, $2, $3, $4) RETURNING *;
+
+-- name: ListWorkspaces :many
+SELECT * FROM wss WHERE id > sqlc.arg(after_id) ORDER BY id ASC LIMIT sqlc.arg(page_size);
+
+-- name: ListUserWorkspaces :many
+SELECT wss.* FROM wss
+    JOIN ws_members ON ws_members.ws_id = wss.id
+    WHERE
+        ws_members.user_id = sqlc.arg(user_id)
+        AND wss.id > sqlc.arg(after_id)
+    ORDER BY wss.id ASC
+    LIMIT sqlc.arg(page_size);
+
+-- name: UpdateWorkspace :one
+UPDATE wss SET name = $2, updated_at = NOW() WHERE id = $1 RETURNING *;
+
+-- name: GetWorkspaceMember :one
+SELECT * FROM ws_members WHERE ws_id = $1 AND id = $2;
+
+-- name: ListWorkspaceMembers :many
+SELECT * FROM ws_members WHERE ws_id = sqlc.arg(ws_id) AND id > sqlc.arg(after_id) ORDER BY id ASC LIMIT sqlc.arg(page_size);
+
+-- name: UpdateWorkspaceMemberRole :one
+UPDATE ws_members SET role = $3, updated_at = NOW() WHERE ws_id = $1 AND id = $2 RETURNING *;
+
+-- name: LockWorkspaceOwners :many
+SELECT id FROM ws_members WHERE ws_id = $1 AND role = 'OWNER' FOR UPDATE;
+
+-- name: DeleteWorkspaceMember :execrows
+DELETE FROM ws_members WHERE ws_id = $1 AND id = $2;
+
+-- name: DeleteMemberRoles :exec
+DELETE FROM ws_role_members WHERE ws_id = $1 AND ws_member_id = $2;
+
+-- name: CreateWorkspaceRole :one
+INSERT INTO ws_roles (id, ws_id, name, permissions) VALUES ($1, $2, $3, $4) RETURNING *;
+
+-- name: GetWorkspaceRole :one
+SELECT * FROM ws_roles WHERE ws_id = $1 AND id = $2;
+
+-- name: ListWorkspaceRoles :many
+SELECT * FROM ws_roles WHERE ws_id = sqlc.arg(ws_id) AND id > sqlc.arg(after_id) ORDER BY id ASC LIMIT sqlc.arg(page_size);
+
+-- name: UpdateWorkspaceRole :one
+UPDATE ws_roles SET name = $3, permissions = $4, updated_at = NOW() WHERE ws_id = $1 AND id = $2 RETURNING *;
+
+-- name: DeleteWorkspaceRole :execrows
+DELETE FROM ws_roles WHERE ws_id = $1 AND id = $2;
+
+-- name: DeleteRoleMembers :exec
+DELETE FROM ws_role_members WHERE ws_id = $1 AND ws_role_id = $2;
+
+-- name: AddWorkspaceRoleMember :exec
+INSERT INTO ws_role_members (id, ws_id, ws_role_id, ws_member_id) VALUES ($1, $2, $3, $4)
+    ON CONFLICT (ws_id, ws_role_id, ws_member_id) DO NOTHING;
+
+-- name: RemoveWorkspaceRoleMember :execrows
+DELETE FROM ws_role_members WHERE ws_id = $1 AND ws_role_id = $2 AND ws_member_id = $3;
+
+-- name: ListWorkspaceRoleMembers :many
+SELECT ws_members.* FROM ws_members
+    JOIN ws_role_members ON ws_role_members.ws_member_id = ws_members.id
+    WHERE
+        ws_role_members.ws_id = sqlc.arg(ws_id)
+        AND ws_role_members.ws_role_id = sqlc.arg(ws_role_id)
+        AND ws_members.id > sqlc.arg(after_id)
+    ORDER BY ws_members.id ASC
+    LIMIT sqlc.arg(page_size);
+
+-- name: GetWorkspaceMemberByUser :one
+SELECT * FROM ws_members WHERE ws_id = $1 AND user_id = $2;
+
+-- name: GetMemberAccess :one
+SELECT
+    ws_members.id,
+    ws_members.role,
+    ARRAY(
+        SELECT DISTINCT unnest(ws_roles.permissions) FROM ws_role_members
+            JOIN ws_roles ON ws_roles.id = ws_role_members.ws_role_id
+            WHERE ws_role_members.ws_id = ws_members.ws_id AND ws_role_members.ws_member_id = ws_members.id
+    )::TEXT[] AS permissions
+FROM ws_members WHERE ws_members.ws_id = $1 AND ws_members.user_id = $2;
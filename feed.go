@@ -0,0 +1,43 @@
+package main
+
+import (
+	"net/http"
+	"time"
+
+	"gosuda.org/jimin/database"
+	"gosuda.org/jimin/internal/crawler"
+	"gosuda.org/jimin/internal/feed"
+	"gosuda.org/jimin/internal/rbac"
+	"gosuda.org/jimin/internal/recrawl"
+)
+
+type FeedConfig struct {
+	// Interval is the time in seconds between fetches of a subscribed feed.
+	Interval int `json:"interval,omitempty"`
+	// RetryInterval is the time in seconds before a feed that failed to fetch is fetched again.
+	RetryInterval int `json:"retry_interval,omitempty"`
+	// MaxEntries is the number of the first entries of a feed added on each fetch.
+	MaxEntries  int `json:"max_entries,omitempty"`
+	Concurrency int `json:"concurrency,omitempty"`
+}
+
+// NewFeedPoller returns the poller adding the entries of the subscribed feeds to the recrawl of rc.
+// Feeds are fetched with the profiles and proxies of cr if it is not nil,
+// and the feeds of users while their users may still add documents to the workspace.
+func NewFeedPoller(c *Config, db database.DBTX, cr *crawler.Crawler, rc *recrawl.Recrawler) *feed.Poller {
+	client := &http.Client{Timeout: time.Second * 30}
+	if cr != nil {
+		client.Transport = cr.Transport(nil)
+	}
+
+	return feed.NewPoller(feed.PollerConfig{
+		DB:            db,
+		Adder:         documentAdder{cfg: c, r: rc},
+		Authz:         rbac.New(db),
+		Client:        client,
+		Interval:      time.Duration(c.Feeds.Interval) * time.Second,
+		RetryInterval: time.Duration(c.Feeds.RetryInterval) * time.Second,
+		MaxEntries:    c.Feeds.MaxEntries,
+		Concurrency:   c.Feeds.Concurrency,
+	})
+}
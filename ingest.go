@@ -0,0 +1,340 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/fs"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"gosuda.org/jimin/database"
+	"gosuda.org/jimin/internal/convert"
+	"gosuda.org/jimin/internal/crawler"
+	"gosuda.org/jimin/internal/indexer"
+	"gosuda.org/jimin/internal/ingest"
+	"gosuda.org/jimin/internal/pipeline"
+	"gosuda.org/jimin/internal/recrawl"
+)
+
+const (
+	_FILE_SOURCE    = "file"
+	_ARCHIVE_SOURCE = "archive"
+)
+
+func (c *cli) crawl(ctx context.Context, args []string) error {
+	fs := c.flags("crawl", "<url>")
+	wsID := fs.Int64("ws", 0, "workspace id to store the page in, the page is only printed if 0")
+	pos, err := c.parse(fs, args, 1)
+	if err != nil {
+		return err
+	}
+	url := pos[0]
+	if !strings.HasPrefix(url, "http://") && !strings.HasPrefix(url, "https://") {
+		return usageErrorf("crawl: %q is not an http url", url)
+	}
+
+	cfg, err := c.loadConfig()
+	if err != nil {
+		return err
+	}
+	cr, closeCrawler, err := NewCrawler(cfg)
+	if err != nil {
+		return withCode(_EXIT_CONFIG, err)
+	}
+	defer closeCrawler()
+
+	if *wsID == 0 {
+		return c.crawlPage(ctx, cfg, cr, url)
+	}
+
+	a, err := open(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	defer a.Close()
+
+	r, err := NewRecrawler(cfg, a.pool, a.ids, cr, nil)
+	if err != nil {
+		return withCode(_EXIT_CONFIG, err)
+	}
+
+	source, _ := cfg.Source(url)
+	doc, err := r.Add(ctx, *wsID, source.Name, url)
+	if err != nil {
+		return err
+	}
+	changed, err := r.Crawl(ctx, doc)
+	if err != nil {
+		return err
+	}
+	if doc, err = database.New(a.pool).GetDocument(ctx, doc.ID); err != nil {
+		return err
+	}
+
+	return c.print(map[string]any{"document": doc, "changed": changed}, func(w io.Writer) {
+		state := "unchanged"
+		if changed {
+			state = "updated"
+		}
+		fmt.Fprintf(w, "%s document %d %s\n", state, doc.ID, doc.Url)
+	})
+}
+
+// crawlPage renders url and prints the converted page without storing it.
+// The snapshots of the page are kept in the blob store and printed with their keys.
+func (c *cli) crawlPage(ctx context.Context, cfg *Config, cr *crawler.Crawler, url string) error {
+	source, _ := cfg.Source(url)
+	page, err := cr.Crawl(ctx, url, source.Crawl)
+	if err != nil {
+		return err
+	}
+
+	ps, err := cfg.NewPipelines(nil, nil)
+	if err != nil {
+		return withCode(_EXIT_CONFIG, err)
+	}
+	item := &pipeline.Item{URL: url, Policy: source.Policy, ContentType: "text/html; charset=utf-8", Raw: []byte(page.HTML)}
+	traces, err := ps.forSource(source).Select(pipeline.StageExtract, pipeline.StageClean, pipeline.StageChunk).Run(ctx, item)
+	if err != nil {
+		return err
+	}
+
+	return c.print(map[string]any{
+		"url":       url,
+		"title":     item.Title,
+		"markdown":  item.Markdown,
+		"chunks":    item.Chunks,
+		"snapshots": page.Snapshots,
+		"traces":    traces,
+	}, func(w io.Writer) {
+		fmt.Fprintln(w, item.Markdown)
+		for _, s := range page.Snapshots {
+			fmt.Fprintf(w, "%s snapshot %s (%d bytes)\n", s.Kind, s.Blob.Key, s.Blob.Size)
+		}
+	})
+}
+
+type ingestResult struct {
+	Path       string `json:"path"`
+	URL        string `json:"url,omitempty"`
+	DocumentID int64  `json:"document_id,omitempty"`
+	Changed    bool   `json:"changed"`
+	Error      string `json:"error,omitempty"`
+}
+
+// ingester stores files and archived pages as documents of a workspace.
+type ingester struct {
+	cfg    *Config
+	wsID   int64
+	source string
+	pages  *pageProcessor
+	r      *recrawl.Recrawler
+}
+
+func (c *cli) ingest(ctx context.Context, args []string) error {
+	flags := c.flags("ingest", "<path>")
+	wsID := flags.Int64("ws", 0, "workspace id")
+	source := flags.String("source", "", "source name of the documents (default: the matching source, file or archive)")
+	pos, err := c.parse(flags, args, 1)
+	if err != nil {
+		return err
+	}
+	if *wsID == 0 {
+		return usageErrorf("ingest: -ws is required")
+	}
+	root := pos[0]
+	if _, err := os.Stat(root); err != nil {
+		return withCode(_EXIT_NOT_FOUND, err)
+	}
+
+	a, err := c.open(ctx)
+	if err != nil {
+		return err
+	}
+	defer a.Close()
+
+	pages, err := newPageProcessor(a.cfg, a.pool, a.ids, nil, nil, nil)
+	if err != nil {
+		return withCode(_EXIT_CONFIG, err)
+	}
+	r, err := newRecrawler(a.cfg, a.pool, a.ids, nil, nil, pages)
+	if err != nil {
+		return withCode(_EXIT_CONFIG, err)
+	}
+	in := &ingester{
+		cfg:    a.cfg,
+		wsID:   *wsID,
+		source: *source,
+		pages:  pages,
+		r:      r,
+	}
+
+	var results []ingestResult
+	err = filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || !supportedFile(path) {
+			return nil
+		}
+
+		res, err := in.file(ctx, path)
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if err != nil {
+			log.Warn().Err(err).Str("path", path).Msg("Failed to ingest file")
+			res = append(res, ingestResult{Path: path, Error: err.Error()})
+		}
+		results = append(results, res...)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	var changed, failed int
+	for _, res := range results {
+		switch {
+		case res.Error != "":
+			failed++
+		case res.Changed:
+			changed++
+		}
+	}
+
+	err = c.print(map[string]any{"documents": results, "changed": changed, "failed": failed}, func(w io.Writer) {
+		for _, res := range results {
+			switch {
+			case res.Error != "":
+				fmt.Fprintf(w, "failed    %s: %s\n", res.Path, res.Error)
+			case res.Changed:
+				fmt.Fprintf(w, "ingested  %d %s\n", res.DocumentID, res.URL)
+			default:
+				fmt.Fprintf(w, "unchanged %d %s\n", res.DocumentID, res.URL)
+			}
+		}
+		fmt.Fprintf(w, "%d documents, %d changed, %d failed\n", len(results), changed, failed)
+	})
+	if err != nil {
+		return err
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("failed to ingest %d of %d documents", failed, len(results))
+	}
+	return nil
+}
+
+func isArchive(path string) bool {
+	return strings.HasSuffix(path, ".warc") || strings.HasSuffix(path, ".warc.gz") || strings.HasSuffix(path, ".wacz")
+}
+
+func supportedFile(path string) bool {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".html", ".htm", ".xhtml", ".md", ".markdown", ".txt":
+		return true
+	}
+	return isArchive(path)
+}
+
+// file ingests a file, or every page of a web archive.
+func (in *ingester) file(ctx context.Context, path string) ([]ingestResult, error) {
+	if isArchive(path) {
+		var results []ingestResult
+		err := ingest.WalkPages(path, func(p *ingest.Page) error {
+			source := in.source
+			if source == "" {
+				source = _ARCHIVE_SOURCE
+				if s, ok := in.cfg.Source(p.URL); ok {
+					source = s.Name
+				}
+			}
+			res := ingestResult{Path: path, URL: p.URL}
+			doc, changed, err := in.html(ctx, source, p.URL, []byte(p.HTML), "text/html; charset=utf-8", p.FetchedAt)
+			if err != nil {
+				if ctx.Err() != nil {
+					return ctx.Err()
+				}
+				res.Error = err.Error()
+			}
+			res.DocumentID, res.Changed = doc.ID, changed
+			results = append(results, res)
+			return nil
+		})
+		return results, err
+	}
+
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return nil, err
+	}
+	info, err := os.Stat(abs)
+	if err != nil {
+		return nil, err
+	}
+	raw, err := os.ReadFile(abs)
+	if err != nil {
+		return nil, err
+	}
+
+	source := in.source
+	if source == "" {
+		source = _FILE_SOURCE
+	}
+	url := "file://" + filepath.ToSlash(abs)
+
+	var doc database.Document
+	var changed bool
+	switch ext := strings.ToLower(filepath.Ext(abs)); ext {
+	case ".md", ".markdown", ".txt":
+		md := strings.ToValidUTF8(string(raw), "")
+		content := &recrawl.Content{
+			Title:            markdownTitle(md, filepath.Base(abs)),
+			Markdown:         md,
+			ConverterVersion: convert.Version,
+			ChunkerVersion:   indexer.Version,
+		}
+		doc, changed, err = in.r.Ingest(ctx, in.wsID, source, url, content, info.ModTime())
+	default:
+		contentType := mime.TypeByExtension(ext)
+		if contentType == "" {
+			contentType = http.DetectContentType(raw)
+		}
+		doc, changed, err = in.html(ctx, source, url, raw, contentType, info.ModTime())
+	}
+	if err != nil {
+		return nil, err
+	}
+	return []ingestResult{{Path: path, URL: url, DocumentID: doc.ID, Changed: changed}}, nil
+}
+
+// html converts an html page like a crawled one and stores it.
+func (in *ingester) html(ctx context.Context, source, url string, raw []byte, contentType string, fetchedAt time.Time) (database.Document, bool, error) {
+	content, err := in.pages.convert(ctx, database.Document{WsID: in.wsID, Source: source, Url: url}, raw, contentType)
+	if err != nil {
+		return database.Document{}, false, err
+	}
+	if content.Title == "" {
+		content.Title = markdownTitle(content.Markdown, "")
+	}
+	return in.r.Ingest(ctx, in.wsID, source, url, content, fetchedAt)
+}
+
+// markdownTitle returns the first heading of markdown, or fallback if it has none.
+func markdownTitle(markdown, fallback string) string {
+	for _, line := range strings.Split(markdown, "\n") {
+		if t, ok := strings.CutPrefix(strings.TrimSpace(line), "# "); ok {
+			if t = strings.TrimSpace(t); t != "" {
+				return t
+			}
+		}
+	}
+	return fallback
+}
@@ -0,0 +1,296 @@
+// Package api serves the versioned JSON API under /v1 and its OpenAPI document.
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/rs/zerolog/log"
+	"gosuda.org/jimin/database"
+	"gosuda.org/jimin/internal/blob"
+	"gosuda.org/jimin/internal/rbac"
+	"gosuda.org/jimin/internal/reprocess"
+	"gosuda.org/jimin/internal/search"
+)
+
+type DB interface {
+	database.DBTX
+	Begin(ctx context.Context) (pgx.Tx, error)
+}
+
+type IDGenerator interface {
+	Generate(ctx context.Context) (int64, error)
+}
+
+// Crawler adds the documents created through the API to the crawl.
+type Crawler interface {
+	// Add returns the document of url, creating it if needed. The source is resolved from url if empty.
+	Add(ctx context.Context, wsID int64, source, url string) (database.Document, error)
+}
+
+// Searcher searches and answers questions from the documents of a workspace.
+type Searcher interface {
+	Search(ctx context.Context, wsID int64, query string, mode search.Mode, limit int) ([]search.Result, error)
+	Ask(ctx context.Context, gen search.Generator, wsID int64, question string, mode search.Mode, limit int) (*search.Answer, error)
+}
+
+// LinkChecker lists the broken links of a workspace.
+type LinkChecker interface {
+	Broken(ctx context.Context, wsID, after int64, limit int) ([]database.Link, error)
+}
+
+// Reprocessor runs the reprocessing jobs of a workspace.
+type Reprocessor interface {
+	// Start queues a job of a user, or of no user if userID is zero.
+	Start(ctx context.Context, wsID, userID int64, f reprocess.Filter) (database.ReprocessJob, error)
+	Job(ctx context.Context, wsID, id int64) (database.ReprocessJob, error)
+	Cancel(ctx context.Context, wsID, id int64) (database.ReprocessJob, error)
+}
+
+// Feeds keeps the feed suggestions and subscriptions of a workspace.
+type Feeds interface {
+	// List returns the feeds with status, or the suggested and subscribed feeds if status is empty.
+	List(ctx context.Context, wsID int64, status database.FeedStatus) ([]database.Feed, error)
+	// Subscribe subscribes to a feed for a user, or for no user if userID is zero.
+	Subscribe(ctx context.Context, wsID, userID, id int64) (database.Feed, error)
+	Dismiss(ctx context.Context, wsID, id int64) (database.Feed, error)
+}
+
+// Authorizer decides what the members of a workspace may do.
+type Authorizer interface {
+	// Authorize returns rbac.ErrNotMember or rbac.ErrForbidden unless the user may do perm in the workspace,
+	// or on the user wsID for the permissions on users.
+	Authorize(ctx context.Context, userID, wsID int64, perm rbac.Permission) error
+	// Access returns what the user may do in the workspace, or rbac.ErrNotMember.
+	Access(ctx context.Context, userID, wsID int64) (rbac.Access, error)
+}
+
+// SourceInfo is a configured source of documents.
+type SourceInfo struct {
+	Name  string
+	Hosts []string
+}
+
+type Config struct {
+	DB  DB
+	IDs IDGenerator
+	// Sources are the configured sources, listed with the number of documents of each workspace.
+	Sources []SourceInfo
+
+	// Auth authenticates the requests with a session cookie.
+	// Without it the endpoints are served to anyone, and the auth endpoints respond with 503 Service Unavailable.
+	Auth Authenticator
+	// Authz authorizes the requests of the signed in users on workspaces, rbac.New(DB) if nil.
+	// It is not used without Auth.
+	Authz Authorizer
+
+	// The optional subsystems. The endpoints of a missing one respond with 503 Service Unavailable.
+	Crawler   Crawler
+	Searcher  Searcher
+	Generator search.Generator
+	Links     LinkChecker
+	Reprocess Reprocessor
+	Feeds     Feeds
+	// Blobs holds the images captured from the documents.
+	Blobs blob.Store
+
+	// Mode is the default search mode, hybrid if empty.
+	Mode search.Mode
+}
+
+// API holds the handlers of the /v1 endpoints.
+type API struct {
+	cfg    Config
+	q      *database.Queries
+	routes []route
+	spec   []byte
+}
+
+func New(cfg Config) *API {
+	if cfg.Mode == "" {
+		cfg.Mode = search.ModeHybrid
+	}
+	if cfg.Authz == nil {
+		cfg.Authz = rbac.New(cfg.DB)
+	}
+	a := &API{cfg: cfg, q: database.New(cfg.DB)}
+	a.routes = a.table()
+
+	spec, err := json.Marshal(a.openAPI())
+	if err != nil {
+		// the document only holds types known at compile time
+		panic(err)
+	}
+	a.spec = spec
+	return a
+}
+
+// Register registers the endpoints and the OpenAPI document on mux.
+func (a *API) Register(mux *http.ServeMux) {
+	for _, rt := range a.routes {
+		mux.Handle(rt.method+" "+rt.path, a.handler(rt))
+	}
+	mux.HandleFunc("GET "+_SPEC_PATH, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(a.spec)
+	})
+	mux.HandleFunc("/v1/", func(w http.ResponseWriter, r *http.Request) {
+		writeProblem(w, r, problemf(http.StatusNotFound, "no endpoint %s %s", r.Method, r.URL.Path))
+	})
+}
+
+// Handler returns a router of the endpoints.
+func (a *API) Handler() http.Handler {
+	mux := http.NewServeMux()
+	a.Register(mux)
+	return mux
+}
+
+// handler runs the handler of rt and writes its response or error.
+func (a *API) handler(rt route) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if a.cfg.Auth != nil {
+			var err error
+			if r, err = a.authenticate(w, r, rt); err != nil {
+				a.fail(w, r, rt, err)
+				return
+			}
+		}
+		if err := a.check(r, rt); err != nil {
+			a.fail(w, r, rt, err)
+			return
+		}
+
+		resp, err := rt.handle(r)
+		if err != nil {
+			a.fail(w, r, rt, err)
+			return
+		}
+		if c, ok := resp.(withCookies); ok {
+			for _, cookie := range c.cookies {
+				http.SetCookie(w, cookie)
+			}
+			resp = c.resp
+		}
+		if to, ok := resp.(redirect); ok {
+			http.Redirect(w, r, string(to), rt.status)
+			return
+		}
+		if c, ok := resp.(content); ok {
+			defer c.body.Close()
+			h := w.Header()
+			h.Set("Content-Type", c.contentType)
+			h.Set("X-Content-Type-Options", "nosniff")
+			// images may be svg, never run their scripts
+			h.Set("Content-Security-Policy", "default-src 'none'; style-src 'unsafe-inline'; sandbox")
+			// blobs are keyed by their content and never change
+			h.Set("Cache-Control", "private, max-age=31536000, immutable")
+			w.WriteHeader(rt.status)
+			io.Copy(w, c.body)
+			return
+		}
+		if rt.resp == nil {
+			w.WriteHeader(rt.status)
+			return
+		}
+		writeJSON(w, rt.status, resp)
+	})
+}
+
+// check authorizes the routes of a workspace and of users with their permission.
+func (a *API) check(r *http.Request, rt route) error {
+	switch {
+	case strings.Contains(rt.path, "{ws_id}"):
+		return a.checkWorkspace(r, rt.perm)
+	case slices.Contains(rbac.UserPermissions, rt.perm):
+		return a.checkUsers(r, rt.perm)
+	}
+	return nil
+}
+
+// checkWorkspace fails with 404 Not Found if the workspace of the path does not exist, or the user is not a member of it,
+// and with 403 Forbidden if the user may not do perm in it.
+func (a *API) checkWorkspace(r *http.Request, perm rbac.Permission) error {
+	wsID, err := pathID(r, "ws_id")
+	if err != nil {
+		return err
+	}
+	_, err = a.q.GetWorkspace(r.Context(), wsID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return problemf(http.StatusNotFound, "workspace %d not found", wsID)
+	}
+	if err != nil {
+		return err
+	}
+
+	err = a.authorize(r, wsID, perm)
+	if errors.Is(err, rbac.ErrNotMember) {
+		// not telling the workspaces of others apart from missing ones
+		return problemf(http.StatusNotFound, "workspace %d not found", wsID)
+	}
+	return err
+}
+
+// checkUsers fails with 404 Not Found if the signed in user may not see the user of the path,
+// and with 403 Forbidden if it may not do perm on it, or on users at all for the routes without one.
+func (a *API) checkUsers(r *http.Request, perm rbac.Permission) error {
+	var id int64
+	if r.PathValue("user_id") != "" {
+		var err error
+		if id, err = pathID(r, "user_id"); err != nil {
+			return err
+		}
+	}
+
+	err := a.authorize(r, id, perm)
+	if errors.Is(err, rbac.ErrNotMember) {
+		// not telling the users of other workspaces apart from missing ones
+		return problemf(http.StatusNotFound, "user %d not found", id)
+	}
+	return err
+}
+
+// authorize returns an error unless the signed in user may do perm in a workspace, or on a user for the permissions on users.
+// Everyone may do everything without authentication.
+func (a *API) authorize(r *http.Request, wsID int64, perm rbac.Permission) error {
+	if a.cfg.Auth == nil {
+		return nil
+	}
+	p, ok := principalOf(r.Context())
+	if !ok {
+		return problemf(http.StatusUnauthorized, "sign in required")
+	}
+	return a.cfg.Authz.Authorize(r.Context(), p.userID, wsID, perm)
+}
+
+// access returns what the signed in user may do in a workspace, everything an owner may without authentication.
+func (a *API) access(r *http.Request, wsID int64) (rbac.Access, error) {
+	if a.cfg.Auth == nil {
+		return rbac.Access{Role: rbac.Owner}, nil
+	}
+	p, ok := principalOf(r.Context())
+	if !ok {
+		return rbac.Access{}, problemf(http.StatusUnauthorized, "sign in required")
+	}
+	return a.cfg.Authz.Access(r.Context(), p.userID, wsID)
+}
+
+func (a *API) fail(w http.ResponseWriter, r *http.Request, rt route, err error) {
+	p := problemOf(err)
+	if p.Status >= http.StatusInternalServerError && p.Status != http.StatusServiceUnavailable {
+		log.Error().Err(err).Str("operation", rt.id).Str("path", r.URL.Path).Msg("api: request failed")
+	}
+	writeProblem(w, r, p)
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
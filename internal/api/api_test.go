@@ -0,0 +1,255 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"gosuda.org/jimin/internal/search"
+)
+
+// fakeDB answers every single row query with err, and every row with zero values if err is nil.
+type fakeDB struct {
+	err error
+}
+
+func (db fakeDB) Exec(context.Context, string, ...interface{}) (pgconn.CommandTag, error) {
+	return pgconn.CommandTag{}, db.err
+}
+
+func (db fakeDB) Query(context.Context, string, ...interface{}) (pgx.Rows, error) {
+	return nil, db.err
+}
+
+func (db fakeDB) QueryRow(context.Context, string, ...interface{}) pgx.Row {
+	return row{err: db.err}
+}
+
+func (db fakeDB) Begin(context.Context) (pgx.Tx, error) {
+	return nil, db.err
+}
+
+type row struct {
+	err error
+}
+
+func (r row) Scan(...any) error { return r.err }
+
+func serve(a *API, method, path, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	if body != "" {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	rec := httptest.NewRecorder()
+	a.Handler().ServeHTTP(rec, req)
+	return rec
+}
+
+func problem(t *testing.T, rec *httptest.ResponseRecorder) Problem {
+	t.Helper()
+	if ct := rec.Header().Get("Content-Type"); ct != _PROBLEM_CONTENT_TYPE {
+		t.Fatalf("Content-Type = %q, want %q", ct, _PROBLEM_CONTENT_TYPE)
+	}
+	var p Problem
+	if err := json.Unmarshal(rec.Body.Bytes(), &p); err != nil {
+		t.Fatal(err)
+	}
+	if p.Status != rec.Code {
+		t.Errorf("problem status = %d, want %d", p.Status, rec.Code)
+	}
+	return p
+}
+
+func TestInvalidRequests(t *testing.T) {
+	// the subsystems are only called once the request is valid
+	a := New(Config{DB: fakeDB{}, Crawler: struct{ Crawler }{}, Searcher: struct{ Searcher }{}, Generator: struct{ search.Generator }{}, Feeds: struct{ Feeds }{}})
+
+	tests := []struct {
+		method, path, body string
+		status             int
+		fields             []string
+	}{
+		{"POST", "/v1/users", `{}`, http.StatusBadRequest, []string{"name", "email"}},
+		{"POST", "/v1/users", `{"name":"a","email":"not an address"}`, http.StatusBadRequest, []string{"email"}},
+		{"POST", "/v1/users", `{"name":"a","email":"a@example.com","admin":true}`, http.StatusBadRequest, []string{"admin"}},
+		{"POST", "/v1/users", `{"name":1}`, http.StatusBadRequest, []string{"name"}},
+		{"POST", "/v1/users", `{`, http.StatusBadRequest, nil},
+		{"GET", "/v1/users/abc", "", http.StatusBadRequest, []string{"user_id"}},
+		{"GET", "/v1/users/-1", "", http.StatusBadRequest, []string{"user_id"}},
+		{"GET", "/v1/users?cursor=!", "", http.StatusBadRequest, []string{"cursor"}},
+		{"GET", "/v1/users?limit=0&cursor=" + encodeCursor(1), "", http.StatusBadRequest, []string{"limit"}},
+		{"GET", "/v1/workspaces?limit=201", "", http.StatusBadRequest, []string{"limit"}},
+		{"PATCH", "/v1/workspaces/1", `{"name":" "}`, http.StatusBadRequest, []string{"name"}},
+		{"POST", "/v1/workspaces/1/members", `{}`, http.StatusBadRequest, []string{"user_id"}},
+		{"GET", "/v1/workspaces/1/members/x", "", http.StatusBadRequest, []string{"member_id"}},
+		{"POST", "/v1/workspaces/x/reprocess/1/cancel", "", http.StatusBadRequest, []string{"ws_id"}},
+		{"GET", "/v1/workspaces/1/search?q=&mode=fuzzy&limit=100", "", http.StatusBadRequest, []string{"q", "mode", "limit"}},
+		{"POST", "/v1/workspaces/1/ask", `{"question":"why?","limit":-1}`, http.StatusBadRequest, []string{"limit"}},
+		{"POST", "/v1/workspaces/1/documents", `{"url":"ftp://example.com"}`, http.StatusBadRequest, []string{"url"}},
+		{"GET", "/v1/workspaces/1/feeds?status=new", "", http.StatusBadRequest, []string{"status"}},
+		{"POST", "/v1/workspaces/1/feeds/x/dismiss", "", http.StatusBadRequest, []string{"feed_id"}},
+	}
+
+	for _, tt := range tests {
+		rec := serve(a, tt.method, tt.path, tt.body)
+		if rec.Code != tt.status {
+			t.Errorf("%s %s %s = %d, want %d: %s", tt.method, tt.path, tt.body, rec.Code, tt.status, rec.Body)
+			continue
+		}
+		p := problem(t, rec)
+		var fields []string
+		for _, e := range p.Errors {
+			fields = append(fields, e.Field)
+		}
+		if strings.Join(fields, ",") != strings.Join(tt.fields, ",") {
+			t.Errorf("%s %s %s errors = %v, want %v", tt.method, tt.path, tt.body, fields, tt.fields)
+		}
+	}
+}
+
+func TestContentType(t *testing.T) {
+	a := New(Config{DB: fakeDB{}})
+	req := httptest.NewRequest("POST", "/v1/users", strings.NewReader(`name=a`))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	rec := httptest.NewRecorder()
+	a.Handler().ServeHTTP(rec, req)
+	if rec.Code != http.StatusUnsupportedMediaType {
+		t.Errorf("status = %d, want %d", rec.Code, http.StatusUnsupportedMediaType)
+	}
+}
+
+func TestNotFound(t *testing.T) {
+	a := New(Config{DB: fakeDB{err: pgx.ErrNoRows}})
+
+	for _, path := range []string{"/v1/users/1", "/v1/workspaces/1/documents", "/v1/nothing"} {
+		rec := serve(a, "GET", path, "")
+		if rec.Code != http.StatusNotFound {
+			t.Errorf("GET %s = %d, want %d", path, rec.Code, http.StatusNotFound)
+			continue
+		}
+		if p := problem(t, rec); p.Instance != path {
+			t.Errorf("GET %s instance = %q", path, p.Instance)
+		}
+	}
+}
+
+func TestUnavailable(t *testing.T) {
+	a := New(Config{DB: fakeDB{}})
+
+	tests := []struct {
+		method, path, body string
+	}{
+		{"GET", "/v1/workspaces/1/search?q=a", ""},
+		{"POST", "/v1/workspaces/1/ask", `{"question":"a"}`},
+		{"POST", "/v1/workspaces/1/documents", `{"url":"https://example.com"}`},
+		{"GET", "/v1/workspaces/1/links/broken", ""},
+		{"POST", "/v1/workspaces/1/reprocess", ""},
+		{"GET", "/v1/workspaces/1/reprocess/1", ""},
+		{"GET", "/v1/workspaces/1/feeds", ""},
+		{"POST", "/v1/workspaces/1/feeds/1/subscribe", ""},
+		{"POST", "/v1/auth/login", `{"email":"a@example.com","password":"password"}`},
+		{"GET", "/v1/assets/00", ""},
+	}
+	for _, tt := range tests {
+		rec := serve(a, tt.method, tt.path, tt.body)
+		if rec.Code != http.StatusServiceUnavailable {
+			t.Errorf("%s %s = %d, want %d", tt.method, tt.path, rec.Code, http.StatusServiceUnavailable)
+			continue
+		}
+		problem(t, rec)
+	}
+}
+
+func TestCursor(t *testing.T) {
+	for _, after := range []int64{0, 1, 1 << 62} {
+		got, err := decodeCursor(encodeCursor(after))
+		if err != nil || got != after {
+			t.Errorf("decodeCursor(encodeCursor(%d)) = %d, %v", after, got, err)
+		}
+	}
+
+	p := page{limit: 2}
+	key := func(n int64) int64 { return n }
+	if l := list([]int64{1, 2}, p, key); l.NextCursor != encodeCursor(2) {
+		t.Errorf("full page cursor = %q, want %q", l.NextCursor, encodeCursor(2))
+	}
+	if l := list([]int64{1}, p, key); l.NextCursor != "" {
+		t.Errorf("last page cursor = %q, want none", l.NextCursor)
+	}
+	if l := list[int64](nil, p, key); l.Items == nil {
+		t.Error("empty page items are null")
+	}
+}
+
+func TestOpenAPI(t *testing.T) {
+	a := New(Config{})
+	rec := serve(a, "GET", _SPEC_PATH, "")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("GET %s = %d", _SPEC_PATH, rec.Code)
+	}
+
+	var spec struct {
+		OpenAPI    string                               `json:"openapi"`
+		Paths      map[string]map[string]map[string]any `json:"paths"`
+		Components struct {
+			Schemas map[string]any `json:"schemas"`
+		} `json:"components"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &spec); err != nil {
+		t.Fatal(err)
+	}
+	if spec.OpenAPI != "3.1.0" {
+		t.Errorf("openapi = %q", spec.OpenAPI)
+	}
+
+	ids := map[string]bool{}
+	for _, rt := range a.routes {
+		op, ok := spec.Paths[rt.path][strings.ToLower(rt.method)]
+		if !ok {
+			t.Errorf("%s %s is not documented", rt.method, rt.path)
+			continue
+		}
+		if ids[rt.id] {
+			t.Errorf("operation id %s is not unique", rt.id)
+		}
+		ids[rt.id] = true
+		if op["operationId"] != rt.id {
+			t.Errorf("%s %s operationId = %v, want %s", rt.method, rt.path, op["operationId"], rt.id)
+		}
+	}
+
+	// every reference resolves to a component
+	var refs func(v any)
+	refs = func(v any) {
+		switch v := v.(type) {
+		case map[string]any:
+			if ref, ok := v["$ref"].(string); ok {
+				name := strings.TrimPrefix(ref, "#/components/schemas/")
+				if _, ok := spec.Components.Schemas[name]; !ok {
+					t.Errorf("unresolved reference %s", ref)
+				}
+			}
+			for _, e := range v {
+				refs(e)
+			}
+		case []any:
+			for _, e := range v {
+				refs(e)
+			}
+		}
+	}
+	var doc any
+	json.Unmarshal(rec.Body.Bytes(), &doc)
+	refs(doc)
+
+	for _, name := range []string{"UserList", "DocumentDetail", "Problem"} {
+		if _, ok := spec.Components.Schemas[name]; !ok {
+			t.Errorf("schema %s is missing", name)
+		}
+	}
+}
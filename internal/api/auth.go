@@ -0,0 +1,303 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"strings"
+
+	"time"
+
+	"gosuda.org/jimin/database"
+	"gosuda.org/jimin/internal/auth"
+)
+
+// Authenticator signs users up and in, and keeps the sessions of the session cookie.
+type Authenticator interface {
+	Signup(ctx context.Context, name, email, password string) (database.User, error)
+	Login(ctx context.Context, email, password string) (database.User, error)
+	ChangePassword(ctx context.Context, userID, keepSession int64, current, password string) error
+
+	SendVerification(ctx context.Context, userID int64) error
+	VerifyEmail(ctx context.Context, token string) error
+	// RequestPasswordReset sends a reset email, and reports no error for unknown emails.
+	RequestPasswordReset(ctx context.Context, email string) error
+	ResetPassword(ctx context.Context, token, password string) error
+
+	CreateSession(ctx context.Context, userID int64, userAgent, ip string) (string, database.Session, error)
+	// Session returns the session of a token, and whether its expiry was extended.
+	Session(ctx context.Context, token string) (database.Session, bool, error)
+	Sessions(ctx context.Context, userID int64) ([]database.Session, error)
+	Revoke(ctx context.Context, userID, sessionID int64) error
+
+	Cookie(token string, s database.Session) *http.Cookie
+	ClearCookie() *http.Cookie
+	CookieName() string
+
+	// StartOIDC returns the provider URL of a new login, linking to linkUserID if not zero, and its state.
+	StartOIDC(ctx context.Context, provider string, linkUserID int64, returnTo string) (string, string, error)
+	FinishOIDC(ctx context.Context, provider, state, code string) (auth.OIDCLogin, error)
+	Identities(ctx context.Context, userID int64) ([]auth.Identity, error)
+	Unlink(ctx context.Context, userID int64, provider string) error
+
+	StateCookie(state string) *http.Cookie
+	ClearStateCookie() *http.Cookie
+	StateCookieName() string
+
+	// CreateAPIToken returns a new token of a user, of a workspace if wsID is not zero, expiring at expires unless it is zero.
+	CreateAPIToken(ctx context.Context, userID, wsID int64, name string, scopes []string, expires time.Time) (string, database.ApiToken, error)
+	// APIToken returns the valid API token of token and records its use.
+	APIToken(ctx context.Context, token string) (database.ApiToken, error)
+	APITokens(ctx context.Context, userID int64) ([]database.ApiToken, error)
+	RevokeAPIToken(ctx context.Context, userID, id int64) error
+	WorkspaceKeys(ctx context.Context, wsID int64) ([]database.ApiToken, error)
+	RevokeWorkspaceKey(ctx context.Context, wsID, id int64) error
+}
+
+// principal is the signed in user of a request, with the session or the API token it authenticated with.
+type principal struct {
+	userID  int64
+	session database.Session
+	token   *database.ApiToken
+}
+
+type principalKey struct{}
+
+func principalOf(ctx context.Context) (principal, bool) {
+	p, ok := ctx.Value(principalKey{}).(principal)
+	return p, ok
+}
+
+// withCookies is a response setting cookies.
+type withCookies struct {
+	resp    any
+	cookies []*http.Cookie
+}
+
+// redirect is a response redirecting to its URL with the status of the route.
+type redirect string
+
+// authenticate returns r with the principal of its API token or session cookie. The cookie is sent again if the session was extended.
+// Requests to public routes may have no session.
+func (a *API) authenticate(w http.ResponseWriter, r *http.Request, rt route) (*http.Request, error) {
+	if h := r.Header.Get("Authorization"); h != "" {
+		return a.authenticateToken(r, rt, h)
+	}
+
+	c, err := r.Cookie(a.cfg.Auth.CookieName())
+	if err != nil {
+		if rt.public {
+			return r, nil
+		}
+		return r, problemf(http.StatusUnauthorized, "sign in required")
+	}
+
+	s, touched, err := a.cfg.Auth.Session(r.Context(), c.Value)
+	if errors.Is(err, auth.ErrInvalidSession) {
+		http.SetCookie(w, a.cfg.Auth.ClearCookie())
+		if rt.public {
+			return r, nil
+		}
+		return r, problemf(http.StatusUnauthorized, "the session expired or was signed out")
+	}
+	if err != nil {
+		return r, err
+	}
+	if touched {
+		http.SetCookie(w, a.cfg.Auth.Cookie(c.Value, s))
+	}
+	return r.WithContext(context.WithValue(r.Context(), principalKey{}, principal{userID: s.UserID, session: s})), nil
+}
+
+// authenticateToken returns r with the principal of the bearer token of an Authorization header.
+// The token needs the scope of the route, and the key of a workspace only works on the routes of its workspace.
+func (a *API) authenticateToken(r *http.Request, rt route, h string) (*http.Request, error) {
+	scheme, token, ok := strings.Cut(h, " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") {
+		return r, problemf(http.StatusUnauthorized, "the Authorization header is not a bearer token")
+	}
+	t, err := a.cfg.Auth.APIToken(r.Context(), strings.TrimSpace(token))
+	if errors.Is(err, auth.ErrInvalidAPIToken) {
+		return r, problemf(http.StatusUnauthorized, "%v", err)
+	}
+	if err != nil {
+		return r, err
+	}
+
+	if rt.scope == "" {
+		return r, problemf(http.StatusForbidden, "%s %s needs a session, not an API token", rt.method, rt.path)
+	}
+	if !auth.HasScope(t.Scopes, rt.scope) {
+		return r, problemf(http.StatusForbidden, "the API token lacks the %s scope", rt.scope)
+	}
+	if t.WsID != 0 {
+		wsID, err := pathID(r, "ws_id")
+		if !strings.Contains(rt.path, "{ws_id}") || err != nil || wsID != t.WsID {
+			return r, problemf(http.StatusForbidden, "the API key only works on workspace %d", t.WsID)
+		}
+	}
+	return r.WithContext(context.WithValue(r.Context(), principalKey{}, principal{userID: t.UserID, token: &t})), nil
+}
+
+// clientIP returns the address of the client of r, without its port.
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// signIn creates a session of user and responds with it and its cookie.
+func (a *API) signIn(r *http.Request, user database.User) (any, error) {
+	token, s, err := a.cfg.Auth.CreateSession(r.Context(), user.ID, r.UserAgent(), clientIP(r))
+	if err != nil {
+		return nil, err
+	}
+	return withCookies{
+		resp:    CurrentSession{User: userOf(user), Session: sessionOf(s, s.ID)},
+		cookies: []*http.Cookie{a.cfg.Auth.Cookie(token, s)},
+	}, nil
+}
+
+func (v *validation) password(s, field string) {
+	v.check(auth.CheckPassword(s) == nil, field, "must be between 8 and 1024 bytes")
+}
+
+func (a *API) signup(r *http.Request) (any, error) {
+	if a.cfg.Auth == nil {
+		return nil, unavailable("authentication")
+	}
+	var req SignupRequest
+	if err := decode(r, &req); err != nil {
+		return nil, err
+	}
+	var v validation
+	v.required(req.Name, "name")
+	v.email(req.Email, "email")
+	v.password(req.Password, "password")
+	if err := v.err(); err != nil {
+		return nil, err
+	}
+
+	user, err := a.cfg.Auth.Signup(r.Context(), req.Name, req.Email, req.Password)
+	if err != nil {
+		return nil, err
+	}
+	return a.signIn(r, user)
+}
+
+func (a *API) login(r *http.Request) (any, error) {
+	if a.cfg.Auth == nil {
+		return nil, unavailable("authentication")
+	}
+	var req LoginRequest
+	if err := decode(r, &req); err != nil {
+		return nil, err
+	}
+	var v validation
+	v.required(req.Email, "email")
+	v.required(req.Password, "password")
+	if err := v.err(); err != nil {
+		return nil, err
+	}
+
+	user, err := a.cfg.Auth.Login(r.Context(), req.Email, req.Password)
+	if err != nil {
+		return nil, err
+	}
+	return a.signIn(r, user)
+}
+
+// signedIn returns the principal of r, which the authenticated routes always have.
+func (a *API) signedIn(r *http.Request) (principal, error) {
+	if a.cfg.Auth == nil {
+		return principal{}, unavailable("authentication")
+	}
+	p, ok := principalOf(r.Context())
+	if !ok {
+		return p, problemf(http.StatusUnauthorized, "sign in required")
+	}
+	return p, nil
+}
+
+func (a *API) logout(r *http.Request) (any, error) {
+	p, err := a.signedIn(r)
+	if err != nil {
+		return nil, err
+	}
+	if err := a.cfg.Auth.Revoke(r.Context(), p.userID, p.session.ID); err != nil && !errors.Is(err, auth.ErrInvalidSession) {
+		return nil, err
+	}
+	return withCookies{cookies: []*http.Cookie{a.cfg.Auth.ClearCookie()}}, nil
+}
+
+func (a *API) currentSession(r *http.Request) (any, error) {
+	p, err := a.signedIn(r)
+	if err != nil {
+		return nil, err
+	}
+	user, err := a.q.GetUserByID(r.Context(), p.userID)
+	if err != nil {
+		return nil, err
+	}
+	return CurrentSession{User: userOf(user), Session: sessionOf(p.session, p.session.ID)}, nil
+}
+
+func (a *API) changePassword(r *http.Request) (any, error) {
+	p, err := a.signedIn(r)
+	if err != nil {
+		return nil, err
+	}
+	var req ChangePasswordRequest
+	if err := decode(r, &req); err != nil {
+		return nil, err
+	}
+	var v validation
+	v.required(req.CurrentPassword, "current_password")
+	v.password(req.NewPassword, "new_password")
+	if err := v.err(); err != nil {
+		return nil, err
+	}
+
+	err = a.cfg.Auth.ChangePassword(r.Context(), p.userID, p.session.ID, req.CurrentPassword, req.NewPassword)
+	if errors.Is(err, auth.ErrInvalidCredentials) {
+		return nil, invalid(FieldError{Field: "current_password", Detail: "is not the current password"})
+	}
+	return nil, err
+}
+
+func (a *API) listSessions(r *http.Request) (any, error) {
+	p, err := a.signedIn(r)
+	if err != nil {
+		return nil, err
+	}
+	sessions, err := a.cfg.Auth.Sessions(r.Context(), p.userID)
+	if err != nil {
+		return nil, err
+	}
+
+	items := make([]Session, len(sessions))
+	for i, s := range sessions {
+		items[i] = sessionOf(s, p.session.ID)
+	}
+	return items, nil
+}
+
+func (a *API) revokeSession(r *http.Request) (any, error) {
+	p, err := a.signedIn(r)
+	if err != nil {
+		return nil, err
+	}
+	id, err := pathID(r, "session_id")
+	if err != nil {
+		return nil, err
+	}
+
+	err = a.cfg.Auth.Revoke(r.Context(), p.userID, id)
+	if errors.Is(err, auth.ErrInvalidSession) {
+		return nil, problemf(http.StatusNotFound, "session %d not found", id)
+	}
+	return nil, err
+}
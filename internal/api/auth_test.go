@@ -0,0 +1,826 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"gosuda.org/jimin/database"
+	"gosuda.org/jimin/internal/auth"
+	"gosuda.org/jimin/internal/oidc"
+	"gosuda.org/jimin/internal/rbac"
+)
+
+// fakeAuth has a single user with the password "password" and the session "token".
+type fakeAuth struct {
+	touched bool
+	revoked []int64
+
+	linkUserID int64
+	returnTo   string
+
+	verified bool
+	resets   []string
+}
+
+var fakeSession = database.Session{ID: 2, UserID: 1, ExpiresAt: pgtype.Timestamptz{Time: time.Now().Add(time.Hour), Valid: true}}
+
+func (f *fakeAuth) Signup(ctx context.Context, name, email, password string) (database.User, error) {
+	return database.User{ID: 1, Name: name, Email: email}, nil
+}
+
+func (f *fakeAuth) Login(ctx context.Context, email, password string) (database.User, error) {
+	if password != "password" {
+		return database.User{}, auth.ErrInvalidCredentials
+	}
+	return database.User{ID: 1, Email: email}, nil
+}
+
+func (f *fakeAuth) ChangePassword(ctx context.Context, userID, keepSession int64, current, password string) error {
+	if current != "password" {
+		return auth.ErrInvalidCredentials
+	}
+	return nil
+}
+
+func (f *fakeAuth) SendVerification(ctx context.Context, userID int64) error {
+	if f.verified {
+		return auth.ErrAlreadyVerified
+	}
+	return auth.ErrRateLimited
+}
+
+// VerifyEmail and ResetPassword accept the token "valid".
+func (f *fakeAuth) VerifyEmail(ctx context.Context, token string) error {
+	if token != "valid" {
+		return auth.ErrInvalidToken
+	}
+	return nil
+}
+
+func (f *fakeAuth) RequestPasswordReset(ctx context.Context, email string) error {
+	f.resets = append(f.resets, email)
+	return nil
+}
+
+func (f *fakeAuth) ResetPassword(ctx context.Context, token, password string) error {
+	if token != "valid" {
+		return auth.ErrInvalidToken
+	}
+	return nil
+}
+
+func (f *fakeAuth) CreateSession(ctx context.Context, userID int64, userAgent, ip string) (string, database.Session, error) {
+	return "token", fakeSession, nil
+}
+
+func (f *fakeAuth) Session(ctx context.Context, token string) (database.Session, bool, error) {
+	if token != "token" {
+		return database.Session{}, false, auth.ErrInvalidSession
+	}
+	return fakeSession, f.touched, nil
+}
+
+func (f *fakeAuth) Sessions(ctx context.Context, userID int64) ([]database.Session, error) {
+	return []database.Session{fakeSession, {ID: 3, UserID: 1}}, nil
+}
+
+func (f *fakeAuth) Revoke(ctx context.Context, userID, sessionID int64) error {
+	if sessionID != fakeSession.ID && sessionID != 3 {
+		return auth.ErrInvalidSession
+	}
+	f.revoked = append(f.revoked, sessionID)
+	return nil
+}
+
+func (f *fakeAuth) Cookie(token string, s database.Session) *http.Cookie {
+	return &http.Cookie{Name: "session", Value: token, Path: "/"}
+}
+
+func (f *fakeAuth) ClearCookie() *http.Cookie {
+	return &http.Cookie{Name: "session", Path: "/", MaxAge: -1}
+}
+
+func (f *fakeAuth) CookieName() string { return "session" }
+
+// fakeAuth knows the provider "idp", which signs user 1 in, or links it, with the code "code".
+func (f *fakeAuth) StartOIDC(ctx context.Context, provider string, linkUserID int64, returnTo string) (string, string, error) {
+	if provider != "idp" {
+		return "", "", auth.ErrUnknownProvider
+	}
+	f.linkUserID, f.returnTo = linkUserID, returnTo
+	return "https://idp.example/authorize", "state", nil
+}
+
+func (f *fakeAuth) FinishOIDC(ctx context.Context, provider, state, code string) (auth.OIDCLogin, error) {
+	if provider != "idp" {
+		return auth.OIDCLogin{}, auth.ErrUnknownProvider
+	}
+	if state != "state" {
+		return auth.OIDCLogin{}, auth.ErrLoginExpired
+	}
+	if code != "code" {
+		return auth.OIDCLogin{}, oidc.ErrExchange
+	}
+	return auth.OIDCLogin{User: database.User{ID: 1}, Linked: f.linkUserID != 0, ReturnTo: f.returnTo}, nil
+}
+
+func (f *fakeAuth) Identities(ctx context.Context, userID int64) ([]auth.Identity, error) {
+	return []auth.Identity{{Provider: "password", Linked: true}, {Provider: "idp"}}, nil
+}
+
+func (f *fakeAuth) Unlink(ctx context.Context, userID int64, provider string) error {
+	if provider == "password" {
+		return auth.ErrLastCredential
+	}
+	return auth.ErrNotLinked
+}
+
+func (f *fakeAuth) StateCookie(state string) *http.Cookie {
+	return &http.Cookie{Name: "state", Value: state, Path: "/"}
+}
+
+func (f *fakeAuth) ClearStateCookie() *http.Cookie {
+	return &http.Cookie{Name: "state", Path: "/", MaxAge: -1}
+}
+
+func (f *fakeAuth) StateCookieName() string { return "state" }
+
+// fakeTokens are the API tokens of user 1.
+var fakeTokens = map[string]database.ApiToken{
+	"jimin_pat_read":  {ID: 10, UserID: 1, Scopes: []string{auth.ScopeRead}},
+	"jimin_pat_admin": {ID: 11, UserID: 1, Scopes: []string{auth.ScopeAdmin}},
+	"jimin_wsk_ws1":   {ID: 12, UserID: 1, WsID: 1, Scopes: []string{auth.ScopeRead, auth.ScopeIngest}},
+}
+
+// CreateAPIToken fails for workspace 2, of which user 1 is not a member.
+func (f *fakeAuth) CreateAPIToken(ctx context.Context, userID, wsID int64, name string, scopes []string, expires time.Time) (string, database.ApiToken, error) {
+	if wsID == 2 {
+		return "", database.ApiToken{}, auth.ErrNotMember
+	}
+	return "jimin_pat_new", database.ApiToken{ID: 13, UserID: userID, WsID: wsID, Name: name, Scopes: scopes}, nil
+}
+
+func (f *fakeAuth) APIToken(ctx context.Context, token string) (database.ApiToken, error) {
+	t, ok := fakeTokens[token]
+	if !ok {
+		return t, auth.ErrInvalidAPIToken
+	}
+	return t, nil
+}
+
+func (f *fakeAuth) APITokens(ctx context.Context, userID int64) ([]database.ApiToken, error) {
+	return []database.ApiToken{fakeTokens["jimin_pat_read"], fakeTokens["jimin_pat_admin"]}, nil
+}
+
+func (f *fakeAuth) RevokeAPIToken(ctx context.Context, userID, id int64) error {
+	if id != 10 && id != 11 {
+		return auth.ErrInvalidAPIToken
+	}
+	return nil
+}
+
+func (f *fakeAuth) WorkspaceKeys(ctx context.Context, wsID int64) ([]database.ApiToken, error) {
+	return []database.ApiToken{fakeTokens["jimin_wsk_ws1"]}, nil
+}
+
+func (f *fakeAuth) RevokeWorkspaceKey(ctx context.Context, wsID, id int64) error {
+	if wsID != 1 || id != 12 {
+		return auth.ErrInvalidAPIToken
+	}
+	return nil
+}
+
+// fakeAuthz makes every user a member of workspace 1 with access, and of no other workspace,
+// sharing it with user 2 only.
+type fakeAuthz struct {
+	access rbac.Access
+}
+
+func (f fakeAuthz) Authorize(ctx context.Context, userID, wsID int64, perm rbac.Permission) error {
+	if slices.Contains(rbac.UserPermissions, perm) {
+		if perm == rbac.ViewUsers && wsID == 2 {
+			return nil
+		}
+		return rbac.ErrNotMember
+	}
+	if wsID != 1 {
+		return rbac.ErrNotMember
+	}
+	if !f.access.Allows(perm) {
+		return rbac.ErrForbidden
+	}
+	return nil
+}
+
+func (f fakeAuthz) Access(ctx context.Context, userID, wsID int64) (rbac.Access, error) {
+	if wsID != 1 {
+		return rbac.Access{}, rbac.ErrNotMember
+	}
+	return f.access, nil
+}
+
+// fakeIDs generates 100 forever.
+type fakeIDs struct{}
+
+func (fakeIDs) Generate(context.Context) (int64, error) { return 100, nil }
+
+func serveAs(a *API, token, method, path, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	if body != "" {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if token != "" {
+		req.AddCookie(&http.Cookie{Name: "session", Value: token})
+	}
+	rec := httptest.NewRecorder()
+	a.Handler().ServeHTTP(rec, req)
+	return rec
+}
+
+// sessionCookie returns the last session cookie of a response, the one a browser keeps.
+func sessionCookie(rec *httptest.ResponseRecorder) *http.Cookie {
+	var cookie *http.Cookie
+	for _, c := range rec.Result().Cookies() {
+		if c.Name == "session" {
+			cookie = c
+		}
+	}
+	return cookie
+}
+
+func TestAuthRequired(t *testing.T) {
+	a := New(Config{DB: fakeDB{}, Auth: &fakeAuth{}})
+
+	for _, token := range []string{"", "expired"} {
+		rec := serveAs(a, token, "GET", "/v1/users", "")
+		if rec.Code != http.StatusUnauthorized {
+			t.Errorf("GET /v1/users with %q = %d, want %d", token, rec.Code, http.StatusUnauthorized)
+			continue
+		}
+		problem(t, rec)
+		if c := sessionCookie(rec); token != "" && (c == nil || c.MaxAge >= 0) {
+			t.Errorf("invalid session cookie %q is not cleared", token)
+		}
+	}
+
+	// validated once signed in
+	if rec := serveAs(a, "token", "GET", "/v1/users?limit=0", ""); rec.Code != http.StatusBadRequest {
+		t.Errorf("GET /v1/users signed in = %d, want %d", rec.Code, http.StatusBadRequest)
+	}
+	// public routes
+	if rec := serveAs(a, "expired", "POST", "/v1/auth/login", `{}`); rec.Code != http.StatusBadRequest {
+		t.Errorf("POST /v1/auth/login = %d, want %d", rec.Code, http.StatusBadRequest)
+	}
+}
+
+func TestLogin(t *testing.T) {
+	a := New(Config{DB: fakeDB{}, Auth: &fakeAuth{}})
+
+	rec := serveAs(a, "", "POST", "/v1/auth/login", `{"email":"a@example.com","password":"wrong"}`)
+	if rec.Code != http.StatusUnauthorized {
+		t.Errorf("wrong password = %d, want %d", rec.Code, http.StatusUnauthorized)
+	}
+	if sessionCookie(rec) != nil {
+		t.Error("wrong password sets a session cookie")
+	}
+
+	rec = serveAs(a, "", "POST", "/v1/auth/login", `{"email":"a@example.com","password":"password"}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("login = %d: %s", rec.Code, rec.Body)
+	}
+	if c := sessionCookie(rec); c == nil || c.Value != "token" {
+		t.Errorf("login cookie = %v", c)
+	}
+	if !strings.Contains(rec.Body.String(), `"current":true`) {
+		t.Errorf("login body = %s", rec.Body)
+	}
+
+	rec = serveAs(a, "", "POST", "/v1/auth/signup", `{"name":"a","email":"a@example.com","password":"short"}`)
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("short password signup = %d, want %d", rec.Code, http.StatusBadRequest)
+	}
+	rec = serveAs(a, "", "POST", "/v1/auth/signup", `{"name":"a","email":"a@example.com","password":"long enough"}`)
+	if rec.Code != http.StatusCreated || sessionCookie(rec) == nil {
+		t.Errorf("signup = %d, cookie %v", rec.Code, sessionCookie(rec))
+	}
+}
+
+func TestSession(t *testing.T) {
+	f := &fakeAuth{touched: true}
+	a := New(Config{DB: fakeDB{}, Auth: f})
+
+	rec := serveAs(a, "token", "GET", "/v1/auth/sessions", "")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("sessions = %d: %s", rec.Code, rec.Body)
+	}
+	if c := sessionCookie(rec); c == nil || c.Value != "token" {
+		t.Errorf("extended session cookie = %v", c)
+	}
+
+	rec = serveAs(a, "token", "PUT", "/v1/auth/password", `{"current_password":"wrong","new_password":"long enough"}`)
+	if rec.Code != http.StatusBadRequest || problem(t, rec).Errors[0].Field != "current_password" {
+		t.Errorf("wrong current password = %d: %s", rec.Code, rec.Body)
+	}
+	if rec := serveAs(a, "token", "PUT", "/v1/auth/password", `{"current_password":"password","new_password":"long enough"}`); rec.Code != http.StatusNoContent {
+		t.Errorf("change password = %d: %s", rec.Code, rec.Body)
+	}
+
+	if rec := serveAs(a, "token", "DELETE", "/v1/auth/sessions/9", ""); rec.Code != http.StatusNotFound {
+		t.Errorf("revoke unknown session = %d, want %d", rec.Code, http.StatusNotFound)
+	}
+	rec = serveAs(a, "token", "POST", "/v1/auth/logout", "")
+	if rec.Code != http.StatusNoContent {
+		t.Errorf("logout = %d", rec.Code)
+	}
+	if c := sessionCookie(rec); c == nil || c.MaxAge >= 0 {
+		t.Errorf("logout cookie = %v", c)
+	}
+	if len(f.revoked) != 1 || f.revoked[0] != fakeSession.ID {
+		t.Errorf("revoked = %v, want [%d]", f.revoked, fakeSession.ID)
+	}
+}
+
+func TestOIDC(t *testing.T) {
+	f := &fakeAuth{}
+	a := New(Config{DB: fakeDB{}, Auth: f})
+
+	for _, to := range []string{"https://evil.example", "//evil.example", `/\evil.example`} {
+		if rec := serveAs(a, "", "GET", "/v1/auth/oidc/idp/login?return_to="+url.QueryEscape(to), ""); rec.Code != http.StatusBadRequest {
+			t.Errorf("login returning to %s = %d, want %d", to, rec.Code, http.StatusBadRequest)
+		}
+	}
+	if rec := serveAs(a, "", "GET", "/v1/auth/oidc/other/login", ""); rec.Code != http.StatusNotFound {
+		t.Errorf("login with an unknown provider = %d, want %d", rec.Code, http.StatusNotFound)
+	}
+
+	rec := serveAs(a, "", "GET", "/v1/auth/oidc/idp/login?return_to=/docs", "")
+	if rec.Code != http.StatusFound || rec.Header().Get("Location") != "https://idp.example/authorize" {
+		t.Fatalf("login = %d to %q", rec.Code, rec.Header().Get("Location"))
+	}
+	var state *http.Cookie
+	for _, c := range rec.Result().Cookies() {
+		if c.Name == "state" {
+			state = c
+		}
+	}
+	if state == nil || state.Value != "state" {
+		t.Fatalf("state cookie = %v", state)
+	}
+
+	callback := func(query string, cookies ...*http.Cookie) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", "/v1/auth/oidc/idp/callback?"+query, nil)
+		for _, c := range cookies {
+			req.AddCookie(c)
+		}
+		rec := httptest.NewRecorder()
+		a.Handler().ServeHTTP(rec, req)
+		return rec
+	}
+	tests := []struct {
+		name, query string
+		cookie      *http.Cookie
+		want        int
+	}{
+		{"another browser", "state=state&code=code", nil, http.StatusBadRequest},
+		{"another state", "state=other&code=code", &http.Cookie{Name: "state", Value: "other"}, http.StatusBadRequest},
+		{"cookie mismatch", "state=state&code=code", &http.Cookie{Name: "state", Value: "other"}, http.StatusBadRequest},
+		{"bad code", "state=state&code=forged", state, http.StatusUnauthorized},
+		{"refused", "error=access_denied&state=state", state, http.StatusUnauthorized},
+		{"no code", "state=state", state, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		var cookies []*http.Cookie
+		if tt.cookie != nil {
+			cookies = append(cookies, tt.cookie)
+		}
+		if rec := callback(tt.query, cookies...); rec.Code != tt.want {
+			t.Errorf("%s callback = %d, want %d", tt.name, rec.Code, tt.want)
+		}
+	}
+
+	rec = callback("state=state&code=code", state)
+	if rec.Code != http.StatusFound || rec.Header().Get("Location") != "/docs" {
+		t.Fatalf("callback = %d to %q: %s", rec.Code, rec.Header().Get("Location"), rec.Body)
+	}
+	if c := sessionCookie(rec); c == nil || c.Value != "token" {
+		t.Errorf("callback session cookie = %v", c)
+	}
+
+	// linking keeps the session
+	if rec := serveAs(a, "", "GET", "/v1/auth/oidc/idp/link", ""); rec.Code != http.StatusUnauthorized {
+		t.Errorf("link signed out = %d, want %d", rec.Code, http.StatusUnauthorized)
+	}
+	if rec := serveAs(a, "token", "GET", "/v1/auth/oidc/idp/link", ""); rec.Code != http.StatusFound || f.linkUserID != 1 {
+		t.Errorf("link = %d, linking user %d", rec.Code, f.linkUserID)
+	}
+	rec = callback("state=state&code=code", state)
+	if rec.Code != http.StatusFound || rec.Header().Get("Location") != "/" || sessionCookie(rec) != nil {
+		t.Errorf("link callback = %d to %q, session cookie %v", rec.Code, rec.Header().Get("Location"), sessionCookie(rec))
+	}
+}
+
+func TestIdentities(t *testing.T) {
+	a := New(Config{DB: fakeDB{}, Auth: &fakeAuth{}})
+
+	rec := serveAs(a, "token", "GET", "/v1/auth/identities", "")
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"provider":"idp","linked":false`) {
+		t.Errorf("identities = %d: %s", rec.Code, rec.Body)
+	}
+	if rec := serveAs(a, "token", "DELETE", "/v1/auth/identities/password", ""); rec.Code != http.StatusConflict {
+		t.Errorf("unlink the last method = %d, want %d", rec.Code, http.StatusConflict)
+	}
+	if rec := serveAs(a, "token", "DELETE", "/v1/auth/identities/idp", ""); rec.Code != http.StatusNotFound {
+		t.Errorf("unlink an unlinked provider = %d, want %d", rec.Code, http.StatusNotFound)
+	}
+}
+
+func TestEmail(t *testing.T) {
+	f := &fakeAuth{}
+	a := New(Config{DB: fakeDB{}, Auth: f})
+
+	tests := []struct {
+		name, token, method, path, body string
+		want                            int
+	}{
+		{"verify", "", "POST", "/v1/auth/email/verify", `{"token":"valid"}`, http.StatusNoContent},
+		{"verify with a used token", "", "POST", "/v1/auth/email/verify", `{"token":"used"}`, http.StatusBadRequest},
+		{"verify without a token", "", "POST", "/v1/auth/email/verify", `{}`, http.StatusBadRequest},
+		{"resend signed out", "", "POST", "/v1/auth/email/verification", "", http.StatusUnauthorized},
+		{"resend too often", "token", "POST", "/v1/auth/email/verification", "", http.StatusTooManyRequests},
+		{"forgot", "", "POST", "/v1/auth/password/forgot", `{"email":"someone@example.com"}`, http.StatusAccepted},
+		{"forgot an invalid email", "", "POST", "/v1/auth/password/forgot", `{"email":"someone"}`, http.StatusBadRequest},
+		{"reset a short password", "", "POST", "/v1/auth/password/reset", `{"token":"valid","new_password":"short"}`, http.StatusBadRequest},
+		{"reset with an invalid token", "", "POST", "/v1/auth/password/reset", `{"token":"forged","new_password":"long enough"}`, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		if rec := serveAs(a, tt.token, tt.method, tt.path, tt.body); rec.Code != tt.want {
+			t.Errorf("%s = %d, want %d: %s", tt.name, rec.Code, tt.want, rec.Body)
+		}
+	}
+	if len(f.resets) != 1 || f.resets[0] != "someone@example.com" {
+		t.Errorf("reset emails = %v", f.resets)
+	}
+
+	f.verified = true
+	if rec := serveAs(a, "token", "POST", "/v1/auth/email/verification", ""); rec.Code != http.StatusConflict {
+		t.Errorf("resend verified = %d, want %d", rec.Code, http.StatusConflict)
+	}
+
+	rec := serveAs(a, "token", "POST", "/v1/auth/password/reset", `{"token":"valid","new_password":"long enough"}`)
+	if rec.Code != http.StatusNoContent {
+		t.Errorf("reset = %d: %s", rec.Code, rec.Body)
+	}
+	if c := sessionCookie(rec); c == nil || c.MaxAge >= 0 {
+		t.Errorf("reset session cookie = %v, want it cleared", c)
+	}
+}
+
+func TestAPITokens(t *testing.T) {
+	a := New(Config{DB: fakeDB{}, Auth: &fakeAuth{}, Authz: fakeAuthz{rbac.Access{Role: rbac.Owner}}})
+
+	bearer := func(authorization, method, path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Authorization", authorization)
+		// the token is used over the cookie
+		req.AddCookie(&http.Cookie{Name: "session", Value: "token"})
+		rec := httptest.NewRecorder()
+		a.Handler().ServeHTTP(rec, req)
+		return rec
+	}
+	tests := []struct {
+		name, authorization, method, path, body string
+		want                                    int
+	}{
+		{"unknown token", "Bearer jimin_pat_revoked", "GET", "/v1/workspaces/1", "", http.StatusUnauthorized},
+		{"basic auth", "Basic dXNlcjpwYXNz", "GET", "/v1/workspaces/1", "", http.StatusUnauthorized},
+		{"read", "Bearer jimin_pat_read", "GET", "/v1/workspaces/1", "", http.StatusOK},
+		{"lowercase scheme", "bearer jimin_pat_read", "GET", "/v1/workspaces/1", "", http.StatusOK},
+		{"write with read", "Bearer jimin_pat_read", "PATCH", "/v1/workspaces/1", `{"name":"a"}`, http.StatusForbidden},
+		{"ask with read", "Bearer jimin_pat_read", "POST", "/v1/workspaces/1/ask", `{"question":"?"}`, http.StatusForbidden},
+		{"write with admin", "Bearer jimin_pat_admin", "PATCH", "/v1/workspaces/1", `{"name":" "}`, http.StatusBadRequest},
+		{"sessions", "Bearer jimin_pat_admin", "GET", "/v1/auth/sessions", "", http.StatusForbidden},
+		{"create a token", "Bearer jimin_pat_admin", "POST", "/v1/auth/tokens", `{"name":"a","scopes":["read"]}`, http.StatusForbidden},
+		{"workspace key", "Bearer jimin_wsk_ws1", "GET", "/v1/workspaces/1", "", http.StatusOK},
+		{"workspace key ingest", "Bearer jimin_wsk_ws1", "POST", "/v1/workspaces/1/reprocess", `{}`, http.StatusServiceUnavailable},
+		{"workspace key on another workspace", "Bearer jimin_wsk_ws1", "GET", "/v1/workspaces/2", "", http.StatusForbidden},
+		{"workspace key outside workspaces", "Bearer jimin_wsk_ws1", "GET", "/v1/users/1", "", http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		if rec := bearer(tt.authorization, tt.method, tt.path, tt.body); rec.Code != tt.want {
+			t.Errorf("%s = %d, want %d: %s", tt.name, rec.Code, tt.want, rec.Body)
+		}
+	}
+}
+
+func TestManageAPITokens(t *testing.T) {
+	a := New(Config{DB: fakeDB{}, Auth: &fakeAuth{}, Authz: fakeAuthz{rbac.Access{Role: rbac.Owner}}})
+
+	past := time.Now().Add(-time.Hour).Format(time.RFC3339)
+	tests := []struct {
+		name, method, path, body string
+		want                     int
+		fields                   []string
+	}{
+		{"no name", "POST", "/v1/auth/tokens", `{"scopes":["read"]}`, http.StatusBadRequest, []string{"name"}},
+		{"no scopes", "POST", "/v1/auth/tokens", `{"name":"ci"}`, http.StatusBadRequest, []string{"scopes"}},
+		{"unknown scope", "POST", "/v1/auth/tokens", `{"name":"ci","scopes":["write"]}`, http.StatusBadRequest, []string{"scopes"}},
+		{"expired", "POST", "/v1/auth/tokens", `{"name":"ci","scopes":["read"],"expires_at":"` + past + `"}`, http.StatusBadRequest, []string{"expires_at"}},
+		{"create", "POST", "/v1/auth/tokens", `{"name":"ci","scopes":["read","ingest"]}`, http.StatusCreated, nil},
+		{"list", "GET", "/v1/auth/tokens", "", http.StatusOK, nil},
+		{"revoke", "DELETE", "/v1/auth/tokens/10", "", http.StatusNoContent, nil},
+		{"revoke unknown", "DELETE", "/v1/auth/tokens/12", "", http.StatusNotFound, nil},
+		{"create a key", "POST", "/v1/workspaces/1/api-keys", `{"name":"ci","scopes":["ingest"]}`, http.StatusCreated, nil},
+		{"create a key of another workspace", "POST", "/v1/workspaces/2/api-keys", `{"name":"ci","scopes":["ingest"]}`, http.StatusNotFound, nil},
+		{"list keys", "GET", "/v1/workspaces/1/api-keys", "", http.StatusOK, nil},
+		{"revoke a key", "DELETE", "/v1/workspaces/1/api-keys/12", "", http.StatusNoContent, nil},
+		{"revoke a key of another workspace", "DELETE", "/v1/workspaces/2/api-keys/12", "", http.StatusNotFound, nil},
+	}
+	for _, tt := range tests {
+		rec := serveAs(a, "token", tt.method, tt.path, tt.body)
+		if rec.Code != tt.want {
+			t.Errorf("%s = %d, want %d: %s", tt.name, rec.Code, tt.want, rec.Body)
+			continue
+		}
+		if tt.fields != nil {
+			p := problem(t, rec)
+			if len(p.Errors) != len(tt.fields) || p.Errors[0].Field != tt.fields[0] {
+				t.Errorf("%s errors = %v, want %v", tt.name, p.Errors, tt.fields)
+			}
+		}
+	}
+
+	rec := serveAs(a, "token", "POST", "/v1/auth/tokens", `{"name":"ci","scopes":["read"]}`)
+	if !strings.Contains(rec.Body.String(), `"token":"jimin_pat_new"`) {
+		t.Errorf("created token = %s", rec.Body)
+	}
+	if rec := serveAs(a, "", "GET", "/v1/auth/tokens", ""); rec.Code != http.StatusUnauthorized {
+		t.Errorf("list tokens signed out = %d, want %d", rec.Code, http.StatusUnauthorized)
+	}
+}
+
+func TestOpenAPISecurity(t *testing.T) {
+	a := New(Config{Auth: &fakeAuth{}})
+	var spec struct {
+		Paths map[string]map[string]struct {
+			Security *[]map[string][]string `json:"security"`
+		} `json:"paths"`
+	}
+	if err := json.Unmarshal(a.spec, &spec); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, rt := range a.routes {
+		op := spec.Paths[rt.path][strings.ToLower(rt.method)]
+		switch {
+		case rt.public:
+			if op.Security == nil || len(*op.Security) != 0 {
+				t.Errorf("public %s has security %v", rt.id, op.Security)
+			}
+		case rt.scope != "":
+			if op.Security == nil || len(*op.Security) != 2 || (*op.Security)[1]["token"][0] != rt.scope {
+				t.Errorf("%s security = %v, want a session or a token with %s", rt.id, op.Security, rt.scope)
+			}
+		default:
+			// the document default, sessions only
+			if op.Security != nil {
+				t.Errorf("session only %s has security %v", rt.id, *op.Security)
+			}
+		}
+	}
+}
+
+func TestAuthorization(t *testing.T) {
+	for _, rt := range New(Config{}).routes {
+		if strings.Contains(rt.path, "{ws_id}") && (rt.perm == "" || slices.Contains(rbac.UserPermissions, rt.perm)) {
+			t.Errorf("%s has the permission %q", rt.id, rt.perm)
+		}
+		if strings.HasPrefix(rt.path, "/v1/users") && !slices.Contains(rbac.UserPermissions, rt.perm) {
+			t.Errorf("%s has the permission %q, want a permission on users", rt.id, rt.perm)
+		}
+	}
+
+	tests := []struct {
+		name               string
+		access             rbac.Access
+		method, path, body string
+		want               int
+	}{
+		{"viewer views", rbac.Access{Role: rbac.Viewer}, "GET", "/v1/workspaces/1", "", http.StatusOK},
+		{"viewer renames", rbac.Access{Role: rbac.Viewer}, "PATCH", "/v1/workspaces/1", `{"name":"a"}`, http.StatusForbidden},
+		{"viewer adds a document", rbac.Access{Role: rbac.Viewer}, "POST", "/v1/workspaces/1/documents", `{"url":"https://example.com"}`, http.StatusForbidden},
+		{"viewer with a custom role adds a document", rbac.Access{Role: rbac.Viewer, Granted: []rbac.Permission{rbac.AddDocuments}}, "POST", "/v1/workspaces/1/documents", `{"url":"https://example.com"}`, http.StatusServiceUnavailable},
+		{"editor renames", rbac.Access{Role: rbac.Editor}, "PATCH", "/v1/workspaces/1", `{"name":"a"}`, http.StatusForbidden},
+		{"editor adds a member", rbac.Access{Role: rbac.Editor}, "POST", "/v1/workspaces/1/members", `{"user_id":2}`, http.StatusForbidden},
+		{"editor lists API keys", rbac.Access{Role: rbac.Editor}, "GET", "/v1/workspaces/1/api-keys", "", http.StatusForbidden},
+		{"admin renames", rbac.Access{Role: rbac.Admin}, "PATCH", "/v1/workspaces/1", `{"name":"a"}`, http.StatusOK},
+		{"admin adds a member", rbac.Access{Role: rbac.Admin}, "POST", "/v1/workspaces/1/members", `{"user_id":2,"role":"editor"}`, http.StatusCreated},
+		{"admin adds an owner", rbac.Access{Role: rbac.Admin}, "POST", "/v1/workspaces/1/members", `{"user_id":2,"role":"owner"}`, http.StatusForbidden},
+		{"admin makes an owner", rbac.Access{Role: rbac.Admin}, "PATCH", "/v1/workspaces/1/members/3", `{"role":"owner"}`, http.StatusForbidden},
+		{"admin with a custom owner role", rbac.Access{Role: rbac.Admin, Granted: []rbac.Permission{rbac.ManageOwners}}, "PATCH", "/v1/workspaces/1/members/3", `{"role":"owner"}`, http.StatusForbidden},
+		{"owner adds an owner", rbac.Access{Role: rbac.Owner}, "POST", "/v1/workspaces/1/members", `{"user_id":2,"role":"owner"}`, http.StatusCreated},
+		{"admin adds an admin", rbac.Access{Role: rbac.Admin}, "POST", "/v1/workspaces/1/members", `{"user_id":2,"role":"admin"}`, http.StatusForbidden},
+		{"owner adds an admin", rbac.Access{Role: rbac.Owner}, "POST", "/v1/workspaces/1/members", `{"user_id":2,"role":"admin"}`, http.StatusCreated},
+		{"editor managing members adds an editor", rbac.Access{Role: rbac.Editor, Granted: []rbac.Permission{rbac.ManageMembers}}, "POST", "/v1/workspaces/1/members", `{"user_id":2,"role":"editor"}`, http.StatusCreated},
+		{"viewer managing members adds an editor", rbac.Access{Role: rbac.Viewer, Granted: []rbac.Permission{rbac.ManageMembers}}, "POST", "/v1/workspaces/1/members", `{"user_id":2,"role":"editor"}`, http.StatusForbidden},
+		{"unknown member role", rbac.Access{Role: rbac.Owner}, "POST", "/v1/workspaces/1/members", `{"user_id":2,"role":"guest"}`, http.StatusBadRequest},
+		{"admin creates a role", rbac.Access{Role: rbac.Admin}, "POST", "/v1/workspaces/1/roles", `{"name":"reviewer","permissions":["documents.view","search.ask"]}`, http.StatusCreated},
+		{"role named as a built-in role", rbac.Access{Role: rbac.Admin}, "POST", "/v1/workspaces/1/roles", `{"name":"Owner"}`, http.StatusBadRequest},
+		{"role granting owners", rbac.Access{Role: rbac.Owner}, "POST", "/v1/workspaces/1/roles", `{"name":"co-owner","permissions":["owners.manage"]}`, http.StatusBadRequest},
+		{"role granting more than its author has", rbac.Access{Role: rbac.Viewer, Granted: []rbac.Permission{rbac.ManageRoles}}, "POST", "/v1/workspaces/1/roles", `{"name":"manager","permissions":["members.manage"]}`, http.StatusForbidden},
+		{"role granting what its author has", rbac.Access{Role: rbac.Viewer, Granted: []rbac.Permission{rbac.ManageRoles}}, "POST", "/v1/workspaces/1/roles", `{"name":"reader","permissions":["documents.view","roles.manage"]}`, http.StatusCreated},
+		{"role changed to grant more than its author has", rbac.Access{Role: rbac.Editor, Granted: []rbac.Permission{rbac.ManageRoles}}, "PATCH", "/v1/workspaces/1/roles/4", `{"permissions":["api_keys.manage"]}`, http.StatusForbidden},
+		{"role renamed by a member without its permissions", rbac.Access{Role: rbac.Editor, Granted: []rbac.Permission{rbac.ManageRoles}}, "PATCH", "/v1/workspaces/1/roles/4", `{"name":"renamed"}`, http.StatusOK},
+		{"admin changes a role", rbac.Access{Role: rbac.Admin}, "PATCH", "/v1/workspaces/1/roles/4", `{"permissions":["documents.add"]}`, http.StatusOK},
+		{"editor changes a role", rbac.Access{Role: rbac.Editor}, "PATCH", "/v1/workspaces/1/roles/4", `{"permissions":["roles.manage"]}`, http.StatusForbidden},
+		{"not a member", rbac.Access{Role: rbac.Owner}, "GET", "/v1/workspaces/2", "", http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		a := New(Config{DB: fakeDB{}, IDs: fakeIDs{}, Auth: &fakeAuth{}, Authz: fakeAuthz{tt.access}})
+		if rec := serveAs(a, "token", tt.method, tt.path, tt.body); rec.Code != tt.want {
+			t.Errorf("%s = %d, want %d: %s", tt.name, rec.Code, tt.want, rec.Body)
+		}
+	}
+}
+
+// memberDB returns member 3 of workspace 1 with role, and fails the transactions changing it.
+type memberDB struct {
+	fakeDB
+	role database.WsMemberRole
+}
+
+func (db memberDB) QueryRow(_ context.Context, sql string, _ ...any) pgx.Row {
+	if !strings.Contains(sql, "GetWorkspaceMember ") {
+		return row{}
+	}
+	return valuesRow{int64(3), int64(1), int64(2), pgtype.Timestamptz{}, pgtype.Timestamptz{}, db.role}
+}
+
+func (db memberDB) Begin(context.Context) (pgx.Tx, error) {
+	return nil, errors.New("changed")
+}
+
+func TestMemberRoles(t *testing.T) {
+	// the members that may be changed fail in the transaction changing them
+	const changed = http.StatusInternalServerError
+	manager := func(role database.WsMemberRole) rbac.Access {
+		return rbac.Access{Role: role, Granted: []rbac.Permission{rbac.ManageMembers}}
+	}
+	tests := []struct {
+		name         string
+		access       rbac.Access
+		role         database.WsMemberRole
+		method, body string
+		want         int
+	}{
+		{"admin demotes an editor", manager(rbac.Admin), rbac.Editor, "PATCH", `{"role":"viewer"}`, changed},
+		{"admin promotes an editor", manager(rbac.Admin), rbac.Editor, "PATCH", `{"role":"admin"}`, http.StatusForbidden},
+		{"admin demotes an admin", manager(rbac.Admin), rbac.Admin, "PATCH", `{"role":"editor"}`, http.StatusForbidden},
+		{"admin demotes an owner", manager(rbac.Admin), rbac.Owner, "PATCH", `{"role":"viewer"}`, http.StatusForbidden},
+		{"owner demotes an admin", manager(rbac.Owner), rbac.Admin, "PATCH", `{"role":"editor"}`, changed},
+		{"owner promotes an editor", manager(rbac.Owner), rbac.Editor, "PATCH", `{"role":"admin"}`, changed},
+		{"editor promotes a viewer", manager(rbac.Editor), rbac.Viewer, "PATCH", `{"role":"editor"}`, changed},
+		{"editor demotes an admin", manager(rbac.Editor), rbac.Admin, "PATCH", `{"role":"viewer"}`, http.StatusForbidden},
+		{"viewer promotes itself", manager(rbac.Viewer), rbac.Viewer, "PATCH", `{"role":"editor"}`, http.StatusForbidden},
+		{"admin removes an editor", manager(rbac.Admin), rbac.Editor, "DELETE", "", changed},
+		{"admin removes an admin", manager(rbac.Admin), rbac.Admin, "DELETE", "", http.StatusForbidden},
+		{"owner removes an admin", manager(rbac.Owner), rbac.Admin, "DELETE", "", changed},
+	}
+	for _, tt := range tests {
+		a := New(Config{DB: memberDB{role: tt.role}, IDs: fakeIDs{}, Auth: &fakeAuth{}, Authz: fakeAuthz{tt.access}})
+		if rec := serveAs(a, "token", tt.method, "/v1/workspaces/1/members/3", tt.body); rec.Code != tt.want {
+			t.Errorf("%s = %d, want %d: %s", tt.name, rec.Code, tt.want, rec.Body)
+		}
+	}
+}
+
+// usersDB returns the users by id, an admin if admin is set, sharing a workspace if shared is set,
+// and fails the list queries after recording them. It is authorized by rbac itself.
+type usersDB struct {
+	fakeDB
+	admin, shared bool
+	listed        *string
+}
+
+func (db usersDB) Query(_ context.Context, sql string, _ ...any) (pgx.Rows, error) {
+	*db.listed = strings.Fields(sql)[2]
+	return nil, errors.New("listed")
+}
+
+func (db usersDB) QueryRow(_ context.Context, sql string, args ...any) pgx.Row {
+	if strings.Contains(sql, "SharesWorkspace") {
+		return valuesRow{db.shared}
+	}
+	return valuesRow{args[0].(int64), "a", "a@example.com", true, pgtype.Timestamptz{}, pgtype.Timestamptz{}, db.admin}
+}
+
+type valuesRow []any
+
+func (r valuesRow) Scan(dest ...any) error {
+	for i, v := range r {
+		reflect.ValueOf(dest[i]).Elem().Set(reflect.ValueOf(v))
+	}
+	return nil
+}
+
+func TestUserAccess(t *testing.T) {
+	// the signed in user is user 1
+	tests := []struct {
+		name               string
+		admin, shared      bool
+		method, path, body string
+		want               int
+		listed             string
+	}{
+		{"user creates a user", false, false, "POST", "/v1/users", `{"name":"b","email":"b@example.com"}`, http.StatusForbidden, ""},
+		{"admin creates a user", true, false, "POST", "/v1/users", `{"name":"b","email":"b@example.com"}`, http.StatusCreated, ""},
+		{"created user verified", true, false, "POST", "/v1/users", `{"name":"b","email":"b@example.com","email_verified":true}`, http.StatusBadRequest, ""},
+		{"user gets itself", false, false, "GET", "/v1/users/1", "", http.StatusOK, ""},
+		{"user gets a coworker", false, true, "GET", "/v1/users/2", "", http.StatusOK, ""},
+		{"user gets a stranger", false, false, "GET", "/v1/users/2", "", http.StatusNotFound, ""},
+		{"admin gets a stranger", true, false, "GET", "/v1/users/2", "", http.StatusOK, ""},
+		{"user renames itself", false, false, "PATCH", "/v1/users/1", `{"name":"b"}`, http.StatusOK, ""},
+		{"user renames a coworker", false, true, "PATCH", "/v1/users/2", `{"name":"b"}`, http.StatusForbidden, ""},
+		{"user renames a stranger", false, false, "PATCH", "/v1/users/2", `{"name":"b"}`, http.StatusNotFound, ""},
+		{"admin renames a stranger", true, false, "PATCH", "/v1/users/2", `{"name":"b"}`, http.StatusOK, ""},
+		{"user lists users", false, false, "GET", "/v1/users", "", http.StatusInternalServerError, "ListVisibleUsers"},
+		{"admin lists users", true, false, "GET", "/v1/users", "", http.StatusInternalServerError, "ListUsers"},
+	}
+	for _, tt := range tests {
+		var listed string
+		db := usersDB{admin: tt.admin, shared: tt.shared, listed: &listed}
+		a := New(Config{DB: db, IDs: fakeIDs{}, Auth: &fakeAuth{}})
+		if rec := serveAs(a, "token", tt.method, tt.path, tt.body); rec.Code != tt.want {
+			t.Errorf("%s = %d, want %d: %s", tt.name, rec.Code, tt.want, rec.Body)
+		}
+		if listed != tt.listed {
+			t.Errorf("%s listed with %q, want %q", tt.name, listed, tt.listed)
+		}
+	}
+}
+
+// ownerDB makes user 1 the owner of workspace 1, an admin if admin is set,
+// and a coworker of user 2 but not of user 3. Users above 3 do not exist.
+// It fails the transactions creating workspaces, and is authorized by rbac itself.
+type ownerDB struct {
+	fakeDB
+	admin bool
+}
+
+func (db ownerDB) QueryRow(_ context.Context, sql string, args ...any) pgx.Row {
+	switch strings.Fields(sql)[2] {
+	case "GetMemberAccess":
+		return valuesRow{int64(7), rbac.Owner, []string(nil)}
+	case "SharesWorkspace":
+		return valuesRow{args[1] == int64(2)}
+	case "GetUserByID":
+		if id := args[0].(int64); id <= 3 {
+			return valuesRow{id, "a", "a@example.com", true, pgtype.Timestamptz{}, pgtype.Timestamptz{}, db.admin && id == 1}
+		}
+		return row{err: pgx.ErrNoRows}
+	}
+	return row{}
+}
+
+func (db ownerDB) Begin(context.Context) (pgx.Tx, error) {
+	return nil, errors.New("created")
+}
+
+func TestUserChecks(t *testing.T) {
+	// the signed in user is user 1, and the workspaces that may be created fail in the transaction creating them
+	const created = http.StatusInternalServerError
+	tests := []struct {
+		name       string
+		admin      bool
+		path, body string
+		want       int
+	}{
+		{"user creates a workspace", false, "/v1/workspaces", `{"name":"a"}`, created},
+		{"user creates a workspace for itself", false, "/v1/workspaces", `{"name":"a","owner_id":1}`, created},
+		{"user creates a workspace for a coworker", false, "/v1/workspaces", `{"name":"a","owner_id":2}`, http.StatusForbidden},
+		{"user creates a workspace for a stranger", false, "/v1/workspaces", `{"name":"a","owner_id":3}`, http.StatusForbidden},
+		{"admin creates a workspace for a stranger", true, "/v1/workspaces", `{"name":"a","owner_id":3}`, created},
+		{"admin creates a workspace for a missing user", true, "/v1/workspaces", `{"name":"a","owner_id":4}`, http.StatusNotFound},
+		{"owner adds a coworker", false, "/v1/workspaces/1/members", `{"user_id":2}`, http.StatusCreated},
+		{"owner adds a stranger", false, "/v1/workspaces/1/members", `{"user_id":3}`, http.StatusNotFound},
+		{"owner adds a missing user", false, "/v1/workspaces/1/members", `{"user_id":4}`, http.StatusNotFound},
+		{"admin adds a stranger", true, "/v1/workspaces/1/members", `{"user_id":3}`, http.StatusCreated},
+		{"admin adds a missing user", true, "/v1/workspaces/1/members", `{"user_id":4}`, http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		a := New(Config{DB: ownerDB{admin: tt.admin}, IDs: fakeIDs{}, Auth: &fakeAuth{}})
+		if rec := serveAs(a, "token", "POST", tt.path, tt.body); rec.Code != tt.want {
+			t.Errorf("%s = %d, want %d: %s", tt.name, rec.Code, tt.want, rec.Body)
+		}
+	}
+}
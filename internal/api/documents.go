@@ -0,0 +1,301 @@
+package api
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"gosuda.org/jimin/database"
+	"gosuda.org/jimin/internal/blob"
+	"gosuda.org/jimin/internal/rbac"
+	"gosuda.org/jimin/internal/reprocess"
+)
+
+func (a *API) listSources(r *http.Request) (any, error) {
+	wsID, err := pathID(r, "ws_id")
+	if err != nil {
+		return nil, err
+	}
+	counts, err := a.q.CountDocumentsBySource(r.Context(), wsID)
+	if err != nil {
+		return nil, err
+	}
+
+	documents := make(map[string]int64, len(counts))
+	for _, c := range counts {
+		documents[c.Source] = c.Documents
+	}
+	sources := make([]Source, 0, len(a.cfg.Sources)+len(counts))
+	for _, s := range a.cfg.Sources {
+		hosts := s.Hosts
+		if hosts == nil {
+			hosts = []string{}
+		}
+		sources = append(sources, Source{Name: s.Name, Hosts: hosts, Documents: documents[s.Name]})
+		delete(documents, s.Name)
+	}
+	// the documents of the sources removed from the config, and of the files and archives
+	for _, c := range counts {
+		if n, ok := documents[c.Source]; ok {
+			sources = append(sources, Source{Name: c.Source, Hosts: []string{}, Documents: n})
+		}
+	}
+	return sources, nil
+}
+
+func (a *API) listDocuments(r *http.Request) (any, error) {
+	wsID, err := pathID(r, "ws_id")
+	if err != nil {
+		return nil, err
+	}
+	p, err := pageOf(r)
+	if err != nil {
+		return nil, err
+	}
+	docs, err := a.q.ListDocuments(r.Context(), database.ListDocumentsParams{
+		WsID:     wsID,
+		AfterID:  p.after,
+		Source:   r.URL.Query().Get("source"),
+		PageSize: int32(p.limit),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	items := make([]Document, len(docs))
+	for i, d := range docs {
+		items[i] = documentOf(d)
+	}
+	return list(items, p, func(d Document) int64 { return d.ID }), nil
+}
+
+func (a *API) createDocument(r *http.Request) (any, error) {
+	if a.cfg.Crawler == nil {
+		return nil, unavailable("crawling")
+	}
+	wsID, err := pathID(r, "ws_id")
+	if err != nil {
+		return nil, err
+	}
+	var req CreateDocumentRequest
+	if err := decode(r, &req); err != nil {
+		return nil, err
+	}
+	var v validation
+	v.httpURL(req.URL, "url")
+	if err := v.err(); err != nil {
+		return nil, err
+	}
+
+	doc, err := a.cfg.Crawler.Add(r.Context(), wsID, strings.TrimSpace(req.Source), req.URL)
+	if err != nil {
+		return nil, err
+	}
+	return documentOf(doc), nil
+}
+
+// document returns the document in the document_id path value.
+func (a *API) document(r *http.Request, wsID int64) (database.Document, error) {
+	id, err := pathID(r, "document_id")
+	if err != nil {
+		return database.Document{}, err
+	}
+	doc, err := a.q.GetDocument(r.Context(), id)
+	if errors.Is(err, pgx.ErrNoRows) || (err == nil && doc.WsID != wsID) {
+		return database.Document{}, problemf(http.StatusNotFound, "document %d not found", id)
+	}
+	return doc, err
+}
+
+func (a *API) getDocument(r *http.Request) (any, error) {
+	wsID, err := pathID(r, "ws_id")
+	if err != nil {
+		return nil, err
+	}
+	doc, err := a.document(r, wsID)
+	if err != nil {
+		return nil, err
+	}
+
+	detail := DocumentDetail{Document: documentOf(doc)}
+	if doc.CurrentVersionID != 0 {
+		v, err := a.q.GetDocumentVersionByID(r.Context(), doc.CurrentVersionID)
+		if err != nil {
+			return nil, err
+		}
+		detail.Version, detail.Markdown, detail.Summary, detail.Tags = v.Version, v.Content, v.Summary, v.Tags
+
+		assets, err := a.q.ListDocumentVersionAssets(r.Context(), v.ID)
+		if err != nil {
+			return nil, err
+		}
+		for _, as := range assets {
+			detail.Assets = append(detail.Assets, assetOf(as))
+		}
+	}
+	return detail, nil
+}
+
+// content is a response with a body of its content type.
+type content struct {
+	contentType string
+	body        io.ReadCloser
+}
+
+// getAsset serves a captured image to the users who may view a document it was captured from.
+func (a *API) getAsset(r *http.Request) (any, error) {
+	if a.cfg.Blobs == nil {
+		return nil, unavailable("assets")
+	}
+	key := r.PathValue("key")
+	notFound := problemf(http.StatusNotFound, "asset %s not found", key)
+
+	wsIDs, err := a.q.ListAssetWorkspaces(r.Context(), key)
+	if err != nil {
+		return nil, err
+	}
+	for _, wsID := range wsIDs {
+		err := a.authorize(r, wsID, rbac.ViewDocuments)
+		if errors.Is(err, rbac.ErrNotMember) || errors.Is(err, rbac.ErrForbidden) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		as, err := a.q.GetAssetByKey(r.Context(), database.GetAssetByKeyParams{BlobKey: key, WsID: wsID})
+		if err != nil {
+			return nil, err
+		}
+		body, err := a.cfg.Blobs.Open(r.Context(), key)
+		if errors.Is(err, blob.ErrNotFound) {
+			return nil, notFound
+		}
+		if err != nil {
+			return nil, err
+		}
+		return content{contentType: as.ContentType, body: body}, nil
+	}
+	// not telling the assets of others apart from missing ones
+	return nil, notFound
+}
+
+func (a *API) listChunks(r *http.Request) (any, error) {
+	wsID, err := pathID(r, "ws_id")
+	if err != nil {
+		return nil, err
+	}
+	doc, err := a.document(r, wsID)
+	if err != nil {
+		return nil, err
+	}
+	p, err := pageOf(r)
+	if err != nil {
+		return nil, err
+	}
+	chunks, err := a.q.ListDocumentChunksAfter(r.Context(), database.ListDocumentChunksAfterParams{
+		DocumentID: doc.ID,
+		// the cursor of the first page is 0, before the first chunk
+		AfterSeq: int32(p.after) - 1,
+		PageSize: int32(p.limit),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	items := make([]Chunk, len(chunks))
+	for i, c := range chunks {
+		items[i] = chunkOf(c)
+	}
+	return list(items, p, func(c Chunk) int64 { return int64(c.Seq) + 1 }), nil
+}
+
+func (a *API) listBrokenLinks(r *http.Request) (any, error) {
+	if a.cfg.Links == nil {
+		return nil, unavailable("link checking")
+	}
+	wsID, err := pathID(r, "ws_id")
+	if err != nil {
+		return nil, err
+	}
+	p, err := pageOf(r)
+	if err != nil {
+		return nil, err
+	}
+	links, err := a.cfg.Links.Broken(r.Context(), wsID, p.after, p.limit)
+	if err != nil {
+		return nil, err
+	}
+
+	items := make([]Link, len(links))
+	for i, l := range links {
+		items[i] = linkOf(l)
+	}
+	return list(items, p, func(l Link) int64 { return l.ID }), nil
+}
+
+func (a *API) startReprocess(r *http.Request) (any, error) {
+	if a.cfg.Reprocess == nil {
+		return nil, unavailable("reprocessing")
+	}
+	wsID, err := pathID(r, "ws_id")
+	if err != nil {
+		return nil, err
+	}
+	var req ReprocessRequest
+	if err := decode(r, &req); err != nil {
+		return nil, err
+	}
+
+	var userID int64
+	if p, ok := principalOf(r.Context()); ok {
+		userID = p.userID
+	}
+	job, err := a.cfg.Reprocess.Start(r.Context(), wsID, userID, reprocess.Filter(req))
+	if err != nil {
+		return nil, err
+	}
+	return jobOf(job), nil
+}
+
+func (a *API) getReprocess(r *http.Request) (any, error) {
+	if a.cfg.Reprocess == nil {
+		return nil, unavailable("reprocessing")
+	}
+	wsID, err := pathID(r, "ws_id")
+	if err != nil {
+		return nil, err
+	}
+	id, err := pathID(r, "job_id")
+	if err != nil {
+		return nil, err
+	}
+
+	job, err := a.cfg.Reprocess.Job(r.Context(), wsID, id)
+	if err != nil {
+		return nil, err
+	}
+	return jobOf(job), nil
+}
+
+func (a *API) cancelReprocess(r *http.Request) (any, error) {
+	if a.cfg.Reprocess == nil {
+		return nil, unavailable("reprocessing")
+	}
+	wsID, err := pathID(r, "ws_id")
+	if err != nil {
+		return nil, err
+	}
+	id, err := pathID(r, "job_id")
+	if err != nil {
+		return nil, err
+	}
+
+	job, err := a.cfg.Reprocess.Cancel(r.Context(), wsID, id)
+	if err != nil {
+		return nil, err
+	}
+	return jobOf(job), nil
+}
@@ -0,0 +1,65 @@
+package api
+
+import "net/http"
+
+func (a *API) sendVerification(r *http.Request) (any, error) {
+	p, err := a.signedIn(r)
+	if err != nil {
+		return nil, err
+	}
+	return nil, a.cfg.Auth.SendVerification(r.Context(), p.userID)
+}
+
+func (a *API) verifyEmail(r *http.Request) (any, error) {
+	if a.cfg.Auth == nil {
+		return nil, unavailable("authentication")
+	}
+	var req VerifyEmailRequest
+	if err := decode(r, &req); err != nil {
+		return nil, err
+	}
+	var v validation
+	v.required(req.Token, "token")
+	if err := v.err(); err != nil {
+		return nil, err
+	}
+	return nil, a.cfg.Auth.VerifyEmail(r.Context(), req.Token)
+}
+
+func (a *API) forgotPassword(r *http.Request) (any, error) {
+	if a.cfg.Auth == nil {
+		return nil, unavailable("authentication")
+	}
+	var req ForgotPasswordRequest
+	if err := decode(r, &req); err != nil {
+		return nil, err
+	}
+	var v validation
+	v.email(req.Email, "email")
+	if err := v.err(); err != nil {
+		return nil, err
+	}
+	return nil, a.cfg.Auth.RequestPasswordReset(r.Context(), req.Email)
+}
+
+// resetPassword sets the password and clears the session cookie, whose session the reset signed out.
+func (a *API) resetPassword(r *http.Request) (any, error) {
+	if a.cfg.Auth == nil {
+		return nil, unavailable("authentication")
+	}
+	var req ResetPasswordRequest
+	if err := decode(r, &req); err != nil {
+		return nil, err
+	}
+	var v validation
+	v.required(req.Token, "token")
+	v.password(req.NewPassword, "new_password")
+	if err := v.err(); err != nil {
+		return nil, err
+	}
+
+	if err := a.cfg.Auth.ResetPassword(r.Context(), req.Token, req.NewPassword); err != nil {
+		return nil, err
+	}
+	return withCookies{cookies: []*http.Cookie{a.cfg.Auth.ClearCookie()}}, nil
+}
@@ -0,0 +1,81 @@
+package api
+
+import (
+	"net/http"
+
+	"gosuda.org/jimin/database"
+)
+
+func (a *API) listFeeds(r *http.Request) (any, error) {
+	if a.cfg.Feeds == nil {
+		return nil, unavailable("feeds")
+	}
+	wsID, err := pathID(r, "ws_id")
+	if err != nil {
+		return nil, err
+	}
+	status := database.FeedStatus(r.URL.Query().Get("status"))
+	var v validation
+	switch status {
+	case "", database.FeedStatusSUGGESTED, database.FeedStatusSUBSCRIBED, database.FeedStatusDISMISSED:
+	default:
+		v.check(false, "status", "must be SUGGESTED, SUBSCRIBED or DISMISSED")
+	}
+	if err := v.err(); err != nil {
+		return nil, err
+	}
+
+	feeds, err := a.cfg.Feeds.List(r.Context(), wsID, status)
+	if err != nil {
+		return nil, err
+	}
+	items := make([]Feed, len(feeds))
+	for i, f := range feeds {
+		items[i] = feedOf(f)
+	}
+	return items, nil
+}
+
+func (a *API) subscribeFeed(r *http.Request) (any, error) {
+	if a.cfg.Feeds == nil {
+		return nil, unavailable("feeds")
+	}
+	wsID, err := pathID(r, "ws_id")
+	if err != nil {
+		return nil, err
+	}
+	id, err := pathID(r, "feed_id")
+	if err != nil {
+		return nil, err
+	}
+
+	var userID int64
+	if p, ok := principalOf(r.Context()); ok {
+		userID = p.userID
+	}
+	f, err := a.cfg.Feeds.Subscribe(r.Context(), wsID, userID, id)
+	if err != nil {
+		return nil, err
+	}
+	return feedOf(f), nil
+}
+
+func (a *API) dismissFeed(r *http.Request) (any, error) {
+	if a.cfg.Feeds == nil {
+		return nil, unavailable("feeds")
+	}
+	wsID, err := pathID(r, "ws_id")
+	if err != nil {
+		return nil, err
+	}
+	id, err := pathID(r, "feed_id")
+	if err != nil {
+		return nil, err
+	}
+
+	f, err := a.cfg.Feeds.Dismiss(r.Context(), wsID, id)
+	if err != nil {
+		return nil, err
+	}
+	return feedOf(f), nil
+}
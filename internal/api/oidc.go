@@ -0,0 +1,110 @@
+package api
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strings"
+)
+
+// returnTo returns the local path a login redirects to once completed.
+func returnTo(r *http.Request) (string, error) {
+	to := r.URL.Query().Get("return_to")
+	if to == "" {
+		return "/", nil
+	}
+	// a path of this origin, not a protocol relative URL of another one
+	if !strings.HasPrefix(to, "/") || strings.HasPrefix(to, "//") || strings.ContainsAny(to, "\\\r\n") {
+		return "", invalid(FieldError{Field: "return_to", Detail: "must be a path of this site"})
+	}
+	return to, nil
+}
+
+// startOIDC redirects to the provider of the path to sign in, or to link its identity to linkUserID if not zero.
+func (a *API) startOIDC(r *http.Request, linkUserID int64) (any, error) {
+	to, err := returnTo(r)
+	if err != nil {
+		return nil, err
+	}
+	authURL, state, err := a.cfg.Auth.StartOIDC(r.Context(), r.PathValue("provider"), linkUserID, to)
+	if err != nil {
+		return nil, err
+	}
+	return withCookies{resp: redirect(authURL), cookies: []*http.Cookie{a.cfg.Auth.StateCookie(state)}}, nil
+}
+
+func (a *API) oidcLogin(r *http.Request) (any, error) {
+	if a.cfg.Auth == nil {
+		return nil, unavailable("authentication")
+	}
+	return a.startOIDC(r, 0)
+}
+
+func (a *API) oidcLink(r *http.Request) (any, error) {
+	p, err := a.signedIn(r)
+	if err != nil {
+		return nil, err
+	}
+	return a.startOIDC(r, p.userID)
+}
+
+// oidcCallback completes a login of the browser it started in, and redirects to its return_to.
+func (a *API) oidcCallback(r *http.Request) (any, error) {
+	if a.cfg.Auth == nil {
+		return nil, unavailable("authentication")
+	}
+	q := r.URL.Query()
+	if e := q.Get("error"); e != "" {
+		return nil, problemf(http.StatusUnauthorized, "the provider refused the login: %s", e)
+	}
+	state, code := q.Get("state"), q.Get("code")
+	var v validation
+	v.required(state, "state")
+	v.required(code, "code")
+	if err := v.err(); err != nil {
+		return nil, err
+	}
+	// a login completes only in the browser that started it
+	c, err := r.Cookie(a.cfg.Auth.StateCookieName())
+	if err != nil || subtle.ConstantTimeCompare([]byte(c.Value), []byte(state)) != 1 {
+		return nil, problemf(http.StatusBadRequest, "the login was not started in this browser")
+	}
+
+	login, err := a.cfg.Auth.FinishOIDC(r.Context(), r.PathValue("provider"), state, code)
+	if err != nil {
+		return nil, err
+	}
+	cookies := []*http.Cookie{a.cfg.Auth.ClearStateCookie()}
+	if !login.Linked {
+		token, s, err := a.cfg.Auth.CreateSession(r.Context(), login.User.ID, r.UserAgent(), clientIP(r))
+		if err != nil {
+			return nil, err
+		}
+		cookies = append(cookies, a.cfg.Auth.Cookie(token, s))
+	}
+	return withCookies{resp: redirect(login.ReturnTo), cookies: cookies}, nil
+}
+
+func (a *API) listIdentities(r *http.Request) (any, error) {
+	p, err := a.signedIn(r)
+	if err != nil {
+		return nil, err
+	}
+	ids, err := a.cfg.Auth.Identities(r.Context(), p.userID)
+	if err != nil {
+		return nil, err
+	}
+
+	items := make([]Identity, len(ids))
+	for i, id := range ids {
+		items[i] = identityOf(id)
+	}
+	return items, nil
+}
+
+func (a *API) unlinkIdentity(r *http.Request) (any, error) {
+	p, err := a.signedIn(r)
+	if err != nil {
+		return nil, err
+	}
+	return nil, a.cfg.Auth.Unlink(r.Context(), p.userID, r.PathValue("provider"))
+}
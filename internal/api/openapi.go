@@ -0,0 +1,227 @@
+package api
+
+import (
+	"net/http"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const _SPEC_PATH = "/v1/openapi.json"
+
+type object = map[string]any
+
+var pathParam = regexp.MustCompile(`\{(\w+)\}`)
+
+// openAPI describes the routes as an OpenAPI 3.1 document,
+// with the schemas of the request and response types.
+func (a *API) openAPI() object {
+	s := &schemas{defs: object{}}
+	problem := object{_PROBLEM_CONTENT_TYPE: object{"schema": s.of(reflect.TypeOf(Problem{}))}}
+
+	paths := object{}
+	for _, rt := range a.routes {
+		item, ok := paths[rt.path].(object)
+		if !ok {
+			item = object{}
+			paths[rt.path] = item
+		}
+
+		var params []any
+		for _, m := range pathParam.FindAllStringSubmatch(rt.path, -1) {
+			schema := object{"type": "string"}
+			if strings.HasSuffix(m[1], "_id") {
+				schema = object{"type": "integer", "format": "int64"}
+			}
+			params = append(params, object{
+				"name":     m[1],
+				"in":       "path",
+				"required": true,
+				"schema":   schema,
+			})
+		}
+		for _, p := range rt.query {
+			params = append(params, object{
+				"name":        p.name,
+				"in":          "query",
+				"required":    p.required,
+				"description": p.desc,
+				"schema":      object{"type": p.typ},
+			})
+		}
+
+		op := object{
+			"operationId": rt.id,
+			"summary":     rt.summary,
+			"tags":        []string{rt.tag},
+		}
+		if params != nil {
+			op["parameters"] = params
+		}
+		if rt.body != nil {
+			t := reflect.TypeOf(rt.body)
+			op["requestBody"] = object{
+				"required": len(s.required(t)) > 0,
+				"content":  object{"application/json": object{"schema": s.of(t)}},
+			}
+		}
+
+		success := object{"description": http.StatusText(rt.status)}
+		if rt.resp != nil {
+			success["content"] = object{"application/json": object{"schema": s.of(reflect.TypeOf(rt.resp))}}
+		}
+		if rt.status == http.StatusFound {
+			success["headers"] = object{"Location": object{"required": true, "schema": object{"type": "string"}}}
+		}
+		op["responses"] = object{
+			strconv.Itoa(rt.status): success,
+			"default":               object{"description": "Error", "content": problem},
+		}
+		if rt.public && a.cfg.Auth != nil {
+			op["security"] = []any{}
+		} else if rt.scope != "" && a.cfg.Auth != nil {
+			op["security"] = []any{object{"session": []string{}}, object{"token": []string{rt.scope}}}
+		}
+		item[strings.ToLower(rt.method)] = op
+	}
+
+	components := object{"schemas": s.defs}
+	doc := object{
+		"openapi": "3.1.0",
+		"info": object{
+			"title":   "Jimin API",
+			"version": "v1",
+		},
+		"paths":      paths,
+		"components": components,
+	}
+	if a.cfg.Auth != nil {
+		components["securitySchemes"] = object{
+			"session": object{"type": "apiKey", "in": "cookie", "name": a.cfg.Auth.CookieName()},
+			"token": object{
+				"type":        "http",
+				"scheme":      "bearer",
+				"description": "Personal access token or workspace API key, with the scopes read, ingest, ask or admin. Admin has every scope.",
+			},
+		}
+		doc["security"] = []any{object{"session": []string{}}}
+	}
+	return doc
+}
+
+// schemas builds the JSON schemas of Go types, with the structs as components.
+type schemas struct {
+	defs object
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+func (s *schemas) of(t reflect.Type) object {
+	switch {
+	case t == timeType:
+		return object{"type": "string", "format": "date-time"}
+	case t.Kind() == reflect.Pointer:
+		return s.of(t.Elem())
+	case t.Kind() == reflect.Slice:
+		return object{"type": "array", "items": s.of(t.Elem())}
+	case t.Kind() == reflect.Map:
+		return object{"type": "object", "additionalProperties": s.of(t.Elem())}
+	case t.Kind() == reflect.Struct:
+		return s.ref(t)
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		return object{"type": "string"}
+	case reflect.Bool:
+		return object{"type": "boolean"}
+	case reflect.Int32:
+		return object{"type": "integer", "format": "int32"}
+	case reflect.Int, reflect.Int64:
+		return object{"type": "integer", "format": "int64"}
+	case reflect.Float32, reflect.Float64:
+		return object{"type": "number"}
+	}
+	return object{}
+}
+
+// ref returns a reference to the component of a struct type, adding it on first use.
+func (s *schemas) ref(t reflect.Type) object {
+	name := schemaName(t)
+	if _, ok := s.defs[name]; !ok {
+		// reserved first, so recursive types refer to it
+		s.defs[name] = object{}
+		s.defs[name] = s.object(t)
+	}
+	return object{"$ref": "#/components/schemas/" + name}
+}
+
+// schemaName names List[T] types TList after the name of T.
+func schemaName(t reflect.Type) string {
+	name := t.Name()
+	if elem, ok := strings.CutPrefix(name, "List["); ok {
+		elem = strings.TrimSuffix(elem, "]")
+		return elem[strings.LastIndex(elem, ".")+1:] + "List"
+	}
+	return name
+}
+
+type field struct {
+	name     string
+	required bool
+	typ      reflect.Type
+	enum     string
+}
+
+// fields returns the JSON fields of a struct type, with the fields of its embedded structs.
+func fields(t reflect.Type) []field {
+	var fs []field
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.Anonymous && f.Type.Kind() == reflect.Struct {
+			fs = append(fs, fields(f.Type)...)
+			continue
+		}
+		if !f.IsExported() {
+			continue
+		}
+		name, opts, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		fs = append(fs, field{name: name, required: !strings.Contains(opts, "omitempty"), typ: f.Type, enum: f.Tag.Get("enum")})
+	}
+	return fs
+}
+
+func (s *schemas) required(t reflect.Type) []string {
+	var names []string
+	for _, f := range fields(t) {
+		if f.required {
+			names = append(names, f.name)
+		}
+	}
+	return names
+}
+
+func (s *schemas) object(t reflect.Type) object {
+	props := object{}
+	for _, f := range fields(t) {
+		p := s.of(f.typ)
+		if f.enum != "" {
+			p["enum"] = strings.Split(f.enum, ",")
+		}
+		props[f.name] = p
+	}
+
+	o := object{"type": "object", "properties": props}
+	if names := s.required(t); names != nil {
+		o["required"] = names
+	}
+	return o
+}
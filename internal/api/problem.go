@@ -0,0 +1,116 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"gosuda.org/jimin/internal/auth"
+	"gosuda.org/jimin/internal/feed"
+	"gosuda.org/jimin/internal/oidc"
+	"gosuda.org/jimin/internal/rbac"
+	"gosuda.org/jimin/internal/reprocess"
+	"gosuda.org/jimin/internal/search"
+)
+
+const _PROBLEM_CONTENT_TYPE = "application/problem+json"
+
+// Problem is an RFC 9457 problem detail, the body of every error response.
+type Problem struct {
+	Type     string `json:"type"`
+	Title    string `json:"title"`
+	Status   int    `json:"status"`
+	Detail   string `json:"detail,omitempty"`
+	Instance string `json:"instance,omitempty"`
+	// Errors are the invalid fields of a 400 Bad Request.
+	Errors []FieldError `json:"errors,omitempty"`
+}
+
+// FieldError is an invalid field of a request body or an invalid parameter.
+type FieldError struct {
+	Field  string `json:"field"`
+	Detail string `json:"detail"`
+}
+
+func (p *Problem) Error() string {
+	if p.Detail == "" {
+		return p.Title
+	}
+	return p.Detail
+}
+
+func problemf(status int, format string, args ...any) *Problem {
+	return &Problem{Type: "about:blank", Title: http.StatusText(status), Status: status, Detail: fmt.Sprintf(format, args...)}
+}
+
+// invalid returns a 400 Bad Request of the invalid fields.
+func invalid(errs ...FieldError) *Problem {
+	p := problemf(http.StatusBadRequest, "invalid request")
+	p.Errors = errs
+	return p
+}
+
+// unavailable returns a 503 Service Unavailable of a disabled subsystem.
+func unavailable(what string) *Problem {
+	return problemf(http.StatusServiceUnavailable, "%s is not enabled on this server", what)
+}
+
+// problemOf maps err to the problem it is reported with.
+func problemOf(err error) *Problem {
+	var p *Problem
+	var pgErr *pgconn.PgError
+	switch {
+	case errors.As(err, &p):
+		return p
+	case errors.Is(err, pgx.ErrNoRows), errors.Is(err, reprocess.ErrNotFound), errors.Is(err, feed.ErrNotFound):
+		return problemf(http.StatusNotFound, "not found")
+	case errors.As(err, &pgErr) && pgErr.Code == "23505":
+		return problemf(http.StatusConflict, "already exists")
+	case errors.Is(err, auth.ErrInvalidCredentials), errors.Is(err, oidc.ErrInvalidToken), errors.Is(err, oidc.ErrExchange):
+		return problemf(http.StatusUnauthorized, "%v", err)
+	case errors.Is(err, auth.ErrUnknownProvider), errors.Is(err, auth.ErrNotLinked):
+		return problemf(http.StatusNotFound, "%v", err)
+	case errors.Is(err, auth.ErrLoginExpired):
+		return problemf(http.StatusBadRequest, "%v", err)
+	case errors.Is(err, auth.ErrAlreadyLinked), errors.Is(err, auth.ErrUnverifiedEmail), errors.Is(err, auth.ErrLastCredential):
+		return problemf(http.StatusConflict, "%v", err)
+	case errors.Is(err, auth.ErrNoEmail):
+		return problemf(http.StatusUnprocessableEntity, "%v", err)
+	case errors.Is(err, oidc.ErrDiscovery):
+		return problemf(http.StatusBadGateway, "%v", err)
+	case errors.Is(err, auth.ErrNoMailer):
+		return unavailable("email")
+	case errors.Is(err, auth.ErrInvalidToken):
+		return invalid(FieldError{Field: "token", Detail: "is invalid, expired or already used"})
+	case errors.Is(err, auth.ErrAlreadyVerified):
+		return problemf(http.StatusConflict, "%v", err)
+	case errors.Is(err, auth.ErrRateLimited):
+		return problemf(http.StatusTooManyRequests, "%v", err)
+	case errors.Is(err, auth.ErrNotMember), errors.Is(err, rbac.ErrNotMember):
+		return problemf(http.StatusForbidden, "%v", err)
+	case errors.Is(err, rbac.ErrForbidden):
+		return problemf(http.StatusForbidden, "%v", err)
+	case errors.Is(err, rbac.ErrLastOwner):
+		return problemf(http.StatusConflict, "%v", err)
+	case errors.Is(err, reprocess.ErrFinished):
+		return problemf(http.StatusConflict, "%v", err)
+	case errors.Is(err, search.ErrUnknownMode):
+		return invalid(FieldError{Field: "mode", Detail: err.Error()})
+	case errors.Is(err, search.ErrNoEmbedder):
+		return problemf(http.StatusUnprocessableEntity, "%v", err)
+	case errors.Is(err, search.ErrNoSources):
+		return problemf(http.StatusNotFound, "%v", err)
+	}
+	return problemf(http.StatusInternalServerError, "internal error")
+}
+
+func writeProblem(w http.ResponseWriter, r *http.Request, p *Problem) {
+	resp := *p
+	resp.Instance = r.URL.Path
+	w.Header().Set("Content-Type", _PROBLEM_CONTENT_TYPE)
+	w.WriteHeader(p.Status)
+	json.NewEncoder(w).Encode(resp)
+}
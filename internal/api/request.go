@@ -0,0 +1,152 @@
+package api
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/mail"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+const (
+	_MAX_BODY_BYTES = 1 << 20
+
+	_DEFAULT_PAGE_SIZE = 50
+	_MAX_PAGE_SIZE     = 200
+)
+
+// pathID parses the id path value name.
+func pathID(r *http.Request, name string) (int64, error) {
+	id, err := strconv.ParseInt(r.PathValue(name), 10, 64)
+	if err != nil || id <= 0 {
+		return 0, invalid(FieldError{Field: name, Detail: "must be a positive integer"})
+	}
+	return id, nil
+}
+
+// decode decodes the JSON body of r into v, rejecting unknown fields.
+// An empty body leaves v unchanged.
+func decode(r *http.Request, v any) error {
+	if ct := r.Header.Get("Content-Type"); ct != "" {
+		if mt, _, _ := mime.ParseMediaType(ct); mt != "application/json" {
+			return problemf(http.StatusUnsupportedMediaType, "the request body must be application/json")
+		}
+	}
+
+	dec := json.NewDecoder(io.LimitReader(r.Body, _MAX_BODY_BYTES))
+	dec.DisallowUnknownFields()
+	err := dec.Decode(v)
+	if errors.Is(err, io.EOF) {
+		return nil
+	}
+
+	var typeErr *json.UnmarshalTypeError
+	switch {
+	case errors.As(err, &typeErr):
+		return invalid(FieldError{Field: typeErr.Field, Detail: "must be a " + typeErr.Type.Kind().String()})
+	case err != nil && strings.HasPrefix(err.Error(), "json: unknown field "):
+		field, _ := strconv.Unquote(strings.TrimPrefix(err.Error(), "json: unknown field "))
+		return invalid(FieldError{Field: field, Detail: "is not a known field"})
+	case err != nil:
+		return problemf(http.StatusBadRequest, "the request body is not valid JSON: %v", err)
+	}
+	if dec.More() {
+		return problemf(http.StatusBadRequest, "the request body must hold a single JSON value")
+	}
+	return nil
+}
+
+// validation collects the invalid fields of a request.
+type validation []FieldError
+
+func (v *validation) check(ok bool, field, detail string) {
+	if !ok {
+		*v = append(*v, FieldError{Field: field, Detail: detail})
+	}
+}
+
+func (v *validation) required(s, field string) {
+	v.check(strings.TrimSpace(s) != "", field, "is required")
+}
+
+func (v *validation) email(s, field string) {
+	if strings.TrimSpace(s) == "" {
+		v.required(s, field)
+		return
+	}
+	_, err := mail.ParseAddress(s)
+	v.check(err == nil, field, "must be an email address")
+}
+
+func (v *validation) httpURL(s, field string) {
+	u, err := url.Parse(s)
+	v.check(err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != "", field, "must be an http or https url")
+}
+
+func (v validation) err() error {
+	if len(v) == 0 {
+		return nil
+	}
+	return invalid(v...)
+}
+
+// List is a page of a collection.
+type List[T any] struct {
+	Items []T `json:"items"`
+	// NextCursor is the cursor parameter of the next page, empty on the last page.
+	NextCursor string `json:"next_cursor,omitempty"`
+}
+
+// page is the position of a page of a collection ordered by a key.
+type page struct {
+	after int64
+	limit int
+}
+
+// pageOf parses the cursor and limit query parameters.
+func pageOf(r *http.Request) (page, error) {
+	p := page{limit: _DEFAULT_PAGE_SIZE}
+	var v validation
+	q := r.URL.Query()
+	if c := q.Get("cursor"); c != "" {
+		var err error
+		p.after, err = decodeCursor(c)
+		v.check(err == nil, "cursor", "is not a cursor of this collection")
+	}
+	if l := q.Get("limit"); l != "" {
+		n, err := strconv.Atoi(l)
+		v.check(err == nil && n > 0 && n <= _MAX_PAGE_SIZE, "limit", fmt.Sprintf("must be between 1 and %d", _MAX_PAGE_SIZE))
+		p.limit = n
+	}
+	return p, v.err()
+}
+
+func encodeCursor(after int64) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(strconv.FormatInt(after, 10)))
+}
+
+func decodeCursor(c string) (int64, error) {
+	b, err := base64.RawURLEncoding.DecodeString(c)
+	if err != nil {
+		return 0, err
+	}
+	return strconv.ParseInt(string(b), 10, 64)
+}
+
+// list returns the page of items fetched with p, whose last item has the key of the next page.
+func list[T any](items []T, p page, key func(T) int64) *List[T] {
+	l := &List[T]{Items: items}
+	if l.Items == nil {
+		l.Items = []T{}
+	}
+	if len(items) == p.limit {
+		l.NextCursor = encodeCursor(key(items[len(items)-1]))
+	}
+	return l
+}
@@ -0,0 +1,188 @@
+package api
+
+import (
+	"net/http"
+
+	"gosuda.org/jimin/internal/auth"
+	"gosuda.org/jimin/internal/rbac"
+)
+
+// route is an endpoint with the description it is documented with in the OpenAPI document.
+type route struct {
+	method, path string
+	id           string
+	summary      string
+	tag          string
+	query        []param
+	// body is the request body type, nil if it takes none.
+	body any
+	// status is the status of a success, with a body of the resp type, or with no body if resp is nil.
+	status int
+	resp   any
+	handle func(r *http.Request) (any, error)
+	// public is whether the route is served without a session.
+	public bool
+	// scope is the scope an API token needs for the route. Routes without one only take sessions.
+	scope string
+	// perm is the permission the routes of a workspace or of users need.
+	// Routes of resources shared by workspaces check it themselves on the workspaces of the resource.
+	perm rbac.Permission
+}
+
+// param is a query parameter.
+type param struct {
+	name, typ, desc string
+	required        bool
+}
+
+var pageParams = []param{
+	{name: "cursor", typ: "string", desc: "next_cursor of the previous page"},
+	{name: "limit", typ: "integer", desc: "maximum number of items, 50 by default and at most 200"},
+}
+
+var returnToParams = []param{
+	{name: "return_to", typ: "string", desc: "path redirected to once signed in, / by default"},
+}
+
+var callbackParams = []param{
+	{name: "state", typ: "string", desc: "state of the login"},
+	{name: "code", typ: "string", desc: "authorization code of the provider"},
+	{name: "error", typ: "string", desc: "error of a refused login"},
+}
+
+func (a *API) table() []route {
+	return []route{
+		{method: "POST", path: "/v1/auth/signup", id: "signup", summary: "Create a user with a password and sign in", tag: "auth",
+			public: true, body: SignupRequest{}, status: http.StatusCreated, resp: CurrentSession{}, handle: a.signup},
+		{method: "POST", path: "/v1/auth/login", id: "login", summary: "Sign in with a password", tag: "auth",
+			public: true, body: LoginRequest{}, status: http.StatusOK, resp: CurrentSession{}, handle: a.login},
+		{method: "POST", path: "/v1/auth/logout", id: "logout", summary: "Sign out of the session", tag: "auth",
+			status: http.StatusNoContent, handle: a.logout},
+		{method: "GET", path: "/v1/auth/session", id: "getSession", summary: "Get the signed in user and session", tag: "auth",
+			status: http.StatusOK, resp: CurrentSession{}, handle: a.currentSession},
+		{method: "PUT", path: "/v1/auth/password", id: "changePassword", summary: "Change the password, sign out of the other sessions and revoke the personal access tokens", tag: "auth",
+			body: ChangePasswordRequest{}, status: http.StatusNoContent, handle: a.changePassword},
+		{method: "POST", path: "/v1/auth/password/forgot", id: "forgotPassword", summary: "Email a password reset link", tag: "auth",
+			public: true, body: ForgotPasswordRequest{}, status: http.StatusAccepted, handle: a.forgotPassword},
+		{method: "POST", path: "/v1/auth/password/reset", id: "resetPassword", summary: "Set the password with a reset token, sign out of every session and revoke the personal access tokens", tag: "auth",
+			public: true, body: ResetPasswordRequest{}, status: http.StatusNoContent, handle: a.resetPassword},
+		{method: "POST", path: "/v1/auth/email/verification", id: "sendVerification", summary: "Email a verification link to the signed in user", tag: "auth",
+			status: http.StatusAccepted, handle: a.sendVerification},
+		{method: "POST", path: "/v1/auth/email/verify", id: "verifyEmail", summary: "Verify an email with a verification token", tag: "auth",
+			public: true, body: VerifyEmailRequest{}, status: http.StatusNoContent, handle: a.verifyEmail},
+		{method: "GET", path: "/v1/auth/sessions", id: "listSessions", summary: "List the sessions of the signed in user", tag: "auth",
+			status: http.StatusOK, resp: []Session{}, handle: a.listSessions},
+		{method: "DELETE", path: "/v1/auth/sessions/{session_id}", id: "revokeSession", summary: "Sign out of a session", tag: "auth",
+			status: http.StatusNoContent, handle: a.revokeSession},
+		{method: "GET", path: "/v1/auth/oidc/{provider}/login", id: "oidcLogin", summary: "Sign in with an OpenID Connect provider", tag: "auth",
+			public: true, query: returnToParams, status: http.StatusFound, handle: a.oidcLogin},
+		{method: "GET", path: "/v1/auth/oidc/{provider}/link", id: "oidcLink", summary: "Link an OpenID Connect provider to the signed in user", tag: "auth",
+			query: returnToParams, status: http.StatusFound, handle: a.oidcLink},
+		{method: "GET", path: "/v1/auth/oidc/{provider}/callback", id: "oidcCallback", summary: "Complete a login with an OpenID Connect provider", tag: "auth",
+			public: true, query: callbackParams, status: http.StatusFound, handle: a.oidcCallback},
+		{method: "GET", path: "/v1/auth/identities", id: "listIdentities", summary: "List the sign in methods of the signed in user", tag: "auth",
+			status: http.StatusOK, resp: []Identity{}, handle: a.listIdentities},
+		{method: "DELETE", path: "/v1/auth/identities/{provider}", id: "unlinkIdentity", summary: "Remove a sign in method of the signed in user", tag: "auth",
+			status: http.StatusNoContent, handle: a.unlinkIdentity},
+		{method: "GET", path: "/v1/auth/tokens", id: "listTokens", summary: "List the personal access tokens of the signed in user", tag: "auth",
+			status: http.StatusOK, resp: []APIToken{}, handle: a.listPersonalTokens},
+		{method: "POST", path: "/v1/auth/tokens", id: "createToken", summary: "Create a personal access token acting as the signed in user", tag: "auth",
+			body: CreateAPITokenRequest{}, status: http.StatusCreated, resp: CreatedAPIToken{}, handle: a.createPersonalToken},
+		{method: "DELETE", path: "/v1/auth/tokens/{token_id}", id: "revokeToken", summary: "Revoke a personal access token", tag: "auth",
+			status: http.StatusNoContent, handle: a.revokePersonalToken},
+
+		{method: "GET", path: "/v1/users", id: "listUsers", summary: "List the signed in user and the users sharing a workspace with it, or every user for admins", tag: "users",
+			scope: auth.ScopeRead, perm: rbac.ViewUsers, query: pageParams, status: http.StatusOK, resp: List[User]{}, handle: a.listUsers},
+		{method: "POST", path: "/v1/users", id: "createUser", summary: "Create a user with an unverified email, for admins", tag: "users",
+			scope: auth.ScopeAdmin, perm: rbac.ManageUsers, body: CreateUserRequest{}, status: http.StatusCreated, resp: User{}, handle: a.createUser},
+		{method: "GET", path: "/v1/users/{user_id}", id: "getUser", summary: "Get a user", tag: "users",
+			scope: auth.ScopeRead, perm: rbac.ViewUsers, status: http.StatusOK, resp: User{}, handle: a.getUser},
+		{method: "PATCH", path: "/v1/users/{user_id}", id: "updateUser", summary: "Rename the signed in user, or any user for admins", tag: "users",
+			scope: auth.ScopeAdmin, perm: rbac.UpdateUsers, body: UpdateUserRequest{}, status: http.StatusOK, resp: User{}, handle: a.updateUser},
+
+		{method: "GET", path: "/v1/workspaces", id: "listWorkspaces", summary: "List the workspaces of the signed in user", tag: "workspaces",
+			scope: auth.ScopeRead, query: pageParams, status: http.StatusOK, resp: List[Workspace]{}, handle: a.listWorkspaces},
+		{method: "POST", path: "/v1/workspaces", id: "createWorkspace", summary: "Create a workspace, with its owner as the first member", tag: "workspaces",
+			scope: auth.ScopeAdmin, body: CreateWorkspaceRequest{}, status: http.StatusCreated, resp: Workspace{}, handle: a.createWorkspace},
+		{method: "GET", path: "/v1/workspaces/{ws_id}", id: "getWorkspace", summary: "Get a workspace", tag: "workspaces",
+			scope: auth.ScopeRead, perm: rbac.ViewWorkspace, status: http.StatusOK, resp: Workspace{}, handle: a.getWorkspace},
+		{method: "PATCH", path: "/v1/workspaces/{ws_id}", id: "updateWorkspace", summary: "Rename a workspace", tag: "workspaces",
+			scope: auth.ScopeAdmin, perm: rbac.UpdateWorkspace, body: UpdateWorkspaceRequest{}, status: http.StatusOK, resp: Workspace{}, handle: a.updateWorkspace},
+
+		{method: "GET", path: "/v1/workspaces/{ws_id}/members", id: "listMembers", summary: "List the members of a workspace", tag: "members",
+			scope: auth.ScopeRead, perm: rbac.ViewWorkspace, query: pageParams, status: http.StatusOK, resp: List[Member]{}, handle: a.listMembers},
+		{method: "POST", path: "/v1/workspaces/{ws_id}/members", id: "addMember", summary: "Add a user to a workspace", tag: "members",
+			scope: auth.ScopeAdmin, perm: rbac.ManageMembers, body: AddMemberRequest{}, status: http.StatusCreated, resp: Member{}, handle: a.addMember},
+		{method: "GET", path: "/v1/workspaces/{ws_id}/members/{member_id}", id: "getMember", summary: "Get a member", tag: "members",
+			scope: auth.ScopeRead, perm: rbac.ViewWorkspace, status: http.StatusOK, resp: Member{}, handle: a.getMember},
+		{method: "PATCH", path: "/v1/workspaces/{ws_id}/members/{member_id}", id: "updateMember", summary: "Change the built-in role of a member", tag: "members",
+			scope: auth.ScopeAdmin, perm: rbac.ManageMembers, body: UpdateMemberRequest{}, status: http.StatusOK, resp: Member{}, handle: a.updateMember},
+		{method: "DELETE", path: "/v1/workspaces/{ws_id}/members/{member_id}", id: "removeMember", summary: "Remove a member and its roles", tag: "members",
+			scope: auth.ScopeAdmin, perm: rbac.ManageMembers, status: http.StatusNoContent, handle: a.removeMember},
+
+		{method: "GET", path: "/v1/workspaces/{ws_id}/roles", id: "listRoles", summary: "List the roles of a workspace", tag: "roles",
+			scope: auth.ScopeRead, perm: rbac.ViewWorkspace, query: pageParams, status: http.StatusOK, resp: List[Role]{}, handle: a.listRoles},
+		{method: "POST", path: "/v1/workspaces/{ws_id}/roles", id: "createRole", summary: "Create a custom role granting permissions to its members", tag: "roles",
+			scope: auth.ScopeAdmin, perm: rbac.ManageRoles, body: CreateRoleRequest{}, status: http.StatusCreated, resp: Role{}, handle: a.createRole},
+		{method: "GET", path: "/v1/workspaces/{ws_id}/roles/{role_id}", id: "getRole", summary: "Get a role", tag: "roles",
+			scope: auth.ScopeRead, perm: rbac.ViewWorkspace, status: http.StatusOK, resp: Role{}, handle: a.getRole},
+		{method: "PATCH", path: "/v1/workspaces/{ws_id}/roles/{role_id}", id: "updateRole", summary: "Rename a role or replace its permissions", tag: "roles",
+			scope: auth.ScopeAdmin, perm: rbac.ManageRoles, body: UpdateRoleRequest{}, status: http.StatusOK, resp: Role{}, handle: a.updateRole},
+		{method: "DELETE", path: "/v1/workspaces/{ws_id}/roles/{role_id}", id: "deleteRole", summary: "Delete a role", tag: "roles",
+			scope: auth.ScopeAdmin, perm: rbac.ManageRoles, status: http.StatusNoContent, handle: a.deleteRole},
+		{method: "GET", path: "/v1/workspaces/{ws_id}/roles/{role_id}/members", id: "listRoleMembers", summary: "List the members with a role", tag: "roles",
+			scope: auth.ScopeRead, perm: rbac.ViewWorkspace, query: pageParams, status: http.StatusOK, resp: List[Member]{}, handle: a.listRoleMembers},
+		{method: "PUT", path: "/v1/workspaces/{ws_id}/roles/{role_id}/members/{member_id}", id: "assignRole", summary: "Give a role to a member", tag: "roles",
+			scope: auth.ScopeAdmin, perm: rbac.ManageMembers, status: http.StatusNoContent, handle: a.assignRole},
+		{method: "DELETE", path: "/v1/workspaces/{ws_id}/roles/{role_id}/members/{member_id}", id: "unassignRole", summary: "Take a role from a member", tag: "roles",
+			scope: auth.ScopeAdmin, perm: rbac.ManageMembers, status: http.StatusNoContent, handle: a.unassignRole},
+
+		{method: "GET", path: "/v1/workspaces/{ws_id}/api-keys", id: "listAPIKeys", summary: "List the API keys of a workspace", tag: "workspaces",
+			perm: rbac.ManageAPIKeys, status: http.StatusOK, resp: []APIToken{}, handle: a.listWorkspaceKeys},
+		{method: "POST", path: "/v1/workspaces/{ws_id}/api-keys", id: "createAPIKey", summary: "Create an API key of a workspace, working while its creator is a member", tag: "workspaces",
+			perm: rbac.ManageAPIKeys, body: CreateAPITokenRequest{}, status: http.StatusCreated, resp: CreatedAPIToken{}, handle: a.createWorkspaceKey},
+		{method: "DELETE", path: "/v1/workspaces/{ws_id}/api-keys/{key_id}", id: "revokeAPIKey", summary: "Revoke an API key of a workspace", tag: "workspaces",
+			perm: rbac.ManageAPIKeys, status: http.StatusNoContent, handle: a.revokeWorkspaceKey},
+
+		{method: "GET", path: "/v1/workspaces/{ws_id}/sources", id: "listSources", summary: "List the sources with their number of documents", tag: "documents",
+			scope: auth.ScopeRead, perm: rbac.ViewDocuments, status: http.StatusOK, resp: []Source{}, handle: a.listSources},
+		{method: "GET", path: "/v1/workspaces/{ws_id}/documents", id: "listDocuments", summary: "List the documents of a workspace", tag: "documents",
+			query: append([]param{{name: "source", typ: "string", desc: "only the documents of this source"}}, pageParams...),
+			scope: auth.ScopeRead, perm: rbac.ViewDocuments, status: http.StatusOK, resp: List[Document]{}, handle: a.listDocuments},
+		{method: "POST", path: "/v1/workspaces/{ws_id}/documents", id: "createDocument", summary: "Add a page to crawl", tag: "documents",
+			scope: auth.ScopeIngest, perm: rbac.AddDocuments, body: CreateDocumentRequest{}, status: http.StatusCreated, resp: Document{}, handle: a.createDocument},
+		{method: "GET", path: "/v1/workspaces/{ws_id}/documents/{document_id}", id: "getDocument", summary: "Get a document with its current content", tag: "documents",
+			scope: auth.ScopeRead, perm: rbac.ViewDocuments, status: http.StatusOK, resp: DocumentDetail{}, handle: a.getDocument},
+		{method: "GET", path: "/v1/workspaces/{ws_id}/documents/{document_id}/chunks", id: "listChunks", summary: "List the chunks of a document", tag: "documents",
+			scope: auth.ScopeRead, perm: rbac.ViewDocuments, query: pageParams, status: http.StatusOK, resp: List[Chunk]{}, handle: a.listChunks},
+		{method: "GET", path: "/v1/assets/{key}", id: "getAsset", summary: "Get an image captured from the documents", tag: "documents",
+			scope: auth.ScopeRead, perm: rbac.ViewDocuments, status: http.StatusOK, handle: a.getAsset},
+		{method: "GET", path: "/v1/workspaces/{ws_id}/links/broken", id: "listBrokenLinks", summary: "List the broken and parked links", tag: "documents",
+			scope: auth.ScopeRead, perm: rbac.ViewDocuments, query: pageParams, status: http.StatusOK, resp: List[Link]{}, handle: a.listBrokenLinks},
+
+		{method: "GET", path: "/v1/workspaces/{ws_id}/feeds", id: "listFeeds", summary: "List the feeds suggested to or subscribed by a workspace", tag: "feeds",
+			query: []param{{name: "status", typ: "string", desc: "SUGGESTED, SUBSCRIBED or DISMISSED, the suggested and subscribed feeds if empty"}},
+			scope: auth.ScopeRead, perm: rbac.ViewDocuments, status: http.StatusOK, resp: []Feed{}, handle: a.listFeeds},
+		{method: "POST", path: "/v1/workspaces/{ws_id}/feeds/{feed_id}/subscribe", id: "subscribeFeed", summary: "Subscribe to a feed, adding the pages of its entries", tag: "feeds",
+			scope: auth.ScopeIngest, perm: rbac.AddDocuments, status: http.StatusOK, resp: Feed{}, handle: a.subscribeFeed},
+		{method: "POST", path: "/v1/workspaces/{ws_id}/feeds/{feed_id}/dismiss", id: "dismissFeed", summary: "Dismiss a suggested feed or cancel a subscription", tag: "feeds",
+			scope: auth.ScopeIngest, perm: rbac.AddDocuments, status: http.StatusOK, resp: Feed{}, handle: a.dismissFeed},
+
+		{method: "POST", path: "/v1/workspaces/{ws_id}/reprocess", id: "startReprocess", summary: "Queue a job reprocessing the stored pages", tag: "reprocess",
+			scope: auth.ScopeIngest, perm: rbac.ReprocessDocuments, body: ReprocessRequest{}, status: http.StatusAccepted, resp: ReprocessJob{}, handle: a.startReprocess},
+		{method: "GET", path: "/v1/workspaces/{ws_id}/reprocess/{job_id}", id: "getReprocess", summary: "Get a reprocessing job", tag: "reprocess",
+			scope: auth.ScopeRead, perm: rbac.ViewDocuments, status: http.StatusOK, resp: ReprocessJob{}, handle: a.getReprocess},
+		{method: "POST", path: "/v1/workspaces/{ws_id}/reprocess/{job_id}/cancel", id: "cancelReprocess", summary: "Cancel a reprocessing job", tag: "reprocess",
+			scope: auth.ScopeIngest, perm: rbac.ReprocessDocuments, status: http.StatusOK, resp: ReprocessJob{}, handle: a.cancelReprocess},
+
+		{method: "GET", path: "/v1/workspaces/{ws_id}/search", id: "search", summary: "Search the documents of a workspace", tag: "search",
+			query: []param{
+				{name: "q", typ: "string", desc: "query", required: true},
+				{name: "mode", typ: "string", desc: "keyword, vector or hybrid, the server default if empty"},
+				{name: "limit", typ: "integer", desc: "maximum number of results, 10 by default and at most 50"},
+			},
+			scope: auth.ScopeRead, perm: rbac.ViewDocuments, status: http.StatusOK, resp: SearchResults{}, handle: a.search},
+		{method: "POST", path: "/v1/workspaces/{ws_id}/ask", id: "ask", summary: "Answer a question from the documents of a workspace", tag: "search",
+			scope: auth.ScopeAsk, perm: rbac.Ask, body: AskRequest{}, status: http.StatusOK, resp: Answer{}, handle: a.ask},
+	}
+}
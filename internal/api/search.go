@@ -0,0 +1,87 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"gosuda.org/jimin/internal/search"
+)
+
+const (
+	_DEFAULT_RESULTS = 10
+	_MAX_RESULTS     = 50
+)
+
+// searchParams validates the mode and the limit of a search, which default to the config and 10.
+func (a *API) searchParams(v *validation, mode string, limit int) (search.Mode, int) {
+	m := a.cfg.Mode
+	if mode != "" {
+		var err error
+		m, err = search.ParseMode(mode)
+		v.check(err == nil, "mode", "must be keyword, vector or hybrid")
+	}
+	if limit == 0 {
+		limit = _DEFAULT_RESULTS
+	}
+	v.check(limit > 0 && limit <= _MAX_RESULTS, "limit", "must be between 1 and "+strconv.Itoa(_MAX_RESULTS))
+	return m, limit
+}
+
+func (a *API) search(r *http.Request) (any, error) {
+	if a.cfg.Searcher == nil {
+		return nil, unavailable("search")
+	}
+	wsID, err := pathID(r, "ws_id")
+	if err != nil {
+		return nil, err
+	}
+
+	var v validation
+	q := r.URL.Query()
+	query := strings.TrimSpace(q.Get("q"))
+	v.required(query, "q")
+	var limit int
+	if l := q.Get("limit"); l != "" {
+		if limit, err = strconv.Atoi(l); err != nil {
+			limit = -1
+		}
+	}
+	mode, limit := a.searchParams(&v, q.Get("mode"), limit)
+	if err := v.err(); err != nil {
+		return nil, err
+	}
+
+	results, err := a.cfg.Searcher.Search(r.Context(), wsID, query, mode, limit)
+	if err != nil {
+		return nil, err
+	}
+	return SearchResults{Query: query, Mode: string(mode), Results: resultsOf(results)}, nil
+}
+
+func (a *API) ask(r *http.Request) (any, error) {
+	if a.cfg.Searcher == nil || a.cfg.Generator == nil {
+		return nil, unavailable("answering")
+	}
+	wsID, err := pathID(r, "ws_id")
+	if err != nil {
+		return nil, err
+	}
+	var req AskRequest
+	if err := decode(r, &req); err != nil {
+		return nil, err
+	}
+	var v validation
+	question := strings.TrimSpace(req.Question)
+	v.required(question, "question")
+	mode, limit := a.searchParams(&v, req.Mode, req.Limit)
+	if err := v.err(); err != nil {
+		return nil, err
+	}
+
+	answer, err := a.cfg.Searcher.Ask(r.Context(), a.cfg.Generator, wsID, question, mode, limit)
+	if err != nil {
+		return nil, err
+	}
+	return Answer{Question: answer.Question, Answer: answer.Answer, Sources: resultsOf(answer.Sources)}, nil
+}
@@ -0,0 +1,126 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"gosuda.org/jimin/database"
+	"gosuda.org/jimin/internal/auth"
+)
+
+// decodeToken returns the valid request of a new API token.
+func decodeToken(r *http.Request) (CreateAPITokenRequest, error) {
+	var req CreateAPITokenRequest
+	if err := decode(r, &req); err != nil {
+		return req, err
+	}
+	var v validation
+	v.required(req.Name, "name")
+	v.check(auth.CheckScopes(req.Scopes) == nil, "scopes", "must be some of read, ingest, ask and admin")
+	v.check(req.ExpiresAt == nil || req.ExpiresAt.After(time.Now()), "expires_at", "must be in the future")
+	return req, v.err()
+}
+
+func (a *API) createToken(r *http.Request, wsID int64) (any, error) {
+	p, err := a.signedIn(r)
+	if err != nil {
+		return nil, err
+	}
+	req, err := decodeToken(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var expires time.Time
+	if req.ExpiresAt != nil {
+		expires = *req.ExpiresAt
+	}
+	token, t, err := a.cfg.Auth.CreateAPIToken(r.Context(), p.userID, wsID, req.Name, req.Scopes, expires)
+	if err != nil {
+		return nil, err
+	}
+	return CreatedAPIToken{APIToken: apiTokenOf(t), Token: token}, nil
+}
+
+// listTokens returns the API tokens of list(id).
+func listTokens(ctx context.Context, list func(context.Context, int64) ([]database.ApiToken, error), id int64) (any, error) {
+	tokens, err := list(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	items := make([]APIToken, len(tokens))
+	for i, t := range tokens {
+		items[i] = apiTokenOf(t)
+	}
+	return items, nil
+}
+
+func (a *API) listPersonalTokens(r *http.Request) (any, error) {
+	p, err := a.signedIn(r)
+	if err != nil {
+		return nil, err
+	}
+	return listTokens(r.Context(), a.cfg.Auth.APITokens, p.userID)
+}
+
+func (a *API) createPersonalToken(r *http.Request) (any, error) {
+	return a.createToken(r, 0)
+}
+
+func (a *API) revokePersonalToken(r *http.Request) (any, error) {
+	p, err := a.signedIn(r)
+	if err != nil {
+		return nil, err
+	}
+	id, err := pathID(r, "token_id")
+	if err != nil {
+		return nil, err
+	}
+
+	err = a.cfg.Auth.RevokeAPIToken(r.Context(), p.userID, id)
+	if errors.Is(err, auth.ErrInvalidAPIToken) {
+		return nil, problemf(http.StatusNotFound, "token %d not found", id)
+	}
+	return nil, err
+}
+
+func (a *API) listWorkspaceKeys(r *http.Request) (any, error) {
+	if _, err := a.signedIn(r); err != nil {
+		return nil, err
+	}
+	wsID, err := pathID(r, "ws_id")
+	if err != nil {
+		return nil, err
+	}
+	return listTokens(r.Context(), a.cfg.Auth.WorkspaceKeys, wsID)
+}
+
+func (a *API) createWorkspaceKey(r *http.Request) (any, error) {
+	wsID, err := pathID(r, "ws_id")
+	if err != nil {
+		return nil, err
+	}
+	return a.createToken(r, wsID)
+}
+
+func (a *API) revokeWorkspaceKey(r *http.Request) (any, error) {
+	if _, err := a.signedIn(r); err != nil {
+		return nil, err
+	}
+	wsID, err := pathID(r, "ws_id")
+	if err != nil {
+		return nil, err
+	}
+	id, err := pathID(r, "key_id")
+	if err != nil {
+		return nil, err
+	}
+
+	err = a.cfg.Auth.RevokeWorkspaceKey(r.Context(), wsID, id)
+	if errors.Is(err, auth.ErrInvalidAPIToken) {
+		return nil, problemf(http.StatusNotFound, "API key %d not found", id)
+	}
+	return nil, err
+}
@@ -0,0 +1,458 @@
+package api
+
+import (
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"gosuda.org/jimin/database"
+	"gosuda.org/jimin/internal/auth"
+	"gosuda.org/jimin/internal/search"
+)
+
+type User struct {
+	ID            int64     `json:"id"`
+	Name          string    `json:"name"`
+	Email         string    `json:"email"`
+	EmailVerified bool      `json:"email_verified"`
+	Admin         bool      `json:"admin"`
+	CreatedAt     time.Time `json:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at"`
+}
+
+type Workspace struct {
+	ID        int64     `json:"id"`
+	Name      string    `json:"name"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Member is the membership of a user in a workspace.
+type Member struct {
+	ID     int64 `json:"id"`
+	WsID   int64 `json:"ws_id"`
+	UserID int64 `json:"user_id"`
+	// Role is the built-in role of the member. Custom roles add to its permissions.
+	Role      string    `json:"role" enum:"owner,admin,editor,viewer"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Role is a custom role, granting its permissions to its members.
+type Role struct {
+	ID          int64     `json:"id"`
+	WsID        int64     `json:"ws_id"`
+	Name        string    `json:"name"`
+	Permissions []string  `json:"permissions"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// Source is a configured source with the number of documents it holds in a workspace.
+type Source struct {
+	Name      string   `json:"name"`
+	Hosts     []string `json:"hosts"`
+	Documents int64    `json:"documents"`
+}
+
+type Document struct {
+	ID         int64  `json:"id"`
+	WsID       int64  `json:"ws_id"`
+	Source     string `json:"source"`
+	URL        string `json:"url"`
+	Title      string `json:"title"`
+	LinkStatus string `json:"link_status" enum:"UNKNOWN,OK,BROKEN,PARKED,ERROR"`
+	// VersionID is the id of the current version, 0 until the document is crawled.
+	VersionID     int64      `json:"version_id"`
+	LastCrawledAt *time.Time `json:"last_crawled_at,omitempty"`
+	LastChangedAt *time.Time `json:"last_changed_at,omitempty"`
+	// NextCrawlAt is absent if the document is never recrawled.
+	NextCrawlAt *time.Time `json:"next_crawl_at,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+}
+
+// DocumentDetail is a document with the content of its current version.
+type DocumentDetail struct {
+	Document
+	Version  int32    `json:"version"`
+	Markdown string   `json:"markdown"`
+	Summary  string   `json:"summary,omitempty"`
+	Tags     []string `json:"tags,omitempty"`
+	// Assets are the images captured from the current version.
+	Assets []Asset `json:"assets,omitempty"`
+}
+
+// Asset is an image captured from a document, served at /v1/assets/{key}.
+type Asset struct {
+	Key         string `json:"key"`
+	SourceURL   string `json:"source_url"`
+	ContentType string `json:"content_type"`
+	Size        int64  `json:"size"`
+	Caption     string `json:"caption,omitempty"`
+}
+
+type Chunk struct {
+	ID          int64  `json:"id"`
+	DocumentID  int64  `json:"document_id"`
+	VersionID   int64  `json:"version_id"`
+	Seq         int32  `json:"seq"`
+	Heading     string `json:"heading,omitempty"`
+	Content     string `json:"content"`
+	StartOffset int32  `json:"start_offset"`
+	EndOffset   int32  `json:"end_offset"`
+}
+
+// SearchResult is a document matching a query with its best matching chunk.
+type SearchResult struct {
+	DocumentID int64   `json:"document_id"`
+	URL        string  `json:"url"`
+	Title      string  `json:"title"`
+	ChunkID    int64   `json:"chunk_id"`
+	Heading    string  `json:"heading,omitempty"`
+	Content    string  `json:"content"`
+	Score      float64 `json:"score"`
+}
+
+type SearchResults struct {
+	Query   string         `json:"query"`
+	Mode    string         `json:"mode" enum:"keyword,vector,hybrid"`
+	Results []SearchResult `json:"results"`
+}
+
+type Answer struct {
+	Question string         `json:"question"`
+	Answer   string         `json:"answer"`
+	Sources  []SearchResult `json:"sources"`
+}
+
+type Link struct {
+	ID            int64      `json:"id"`
+	DocumentID    int64      `json:"document_id"`
+	URL           string     `json:"url"`
+	Kind          string     `json:"kind" enum:"SOURCE,OUTLINK"`
+	Status        string     `json:"status" enum:"UNKNOWN,OK,BROKEN,PARKED,ERROR"`
+	StatusCode    int32      `json:"status_code"`
+	Failures      int32      `json:"failures"`
+	LastCheckedAt *time.Time `json:"last_checked_at,omitempty"`
+}
+
+// Feed is a feed discovered on the pages of a workspace.
+// The pages of the entries of subscribed feeds are added to the workspace.
+type Feed struct {
+	ID             int64      `json:"id"`
+	WsID           int64      `json:"ws_id"`
+	URL            string     `json:"url"`
+	Title          string     `json:"title,omitempty"`
+	Type           string     `json:"type" enum:"rss,atom,json"`
+	Status         string     `json:"status" enum:"SUGGESTED,SUBSCRIBED,DISMISSED"`
+	DiscoveredFrom string     `json:"discovered_from"`
+	LastFetchedAt  *time.Time `json:"last_fetched_at,omitempty"`
+	FetchError     string     `json:"fetch_error,omitempty"`
+	CreatedAt      time.Time  `json:"created_at"`
+}
+
+type ReprocessJob struct {
+	ID        int64     `json:"id"`
+	WsID      int64     `json:"ws_id"`
+	Source    string    `json:"source,omitempty"`
+	URLPrefix string    `json:"url_prefix,omitempty"`
+	StaleOnly bool      `json:"stale_only"`
+	Status    string    `json:"status" enum:"PENDING,RUNNING,DONE,FAILED,CANCELED"`
+	Processed int32     `json:"processed"`
+	Failed    int32     `json:"failed"`
+	Error     string    `json:"error,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Session is a signed in session of the current user.
+type Session struct {
+	ID         int64     `json:"id"`
+	UserAgent  string    `json:"user_agent"`
+	IPAddress  string    `json:"ip_address"`
+	CreatedAt  time.Time `json:"created_at"`
+	LastSeenAt time.Time `json:"last_seen_at"`
+	ExpiresAt  time.Time `json:"expires_at"`
+	// Current is whether the session is the one of the request.
+	Current bool `json:"current"`
+}
+
+// CurrentSession is the signed in user with the session of the request.
+type CurrentSession struct {
+	User    User    `json:"user"`
+	Session Session `json:"session"`
+}
+
+// Identity is a sign in method of the current user.
+type Identity struct {
+	// Provider is password or the name of an OpenID Connect provider.
+	Provider string     `json:"provider"`
+	Linked   bool       `json:"linked"`
+	Email    string     `json:"email,omitempty"`
+	LinkedAt *time.Time `json:"linked_at,omitempty"`
+}
+
+// APIToken is a personal access token of the current user or an API key of a workspace.
+type APIToken struct {
+	ID   int64  `json:"id"`
+	Name string `json:"name"`
+	// Prefix is the start of the token, to tell it apart from the others.
+	Prefix     string     `json:"prefix"`
+	Scopes     []string   `json:"scopes"`
+	WsID       int64      `json:"ws_id,omitempty"`
+	CreatedBy  int64      `json:"created_by"`
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+// CreatedAPIToken is a new API token with its secret, which is only ever shown in this response.
+type CreatedAPIToken struct {
+	APIToken
+	Token string `json:"token"`
+}
+
+// The request bodies.
+
+// CreateUserRequest creates a user with an unverified email, verified by the user signing up with it or by a verification email.
+type CreateUserRequest struct {
+	Name  string `json:"name"`
+	Email string `json:"email"`
+}
+
+type UpdateUserRequest struct {
+	Name string `json:"name"`
+}
+
+type CreateWorkspaceRequest struct {
+	Name string `json:"name"`
+	// OwnerID is the user added as the first member, with the owner role. It is the signed in user if empty.
+	// Only the admins of the server name another user.
+	OwnerID int64 `json:"owner_id,omitempty"`
+}
+
+type UpdateWorkspaceRequest struct {
+	Name string `json:"name"`
+}
+
+type AddMemberRequest struct {
+	UserID int64 `json:"user_id"`
+	// Role is the built-in role of the member, viewer if empty.
+	Role string `json:"role,omitempty" enum:"owner,admin,editor,viewer"`
+}
+
+type UpdateMemberRequest struct {
+	Role string `json:"role" enum:"owner,admin,editor,viewer"`
+}
+
+type CreateRoleRequest struct {
+	Name        string   `json:"name"`
+	Permissions []string `json:"permissions,omitempty"`
+}
+
+// UpdateRoleRequest changes the fields it has.
+type UpdateRoleRequest struct {
+	Name        string    `json:"name,omitempty"`
+	Permissions *[]string `json:"permissions,omitempty"`
+}
+
+type CreateDocumentRequest struct {
+	URL string `json:"url"`
+	// Source is the source of the document, resolved from its host if empty.
+	Source string `json:"source,omitempty"`
+}
+
+type AskRequest struct {
+	Question string `json:"question"`
+	Mode     string `json:"mode,omitempty" enum:"keyword,vector,hybrid"`
+	Limit    int    `json:"limit,omitempty"`
+}
+
+type ReprocessRequest struct {
+	Source    string `json:"source,omitempty"`
+	URLPrefix string `json:"url_prefix,omitempty"`
+	// Stale selects only the documents processed by an older converter or chunker.
+	Stale bool `json:"stale,omitempty"`
+}
+
+type SignupRequest struct {
+	Name     string `json:"name"`
+	Email    string `json:"email"`
+	Password string `json:"password"`
+}
+
+type LoginRequest struct {
+	Email    string `json:"email"`
+	Password string `json:"password"`
+}
+
+type ChangePasswordRequest struct {
+	CurrentPassword string `json:"current_password"`
+	NewPassword     string `json:"new_password"`
+}
+
+type VerifyEmailRequest struct {
+	Token string `json:"token"`
+}
+
+type ForgotPasswordRequest struct {
+	Email string `json:"email"`
+}
+
+type CreateAPITokenRequest struct {
+	Name string `json:"name"`
+	// Scopes are some of read, ingest, ask and admin.
+	Scopes []string `json:"scopes"`
+	// ExpiresAt is when the token stops working, never if empty.
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+}
+
+type ResetPasswordRequest struct {
+	Token       string `json:"token"`
+	NewPassword string `json:"new_password"`
+}
+
+// The conversions from the database models.
+
+func timeOf(t pgtype.Timestamptz) time.Time {
+	return t.Time
+}
+
+// optionalTime returns nil for a null or infinite timestamp.
+func optionalTime(t pgtype.Timestamptz) *time.Time {
+	if !t.Valid || t.InfinityModifier != pgtype.Finite {
+		return nil
+	}
+	return &t.Time
+}
+
+func userOf(u database.User) User {
+	return User{ID: u.ID, Name: u.Name, Email: u.Email, EmailVerified: u.EmailVerified, Admin: u.IsAdmin, CreatedAt: timeOf(u.CreatedAt), UpdatedAt: timeOf(u.UpdatedAt)}
+}
+
+func workspaceOf(ws database.Wss) Workspace {
+	return Workspace{ID: ws.ID, Name: ws.Name, CreatedAt: timeOf(ws.CreatedAt), UpdatedAt: timeOf(ws.UpdatedAt)}
+}
+
+func memberOf(m database.WsMember) Member {
+	return Member{ID: m.ID, WsID: m.WsID, UserID: m.UserID, Role: strings.ToLower(string(m.Role)), CreatedAt: timeOf(m.CreatedAt)}
+}
+
+func roleOf(r database.WsRole) Role {
+	return Role{ID: r.ID, WsID: r.WsID, Name: r.Name, Permissions: r.Permissions, CreatedAt: timeOf(r.CreatedAt)}
+}
+
+func documentOf(d database.Document) Document {
+	return Document{
+		ID:            d.ID,
+		WsID:          d.WsID,
+		Source:        d.Source,
+		URL:           d.Url,
+		Title:         d.Title,
+		LinkStatus:    string(d.LinkStatus),
+		VersionID:     d.CurrentVersionID,
+		LastCrawledAt: optionalTime(d.LastCrawledAt),
+		LastChangedAt: optionalTime(d.LastChangedAt),
+		NextCrawlAt:   optionalTime(d.NextCrawlAt),
+		CreatedAt:     timeOf(d.CreatedAt),
+		UpdatedAt:     timeOf(d.UpdatedAt),
+	}
+}
+
+func assetOf(a database.DocumentAsset) Asset {
+	return Asset{Key: a.BlobKey, SourceURL: a.SourceUrl, ContentType: a.ContentType, Size: a.Size, Caption: a.Caption}
+}
+
+func chunkOf(c database.DocumentChunk) Chunk {
+	return Chunk{
+		ID:          c.ID,
+		DocumentID:  c.DocumentID,
+		VersionID:   c.VersionID,
+		Seq:         c.Seq,
+		Heading:     c.Heading,
+		Content:     c.Content,
+		StartOffset: c.StartOffset,
+		EndOffset:   c.EndOffset,
+	}
+}
+
+func resultsOf(results []search.Result) []SearchResult {
+	out := make([]SearchResult, len(results))
+	for i, r := range results {
+		out[i] = SearchResult(r)
+	}
+	return out
+}
+
+func linkOf(l database.Link) Link {
+	return Link{
+		ID:            l.ID,
+		DocumentID:    l.DocumentID,
+		URL:           l.Url,
+		Kind:          string(l.Kind),
+		Status:        string(l.Status),
+		StatusCode:    l.StatusCode,
+		Failures:      l.Failures,
+		LastCheckedAt: optionalTime(l.LastCheckedAt),
+	}
+}
+
+func feedOf(f database.Feed) Feed {
+	return Feed{
+		ID:             f.ID,
+		WsID:           f.WsID,
+		URL:            f.Url,
+		Title:          f.Title,
+		Type:           f.Type,
+		Status:         string(f.Status),
+		DiscoveredFrom: f.DiscoveredFrom,
+		LastFetchedAt:  optionalTime(f.LastFetchedAt),
+		FetchError:     f.FetchError,
+		CreatedAt:      timeOf(f.CreatedAt),
+	}
+}
+
+func jobOf(j database.ReprocessJob) ReprocessJob {
+	return ReprocessJob{
+		ID:        j.ID,
+		WsID:      j.WsID,
+		Source:    j.Source,
+		URLPrefix: j.UrlPrefix,
+		StaleOnly: j.StaleOnly,
+		Status:    string(j.Status),
+		Processed: j.Processed,
+		Failed:    j.Failed,
+		Error:     j.Error,
+		CreatedAt: timeOf(j.CreatedAt),
+		UpdatedAt: timeOf(j.UpdatedAt),
+	}
+}
+
+func identityOf(id auth.Identity) Identity {
+	return Identity{Provider: id.Provider, Linked: id.Linked, Email: id.Email, LinkedAt: optionalTime(id.LinkedAt)}
+}
+
+func apiTokenOf(t database.ApiToken) APIToken {
+	return APIToken{
+		ID:         t.ID,
+		Name:       t.Name,
+		Prefix:     t.Prefix,
+		Scopes:     t.Scopes,
+		WsID:       t.WsID,
+		CreatedBy:  t.UserID,
+		ExpiresAt:  optionalTime(t.ExpiresAt),
+		LastUsedAt: optionalTime(t.LastUsedAt),
+		CreatedAt:  timeOf(t.CreatedAt),
+	}
+}
+
+func sessionOf(s database.Session, current int64) Session {
+	return Session{
+		ID:         s.ID,
+		UserAgent:  s.UserAgent,
+		IPAddress:  s.IpAddress,
+		CreatedAt:  timeOf(s.CreatedAt),
+		LastSeenAt: timeOf(s.LastSeenAt),
+		ExpiresAt:  timeOf(s.ExpiresAt),
+		Current:    s.ID == current,
+	}
+}
@@ -0,0 +1,100 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"gosuda.org/jimin/database"
+	"gosuda.org/jimin/internal/rbac"
+)
+
+// listUsers lists the signed in user and the users sharing a workspace with it, or every user for an admin.
+func (a *API) listUsers(r *http.Request) (any, error) {
+	p, err := pageOf(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var users []database.User
+	err = a.authorize(r, 0, rbac.ManageUsers)
+	switch {
+	case err == nil:
+		users, err = a.q.ListUsers(r.Context(), database.ListUsersParams{AfterID: p.after, PageSize: int32(p.limit)})
+	case errors.Is(err, rbac.ErrForbidden):
+		caller, _ := principalOf(r.Context())
+		users, err = a.q.ListVisibleUsers(r.Context(), database.ListVisibleUsersParams{AfterID: p.after, UserID: caller.userID, PageSize: int32(p.limit)})
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	items := make([]User, len(users))
+	for i, u := range users {
+		items[i] = userOf(u)
+	}
+	return list(items, p, func(u User) int64 { return u.ID }), nil
+}
+
+// createUser creates a user with an unverified email. Only admins may create users.
+func (a *API) createUser(r *http.Request) (any, error) {
+	var req CreateUserRequest
+	if err := decode(r, &req); err != nil {
+		return nil, err
+	}
+	var v validation
+	v.required(req.Name, "name")
+	v.email(req.Email, "email")
+	if err := v.err(); err != nil {
+		return nil, err
+	}
+
+	id, err := a.cfg.IDs.Generate(r.Context())
+	if err != nil {
+		return nil, err
+	}
+	user, err := a.q.CreateUser(r.Context(), database.CreateUserParams{
+		ID:    id,
+		Name:  strings.TrimSpace(req.Name),
+		Email: req.Email,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return userOf(user), nil
+}
+
+func (a *API) getUser(r *http.Request) (any, error) {
+	id, err := pathID(r, "user_id")
+	if err != nil {
+		return nil, err
+	}
+	user, err := a.q.GetUserByID(r.Context(), id)
+	if err != nil {
+		return nil, err
+	}
+	return userOf(user), nil
+}
+
+// updateUser renames a user. Users rename themselves, and admins rename anyone.
+func (a *API) updateUser(r *http.Request) (any, error) {
+	id, err := pathID(r, "user_id")
+	if err != nil {
+		return nil, err
+	}
+	var req UpdateUserRequest
+	if err := decode(r, &req); err != nil {
+		return nil, err
+	}
+	var v validation
+	v.required(req.Name, "name")
+	if err := v.err(); err != nil {
+		return nil, err
+	}
+
+	user, err := a.q.UpdateUserName(r.Context(), database.UpdateUserNameParams{ID: id, Name: strings.TrimSpace(req.Name)})
+	if err != nil {
+		return nil, err
+	}
+	return userOf(user), nil
+}
@@ -0,0 +1,579 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"gosuda.org/jimin/database"
+	"gosuda.org/jimin/internal/rbac"
+)
+
+// listWorkspaces lists every workspace, or the workspaces of the signed in user with authentication.
+func (a *API) listWorkspaces(r *http.Request) (any, error) {
+	p, err := pageOf(r)
+	if err != nil {
+		return nil, err
+	}
+	var wss []database.Wss
+	if user, ok := principalOf(r.Context()); ok {
+		wss, err = a.q.ListUserWorkspaces(r.Context(), database.ListUserWorkspacesParams{UserID: user.userID, AfterID: p.after, PageSize: int32(p.limit)})
+	} else {
+		wss, err = a.q.ListWorkspaces(r.Context(), database.ListWorkspacesParams{AfterID: p.after, PageSize: int32(p.limit)})
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	items := make([]Workspace, len(wss))
+	for i, ws := range wss {
+		items[i] = workspaceOf(ws)
+	}
+	return list(items, p, func(ws Workspace) int64 { return ws.ID }), nil
+}
+
+func (a *API) createWorkspace(r *http.Request) (any, error) {
+	var req CreateWorkspaceRequest
+	if err := decode(r, &req); err != nil {
+		return nil, err
+	}
+	var v validation
+	v.required(req.Name, "name")
+	v.check(req.OwnerID >= 0, "owner_id", "must be a user id")
+	if err := v.err(); err != nil {
+		return nil, err
+	}
+
+	ctx := r.Context()
+	ownerID := req.OwnerID
+	if user, ok := principalOf(ctx); ok && ownerID == 0 {
+		ownerID = user.userID
+	} else if ok && ownerID != user.userID {
+		// only admins create workspaces for others
+		if err := a.authorize(r, 0, rbac.ManageUsers); err != nil {
+			return nil, err
+		}
+	}
+	if ownerID != 0 {
+		if err := a.checkUser(r, ownerID); err != nil {
+			return nil, err
+		}
+	}
+	wsID, err := a.cfg.IDs.Generate(ctx)
+	if err != nil {
+		return nil, err
+	}
+	memberID, err := a.cfg.IDs.Generate(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var ws database.Wss
+	err = pgx.BeginFunc(ctx, a.cfg.DB, func(tx pgx.Tx) error {
+		q := a.q.WithTx(tx)
+
+		var err error
+		ws, err = q.CreateWorkspace(ctx, database.CreateWorkspaceParams{ID: wsID, Name: strings.TrimSpace(req.Name)})
+		if err != nil || ownerID == 0 {
+			return err
+		}
+		_, err = q.CreateWorkspaceMember(ctx, database.CreateWorkspaceMemberParams{ID: memberID, WsID: ws.ID, UserID: ownerID, Role: rbac.Owner})
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return workspaceOf(ws), nil
+}
+
+// checkUser fails with 404 Not Found if the user does not exist or the signed in user may not see it,
+// telling the users of other workspaces apart from missing ones no more than the user endpoints do.
+func (a *API) checkUser(r *http.Request, id int64) error {
+	err := a.authorize(r, id, rbac.ViewUsers)
+	if err == nil {
+		_, err = a.q.GetUserByID(r.Context(), id)
+	}
+	if errors.Is(err, rbac.ErrNotMember) || errors.Is(err, pgx.ErrNoRows) {
+		return problemf(http.StatusNotFound, "user %d not found", id)
+	}
+	return err
+}
+
+func (a *API) getWorkspace(r *http.Request) (any, error) {
+	wsID, err := pathID(r, "ws_id")
+	if err != nil {
+		return nil, err
+	}
+	ws, err := a.q.GetWorkspace(r.Context(), wsID)
+	if err != nil {
+		return nil, err
+	}
+	return workspaceOf(ws), nil
+}
+
+func (a *API) updateWorkspace(r *http.Request) (any, error) {
+	wsID, err := pathID(r, "ws_id")
+	if err != nil {
+		return nil, err
+	}
+	var req UpdateWorkspaceRequest
+	if err := decode(r, &req); err != nil {
+		return nil, err
+	}
+	var v validation
+	v.required(req.Name, "name")
+	if err := v.err(); err != nil {
+		return nil, err
+	}
+
+	ws, err := a.q.UpdateWorkspace(r.Context(), database.UpdateWorkspaceParams{ID: wsID, Name: strings.TrimSpace(req.Name)})
+	if err != nil {
+		return nil, err
+	}
+	return workspaceOf(ws), nil
+}
+
+func (a *API) listMembers(r *http.Request) (any, error) {
+	wsID, err := pathID(r, "ws_id")
+	if err != nil {
+		return nil, err
+	}
+	p, err := pageOf(r)
+	if err != nil {
+		return nil, err
+	}
+	members, err := a.q.ListWorkspaceMembers(r.Context(), database.ListWorkspaceMembersParams{WsID: wsID, AfterID: p.after, PageSize: int32(p.limit)})
+	if err != nil {
+		return nil, err
+	}
+	return list(membersOf(members), p, func(m Member) int64 { return m.ID }), nil
+}
+
+func membersOf(members []database.WsMember) []Member {
+	items := make([]Member, len(members))
+	for i, m := range members {
+		items[i] = memberOf(m)
+	}
+	return items
+}
+
+func (a *API) addMember(r *http.Request) (any, error) {
+	wsID, err := pathID(r, "ws_id")
+	if err != nil {
+		return nil, err
+	}
+	var req AddMemberRequest
+	if err := decode(r, &req); err != nil {
+		return nil, err
+	}
+	if req.Role == "" {
+		req.Role = "viewer"
+	}
+	var v validation
+	v.check(req.UserID > 0, "user_id", "is required")
+	v.role(req.Role, "role")
+	if err := v.err(); err != nil {
+		return nil, err
+	}
+	role, _ := rbac.ParseRole(req.Role)
+	if err := a.checkRoles(r, wsID, role); err != nil {
+		return nil, err
+	}
+	if err := a.checkUser(r, req.UserID); err != nil {
+		return nil, err
+	}
+
+	id, err := a.cfg.IDs.Generate(r.Context())
+	if err != nil {
+		return nil, err
+	}
+	m, err := a.q.CreateWorkspaceMember(r.Context(), database.CreateWorkspaceMemberParams{ID: id, WsID: wsID, UserID: req.UserID, Role: role})
+	if err != nil {
+		return nil, err
+	}
+	return memberOf(m), nil
+}
+
+// member returns the member in the member_id path value.
+func (a *API) member(r *http.Request, wsID int64) (database.WsMember, error) {
+	id, err := pathID(r, "member_id")
+	if err != nil {
+		return database.WsMember{}, err
+	}
+	m, err := a.q.GetWorkspaceMember(r.Context(), database.GetWorkspaceMemberParams{WsID: wsID, ID: id})
+	if errors.Is(err, pgx.ErrNoRows) {
+		return m, problemf(http.StatusNotFound, "member %d not found", id)
+	}
+	return m, err
+}
+
+func (a *API) getMember(r *http.Request) (any, error) {
+	wsID, err := pathID(r, "ws_id")
+	if err != nil {
+		return nil, err
+	}
+	m, err := a.member(r, wsID)
+	if err != nil {
+		return nil, err
+	}
+	return memberOf(m), nil
+}
+
+func (v *validation) role(s, field string) {
+	_, err := rbac.ParseRole(s)
+	v.check(err == nil, field, "must be owner, admin, editor or viewer")
+}
+
+// keepOwner fails with rbac.ErrLastOwner unless the workspace has another owner than the one tx removes.
+// The owners stay locked until tx ends, so that concurrent removals cannot leave none.
+func keepOwner(ctx context.Context, q *database.Queries, wsID int64) error {
+	owners, err := q.LockWorkspaceOwners(ctx, wsID)
+	if err != nil {
+		return err
+	}
+	if len(owners) <= 1 {
+		return rbac.ErrLastOwner
+	}
+	return nil
+}
+
+// checkRoles fails with 403 Forbidden unless the signed in user may give and take every built-in role of roles,
+// so that members cannot raise others, or themselves, above their own role.
+func (a *API) checkRoles(r *http.Request, wsID int64, roles ...database.WsMemberRole) error {
+	access, err := a.access(r, wsID)
+	if err != nil {
+		return err
+	}
+	for _, role := range roles {
+		if !access.Grants(role) {
+			return problemf(http.StatusForbidden, "the member may not give or take the %s role", strings.ToLower(string(role)))
+		}
+	}
+	return nil
+}
+
+// updateMember changes the built-in role of a member.
+// The signed in user ranks at or above both roles, and only owners make or remove owners and admins.
+func (a *API) updateMember(r *http.Request) (any, error) {
+	wsID, err := pathID(r, "ws_id")
+	if err != nil {
+		return nil, err
+	}
+	var req UpdateMemberRequest
+	if err := decode(r, &req); err != nil {
+		return nil, err
+	}
+	var v validation
+	v.role(req.Role, "role")
+	if err := v.err(); err != nil {
+		return nil, err
+	}
+	role, _ := rbac.ParseRole(req.Role)
+	m, err := a.member(r, wsID)
+	if err != nil {
+		return nil, err
+	}
+	if err := a.checkRoles(r, wsID, m.Role, role); err != nil {
+		return nil, err
+	}
+
+	ctx := r.Context()
+	err = pgx.BeginFunc(ctx, a.cfg.DB, func(tx pgx.Tx) error {
+		q := a.q.WithTx(tx)
+		if m.Role == rbac.Owner && role != rbac.Owner {
+			if err := keepOwner(ctx, q, wsID); err != nil {
+				return err
+			}
+		}
+		var err error
+		m, err = q.UpdateWorkspaceMemberRole(ctx, database.UpdateWorkspaceMemberRoleParams{WsID: wsID, ID: m.ID, Role: role})
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return memberOf(m), nil
+}
+
+// removeMember removes a member and its roles. Only owners remove owners and admins.
+func (a *API) removeMember(r *http.Request) (any, error) {
+	wsID, err := pathID(r, "ws_id")
+	if err != nil {
+		return nil, err
+	}
+	m, err := a.member(r, wsID)
+	if err != nil {
+		return nil, err
+	}
+	if err := a.checkRoles(r, wsID, m.Role); err != nil {
+		return nil, err
+	}
+
+	ctx := r.Context()
+	return nil, pgx.BeginFunc(ctx, a.cfg.DB, func(tx pgx.Tx) error {
+		q := a.q.WithTx(tx)
+		if m.Role == rbac.Owner {
+			if err := keepOwner(ctx, q, wsID); err != nil {
+				return err
+			}
+		}
+		if err := q.DeleteMemberRoles(ctx, database.DeleteMemberRolesParams{WsID: wsID, WsMemberID: m.ID}); err != nil {
+			return err
+		}
+		_, err := q.DeleteWorkspaceMember(ctx, database.DeleteWorkspaceMemberParams{WsID: wsID, ID: m.ID})
+		return err
+	})
+}
+
+func (a *API) listRoles(r *http.Request) (any, error) {
+	wsID, err := pathID(r, "ws_id")
+	if err != nil {
+		return nil, err
+	}
+	p, err := pageOf(r)
+	if err != nil {
+		return nil, err
+	}
+	roles, err := a.q.ListWorkspaceRoles(r.Context(), database.ListWorkspaceRolesParams{WsID: wsID, AfterID: p.after, PageSize: int32(p.limit)})
+	if err != nil {
+		return nil, err
+	}
+
+	items := make([]Role, len(roles))
+	for i, role := range roles {
+		items[i] = roleOf(role)
+	}
+	return list(items, p, func(role Role) int64 { return role.ID }), nil
+}
+
+func (a *API) createRole(r *http.Request) (any, error) {
+	wsID, err := pathID(r, "ws_id")
+	if err != nil {
+		return nil, err
+	}
+	var req CreateRoleRequest
+	if err := decode(r, &req); err != nil {
+		return nil, err
+	}
+	var v validation
+	v.roleName(req.Name, "name")
+	v.permissions(req.Permissions, "permissions")
+	if err := v.err(); err != nil {
+		return nil, err
+	}
+	if err := a.checkGrants(r, wsID, req.Permissions); err != nil {
+		return nil, err
+	}
+
+	id, err := a.cfg.IDs.Generate(r.Context())
+	if err != nil {
+		return nil, err
+	}
+	role, err := a.q.CreateWorkspaceRole(r.Context(), database.CreateWorkspaceRoleParams{
+		ID:          id,
+		WsID:        wsID,
+		Name:        strings.TrimSpace(req.Name),
+		Permissions: permissionsOf(req.Permissions),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return roleOf(role), nil
+}
+
+func (v *validation) roleName(s, field string) {
+	v.required(s, field)
+	v.check(!rbac.IsBuiltIn(s), field, "is the name of a built-in role")
+}
+
+func (v *validation) permissions(perms []string, field string) {
+	if rbac.CheckPermissions(perms) != nil {
+		names := make([]string, len(rbac.Permissions))
+		for i, p := range rbac.Permissions {
+			names[i] = string(p)
+		}
+		v.check(false, field, "must be some of "+strings.Join(names, ", "))
+	}
+}
+
+// checkGrants fails with 403 Forbidden unless the signed in user has every permission of perms,
+// so that custom roles grant no more than the member creating or changing them has.
+func (a *API) checkGrants(r *http.Request, wsID int64, perms []string) error {
+	access, err := a.access(r, wsID)
+	if err != nil {
+		return err
+	}
+	for _, p := range perms {
+		if !access.Allows(rbac.Permission(p)) {
+			return problemf(http.StatusForbidden, "the member lacks the %s permission it grants", p)
+		}
+	}
+	return nil
+}
+
+// permissionsOf returns the sorted permissions of a custom role, never nil so that they are not stored as NULL.
+func permissionsOf(perms []string) []string {
+	perms = append([]string{}, perms...)
+	slices.Sort(perms)
+	return slices.Compact(perms)
+}
+
+// updateRole renames a custom role or replaces its permissions.
+func (a *API) updateRole(r *http.Request) (any, error) {
+	wsID, err := pathID(r, "ws_id")
+	if err != nil {
+		return nil, err
+	}
+	var req UpdateRoleRequest
+	if err := decode(r, &req); err != nil {
+		return nil, err
+	}
+	var v validation
+	if req.Name != "" {
+		v.roleName(req.Name, "name")
+	}
+	if req.Permissions != nil {
+		v.permissions(*req.Permissions, "permissions")
+	}
+	if err := v.err(); err != nil {
+		return nil, err
+	}
+	if req.Permissions != nil {
+		if err := a.checkGrants(r, wsID, *req.Permissions); err != nil {
+			return nil, err
+		}
+	}
+	role, err := a.role(r, wsID)
+	if err != nil {
+		return nil, err
+	}
+
+	params := database.UpdateWorkspaceRoleParams{WsID: wsID, ID: role.ID, Name: role.Name, Permissions: role.Permissions}
+	if req.Name != "" {
+		params.Name = strings.TrimSpace(req.Name)
+	}
+	if req.Permissions != nil {
+		params.Permissions = permissionsOf(*req.Permissions)
+	}
+	role, err = a.q.UpdateWorkspaceRole(r.Context(), params)
+	if err != nil {
+		return nil, err
+	}
+	return roleOf(role), nil
+}
+
+// role returns the role in the role_id path value.
+func (a *API) role(r *http.Request, wsID int64) (database.WsRole, error) {
+	id, err := pathID(r, "role_id")
+	if err != nil {
+		return database.WsRole{}, err
+	}
+	role, err := a.q.GetWorkspaceRole(r.Context(), database.GetWorkspaceRoleParams{WsID: wsID, ID: id})
+	if errors.Is(err, pgx.ErrNoRows) {
+		return role, problemf(http.StatusNotFound, "role %d not found", id)
+	}
+	return role, err
+}
+
+func (a *API) getRole(r *http.Request) (any, error) {
+	wsID, err := pathID(r, "ws_id")
+	if err != nil {
+		return nil, err
+	}
+	role, err := a.role(r, wsID)
+	if err != nil {
+		return nil, err
+	}
+	return roleOf(role), nil
+}
+
+func (a *API) deleteRole(r *http.Request) (any, error) {
+	wsID, err := pathID(r, "ws_id")
+	if err != nil {
+		return nil, err
+	}
+	role, err := a.role(r, wsID)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx := r.Context()
+	return nil, pgx.BeginFunc(ctx, a.cfg.DB, func(tx pgx.Tx) error {
+		q := a.q.WithTx(tx)
+		if err := q.DeleteRoleMembers(ctx, database.DeleteRoleMembersParams{WsID: wsID, WsRoleID: role.ID}); err != nil {
+			return err
+		}
+		_, err := q.DeleteWorkspaceRole(ctx, database.DeleteWorkspaceRoleParams{WsID: wsID, ID: role.ID})
+		return err
+	})
+}
+
+func (a *API) listRoleMembers(r *http.Request) (any, error) {
+	wsID, err := pathID(r, "ws_id")
+	if err != nil {
+		return nil, err
+	}
+	role, err := a.role(r, wsID)
+	if err != nil {
+		return nil, err
+	}
+	p, err := pageOf(r)
+	if err != nil {
+		return nil, err
+	}
+	members, err := a.q.ListWorkspaceRoleMembers(r.Context(), database.ListWorkspaceRoleMembersParams{
+		WsID:     wsID,
+		WsRoleID: role.ID,
+		AfterID:  p.after,
+		PageSize: int32(p.limit),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return list(membersOf(members), p, func(m Member) int64 { return m.ID }), nil
+}
+
+func (a *API) assignRole(r *http.Request) (any, error) {
+	wsID, err := pathID(r, "ws_id")
+	if err != nil {
+		return nil, err
+	}
+	role, err := a.role(r, wsID)
+	if err != nil {
+		return nil, err
+	}
+	m, err := a.member(r, wsID)
+	if err != nil {
+		return nil, err
+	}
+
+	id, err := a.cfg.IDs.Generate(r.Context())
+	if err != nil {
+		return nil, err
+	}
+	return nil, a.q.AddWorkspaceRoleMember(r.Context(), database.AddWorkspaceRoleMemberParams{ID: id, WsID: wsID, WsRoleID: role.ID, WsMemberID: m.ID})
+}
+
+func (a *API) unassignRole(r *http.Request) (any, error) {
+	wsID, err := pathID(r, "ws_id")
+	if err != nil {
+		return nil, err
+	}
+	role, err := a.role(r, wsID)
+	if err != nil {
+		return nil, err
+	}
+	m, err := a.member(r, wsID)
+	if err != nil {
+		return nil, err
+	}
+
+	n, err := a.q.RemoveWorkspaceRoleMember(r.Context(), database.RemoveWorkspaceRoleMemberParams{WsID: wsID, WsRoleID: role.ID, WsMemberID: m.ID})
+	if err == nil && n == 0 {
+		err = problemf(http.StatusNotFound, "member %d does not have role %d", m.ID, role.ID)
+	}
+	return nil, err
+}
@@ -0,0 +1,250 @@
+package asset
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"gosuda.org/jimin/internal/blob"
+	"gosuda.org/jimin/internal/convert"
+)
+
+const (
+	_DEFAULT_MAX_BYTES  = 10 << 20
+	_DEFAULT_MAX_IMAGES = 64
+	_DEFAULT_TIMEOUT    = time.Second * 30
+)
+
+var (
+	ErrTooLarge       = errors.New("asset: image too large")
+	ErrNotImage       = errors.New("asset: not an image")
+	ErrUnexpectedCode = errors.New("asset: unexpected status code")
+)
+
+// Captioner generates a short description of an image.
+type Captioner interface {
+	Caption(ctx context.Context, image []byte, contentType string) (string, error)
+}
+
+type Config struct {
+	Store blob.Store
+	// BaseURL is prepended to the blob key to reference the stored copy.
+	BaseURL   string
+	Client    *http.Client
+	MaxBytes  int64
+	MaxImages int
+	// Captioner is optional. If set, images are captioned after they are stored.
+	Captioner Captioner
+}
+
+// Asset is an image captured from a document.
+type Asset struct {
+	Source      string    `json:"source"`
+	URL         string    `json:"url"`
+	Blob        blob.Blob `json:"blob"`
+	ContentType string    `json:"content_type"`
+	Caption     string    `json:"caption,omitempty"`
+}
+
+type Capturer struct {
+	cfg Config
+}
+
+func NewCapturer(cfg Config) *Capturer {
+	if cfg.Client == nil {
+		cfg.Client = &http.Client{Timeout: _DEFAULT_TIMEOUT}
+	}
+	if cfg.MaxBytes <= 0 {
+		cfg.MaxBytes = _DEFAULT_MAX_BYTES
+	}
+	if cfg.MaxImages <= 0 {
+		cfg.MaxImages = _DEFAULT_MAX_IMAGES
+	}
+	return &Capturer{cfg: cfg}
+}
+
+// CaptureDocument stores the images of doc and points the image nodes at the stored copies.
+// Images that cannot be captured keep their original URL.
+// Captions become the alt text of images without one, or else their title, so they are indexed with the text.
+func (c *Capturer) CaptureDocument(ctx context.Context, doc *convert.Document) ([]Asset, error) {
+	var images []*convert.Node
+	doc.Walk(func(n *convert.Node) bool {
+		if n.Kind == convert.NodeImage {
+			images = append(images, n)
+		}
+		return true
+	})
+
+	assets, err := c.capture(ctx, len(images), func(i int) string { return images[i].URL })
+	if err != nil {
+		return nil, err
+	}
+
+	for i, a := range assets {
+		if a == nil {
+			continue
+		}
+		images[i].URL = a.URL
+		switch {
+		case images[i].Alt == "":
+			images[i].Alt = a.Caption
+		case images[i].Title == "":
+			images[i].Title = a.Caption
+		}
+	}
+	doc.Render()
+
+	return compact(assets), nil
+}
+
+var markdownImagePattern = regexp.MustCompile(`!\[([^\]]*)\]\(([^)\s]+)((?:\s+"[^"]*")?)\)`)
+
+// CaptureMarkdown stores the images referenced by md and rewrites the image links to the stored copies.
+func (c *Capturer) CaptureMarkdown(ctx context.Context, md string) (string, []Asset, error) {
+	matches := markdownImagePattern.FindAllStringSubmatch(md, -1)
+
+	assets, err := c.capture(ctx, len(matches), func(i int) string { return matches[i][2] })
+	if err != nil {
+		return "", nil, err
+	}
+
+	i := 0
+	md = markdownImagePattern.ReplaceAllStringFunc(md, func(m string) string {
+		a, match := assets[i], matches[i]
+		i++
+		if a == nil {
+			return m
+		}
+		alt := match[1]
+		if alt == "" {
+			alt = strings.NewReplacer("[", "", "]", "").Replace(a.Caption)
+		}
+		return "![" + alt + "](" + a.URL + match[3] + ")"
+	})
+
+	return md, compact(assets), nil
+}
+
+func (c *Capturer) capture(ctx context.Context, n int, source func(i int) string) ([]*Asset, error) {
+	assets := make([]*Asset, n)
+	seen := make(map[string]*Asset)
+	captured := 0
+
+	for i := range assets {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		src := source(i)
+		if a, ok := seen[src]; ok {
+			assets[i] = a
+			continue
+		}
+		if captured >= c.cfg.MaxImages {
+			break
+		}
+
+		a, data, err := c.fetch(ctx, src)
+		if err != nil {
+			log.Warn().Err(err).Str("url", src).Msg("asset: failed to capture image")
+			seen[src] = nil
+			continue
+		}
+		captured++
+
+		if c.cfg.Captioner != nil {
+			a.Caption = c.caption(ctx, a, data)
+		}
+
+		seen[src] = a
+		assets[i] = a
+	}
+
+	return assets, nil
+}
+
+func (c *Capturer) fetch(ctx context.Context, src string) (*Asset, []byte, error) {
+	u, err := url.Parse(src)
+	if err != nil {
+		return nil, nil, err
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, nil, ErrNotImage
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, src, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	resp, err := c.cfg.Client.Do(req)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, nil, ErrUnexpectedCode
+	}
+	if resp.ContentLength > c.cfg.MaxBytes {
+		return nil, nil, ErrTooLarge
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, c.cfg.MaxBytes+1))
+	if err != nil {
+		return nil, nil, err
+	}
+	if int64(len(data)) > c.cfg.MaxBytes {
+		return nil, nil, ErrTooLarge
+	}
+
+	contentType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if !strings.HasPrefix(contentType, "image/") {
+		contentType = http.DetectContentType(data)
+	}
+	if !strings.HasPrefix(contentType, "image/") {
+		return nil, nil, ErrNotImage
+	}
+
+	b, err := c.cfg.Store.Put(ctx, bytes.NewReader(data))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return &Asset{
+		Source:      src,
+		URL:         c.cfg.BaseURL + b.Key,
+		Blob:        b,
+		ContentType: contentType,
+	}, data, nil
+}
+
+func (c *Capturer) caption(ctx context.Context, a *Asset, data []byte) string {
+	caption, err := c.cfg.Captioner.Caption(ctx, data, a.ContentType)
+	if err != nil {
+		log.Warn().Err(err).Str("url", a.Source).Msg("asset: failed to caption image")
+		return ""
+	}
+	return strings.TrimSpace(caption)
+}
+
+func compact(assets []*Asset) []Asset {
+	var out []Asset
+	seen := make(map[*Asset]bool)
+	for _, a := range assets {
+		if a == nil || seen[a] {
+			continue
+		}
+		seen[a] = true
+		out = append(out, *a)
+	}
+	return out
+}
@@ -0,0 +1,117 @@
+package asset
+
+import (
+	"bytes"
+	"context"
+	"image"
+	"image/png"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"gosuda.org/jimin/internal/blob"
+	"gosuda.org/jimin/internal/convert"
+)
+
+type fakeCaptioner struct {
+	calls int
+}
+
+func (f *fakeCaptioner) Caption(ctx context.Context, image []byte, contentType string) (string, error) {
+	f.calls++
+	return "a white square", nil
+}
+
+func TestCaptureMarkdown(t *testing.T) {
+	var img bytes.Buffer
+	png.Encode(&img, image.NewGray(image.Rect(0, 0, 4, 4)))
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/a.png", "/b.png":
+			w.Header().Set("Content-Type", "image/png")
+			w.Write(img.Bytes())
+		case "/large.png":
+			w.Write(bytes.Repeat([]byte{0}, 4096))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer ts.Close()
+
+	store, err := blob.NewFSStore(t.TempDir(), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	captioner := &fakeCaptioner{}
+	c := NewCapturer(Config{
+		Store:     store,
+		BaseURL:   "/blobs/",
+		MaxBytes:  1024,
+		Captioner: captioner,
+	})
+
+	md := "![](" + ts.URL + "/a.png) ![b](" + ts.URL + "/b.png) ![](" + ts.URL + "/a.png)\n" +
+		"![big](" + ts.URL + "/large.png) ![gone](" + ts.URL + "/missing.png)"
+
+	out, assets, err := c.CaptureMarkdown(context.Background(), md)
+	if err != nil {
+		t.Fatalf("CaptureMarkdown() error = %v", err)
+	}
+
+	if len(assets) != 2 {
+		t.Fatalf("CaptureMarkdown() captured %d assets, want 2", len(assets))
+	}
+	if assets[0].Blob.Key != assets[1].Blob.Key {
+		t.Errorf("CaptureMarkdown() identical images were not deduplicated")
+	}
+	if captioner.calls != 2 {
+		t.Errorf("Captioner called %d times, want 2", captioner.calls)
+	}
+
+	stored := "/blobs/" + assets[0].Blob.Key
+	if want := "![a white square](" + stored + ") ![b](" + stored + ") ![a white square](" + stored + ")"; !strings.HasPrefix(out, want) {
+		t.Errorf("CaptureMarkdown() = %q, want prefix %q", out, want)
+	}
+	if !strings.Contains(out, ts.URL+"/large.png") || !strings.Contains(out, ts.URL+"/missing.png") {
+		t.Errorf("CaptureMarkdown() rewrote images that were not captured: %q", out)
+	}
+}
+
+func TestCaptureDocument(t *testing.T) {
+	var img bytes.Buffer
+	png.Encode(&img, image.NewGray(image.Rect(0, 0, 4, 4)))
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "image/png")
+		w.Write(img.Bytes())
+	}))
+	defer ts.Close()
+
+	store, err := blob.NewFSStore(t.TempDir(), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := NewCapturer(Config{Store: store, BaseURL: "/blobs/", Captioner: &fakeCaptioner{}})
+
+	doc, err := convert.ConvertHTMLToDocument(`<p><img src="/a.png"> <img src="/b.png" alt="chart"></p>`, ts.URL+"/page")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assets, err := c.CaptureDocument(context.Background(), doc)
+	if err != nil {
+		t.Fatalf("CaptureDocument() error = %v", err)
+	}
+	if len(assets) != 2 || assets[0].Caption != "a white square" {
+		t.Fatalf("CaptureDocument() = %+v", assets)
+	}
+
+	stored := "/blobs/" + assets[0].Blob.Key
+	want := "![a white square](" + stored + `) ![chart](` + stored + ` "a white square")`
+	if !strings.Contains(doc.Markdown, want) {
+		t.Errorf("CaptureDocument() markdown = %q, want %q", doc.Markdown, want)
+	}
+}
@@ -0,0 +1,178 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"gosuda.org/jimin/database"
+)
+
+// Prefixes of API tokens, which tell personal tokens and workspace keys apart in logs and secret scanners.
+const (
+	PersonalTokenPrefix = "jimin_pat_"
+	WorkspaceKeyPrefix  = "jimin_wsk_"
+
+	// _DISPLAY_SECRET_CHARS are the characters of the secret kept with the prefix to tell tokens apart in lists.
+	_DISPLAY_SECRET_CHARS = 4
+)
+
+// Scopes of API tokens.
+const (
+	// ScopeRead reads users, workspaces, documents and search results.
+	ScopeRead = "read"
+	// ScopeIngest adds documents and reprocesses them.
+	ScopeIngest = "ingest"
+	// ScopeAsk asks questions.
+	ScopeAsk = "ask"
+	// ScopeAdmin changes users, workspaces, members and roles, and has every other scope.
+	ScopeAdmin = "admin"
+)
+
+// Scopes are the scopes of API tokens.
+var Scopes = []string{ScopeRead, ScopeIngest, ScopeAsk, ScopeAdmin}
+
+var (
+	ErrInvalidAPIToken = errors.New("auth: invalid, expired or revoked API token")
+	ErrUnknownScope    = errors.New("auth: unknown API token scope")
+	ErrNoScopes        = errors.New("auth: an API token needs a scope")
+	ErrNotMember       = errors.New("auth: not a member of the workspace")
+)
+
+// HasScope reports whether scopes grant scope.
+func HasScope(scopes []string, scope string) bool {
+	return slices.Contains(scopes, scope) || slices.Contains(scopes, ScopeAdmin)
+}
+
+// CheckScopes returns an error if scopes are empty or hold an unknown scope.
+func CheckScopes(scopes []string) error {
+	if len(scopes) == 0 {
+		return ErrNoScopes
+	}
+	for _, s := range scopes {
+		if !slices.Contains(Scopes, s) {
+			return ErrUnknownScope
+		}
+	}
+	return nil
+}
+
+// IsAPIToken reports whether s has the prefix of an API token.
+func IsAPIToken(s string) bool {
+	return strings.HasPrefix(s, PersonalTokenPrefix) || strings.HasPrefix(s, WorkspaceKeyPrefix)
+}
+
+// CreateAPIToken creates a token of a user with scopes, expiring at expires unless it is zero.
+// A token of a workspace only works on the workspace, and its user must be a member of it.
+// The token is returned once and only its hash is stored.
+func (a *Auth) CreateAPIToken(ctx context.Context, userID, wsID int64, name string, scopes []string, expires time.Time) (string, database.ApiToken, error) {
+	if err := CheckScopes(scopes); err != nil {
+		return "", database.ApiToken{}, err
+	}
+	scopes = slices.Clone(scopes)
+	slices.Sort(scopes)
+	scopes = slices.Compact(scopes)
+
+	prefix := PersonalTokenPrefix
+	if wsID != 0 {
+		prefix = WorkspaceKeyPrefix
+		_, err := a.q.GetWorkspaceMemberByUser(ctx, database.GetWorkspaceMemberByUserParams{WsID: wsID, UserID: userID})
+		if errors.Is(err, pgx.ErrNoRows) {
+			return "", database.ApiToken{}, ErrNotMember
+		}
+		if err != nil {
+			return "", database.ApiToken{}, err
+		}
+	}
+	id, err := a.cfg.IDs.Generate(ctx)
+	if err != nil {
+		return "", database.ApiToken{}, err
+	}
+
+	token := prefix + newToken()
+	var exp pgtype.Timestamptz
+	if !expires.IsZero() {
+		exp = timestamptz(expires)
+	}
+	t, err := a.q.CreateAPIToken(ctx, database.CreateAPITokenParams{
+		ID:        id,
+		UserID:    userID,
+		WsID:      wsID,
+		Name:      strings.TrimSpace(name),
+		TokenHash: hashToken(token),
+		Prefix:    token[:len(prefix)+_DISPLAY_SECRET_CHARS],
+		Scopes:    scopes,
+		ExpiresAt: exp,
+	})
+	return token, t, err
+}
+
+// APIToken returns the valid API token of token and records its use.
+// The key of a workspace stops working when its user leaves the workspace.
+func (a *Auth) APIToken(ctx context.Context, token string) (database.ApiToken, error) {
+	if !IsAPIToken(token) {
+		return database.ApiToken{}, ErrInvalidAPIToken
+	}
+	t, err := a.q.GetAPITokenByHash(ctx, hashToken(token))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return t, ErrInvalidAPIToken
+	}
+	if err != nil {
+		return t, err
+	}
+
+	now := time.Now()
+	if t.RevokedAt.Valid || (t.ExpiresAt.Valid && !now.Before(t.ExpiresAt.Time)) {
+		return t, ErrInvalidAPIToken
+	}
+	if t.WsID != 0 {
+		_, err := a.q.GetWorkspaceMemberByUser(ctx, database.GetWorkspaceMemberByUserParams{WsID: t.WsID, UserID: t.UserID})
+		if errors.Is(err, pgx.ErrNoRows) {
+			return t, ErrInvalidAPIToken
+		}
+		if err != nil {
+			return t, err
+		}
+	}
+
+	if t.LastUsedAt.Valid && now.Sub(t.LastUsedAt.Time) < _TOUCH_INTERVAL {
+		return t, nil
+	}
+	if err := a.q.TouchAPIToken(ctx, t.ID); err != nil {
+		return t, err
+	}
+	t.LastUsedAt = timestamptz(now)
+	return t, nil
+}
+
+// APITokens returns the personal tokens of a user that are not revoked, the newest first.
+func (a *Auth) APITokens(ctx context.Context, userID int64) ([]database.ApiToken, error) {
+	return a.q.ListUserAPITokens(ctx, userID)
+}
+
+// RevokeAPIToken revokes a personal token of a user.
+func (a *Auth) RevokeAPIToken(ctx context.Context, userID, id int64) error {
+	n, err := a.q.RevokeUserAPIToken(ctx, database.RevokeUserAPITokenParams{ID: id, UserID: userID})
+	if err == nil && n == 0 {
+		err = ErrInvalidAPIToken
+	}
+	return err
+}
+
+// WorkspaceKeys returns the keys of a workspace that are not revoked, the newest first.
+func (a *Auth) WorkspaceKeys(ctx context.Context, wsID int64) ([]database.ApiToken, error) {
+	return a.q.ListWorkspaceAPITokens(ctx, wsID)
+}
+
+// RevokeWorkspaceKey revokes a key of a workspace.
+func (a *Auth) RevokeWorkspaceKey(ctx context.Context, wsID, id int64) error {
+	n, err := a.q.RevokeWorkspaceAPIToken(ctx, database.RevokeWorkspaceAPITokenParams{ID: id, WsID: wsID})
+	if err == nil && n == 0 {
+		err = ErrInvalidAPIToken
+	}
+	return err
+}
@@ -0,0 +1,266 @@
+// Package auth stores the credentials of users and the server-side sessions they sign in with.
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/rs/zerolog/log"
+	"gosuda.org/jimin/database"
+	"gosuda.org/jimin/internal/mail"
+	"gosuda.org/jimin/internal/passhash"
+)
+
+// PasswordProvider is the users_auth provider of password credentials.
+// Its subject is the user id and its associated data the password hash.
+const PasswordProvider int64 = 1
+
+const (
+	MinPasswordLength = 8
+	// MaxPasswordLength bounds the bytes hashed for a login attempt.
+	MaxPasswordLength = 1024
+
+	_DEFAULT_SESSION_TTL      = time.Hour * 24 * 14
+	_DEFAULT_SESSION_MAX_AGE  = time.Hour * 24 * 90
+	_DEFAULT_COOKIE_NAME      = "jimin_session"
+	_DEFAULT_CLEANUP_INTERVAL = time.Hour
+)
+
+var (
+	ErrInvalidCredentials = errors.New("auth: invalid email or password")
+	ErrInvalidPassword    = errors.New("auth: password must be between 8 and 1024 bytes")
+	ErrInvalidSession     = errors.New("auth: invalid or expired session")
+)
+
+type DB interface {
+	database.DBTX
+	Begin(ctx context.Context) (pgx.Tx, error)
+}
+
+type IDGenerator interface {
+	Generate(ctx context.Context) (int64, error)
+}
+
+type Config struct {
+	DB  DB
+	IDs IDGenerator
+
+	// SessionTTL is the time a session stays valid without being used.
+	// Every use extends it, up to SessionMaxAge after the sign in.
+	SessionTTL    time.Duration
+	SessionMaxAge time.Duration
+
+	CookieName string
+	// InsecureCookies sends the session cookie over plain HTTP, for local development.
+	InsecureCookies bool
+
+	// CleanupInterval is the time between deletions of the expired and revoked sessions.
+	CleanupInterval time.Duration
+
+	// Providers are the OpenID Connect providers users sign in with besides passwords.
+	Providers []Provider
+
+	// Mailer sends the verification and password reset emails, which are disabled if nil.
+	Mailer mail.Mailer
+	// TokenSecret signs the tokens of the emails. A random one, which does not survive restarts, is used if empty.
+	TokenSecret []byte
+	// VerifyEmailURL and ResetPasswordURL are the pages the emails link to, with the token in the token query parameter.
+	VerifyEmailURL   string
+	ResetPasswordURL string
+}
+
+// Auth signs users up and in with passwords and OpenID Connect providers and keeps their sessions.
+type Auth struct {
+	cfg Config
+	q   *database.Queries
+}
+
+func New(cfg Config) *Auth {
+	if cfg.SessionTTL <= 0 {
+		cfg.SessionTTL = _DEFAULT_SESSION_TTL
+	}
+	if cfg.SessionMaxAge <= 0 {
+		cfg.SessionMaxAge = _DEFAULT_SESSION_MAX_AGE
+	}
+	if cfg.SessionTTL > cfg.SessionMaxAge {
+		cfg.SessionTTL = cfg.SessionMaxAge
+	}
+	if cfg.CookieName == "" {
+		cfg.CookieName = _DEFAULT_COOKIE_NAME
+	}
+	if cfg.CleanupInterval <= 0 {
+		cfg.CleanupInterval = _DEFAULT_CLEANUP_INTERVAL
+	}
+	if len(cfg.TokenSecret) == 0 {
+		cfg.TokenSecret = make([]byte, 32)
+		rand.Read(cfg.TokenSecret)
+	}
+	return &Auth{cfg: cfg, q: database.New(cfg.DB)}
+}
+
+func timestamptz(t time.Time) pgtype.Timestamptz {
+	return pgtype.Timestamptz{Time: t, Valid: true}
+}
+
+// NormalizeEmail returns the form emails are stored and looked up in.
+func NormalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+// CheckPassword returns ErrInvalidPassword if password is too short or too long.
+func CheckPassword(password string) error {
+	if utf8.RuneCountInString(password) < MinPasswordLength || len(password) > MaxPasswordLength {
+		return ErrInvalidPassword
+	}
+	return nil
+}
+
+// dummyHash is verified against when a user has no password, so that unknown emails take as long as wrong passwords.
+var dummyHash = sync.OnceValue(func() string {
+	return passhash.NewPassHash("")
+})
+
+// Signup creates a user with a password, and emails the user a verification link if email is configured.
+func (a *Auth) Signup(ctx context.Context, name, email, password string) (database.User, error) {
+	if err := CheckPassword(password); err != nil {
+		return database.User{}, err
+	}
+	userID, err := a.cfg.IDs.Generate(ctx)
+	if err != nil {
+		return database.User{}, err
+	}
+	authID, err := a.cfg.IDs.Generate(ctx)
+	if err != nil {
+		return database.User{}, err
+	}
+	hash := passhash.NewPassHash(password)
+
+	var user database.User
+	err = pgx.BeginFunc(ctx, a.cfg.DB, func(tx pgx.Tx) error {
+		q := a.q.WithTx(tx)
+
+		var err error
+		user, err = q.CreateUser(ctx, database.CreateUserParams{ID: userID, Name: strings.TrimSpace(name), Email: NormalizeEmail(email)})
+		if err != nil {
+			return err
+		}
+		_, err = q.CreateUserAuth(ctx, database.CreateUserAuthParams{
+			ID:              authID,
+			UserID:          user.ID,
+			ProviderID:      PasswordProvider,
+			ProviderSubject: strconv.FormatInt(user.ID, 10),
+			AssociatedData:  hash,
+		})
+		return err
+	})
+	if err != nil {
+		return user, err
+	}
+
+	if a.cfg.Mailer != nil {
+		if err := a.SendVerification(ctx, user.ID); err != nil {
+			log.Warn().Err(err).Int64("user", user.ID).Msg("auth: failed to send verification email")
+		}
+	}
+	return user, nil
+}
+
+// Login returns the user with email if password is theirs.
+// A hash made with outdated parameters is replaced by a new one.
+func (a *Auth) Login(ctx context.Context, email, password string) (database.User, error) {
+	if len(password) > MaxPasswordLength {
+		return database.User{}, ErrInvalidCredentials
+	}
+
+	user, err := a.q.GetUserByEmail(ctx, NormalizeEmail(email))
+	if errors.Is(err, pgx.ErrNoRows) {
+		passhash.VerifyPassHash(password, dummyHash())
+		return database.User{}, ErrInvalidCredentials
+	}
+	if err != nil {
+		return database.User{}, err
+	}
+
+	if err := a.verify(ctx, user.ID, password); err != nil {
+		return database.User{}, err
+	}
+	return user, nil
+}
+
+// verify checks the password of a user, upgrading its hash if needed.
+func (a *Auth) verify(ctx context.Context, userID int64, password string) error {
+	cred, err := a.q.GetUserAuth(ctx, database.GetUserAuthParams{UserID: userID, ProviderID: PasswordProvider})
+	if errors.Is(err, pgx.ErrNoRows) {
+		passhash.VerifyPassHash(password, dummyHash())
+		return ErrInvalidCredentials
+	}
+	if err != nil {
+		return err
+	}
+
+	ok, upgrade := passhash.VerifyPassHash(password, cred.AssociatedData)
+	if !ok {
+		return ErrInvalidCredentials
+	}
+	if upgrade {
+		// a concurrent password change wins over the upgrade
+		_, err := a.q.UpdateUserAuthData(ctx, database.UpdateUserAuthDataParams{
+			AssociatedData:    passhash.NewPassHash(password),
+			UserID:            userID,
+			ProviderID:        PasswordProvider,
+			OldAssociatedData: cred.AssociatedData,
+		})
+		if err != nil {
+			log.Warn().Err(err).Int64("user", userID).Msg("auth: failed to upgrade password hash")
+		}
+	}
+	return nil
+}
+
+// ChangePassword replaces the password of a user after checking the current one,
+// and revokes the personal access tokens of the user and its other sessions than keepSession.
+// The API keys of workspaces are kept, they belong to the workspaces.
+func (a *Auth) ChangePassword(ctx context.Context, userID, keepSession int64, current, password string) error {
+	if err := CheckPassword(password); err != nil {
+		return err
+	}
+	cred, err := a.q.GetUserAuth(ctx, database.GetUserAuthParams{UserID: userID, ProviderID: PasswordProvider})
+	if errors.Is(err, pgx.ErrNoRows) {
+		return ErrInvalidCredentials
+	}
+	if err != nil {
+		return err
+	}
+	if ok, _ := passhash.VerifyPassHash(current, cred.AssociatedData); !ok {
+		return ErrInvalidCredentials
+	}
+
+	return pgx.BeginFunc(ctx, a.cfg.DB, func(tx pgx.Tx) error {
+		q := a.q.WithTx(tx)
+		n, err := q.UpdateUserAuthData(ctx, database.UpdateUserAuthDataParams{
+			AssociatedData:    passhash.NewPassHash(password),
+			UserID:            userID,
+			ProviderID:        PasswordProvider,
+			OldAssociatedData: cred.AssociatedData,
+		})
+		if err != nil {
+			return err
+		}
+		if n == 0 {
+			// changed since it was verified
+			return ErrInvalidCredentials
+		}
+		if err := q.RevokeUserSessions(ctx, database.RevokeUserSessionsParams{UserID: userID, ExceptID: keepSession}); err != nil {
+			return err
+		}
+		return q.RevokeUserAPITokens(ctx, userID)
+	})
+}
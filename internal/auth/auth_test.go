@@ -0,0 +1,310 @@
+package auth
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgtype"
+	"gosuda.org/jimin/database"
+	"gosuda.org/jimin/internal/oidc"
+	"gosuda.org/jimin/internal/passhash"
+)
+
+func TestCheckPassword(t *testing.T) {
+	tests := map[string]error{
+		"":                            ErrInvalidPassword,
+		"short":                       ErrInvalidPassword,
+		"long enough":                 nil,
+		"비밀번호비밀번호":                    nil,
+		strings.Repeat("a", 1024):     nil,
+		strings.Repeat("a", 1025):     ErrInvalidPassword,
+		strings.Repeat("비", 1024/3+1): ErrInvalidPassword,
+	}
+	for password, want := range tests {
+		if err := CheckPassword(password); err != want {
+			t.Errorf("CheckPassword(%d bytes) = %v, want %v", len(password), err, want)
+		}
+	}
+}
+
+func TestNormalizeEmail(t *testing.T) {
+	if got := NormalizeEmail(" Someone@Example.COM "); got != "someone@example.com" {
+		t.Errorf("NormalizeEmail = %q", got)
+	}
+}
+
+func TestToken(t *testing.T) {
+	a, b := newToken(), newToken()
+	if a == b {
+		t.Error("tokens repeat")
+	}
+	if !bytes.Equal(hashToken(a), hashToken(a)) || bytes.Equal(hashToken(a), hashToken(b)) {
+		t.Error("token hashes do not identify tokens")
+	}
+}
+
+func TestScopes(t *testing.T) {
+	if !HasScope([]string{ScopeRead, ScopeAsk}, ScopeAsk) || HasScope([]string{ScopeRead}, ScopeIngest) {
+		t.Error("scopes are not granted as listed")
+	}
+	for _, s := range Scopes {
+		if !HasScope([]string{ScopeAdmin}, s) {
+			t.Errorf("admin does not grant %s", s)
+		}
+	}
+
+	tests := []struct {
+		scopes []string
+		want   error
+	}{
+		{nil, ErrNoScopes},
+		{[]string{ScopeRead, ScopeIngest}, nil},
+		{[]string{ScopeRead, "write"}, ErrUnknownScope},
+		{[]string{"READ"}, ErrUnknownScope},
+	}
+	for _, tt := range tests {
+		if err := CheckScopes(tt.scopes); err != tt.want {
+			t.Errorf("CheckScopes(%q) = %v, want %v", tt.scopes, err, tt.want)
+		}
+	}
+}
+
+func TestAPITokenPrefix(t *testing.T) {
+	for _, s := range []string{PersonalTokenPrefix + newToken(), WorkspaceKeyPrefix + newToken()} {
+		if !IsAPIToken(s) {
+			t.Errorf("%q is not an API token", s)
+		}
+	}
+	// session tokens are never looked up as API tokens
+	a := New(Config{})
+	if _, err := a.APIToken(context.Background(), newToken()); err != ErrInvalidAPIToken {
+		t.Errorf("APIToken(session token) error = %v, want %v", err, ErrInvalidAPIToken)
+	}
+}
+
+func TestExpiry(t *testing.T) {
+	a := New(Config{SessionTTL: time.Hour, SessionMaxAge: time.Hour * 3})
+	created := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	if got := a.expiry(created, created); !got.Equal(created.Add(time.Hour)) {
+		t.Errorf("new session expiry = %v", got)
+	}
+	if got := a.expiry(created, created.Add(time.Hour/2)); !got.Equal(created.Add(time.Hour * 3 / 2)) {
+		t.Errorf("used session expiry = %v", got)
+	}
+	if got := a.expiry(created, created.Add(time.Hour*5/2)); !got.Equal(created.Add(time.Hour * 3)) {
+		t.Errorf("old session expiry = %v, want the max age", got)
+	}
+}
+
+func TestCookie(t *testing.T) {
+	s := database.Session{ExpiresAt: pgtype.Timestamptz{Time: time.Now().Add(time.Hour), Valid: true}}
+
+	c := New(Config{}).Cookie("token", s)
+	if c.Name != _DEFAULT_COOKIE_NAME || c.Value != "token" || !c.Secure || !c.HttpOnly || c.SameSite != http.SameSiteLaxMode {
+		t.Errorf("cookie = %+v", c)
+	}
+	if c.MaxAge <= 0 || c.MaxAge > 3600 {
+		t.Errorf("cookie max age = %d", c.MaxAge)
+	}
+	if c := New(Config{InsecureCookies: true}).ClearCookie(); c.Secure || c.MaxAge >= 0 {
+		t.Errorf("clear cookie = %+v", c)
+	}
+}
+
+func TestResolve(t *testing.T) {
+	verified := &oidc.Claims{Subject: "s", Email: "a@example.com", EmailVerified: true}
+	unverified := &oidc.Claims{Subject: "s", Email: "a@example.com"}
+	noEmail := &oidc.Claims{Subject: "s"}
+	owner := &database.User{ID: 3, EmailVerified: true}
+	unverifiedOwner := &database.User{ID: 3}
+
+	tests := []struct {
+		name               string
+		linkedID, linkUser int64
+		owner              *database.User
+		claims             *oidc.Claims
+		want               resolution
+		wantUser           int64
+		err                error
+	}{
+		{"linked", 1, 0, nil, unverified, _SIGN_IN, 1, nil},
+		{"linked to the linking user", 1, 1, nil, verified, _SIGN_IN, 1, nil},
+		{"linked to another user", 1, 2, nil, verified, 0, 0, ErrAlreadyLinked},
+		{"link", 0, 2, nil, noEmail, _LINK, 2, nil},
+		{"new", 0, 0, nil, unverified, _CREATE, 0, nil},
+		{"no email", 0, 0, nil, noEmail, 0, 0, ErrNoEmail},
+		{"verified email", 0, 0, owner, verified, _LINK, 3, nil},
+		{"unverified email", 0, 0, owner, unverified, 0, 0, ErrUnverifiedEmail},
+		{"unverified owner", 0, 0, unverifiedOwner, verified, _TAKE_OVER, 3, nil},
+		{"both unverified", 0, 0, unverifiedOwner, unverified, 0, 0, ErrUnverifiedEmail},
+	}
+	for _, tt := range tests {
+		got, user, err := resolve(tt.linkedID, tt.linkUser, tt.owner, tt.claims)
+		if got != tt.want || user != tt.wantUser || err != tt.err {
+			t.Errorf("%s: resolve = %d, %d, %v, want %d, %d, %v", tt.name, got, user, err, tt.want, tt.wantUser, tt.err)
+		}
+	}
+}
+
+func TestStateCookie(t *testing.T) {
+	a := New(Config{})
+	if c := a.StateCookie("state"); c.Name == a.CookieName() || c.Name != a.StateCookieName() || !c.HttpOnly || c.MaxAge <= 0 {
+		t.Errorf("state cookie = %+v", c)
+	}
+	if c := a.ClearStateCookie(); c.Name != a.StateCookieName() || c.MaxAge >= 0 {
+		t.Errorf("clear state cookie = %+v", c)
+	}
+}
+
+func TestEmailToken(t *testing.T) {
+	a := New(Config{TokenSecret: []byte("secret")})
+	verify, reset := database.EmailTokenPurposeVERIFYEMAIL, database.EmailTokenPurposeRESETPASSWORD
+	token := a.signToken(42, verify, time.Now().Add(time.Hour))
+
+	if id, err := a.parseToken(token, verify); id != 42 || err != nil {
+		t.Errorf("parseToken = %d, %v", id, err)
+	}
+
+	b, _ := base64.RawURLEncoding.DecodeString(token)
+	b[7] ^= 1
+	forged := base64.RawURLEncoding.EncodeToString(b)
+	for name, tt := range map[string]struct {
+		a       *Auth
+		token   string
+		purpose database.EmailTokenPurpose
+	}{
+		"other purpose": {a, token, reset},
+		"other secret":  {New(Config{TokenSecret: []byte("other")}), token, verify},
+		"forged id":     {a, forged, verify},
+		"expired":       {a, a.signToken(42, verify, time.Now().Add(-time.Second)), verify},
+		"truncated":     {a, token[:len(token)-2], verify},
+		"garbage":       {a, "not a token", verify},
+	} {
+		if _, err := tt.a.parseToken(tt.token, tt.purpose); err != ErrInvalidToken {
+			t.Errorf("%s: parseToken error = %v, want %v", name, err, ErrInvalidToken)
+		}
+	}
+
+	// a random secret without one configured
+	if bytes.Equal(New(Config{}).cfg.TokenSecret, New(Config{}).cfg.TokenSecret) {
+		t.Error("default token secrets repeat")
+	}
+}
+
+// passwordDB holds user 1 with the password "old password" and the email a@example.com,
+// and records the statements of the committed transactions.
+type passwordDB struct {
+	hash      string
+	executed  []string
+	committed []string
+}
+
+func (db *passwordDB) Exec(_ context.Context, sql string, args ...any) (pgconn.CommandTag, error) {
+	name := strings.Fields(sql)[2]
+	if name == "RevokeUserAPITokens" && args[0] != int64(1) {
+		return pgconn.CommandTag{}, errors.New("revoked the tokens of another user")
+	}
+	db.executed = append(db.executed, name)
+	return pgconn.NewCommandTag("UPDATE 1"), nil
+}
+
+func (db *passwordDB) Query(context.Context, string, ...any) (pgx.Rows, error) {
+	return nil, errors.ErrUnsupported
+}
+
+func (db *passwordDB) QueryRow(_ context.Context, sql string, _ ...any) pgx.Row {
+	switch strings.Fields(sql)[2] {
+	case "GetUserAuth":
+		return structRow{database.UsersAuth{ID: 2, UserID: 1, ProviderID: PasswordProvider, AssociatedData: db.hash}}
+	case "UseEmailToken":
+		return structRow{database.EmailToken{ID: 3, UserID: 1, Purpose: database.EmailTokenPurposeRESETPASSWORD, Email: "a@example.com"}}
+	case "GetUserByID":
+		return structRow{database.User{ID: 1, Email: "a@example.com"}}
+	}
+	return structRow{errors.ErrUnsupported}
+}
+
+func (db *passwordDB) Begin(context.Context) (pgx.Tx, error) {
+	db.executed = nil
+	return passwordTx{db}, nil
+}
+
+type passwordTx struct {
+	*passwordDB
+}
+
+func (tx passwordTx) Begin(context.Context) (pgx.Tx, error) { return nil, errors.ErrUnsupported }
+
+func (tx passwordTx) Commit(context.Context) error {
+	tx.committed = append(tx.committed, tx.executed...)
+	return nil
+}
+
+func (tx passwordTx) Rollback(context.Context) error { return nil }
+
+func (tx passwordTx) CopyFrom(context.Context, pgx.Identifier, []string, pgx.CopyFromSource) (int64, error) {
+	return 0, errors.ErrUnsupported
+}
+
+func (tx passwordTx) SendBatch(context.Context, *pgx.Batch) pgx.BatchResults { return nil }
+
+func (tx passwordTx) LargeObjects() pgx.LargeObjects { return pgx.LargeObjects{} }
+
+func (tx passwordTx) Prepare(context.Context, string, string) (*pgconn.StatementDescription, error) {
+	return nil, errors.ErrUnsupported
+}
+
+func (tx passwordTx) Conn() *pgx.Conn { return nil }
+
+// structRow scans the fields of a row struct, or fails with an error.
+type structRow struct {
+	v any
+}
+
+func (r structRow) Scan(dest ...any) error {
+	if err, ok := r.v.(error); ok {
+		return err
+	}
+	v := reflect.ValueOf(r.v)
+	for i := range dest {
+		reflect.ValueOf(dest[i]).Elem().Set(v.Field(i))
+	}
+	return nil
+}
+
+type fakeIDs struct{}
+
+func (fakeIDs) Generate(context.Context) (int64, error) { return 100, nil }
+
+func TestPasswordRevokesTokens(t *testing.T) {
+	ctx := context.Background()
+	db := &passwordDB{hash: passhash.NewPassHash("old password")}
+	a := New(Config{DB: db, IDs: fakeIDs{}})
+
+	if err := a.ChangePassword(ctx, 1, 5, "old password", "new password"); err != nil {
+		t.Fatalf("ChangePassword() error = %v", err)
+	}
+	if !slices.Contains(db.committed, "RevokeUserSessions") || !slices.Contains(db.committed, "RevokeUserAPITokens") {
+		t.Errorf("ChangePassword() executed %v, want the sessions and the personal access tokens revoked", db.committed)
+	}
+
+	db.committed = nil
+	token := a.signToken(3, database.EmailTokenPurposeRESETPASSWORD, time.Now().Add(time.Hour))
+	if err := a.ResetPassword(ctx, token, "new password"); err != nil {
+		t.Fatalf("ResetPassword() error = %v", err)
+	}
+	if !slices.Contains(db.committed, "RevokeUserSessions") || !slices.Contains(db.committed, "RevokeUserAPITokens") {
+		t.Errorf("ResetPassword() executed %v, want the sessions and the personal access tokens revoked", db.committed)
+	}
+}
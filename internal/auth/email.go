@@ -0,0 +1,265 @@
+package auth
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/rs/zerolog/log"
+	"gosuda.org/jimin/database"
+	"gosuda.org/jimin/internal/mail"
+	"gosuda.org/jimin/internal/passhash"
+)
+
+const (
+	_VERIFY_TOKEN_TTL = time.Hour * 24
+	_RESET_TOKEN_TTL  = time.Hour
+
+	// _RESEND_INTERVAL is the least time between two emails of a purpose to a user.
+	_RESEND_INTERVAL = time.Minute
+	// _MAX_EMAILS_PER_HOUR bounds the emails of a purpose sent to a user in an hour.
+	_MAX_EMAILS_PER_HOUR = 5
+)
+
+var (
+	ErrNoMailer        = errors.New("auth: email is not configured")
+	ErrInvalidToken    = errors.New("auth: invalid, expired or used token")
+	ErrAlreadyVerified = errors.New("auth: email is already verified")
+	ErrRateLimited     = errors.New("auth: too many emails, try again later")
+)
+
+// purposes are the byte a token is signed for each purpose with.
+var purposes = map[database.EmailTokenPurpose]byte{
+	database.EmailTokenPurposeVERIFYEMAIL:   1,
+	database.EmailTokenPurposeRESETPASSWORD: 2,
+}
+
+// _TOKEN_PAYLOAD_BYTES is the size of the id, purpose and expiry a token signs.
+const _TOKEN_PAYLOAD_BYTES = 8 + 1 + 8
+
+// signToken returns the token of the email token id. It names the row that makes it single use,
+// and its signature rejects forged tokens before they reach the database.
+func (a *Auth) signToken(id int64, purpose database.EmailTokenPurpose, expires time.Time) string {
+	b := make([]byte, _TOKEN_PAYLOAD_BYTES, _TOKEN_PAYLOAD_BYTES+sha256.Size)
+	binary.BigEndian.PutUint64(b, uint64(id))
+	b[8] = purposes[purpose]
+	binary.BigEndian.PutUint64(b[9:], uint64(expires.Unix()))
+
+	mac := hmac.New(sha256.New, a.cfg.TokenSecret)
+	mac.Write(b)
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(b))
+}
+
+// parseToken returns the email token id of a token signed for purpose and not expired.
+func (a *Auth) parseToken(token string, purpose database.EmailTokenPurpose) (int64, error) {
+	b, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil || len(b) != _TOKEN_PAYLOAD_BYTES+sha256.Size {
+		return 0, ErrInvalidToken
+	}
+	mac := hmac.New(sha256.New, a.cfg.TokenSecret)
+	mac.Write(b[:_TOKEN_PAYLOAD_BYTES])
+	if !hmac.Equal(mac.Sum(nil), b[_TOKEN_PAYLOAD_BYTES:]) || b[8] != purposes[purpose] {
+		return 0, ErrInvalidToken
+	}
+	if expires := time.Unix(int64(binary.BigEndian.Uint64(b[9:])), 0); !time.Now().Before(expires) {
+		return 0, ErrInvalidToken
+	}
+	return int64(binary.BigEndian.Uint64(b)), nil
+}
+
+// sendToken emails a token of purpose to user, linking to page with the token in its query.
+func (a *Auth) sendToken(ctx context.Context, user database.User, purpose database.EmailTokenPurpose, ttl time.Duration, page string, compose func(link string) mail.Message) error {
+	now := time.Now()
+	recent, err := a.q.CountRecentEmailTokens(ctx, database.CountRecentEmailTokensParams{
+		UserID:    user.ID,
+		Purpose:   purpose,
+		CreatedAt: timestamptz(now.Add(-time.Hour)),
+	})
+	if err != nil {
+		return err
+	}
+	if recent.Sent >= _MAX_EMAILS_PER_HOUR || now.Sub(recent.LastSentAt.Time) < _RESEND_INTERVAL {
+		return ErrRateLimited
+	}
+
+	link, err := url.Parse(page)
+	if err != nil {
+		return err
+	}
+	id, err := a.cfg.IDs.Generate(ctx)
+	if err != nil {
+		return err
+	}
+	t, err := a.q.CreateEmailToken(ctx, database.CreateEmailTokenParams{
+		ID:        id,
+		UserID:    user.ID,
+		Purpose:   purpose,
+		Email:     user.Email,
+		ExpiresAt: timestamptz(now.Add(ttl)),
+	})
+	if err != nil {
+		return err
+	}
+
+	q := link.Query()
+	q.Set("token", a.signToken(t.ID, purpose, t.ExpiresAt.Time))
+	link.RawQuery = q.Encode()
+	m := compose(link.String())
+	m.To = user.Email
+	return a.cfg.Mailer.Send(ctx, m)
+}
+
+// greeting returns the first line of an email to user.
+func greeting(user database.User) string {
+	if user.Name == "" {
+		return "Hello,"
+	}
+	return "Hello " + user.Name + ","
+}
+
+// SendVerification emails a link verifying the email of a user.
+func (a *Auth) SendVerification(ctx context.Context, userID int64) error {
+	if a.cfg.Mailer == nil {
+		return ErrNoMailer
+	}
+	user, err := a.q.GetUserByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if user.EmailVerified {
+		return ErrAlreadyVerified
+	}
+
+	return a.sendToken(ctx, user, database.EmailTokenPurposeVERIFYEMAIL, _VERIFY_TOKEN_TTL, a.cfg.VerifyEmailURL, func(link string) mail.Message {
+		return mail.Message{
+			Subject: "Verify your email",
+			Text: fmt.Sprintf("%s\n\nOpen the link below to verify your email address. It expires in %d hours.\n\n%s\n\nIf you did not sign up, ignore this email.\n",
+				greeting(user), int(_VERIFY_TOKEN_TTL/time.Hour), link),
+		}
+	})
+}
+
+// VerifyEmail marks the email of a verification token verified, if it is still the email of its user.
+func (a *Auth) VerifyEmail(ctx context.Context, token string) error {
+	id, err := a.parseToken(token, database.EmailTokenPurposeVERIFYEMAIL)
+	if err != nil {
+		return err
+	}
+
+	return pgx.BeginFunc(ctx, a.cfg.DB, func(tx pgx.Tx) error {
+		q := a.q.WithTx(tx)
+		t, err := q.UseEmailToken(ctx, database.UseEmailTokenParams{ID: id, Purpose: database.EmailTokenPurposeVERIFYEMAIL})
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ErrInvalidToken
+		}
+		if err != nil {
+			return err
+		}
+		user, err := q.GetUserByID(ctx, t.UserID)
+		if err != nil {
+			return err
+		}
+		if user.Email != t.Email {
+			// sent to an earlier email of the user
+			return ErrInvalidToken
+		}
+
+		if err := q.MarkEmailVerified(ctx, database.MarkEmailVerifiedParams{ID: user.ID, Email: user.Email}); err != nil {
+			return err
+		}
+		return q.ExpireEmailTokens(ctx, database.ExpireEmailTokensParams{UserID: user.ID, Purpose: database.EmailTokenPurposeVERIFYEMAIL})
+	})
+}
+
+// RequestPasswordReset emails a password reset link to the user with email.
+// Unknown emails and rate limited requests are not reported, so that the response does not tell who has an account.
+func (a *Auth) RequestPasswordReset(ctx context.Context, email string) error {
+	if a.cfg.Mailer == nil {
+		return ErrNoMailer
+	}
+	user, err := a.q.GetUserByEmail(ctx, NormalizeEmail(email))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	err = a.sendToken(ctx, user, database.EmailTokenPurposeRESETPASSWORD, _RESET_TOKEN_TTL, a.cfg.ResetPasswordURL, func(link string) mail.Message {
+		return mail.Message{
+			Subject: "Reset your password",
+			Text: fmt.Sprintf("%s\n\nOpen the link below to choose a new password. It expires in %d minutes and signs you out everywhere.\n\n%s\n\nIf you did not ask for it, ignore this email. Your password is unchanged.\n",
+				greeting(user), int(_RESET_TOKEN_TTL/time.Minute), link),
+		}
+	})
+	if errors.Is(err, ErrRateLimited) {
+		log.Warn().Int64("user", user.ID).Msg("auth: password reset emails rate limited")
+		return nil
+	}
+	return err
+}
+
+// ResetPassword sets the password of the user of a reset token, signs the user out of every session
+// and revokes its personal access tokens, like ChangePassword.
+// Receiving the token proves the email, which is marked verified.
+func (a *Auth) ResetPassword(ctx context.Context, token, password string) error {
+	if err := CheckPassword(password); err != nil {
+		return err
+	}
+	id, err := a.parseToken(token, database.EmailTokenPurposeRESETPASSWORD)
+	if err != nil {
+		return err
+	}
+	authID, err := a.cfg.IDs.Generate(ctx)
+	if err != nil {
+		return err
+	}
+	hash := passhash.NewPassHash(password)
+
+	return pgx.BeginFunc(ctx, a.cfg.DB, func(tx pgx.Tx) error {
+		q := a.q.WithTx(tx)
+		t, err := q.UseEmailToken(ctx, database.UseEmailTokenParams{ID: id, Purpose: database.EmailTokenPurposeRESETPASSWORD})
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ErrInvalidToken
+		}
+		if err != nil {
+			return err
+		}
+		user, err := q.GetUserByID(ctx, t.UserID)
+		if err != nil {
+			return err
+		}
+		if user.Email != t.Email {
+			return ErrInvalidToken
+		}
+
+		err = q.UpsertUserAuth(ctx, database.UpsertUserAuthParams{
+			ID:              authID,
+			UserID:          user.ID,
+			ProviderID:      PasswordProvider,
+			ProviderSubject: strconv.FormatInt(user.ID, 10),
+			AssociatedData:  hash,
+		})
+		if err != nil {
+			return err
+		}
+		if err := q.ExpireEmailTokens(ctx, database.ExpireEmailTokensParams{UserID: user.ID, Purpose: database.EmailTokenPurposeRESETPASSWORD}); err != nil {
+			return err
+		}
+		if err := q.RevokeUserSessions(ctx, database.RevokeUserSessionsParams{UserID: user.ID}); err != nil {
+			return err
+		}
+		if err := q.RevokeUserAPITokens(ctx, user.ID); err != nil {
+			return err
+		}
+		return q.MarkEmailVerified(ctx, database.MarkEmailVerifiedParams{ID: user.ID, Email: user.Email})
+	})
+}
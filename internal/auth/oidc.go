@@ -0,0 +1,352 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"gosuda.org/jimin/database"
+	"gosuda.org/jimin/internal/oidc"
+)
+
+// PasswordProviderName names the password credentials among the identity providers.
+const PasswordProviderName = "password"
+
+// _OIDC_LOGIN_TTL is the time a user has to sign in with the provider.
+const _OIDC_LOGIN_TTL = time.Minute * 10
+
+var (
+	ErrUnknownProvider = errors.New("auth: unknown identity provider")
+	ErrLoginExpired    = errors.New("auth: login expired or already completed")
+	ErrAlreadyLinked   = errors.New("auth: identity is linked to another user")
+	ErrUnverifiedEmail = errors.New("auth: email of the identity is not verified by the provider")
+	ErrNoEmail         = errors.New("auth: identity has no email")
+	ErrNotLinked       = errors.New("auth: identity is not linked")
+	ErrLastCredential  = errors.New("auth: cannot remove the last sign in method")
+)
+
+// Provider is an OpenID Connect provider users sign in with.
+type Provider struct {
+	// ID is the users_auth provider id of the provider, greater than PasswordProvider.
+	// It must not change once users signed in.
+	ID int64
+	// Name identifies the provider in URLs.
+	Name string
+	OIDC *oidc.Provider
+}
+
+// identityData is the associated data of an OpenID Connect credential.
+type identityData struct {
+	Email string `json:"email,omitempty"`
+	Name  string `json:"name,omitempty"`
+}
+
+// Identity is a sign in method of a user.
+type Identity struct {
+	Provider string
+	// Linked reports whether the user signs in with the provider.
+	Linked   bool
+	Email    string
+	LinkedAt pgtype.Timestamptz
+}
+
+// OIDCLogin is a completed sign in with a provider.
+type OIDCLogin struct {
+	User database.User
+	// Linked reports whether the login linked the identity to the signed in user instead of signing in.
+	Linked   bool
+	ReturnTo string
+}
+
+func (a *Auth) provider(name string) (Provider, bool) {
+	for _, p := range a.cfg.Providers {
+		if p.Name == name {
+			return p, true
+		}
+	}
+	return Provider{}, false
+}
+
+// StartOIDC begins a login with a provider, or the link of its identity to linkUserID if not zero.
+// It returns the URL the user is redirected to and the state the callback must carry,
+// which the browser keeps in the StateCookie.
+func (a *Auth) StartOIDC(ctx context.Context, provider string, linkUserID int64, returnTo string) (authURL, state string, err error) {
+	p, ok := a.provider(provider)
+	if !ok {
+		return "", "", ErrUnknownProvider
+	}
+	f := oidc.NewFlow()
+	authURL, err = p.OIDC.AuthURL(ctx, f)
+	if err != nil {
+		return "", "", err
+	}
+	id, err := a.cfg.IDs.Generate(ctx)
+	if err != nil {
+		return "", "", err
+	}
+	err = a.q.CreateOIDCLogin(ctx, database.CreateOIDCLoginParams{
+		ID:           id,
+		StateHash:    hashToken(f.State),
+		ProviderID:   p.ID,
+		Nonce:        f.Nonce,
+		CodeVerifier: f.Verifier,
+		LinkUserID:   linkUserID,
+		ReturnTo:     returnTo,
+		ExpiresAt:    timestamptz(time.Now().Add(_OIDC_LOGIN_TTL)),
+	})
+	if err != nil {
+		return "", "", err
+	}
+	return authURL, f.State, nil
+}
+
+// FinishOIDC completes the login of state with the authorization code of the provider.
+// A known identity signs in as its user. A new one is linked to the user of the link flow,
+// to the user with its email if the provider verified it, or else signs up a new user.
+func (a *Auth) FinishOIDC(ctx context.Context, provider, state, code string) (OIDCLogin, error) {
+	p, ok := a.provider(provider)
+	if !ok {
+		return OIDCLogin{}, ErrUnknownProvider
+	}
+	login, err := a.q.TakeOIDCLogin(ctx, hashToken(state))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return OIDCLogin{}, ErrLoginExpired
+	}
+	if err != nil {
+		return OIDCLogin{}, err
+	}
+	if login.ProviderID != p.ID || !time.Now().Before(login.ExpiresAt.Time) {
+		return OIDCLogin{}, ErrLoginExpired
+	}
+
+	claims, err := p.OIDC.Exchange(ctx, code, oidc.Flow{State: state, Nonce: login.Nonce, Verifier: login.CodeVerifier})
+	if err != nil {
+		return OIDCLogin{}, err
+	}
+	data, err := json.Marshal(identityData{Email: NormalizeEmail(claims.Email), Name: claims.Name})
+	if err != nil {
+		return OIDCLogin{}, err
+	}
+	userID, err := a.cfg.IDs.Generate(ctx)
+	if err != nil {
+		return OIDCLogin{}, err
+	}
+	authID, err := a.cfg.IDs.Generate(ctx)
+	if err != nil {
+		return OIDCLogin{}, err
+	}
+
+	result := OIDCLogin{Linked: login.LinkUserID != 0, ReturnTo: login.ReturnTo}
+	err = pgx.BeginFunc(ctx, a.cfg.DB, func(tx pgx.Tx) error {
+		q := a.q.WithTx(tx)
+
+		var linkedID int64
+		cred, err := q.GetUserAuthBySubject(ctx, database.GetUserAuthBySubjectParams{ProviderID: p.ID, ProviderSubject: claims.Subject})
+		switch {
+		case err == nil:
+			linkedID = cred.UserID
+		case !errors.Is(err, pgx.ErrNoRows):
+			return err
+		}
+		var owner *database.User
+		if linkedID == 0 && login.LinkUserID == 0 && claims.Email != "" {
+			u, err := q.GetUserByEmail(ctx, NormalizeEmail(claims.Email))
+			switch {
+			case err == nil:
+				owner = &u
+			case !errors.Is(err, pgx.ErrNoRows):
+				return err
+			}
+		}
+
+		action, targetID, err := resolve(linkedID, login.LinkUserID, owner, claims)
+		if err != nil {
+			return err
+		}
+		switch action {
+		case _CREATE:
+			result.User, err = q.CreateUser(ctx, database.CreateUserParams{
+				ID:            userID,
+				Name:          strings.TrimSpace(claims.Name),
+				Email:         NormalizeEmail(claims.Email),
+				EmailVerified: bool(claims.EmailVerified),
+			})
+			if err != nil {
+				return err
+			}
+			targetID = result.User.ID
+		case _TAKE_OVER:
+			// whoever set the credentials up never proved to own the email
+			if err := q.DeleteUserAuths(ctx, targetID); err != nil {
+				return err
+			}
+			if err := q.RevokeUserSessions(ctx, database.RevokeUserSessionsParams{UserID: targetID}); err != nil {
+				return err
+			}
+			if err := q.RevokeUserAPITokens(ctx, targetID); err != nil {
+				return err
+			}
+			if err := q.MarkEmailVerified(ctx, database.MarkEmailVerifiedParams{ID: targetID, Email: owner.Email}); err != nil {
+				return err
+			}
+		case _LINK:
+			_, err := q.GetUserAuth(ctx, database.GetUserAuthParams{UserID: targetID, ProviderID: p.ID})
+			if err == nil {
+				// the user has another identity of the provider
+				return ErrAlreadyLinked
+			}
+			if !errors.Is(err, pgx.ErrNoRows) {
+				return err
+			}
+		}
+
+		if action != _SIGN_IN {
+			_, err := q.CreateUserAuth(ctx, database.CreateUserAuthParams{
+				ID:              authID,
+				UserID:          targetID,
+				ProviderID:      p.ID,
+				ProviderSubject: claims.Subject,
+				AssociatedData:  string(data),
+			})
+			if err != nil {
+				return err
+			}
+		}
+		if action != _CREATE {
+			result.User, err = q.GetUserByID(ctx, targetID)
+		}
+		return err
+	})
+	return result, err
+}
+
+type resolution int
+
+const (
+	// _SIGN_IN signs in as the user the identity is linked to.
+	_SIGN_IN resolution = iota
+	// _LINK links the identity to a user.
+	_LINK
+	// _TAKE_OVER links the identity to the user with its email, which the user never verified,
+	// and removes the other credentials, sessions and personal access tokens of the user.
+	_TAKE_OVER
+	// _CREATE signs up a new user with the identity.
+	_CREATE
+)
+
+// resolve decides the user an identity signs in as, given the user it is linked to, the user linking it
+// and the user with its email, each zero or nil if none.
+func resolve(linkedID, linkUserID int64, owner *database.User, c *oidc.Claims) (resolution, int64, error) {
+	switch {
+	case linkedID != 0:
+		if linkUserID != 0 && linkedID != linkUserID {
+			return 0, 0, ErrAlreadyLinked
+		}
+		return _SIGN_IN, linkedID, nil
+	case linkUserID != 0:
+		return _LINK, linkUserID, nil
+	case c.Email == "":
+		return 0, 0, ErrNoEmail
+	case owner == nil:
+		return _CREATE, 0, nil
+	case !bool(c.EmailVerified):
+		return 0, 0, ErrUnverifiedEmail
+	case !owner.EmailVerified:
+		return _TAKE_OVER, owner.ID, nil
+	}
+	return _LINK, owner.ID, nil
+}
+
+// Identities returns the sign in methods of a user: the password and every provider, linked or not.
+func (a *Auth) Identities(ctx context.Context, userID int64) ([]Identity, error) {
+	creds, err := a.q.ListUserAuths(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	byProvider := make(map[int64]database.UsersAuth, len(creds))
+	for _, c := range creds {
+		byProvider[c.ProviderID] = c
+	}
+
+	ids := make([]Identity, 0, len(a.cfg.Providers)+1)
+	add := func(name string, providerID int64) {
+		id := Identity{Provider: name}
+		if c, ok := byProvider[providerID]; ok {
+			id.Linked, id.LinkedAt = true, c.CreatedAt
+			if providerID != PasswordProvider {
+				var data identityData
+				json.Unmarshal([]byte(c.AssociatedData), &data)
+				id.Email = data.Email
+			}
+		}
+		ids = append(ids, id)
+	}
+	add(PasswordProviderName, PasswordProvider)
+	for _, p := range a.cfg.Providers {
+		add(p.Name, p.ID)
+	}
+	return ids, nil
+}
+
+// Unlink removes a sign in method of a user, unless it is the last one.
+func (a *Auth) Unlink(ctx context.Context, userID int64, provider string) error {
+	providerID := PasswordProvider
+	if provider != PasswordProviderName {
+		p, ok := a.provider(provider)
+		if !ok {
+			return ErrUnknownProvider
+		}
+		providerID = p.ID
+	}
+
+	return pgx.BeginFunc(ctx, a.cfg.DB, func(tx pgx.Tx) error {
+		q := a.q.WithTx(tx)
+		// locked against a concurrent unlink of the other method
+		providers, err := q.LockUserAuths(ctx, userID)
+		if err != nil {
+			return err
+		}
+		n, err := q.DeleteUserAuth(ctx, database.DeleteUserAuthParams{UserID: userID, ProviderID: providerID})
+		if err != nil {
+			return err
+		}
+		switch {
+		case n == 0:
+			return ErrNotLinked
+		case len(providers) <= 1:
+			return ErrLastCredential
+		}
+		return nil
+	})
+}
+
+// StateCookie returns the cookie binding the browser to the login of state.
+func (a *Auth) StateCookie(state string) *http.Cookie {
+	return &http.Cookie{
+		Name:     a.StateCookieName(),
+		Value:    state,
+		Path:     "/",
+		MaxAge:   int(_OIDC_LOGIN_TTL / time.Second),
+		Secure:   !a.cfg.InsecureCookies,
+		HttpOnly: true,
+		// sent on the top level redirect back from the provider
+		SameSite: http.SameSiteLaxMode,
+	}
+}
+
+// ClearStateCookie returns the cookie removing the state cookie.
+func (a *Auth) ClearStateCookie() *http.Cookie {
+	c := a.StateCookie("")
+	c.MaxAge = -1
+	return c
+}
+
+// StateCookieName returns the name of the state cookie.
+func (a *Auth) StateCookieName() string {
+	return a.cfg.CookieName + "_oidc"
+}
@@ -0,0 +1,177 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/rs/zerolog/log"
+	"gosuda.org/jimin/database"
+)
+
+const (
+	_TOKEN_BYTES = 32
+	// _TOUCH_INTERVAL limits the writes extending a session in use.
+	_TOUCH_INTERVAL = time.Minute
+)
+
+// newToken returns a random session token.
+func newToken() string {
+	var b [_TOKEN_BYTES]byte
+	rand.Read(b[:])
+	return base64.RawURLEncoding.EncodeToString(b[:])
+}
+
+// hashToken returns the hash the session of token is stored with, so that a leak of the table does not leak sessions.
+func hashToken(token string) []byte {
+	h := sha256.Sum256([]byte(token))
+	return h[:]
+}
+
+// expiry returns the expiry of a session created at created and used at now.
+func (a *Auth) expiry(created, now time.Time) time.Time {
+	exp := now.Add(a.cfg.SessionTTL)
+	if max := created.Add(a.cfg.SessionMaxAge); exp.After(max) {
+		exp = max
+	}
+	return exp
+}
+
+// CreateSession signs a user in and returns the token of the new session.
+func (a *Auth) CreateSession(ctx context.Context, userID int64, userAgent, ip string) (string, database.Session, error) {
+	id, err := a.cfg.IDs.Generate(ctx)
+	if err != nil {
+		return "", database.Session{}, err
+	}
+	token := newToken()
+	now := time.Now()
+	s, err := a.q.CreateSession(ctx, database.CreateSessionParams{
+		ID:        id,
+		UserID:    userID,
+		TokenHash: hashToken(token),
+		UserAgent: userAgent,
+		IpAddress: ip,
+		ExpiresAt: timestamptz(a.expiry(now, now)),
+	})
+	return token, s, err
+}
+
+// Session returns the valid session of token and extends its expiry.
+// touched reports whether the expiry changed, for the cookie to be sent again.
+func (a *Auth) Session(ctx context.Context, token string) (s database.Session, touched bool, err error) {
+	if token == "" {
+		return s, false, ErrInvalidSession
+	}
+	s, err = a.q.GetSessionByTokenHash(ctx, hashToken(token))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return s, false, ErrInvalidSession
+	}
+	if err != nil {
+		return s, false, err
+	}
+
+	now := time.Now()
+	if s.RevokedAt.Valid || !now.Before(s.ExpiresAt.Time) {
+		return s, false, ErrInvalidSession
+	}
+
+	exp := a.expiry(s.CreatedAt.Time, now)
+	if exp.Sub(s.ExpiresAt.Time) < _TOUCH_INTERVAL {
+		return s, false, nil
+	}
+	if err := a.q.TouchSession(ctx, database.TouchSessionParams{ID: s.ID, ExpiresAt: timestamptz(exp)}); err != nil {
+		return s, false, err
+	}
+	s.ExpiresAt, s.LastSeenAt = timestamptz(exp), timestamptz(now)
+	return s, true, nil
+}
+
+// Sessions returns the valid sessions of a user, the newest first.
+func (a *Auth) Sessions(ctx context.Context, userID int64) ([]database.Session, error) {
+	return a.q.ListUserSessions(ctx, userID)
+}
+
+// Revoke signs a session of a user out.
+func (a *Auth) Revoke(ctx context.Context, userID, sessionID int64) error {
+	n, err := a.q.RevokeSession(ctx, database.RevokeSessionParams{ID: sessionID, UserID: userID})
+	if err == nil && n == 0 {
+		err = ErrInvalidSession
+	}
+	return err
+}
+
+// Cookie returns the cookie holding the token of a session.
+func (a *Auth) Cookie(token string, s database.Session) *http.Cookie {
+	return &http.Cookie{
+		Name:     a.cfg.CookieName,
+		Value:    token,
+		Path:     "/",
+		Expires:  s.ExpiresAt.Time,
+		MaxAge:   int(time.Until(s.ExpiresAt.Time) / time.Second),
+		Secure:   !a.cfg.InsecureCookies,
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	}
+}
+
+// ClearCookie returns the cookie removing the session cookie.
+func (a *Auth) ClearCookie() *http.Cookie {
+	return &http.Cookie{
+		Name:     a.cfg.CookieName,
+		Path:     "/",
+		MaxAge:   -1,
+		Secure:   !a.cfg.InsecureCookies,
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	}
+}
+
+// CookieName returns the name of the session cookie.
+func (a *Auth) CookieName() string {
+	return a.cfg.CookieName
+}
+
+// Run deletes the expired and revoked sessions, email tokens, API tokens and abandoned logins until ctx is canceled.
+func (a *Auth) Run(ctx context.Context) error {
+	ticker := time.NewTicker(a.cfg.CleanupInterval)
+	defer ticker.Stop()
+
+	for {
+		// ended sessions are kept a day, for the audit of recent sign ins
+		n, err := a.q.DeleteExpiredSessions(ctx, timestamptz(time.Now().Add(-time.Hour*24)))
+		if err != nil && ctx.Err() == nil {
+			log.Error().Err(err).Msg("auth: failed to delete expired sessions")
+		} else if n > 0 {
+			log.Info().Int64("sessions", n).Msg("auth: deleted expired sessions")
+		}
+		n, err = a.q.DeleteExpiredAPITokens(ctx, timestamptz(time.Now().Add(-time.Hour*24)))
+		if err != nil && ctx.Err() == nil {
+			log.Error().Err(err).Msg("auth: failed to delete expired API tokens")
+		} else if n > 0 {
+			log.Info().Int64("tokens", n).Msg("auth: deleted expired API tokens")
+		}
+		n, err = a.q.DeleteExpiredEmailTokens(ctx, timestamptz(time.Now()))
+		if err != nil && ctx.Err() == nil {
+			log.Error().Err(err).Msg("auth: failed to delete expired email tokens")
+		} else if n > 0 {
+			log.Info().Int64("tokens", n).Msg("auth: deleted expired email tokens")
+		}
+		n, err = a.q.DeleteExpiredOIDCLogins(ctx, timestamptz(time.Now()))
+		if err != nil && ctx.Err() == nil {
+			log.Error().Err(err).Msg("auth: failed to delete expired logins")
+		} else if n > 0 {
+			log.Info().Int64("logins", n).Msg("auth: deleted expired logins")
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
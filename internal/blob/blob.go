@@ -0,0 +1,173 @@
+package blob
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+)
+
+var (
+	ErrNotFound   = errors.New("blob: not found")
+	ErrTooLarge   = errors.New("blob: too large")
+	ErrInvalidKey = errors.New("blob: invalid key")
+)
+
+type Blob struct {
+	Key         string `json:"key"`
+	Size        int64  `json:"size"`
+	ContentType string `json:"content_type"`
+}
+
+// Store is a content-addressed blob store.
+// Blobs are keyed by the hex encoded SHA-256 of their content, so storing the same content twice is a no-op.
+type Store interface {
+	Put(ctx context.Context, r io.Reader) (Blob, error)
+	Open(ctx context.Context, key string) (io.ReadCloser, error)
+	Stat(ctx context.Context, key string) (Blob, error)
+	Delete(ctx context.Context, key string) error
+}
+
+// FSStore stores blobs in a local directory.
+type FSStore struct {
+	root    string
+	maxSize int64
+}
+
+// NewFSStore returns a store rooted at dir.
+// Blobs larger than maxSize are rejected, maxSize <= 0 disables the limit.
+func NewFSStore(dir string, maxSize int64) (*FSStore, error) {
+	if err := os.MkdirAll(filepath.Join(dir, "tmp"), 0o755); err != nil {
+		return nil, err
+	}
+	return &FSStore{root: dir, maxSize: maxSize}, nil
+}
+
+func validKey(key string) bool {
+	if len(key) != sha256.Size*2 {
+		return false
+	}
+	_, err := hex.DecodeString(key)
+	return err == nil
+}
+
+func (s *FSStore) path(key string) string {
+	return filepath.Join(s.root, key[:2], key[2:4], key)
+}
+
+func (s *FSStore) Put(ctx context.Context, r io.Reader) (Blob, error) {
+	tmp, err := os.CreateTemp(filepath.Join(s.root, "tmp"), "put-*")
+	if err != nil {
+		return Blob{}, err
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	if s.maxSize > 0 {
+		r = io.LimitReader(r, s.maxSize+1)
+	}
+
+	var head [512]byte
+	n, err := io.ReadFull(r, head[:])
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return Blob{}, err
+	}
+
+	h := sha256.New()
+	w := io.MultiWriter(tmp, h)
+	if _, err := w.Write(head[:n]); err != nil {
+		return Blob{}, err
+	}
+	size, err := io.Copy(w, r)
+	if err != nil {
+		return Blob{}, err
+	}
+	size += int64(n)
+
+	if s.maxSize > 0 && size > s.maxSize {
+		return Blob{}, ErrTooLarge
+	}
+
+	if err := ctx.Err(); err != nil {
+		return Blob{}, err
+	}
+
+	b := Blob{
+		Key:         hex.EncodeToString(h.Sum(nil)),
+		Size:        size,
+		ContentType: http.DetectContentType(head[:n]),
+	}
+
+	p := s.path(b.Key)
+	if _, err := os.Stat(p); err == nil {
+		return b, nil
+	}
+
+	if err := tmp.Close(); err != nil {
+		return Blob{}, err
+	}
+	if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+		return Blob{}, err
+	}
+	if err := os.Rename(tmp.Name(), p); err != nil {
+		return Blob{}, err
+	}
+
+	return b, nil
+}
+
+func (s *FSStore) Open(ctx context.Context, key string) (io.ReadCloser, error) {
+	if !validKey(key) {
+		return nil, ErrInvalidKey
+	}
+
+	f, err := os.Open(s.path(key))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	return f, nil
+}
+
+func (s *FSStore) Stat(ctx context.Context, key string) (Blob, error) {
+	f, err := s.Open(ctx, key)
+	if err != nil {
+		return Blob{}, err
+	}
+	defer f.Close()
+
+	var head [512]byte
+	n, err := io.ReadFull(f, head[:])
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return Blob{}, err
+	}
+
+	fi, err := f.(*os.File).Stat()
+	if err != nil {
+		return Blob{}, err
+	}
+
+	return Blob{
+		Key:         key,
+		Size:        fi.Size(),
+		ContentType: http.DetectContentType(head[:n]),
+	}, nil
+}
+
+func (s *FSStore) Delete(ctx context.Context, key string) error {
+	if !validKey(key) {
+		return ErrInvalidKey
+	}
+
+	err := os.Remove(s.path(key))
+	if errors.Is(err, os.ErrNotExist) {
+		return ErrNotFound
+	}
+	return err
+}
@@ -0,0 +1,95 @@
+package blob
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestFSStore(t *testing.T) {
+	ctx := context.Background()
+	s, err := NewFSStore(t.TempDir(), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	content := "<html><body>hello</body></html>"
+	b, err := s.Put(ctx, strings.NewReader(content))
+	if err != nil {
+		t.Fatalf("Put() error = %v", err)
+	}
+	// the hex encoded sha256 of the content
+	if b.Key != "85052df661cd7c51a9e04eff2a91ed8fc1aa833e95fa0dab4c7cec102cabcb31" {
+		t.Errorf("Put() key = %q", b.Key)
+	}
+	if b.Size != int64(len(content)) || b.ContentType != "text/html; charset=utf-8" {
+		t.Errorf("Put() = %+v", b)
+	}
+
+	again, err := s.Put(ctx, strings.NewReader(content))
+	if err != nil || again != b {
+		t.Errorf("Put() of the same content = %+v, %v, want %+v", again, err, b)
+	}
+
+	r, err := s.Open(ctx, b.Key)
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	got, err := io.ReadAll(r)
+	r.Close()
+	if err != nil || string(got) != content {
+		t.Errorf("Open() = %q, %v", got, err)
+	}
+
+	if st, err := s.Stat(ctx, b.Key); err != nil || st != b {
+		t.Errorf("Stat() = %+v, %v, want %+v", st, err, b)
+	}
+
+	if err := s.Delete(ctx, b.Key); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if _, err := s.Open(ctx, b.Key); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Open() after Delete() error = %v, want %v", err, ErrNotFound)
+	}
+	if err := s.Delete(ctx, b.Key); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Delete() twice error = %v, want %v", err, ErrNotFound)
+	}
+}
+
+func TestFSStoreErrors(t *testing.T) {
+	ctx := context.Background()
+	s, err := NewFSStore(t.TempDir(), 16)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		run  func() error
+		want error
+	}{
+		{"too large", func() error { _, err := s.Put(ctx, bytes.NewReader(make([]byte, 17))); return err }, ErrTooLarge},
+		{"at the limit", func() error { _, err := s.Put(ctx, bytes.NewReader(make([]byte, 16))); return err }, nil},
+		{"open short key", func() error { _, err := s.Open(ctx, "abcd"); return err }, ErrInvalidKey},
+		{"open path key", func() error { _, err := s.Open(ctx, "../../"+strings.Repeat("0", 58)); return err }, ErrInvalidKey},
+		{"open missing", func() error { _, err := s.Open(ctx, strings.Repeat("0", 64)); return err }, ErrNotFound},
+		{"stat missing", func() error { _, err := s.Stat(ctx, strings.Repeat("0", 64)); return err }, ErrNotFound},
+		{"delete invalid", func() error { return s.Delete(ctx, strings.Repeat("z", 64)) }, ErrInvalidKey},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.run(); !errors.Is(err, tt.want) {
+				t.Errorf("error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+
+	canceled, cancel := context.WithCancel(ctx)
+	cancel()
+	if _, err := s.Put(canceled, strings.NewReader("a")); !errors.Is(err, context.Canceled) {
+		t.Errorf("Put() with a canceled context error = %v, want %v", err, context.Canceled)
+	}
+}
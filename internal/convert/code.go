@@ -0,0 +1,154 @@
+package convert
+
+import (
+	"html"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+var languageAliases = map[string]string{
+	"golang":      "go",
+	"js":          "javascript",
+	"jsx":         "javascript",
+	"ts":          "typescript",
+	"tsx":         "typescript",
+	"py":          "python",
+	"python3":     "python",
+	"rb":          "ruby",
+	"rs":          "rust",
+	"sh":          "bash",
+	"shell":       "bash",
+	"zsh":         "bash",
+	"console":     "bash",
+	"yml":         "yaml",
+	"c++":         "cpp",
+	"cs":          "csharp",
+	"c#":          "csharp",
+	"kt":          "kotlin",
+	"md":          "markdown",
+	"plaintext":   "",
+	"plain":       "",
+	"text":        "",
+	"txt":         "",
+	"none":        "",
+	"nohighlight": "",
+}
+
+// knownLanguages are accepted as bare class names next to a highlighter class, e.g. "hljs go".
+var knownLanguages = map[string]bool{
+	"bash": true, "c": true, "cpp": true, "csharp": true, "css": true,
+	"dart": true, "diff": true, "dockerfile": true, "elixir": true, "go": true,
+	"graphql": true, "haskell": true, "html": true, "java": true,
+	"javascript": true, "json": true, "kotlin": true, "lua": true,
+	"makefile": true, "markdown": true, "nginx": true, "objectivec": true,
+	"perl": true, "php": true, "powershell": true, "protobuf": true,
+	"python": true, "r": true, "ruby": true, "rust": true, "scala": true,
+	"scss": true, "sql": true, "swift": true, "toml": true, "typescript": true,
+	"xml": true, "yaml": true, "zig": true,
+}
+
+var highlighterClasses = map[string]bool{
+	"hljs": true, "highlight": true, "chroma": true, "sourcecode": true,
+	"prettyprint": true, "codehilite": true, "syntax": true,
+}
+
+func normalizeLanguage(lang string) string {
+	lang = strings.ToLower(strings.Trim(lang, " ;:"))
+	if alias, ok := languageAliases[lang]; ok {
+		return alias
+	}
+	if !codeClassPattern.MatchString("language-" + lang) {
+		return ""
+	}
+	return lang
+}
+
+// classLanguage infers the code language from the class and data attributes
+// used by highlight.js, Prism, Chroma, Pygments, Rouge and GitHub.
+func classLanguage(s *goquery.Selection) (string, bool) {
+	for _, attr := range []string{"data-lang", "data-language"} {
+		if v, ok := s.Attr(attr); ok && v != "" {
+			return normalizeLanguage(v), true
+		}
+	}
+
+	class, _ := s.Attr("class")
+	fields := strings.Fields(class)
+	highlighted := false
+	for i, f := range fields {
+		lower := strings.ToLower(f)
+		for _, p := range []string{"language-", "lang-", "highlight-source-"} {
+			if v, ok := strings.CutPrefix(lower, p); ok && v != "" {
+				return normalizeLanguage(v), true
+			}
+		}
+		if lower == "brush:" && i+1 < len(fields) {
+			return normalizeLanguage(fields[i+1]), true
+		}
+		if v, ok := strings.CutPrefix(lower, "brush:"); ok && v != "" {
+			return normalizeLanguage(v), true
+		}
+		highlighted = highlighted || highlighterClasses[lower]
+	}
+
+	if highlighted {
+		for _, f := range fields {
+			if lang := normalizeLanguage(f); knownLanguages[lang] {
+				return lang, true
+			}
+		}
+	}
+	return "", false
+}
+
+// rewriteCode restores mermaid diagrams and normalizes code blocks
+// to <pre><code class="language-xxx"> so the language survives sanitizing.
+func rewriteCode(s *goquery.Selection) {
+	s.Find(".mermaid, pre.language-mermaid, code.language-mermaid").Each(func(i int, s *goquery.Selection) {
+		if s.Find("svg").Length() > 0 || s.ParentsFiltered(".mermaid").Length() > 0 {
+			return
+		}
+		src := strings.TrimSpace(s.Text())
+		if src == "" {
+			return
+		}
+		if goquery.NodeName(s) == "code" && goquery.NodeName(s.Parent()) == "pre" {
+			s = s.Parent()
+		}
+		s.ReplaceWithHtml(`<pre><code class="language-mermaid">` + html.EscapeString(src) + "</code></pre>")
+	})
+
+	// drop line number gutters
+	s.Find(".lineno, .linenos, .ln, .lnt, .line-numbers-rows, .gutter").Remove()
+
+	s.Find("pre").Each(func(i int, pre *goquery.Selection) {
+		code := pre.ChildrenFiltered("code").First()
+
+		var lang string
+		var found bool
+		for _, c := range []*goquery.Selection{code, pre, pre.Parent(), pre.Parent().Parent()} {
+			if c.Length() == 0 {
+				continue
+			}
+			if lang, found = classLanguage(c); found {
+				break
+			}
+		}
+
+		if code.Length() == 0 {
+			inner, err := pre.Html()
+			if err != nil {
+				return
+			}
+			pre.SetHtml("<code>" + inner + "</code>")
+			code = pre.ChildrenFiltered("code").First()
+		}
+
+		pre.RemoveAttr("class")
+		code.RemoveAttr("class")
+		if lang != "" {
+			code.SetAttr("class", "language-"+lang)
+		}
+	})
+}
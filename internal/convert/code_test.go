@@ -0,0 +1,36 @@
+package convert
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestConvertCodeLanguage(t *testing.T) {
+	tests := []struct {
+		name string
+		html string
+		want string
+	}{
+		{"highlight.js", `<pre class="hljs go"><span class="k">func</span> main() {
+
+
+}</pre>`, "```go\nfunc main() {\n\n\n}\n```"},
+		{"prism", `<pre><code class="language-js">let a</code></pre>`, "```javascript\nlet a\n```"},
+		{"github", `<div class="highlight highlight-source-python"><pre>print(1)</pre></div>`, "```python\nprint(1)\n```"},
+		{"rouge", `<div class="language-ruby highlighter-rouge"><div class="highlight"><pre class="highlight"><code>puts 1</code></pre></div></div>`, "```ruby\nputs 1\n```"},
+		{"syntaxhighlighter", `<pre class="brush: sql;">select 1</pre>`, "```sql\nselect 1\n```"},
+		{"mermaid", "<div class=\"mermaid\">graph TD\n  A--&gt;B</div>", "```mermaid\ngraph TD\n  A-->B\n```"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			md, err := ConvertHTMLToMarkdown("<html><body>"+tt.html+"</body></html>", "https://example.com/")
+			if err != nil {
+				t.Fatalf("ConvertHTMLToMarkdown() error = %v", err)
+			}
+			if !strings.Contains(md, tt.want) {
+				t.Errorf("ConvertHTMLToMarkdown() = %q, want %q", md, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,622 @@
+package convert
+
+import (
+	"strconv"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+type NodeKind string
+
+const (
+	NodeSection   NodeKind = "section"
+	NodeParagraph NodeKind = "paragraph"
+	NodeQuote     NodeKind = "quote"
+	NodeList      NodeKind = "list"
+	NodeListItem  NodeKind = "list_item"
+	NodeTable     NodeKind = "table"
+	NodeTableRow  NodeKind = "table_row"
+	NodeTableCell NodeKind = "table_cell"
+	NodeCodeBlock NodeKind = "code_block"
+	NodeImage     NodeKind = "image"
+	NodeLink      NodeKind = "link"
+	NodeText      NodeKind = "text"
+	NodeCode      NodeKind = "code"
+	NodeEmphasis  NodeKind = "emphasis"
+	NodeStrong    NodeKind = "strong"
+)
+
+// Node is an element of a converted document.
+// MarkdownStart and MarkdownEnd are the byte offsets of the node in Document.Markdown.
+// They are not offsets into the source HTML: the page is sanitized and serialized again
+// before it is converted, so positions in the source are not kept.
+type Node struct {
+	Kind     NodeKind `json:"kind"`
+	Level    int      `json:"level,omitempty"`
+	Title    string   `json:"title,omitempty"`
+	Text     string   `json:"text,omitempty"`
+	Language string   `json:"language,omitempty"`
+	URL      string   `json:"url,omitempty"`
+	Alt      string   `json:"alt,omitempty"`
+	Ordered  bool     `json:"ordered,omitempty"`
+	Header   bool     `json:"header,omitempty"`
+	Children []*Node  `json:"children,omitempty"`
+
+	MarkdownStart int `json:"markdown_start"`
+	MarkdownEnd   int `json:"markdown_end"`
+}
+
+// Walk calls fn for n and its descendants in document order.
+// The children of a node are skipped if fn returns false.
+func (n *Node) Walk(fn func(*Node) bool) {
+	if !fn(n) {
+		return
+	}
+	for _, c := range n.Children {
+		c.Walk(fn)
+	}
+}
+
+type Document struct {
+	URL      string  `json:"url"`
+	Title    string  `json:"title,omitempty"`
+	Markdown string  `json:"markdown"`
+	Children []*Node `json:"children"`
+	// Feeds are the feeds advertised by the page.
+	Feeds []Feed `json:"feeds,omitempty"`
+}
+
+func (d *Document) Walk(fn func(*Node) bool) {
+	for _, c := range d.Children {
+		c.Walk(fn)
+	}
+}
+
+// ConvertHTMLToDocument converts html to a document tree.
+// The markdown rendering of the tree is stored in Document.Markdown.
+func ConvertHTMLToDocument(html string, curl string, opts ...Option) (*Document, error) {
+	p, err := prepareHTML(html, curl, newOptions(opts))
+	if err != nil {
+		return nil, err
+	}
+
+	root := &Node{Kind: NodeSection}
+	b := docBuilder{sections: []*Node{root}}
+	for _, n := range p.doc.Find("body").Nodes {
+		b.blocks(n, nil)
+	}
+	// math may be anywhere text is, including code spans and blocks
+	root.Walk(func(n *Node) bool {
+		n.Title = p.verbatim.restore(n.Title)
+		n.Text = p.verbatim.restore(n.Text)
+		return true
+	})
+
+	d := &Document{
+		URL:      curl,
+		Title:    p.title,
+		Children: root.Children,
+		Feeds:    p.feeds,
+	}
+	d.Render()
+
+	return d, nil
+}
+
+// Render renders the tree to Document.Markdown and updates the node offsets.
+// It must be called after the tree is modified.
+func (d *Document) Render() {
+	w := mdWriter{}
+	w.blocks(d.Children, "\n\n")
+	d.Markdown = strings.TrimRight(w.sb.String(), "\n") + "\n"
+}
+
+var blockElements = map[string]bool{
+	"address": true, "article": true, "aside": true, "blockquote": true,
+	"body": true, "dd": true, "details": true, "div": true, "dl": true,
+	"dt": true, "fieldset": true, "figcaption": true, "figure": true,
+	"footer": true, "form": true, "h1": true, "h2": true, "h3": true,
+	"h4": true, "h5": true, "h6": true, "header": true, "hgroup": true,
+	"hr": true, "li": true, "main": true, "nav": true, "ol": true, "p": true,
+	"pre": true, "section": true, "summary": true, "table": true, "ul": true,
+}
+
+func isBlock(n *html.Node) bool {
+	return n.Type == html.ElementNode && blockElements[n.Data]
+}
+
+type docBuilder struct {
+	sections []*Node
+}
+
+func (b *docBuilder) section() *Node {
+	return b.sections[len(b.sections)-1]
+}
+
+func (b *docBuilder) openSection(level int, title string) {
+	for len(b.sections) > 1 && b.section().Level >= level {
+		b.sections = b.sections[:len(b.sections)-1]
+	}
+	s := &Node{Kind: NodeSection, Level: level, Title: title}
+	b.section().Children = append(b.section().Children, s)
+	b.sections = append(b.sections, s)
+}
+
+// blocks appends the block nodes of n to parent.
+// If parent is nil, the nodes are appended to the current section and headings open new sections.
+func (b *docBuilder) blocks(n *html.Node, parent *Node) {
+	var pending []*html.Node
+
+	add := func(node *Node) {
+		if parent == nil {
+			b.section().Children = append(b.section().Children, node)
+			return
+		}
+		parent.Children = append(parent.Children, node)
+	}
+
+	flush := func() {
+		if len(pending) == 0 {
+			return
+		}
+		children := inlines(pending)
+		pending = pending[:0]
+		if len(children) > 0 {
+			add(&Node{Kind: NodeParagraph, Children: children})
+		}
+	}
+
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if !isBlock(c) {
+			pending = append(pending, c)
+			continue
+		}
+		flush()
+
+		switch c.Data {
+		case "h1", "h2", "h3", "h4", "h5", "h6":
+			if parent == nil {
+				b.openSection(int(c.Data[1]-'0'), collapseSpace(textContent(c)))
+				continue
+			}
+			if children := inlines(childNodes(c)); len(children) > 0 {
+				add(&Node{Kind: NodeParagraph, Children: []*Node{{Kind: NodeStrong, Children: children}}})
+			}
+		case "p", "dt", "summary", "figcaption":
+			if children := inlines(childNodes(c)); len(children) > 0 {
+				add(&Node{Kind: NodeParagraph, Children: children})
+			}
+		case "pre":
+			add(codeBlock(c))
+		case "ul", "ol":
+			if list := b.list(c); len(list.Children) > 0 {
+				add(list)
+			}
+		case "table":
+			if table := tableNode(c); len(table.Children) > 0 {
+				add(table)
+			}
+		case "blockquote":
+			quote := &Node{Kind: NodeQuote}
+			b.blocks(c, quote)
+			if len(quote.Children) > 0 {
+				add(quote)
+			}
+		case "hr":
+		default:
+			b.blocks(c, parent)
+		}
+	}
+	flush()
+}
+
+func (b *docBuilder) list(n *html.Node) *Node {
+	list := &Node{Kind: NodeList, Ordered: n.Data == "ol"}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if c.Type != html.ElementNode || c.Data != "li" {
+			continue
+		}
+		item := &Node{Kind: NodeListItem}
+		b.blocks(c, item)
+		list.Children = append(list.Children, item)
+	}
+	return list
+}
+
+func tableNode(n *html.Node) *Node {
+	table := &Node{Kind: NodeTable}
+
+	var rows func(n *html.Node, header bool)
+	rows = func(n *html.Node, header bool) {
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			if c.Type != html.ElementNode {
+				continue
+			}
+			switch c.Data {
+			case "thead":
+				rows(c, true)
+			case "tbody", "tfoot":
+				rows(c, false)
+			case "tr":
+				row := &Node{Kind: NodeTableRow, Header: header}
+				allTH := true
+				for cell := c.FirstChild; cell != nil; cell = cell.NextSibling {
+					if cell.Type != html.ElementNode || (cell.Data != "td" && cell.Data != "th") {
+						continue
+					}
+					allTH = allTH && cell.Data == "th"
+					row.Children = append(row.Children, &Node{Kind: NodeTableCell, Children: inlines(childNodes(cell))})
+				}
+				if len(row.Children) == 0 {
+					continue
+				}
+				if len(table.Children) == 0 && allTH {
+					row.Header = true
+				}
+				table.Children = append(table.Children, row)
+			}
+		}
+	}
+	rows(n, false)
+
+	return table
+}
+
+func codeBlock(n *html.Node) *Node {
+	lang := codeLanguage(n)
+	for c := n.FirstChild; c != nil && lang == ""; c = c.NextSibling {
+		if c.Type == html.ElementNode && c.Data == "code" {
+			lang = codeLanguage(c)
+		}
+	}
+
+	return &Node{
+		Kind:     NodeCodeBlock,
+		Language: lang,
+		Text:     strings.TrimRight(strings.TrimPrefix(textContent(n), "\n"), "\n"),
+	}
+}
+
+func codeLanguage(n *html.Node) string {
+	for _, class := range strings.Fields(attr(n, "class")) {
+		for _, p := range []string{"language-", "lang-"} {
+			if strings.HasPrefix(class, p) && len(class) > len(p) {
+				return strings.ToLower(class[len(p):])
+			}
+		}
+	}
+	return ""
+}
+
+func inlines(nodes []*html.Node) []*Node {
+	var out []*Node
+
+	text := func(s string) {
+		if s == "" {
+			return
+		}
+		if len(out) > 0 && out[len(out)-1].Kind == NodeText {
+			last := out[len(out)-1]
+			if strings.HasSuffix(last.Text, " ") || strings.HasSuffix(last.Text, "\n") {
+				s = strings.TrimLeft(s, " ")
+			}
+			last.Text += s
+			return
+		}
+		out = append(out, &Node{Kind: NodeText, Text: s})
+	}
+
+	for _, n := range nodes {
+		switch n.Type {
+		case html.TextNode:
+			text(collapseSpace(n.Data))
+		case html.ElementNode:
+			switch n.Data {
+			case "br":
+				if len(out) > 0 && out[len(out)-1].Kind == NodeText {
+					out[len(out)-1].Text = strings.TrimRight(out[len(out)-1].Text, " ")
+				}
+				text("\n")
+			case "img":
+				src := attr(n, "src")
+				if src == "" {
+					continue
+				}
+				out = append(out, &Node{Kind: NodeImage, URL: src, Alt: attr(n, "alt"), Title: attr(n, "title")})
+			case "a":
+				children := inlines(childNodes(n))
+				href := attr(n, "href")
+				if href == "" {
+					out = append(out, children...)
+					continue
+				}
+				out = append(out, &Node{Kind: NodeLink, URL: href, Children: children})
+			case "code", "kbd", "samp", "tt":
+				if s := collapseSpace(textContent(n)); strings.TrimSpace(s) != "" {
+					out = append(out, &Node{Kind: NodeCode, Text: strings.TrimSpace(s)})
+				}
+			case "em", "i", "strong", "b":
+				children := inlines(childNodes(n))
+				if len(children) == 0 {
+					continue
+				}
+				kind := NodeEmphasis
+				if n.Data == "strong" || n.Data == "b" {
+					kind = NodeStrong
+				}
+				out = append(out, &Node{Kind: kind, Children: children})
+			default:
+				for _, c := range inlines(childNodes(n)) {
+					if c.Kind == NodeText {
+						text(c.Text)
+						continue
+					}
+					out = append(out, c)
+				}
+			}
+		}
+	}
+
+	// trim the whitespace around the inline run
+	if len(out) > 0 && out[0].Kind == NodeText {
+		out[0].Text = strings.TrimLeft(out[0].Text, " \n")
+	}
+	if len(out) > 0 && out[len(out)-1].Kind == NodeText {
+		out[len(out)-1].Text = strings.TrimRight(out[len(out)-1].Text, " \n")
+	}
+
+	filtered := out[:0]
+	for _, n := range out {
+		if n.Kind == NodeText && n.Text == "" {
+			continue
+		}
+		filtered = append(filtered, n)
+	}
+	return filtered
+}
+
+func childNodes(n *html.Node) []*html.Node {
+	var nodes []*html.Node
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		nodes = append(nodes, c)
+	}
+	return nodes
+}
+
+func textContent(n *html.Node) string {
+	var sb strings.Builder
+	var walk func(n *html.Node)
+	walk = func(n *html.Node) {
+		switch {
+		case n.Type == html.TextNode:
+			sb.WriteString(n.Data)
+		case n.Type == html.ElementNode && n.Data == "br":
+			sb.WriteByte('\n')
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(n)
+	return sb.String()
+}
+
+func collapseSpace(s string) string {
+	var sb strings.Builder
+	space := false
+	for _, r := range strings.ReplaceAll(s, "\u200b", "") {
+		switch r {
+		case ' ', '\t', '\n', '\r', '\f':
+			space = true
+			continue
+		}
+		if space {
+			sb.WriteByte(' ')
+			space = false
+		}
+		sb.WriteRune(r)
+	}
+	if space {
+		sb.WriteByte(' ')
+	}
+	return sb.String()
+}
+
+func attr(n *html.Node, key string) string {
+	for _, a := range n.Attr {
+		if a.Key == key {
+			return a.Val
+		}
+	}
+	return ""
+}
+
+// mdWriter renders nodes to markdown and records their offsets.
+type mdWriter struct {
+	sb     strings.Builder
+	prefix string
+	bol    bool
+	table  bool
+}
+
+func (w *mdWriter) write(s string) {
+	if w.table {
+		s = strings.ReplaceAll(s, "|", `\|`)
+		s = strings.ReplaceAll(s, "\n", " ")
+	}
+
+	for len(s) > 0 {
+		if w.bol {
+			if s[0] == '\n' {
+				w.sb.WriteString(strings.TrimRight(w.prefix, " "))
+			} else {
+				w.sb.WriteString(w.prefix)
+			}
+			w.bol = false
+		}
+
+		i := strings.IndexByte(s, '\n')
+		if i < 0 {
+			w.sb.WriteString(s)
+			return
+		}
+		w.sb.WriteString(s[:i+1])
+		s = s[i+1:]
+		w.bol = true
+	}
+}
+
+// mark returns the offset where the next node starts.
+func (w *mdWriter) mark() int {
+	if w.bol && w.prefix != "" {
+		w.sb.WriteString(w.prefix)
+	}
+	w.bol = false
+	return w.sb.Len()
+}
+
+// lineStart reports whether only the prefix was written since the last line break.
+func (w *mdWriter) lineStart() bool {
+	if w.bol {
+		return true
+	}
+	s := w.sb.String()
+	return s[strings.LastIndexByte(s, '\n')+1:] == w.prefix
+}
+
+func (w *mdWriter) blocks(nodes []*Node, sep string) {
+	for i, n := range nodes {
+		if i > 0 {
+			w.write(sep)
+		}
+		w.block(n)
+	}
+}
+
+func (w *mdWriter) block(n *Node) {
+	n.MarkdownStart = w.mark()
+	defer func() { n.MarkdownEnd = w.sb.Len() }()
+
+	switch n.Kind {
+	case NodeSection:
+		w.write(strings.Repeat("#", n.Level) + " " + escapeMarkdown(n.Title, false))
+		for _, c := range n.Children {
+			w.write("\n\n")
+			w.block(c)
+		}
+	case NodeParagraph:
+		w.inlines(n.Children)
+	case NodeQuote:
+		prefix := w.prefix
+		w.prefix += "> "
+		w.write("> ")
+		w.blocks(n.Children, "\n\n")
+		w.prefix = prefix
+	case NodeList:
+		for i, item := range n.Children {
+			if i > 0 {
+				w.write("\n")
+			}
+			marker := "- "
+			if n.Ordered {
+				marker = strconv.Itoa(i+1) + ". "
+			}
+			item.MarkdownStart = w.mark()
+			w.write(marker)
+			prefix := w.prefix
+			w.prefix += strings.Repeat(" ", len(marker))
+			w.blocks(item.Children, "\n")
+			w.prefix = prefix
+			item.MarkdownEnd = w.sb.Len()
+		}
+	case NodeTable:
+		columns := 0
+		for _, row := range n.Children {
+			columns = max(columns, len(row.Children))
+		}
+		for i, row := range n.Children {
+			if i > 0 {
+				w.write("\n")
+			}
+			row.MarkdownStart = w.mark()
+			w.write("|")
+			for j := 0; j < columns; j++ {
+				w.write(" ")
+				if j < len(row.Children) {
+					cell := row.Children[j]
+					cell.MarkdownStart = w.mark()
+					w.table = true
+					w.inlines(cell.Children)
+					w.table = false
+					cell.MarkdownEnd = w.sb.Len()
+				}
+				w.write(" |")
+			}
+			row.MarkdownEnd = w.sb.Len()
+			if i == 0 {
+				w.write("\n|" + strings.Repeat(" --- |", columns))
+			}
+		}
+	case NodeCodeBlock:
+		fence := strings.Repeat("`", max(3, longestRun(n.Text, '`')+1))
+		w.write(fence + n.Language + "\n" + n.Text + "\n" + fence)
+	default:
+		w.inline(n)
+	}
+}
+
+func (w *mdWriter) inlines(nodes []*Node) {
+	for _, n := range nodes {
+		w.inline(n)
+	}
+}
+
+func (w *mdWriter) inline(n *Node) {
+	n.MarkdownStart = w.mark()
+	defer func() { n.MarkdownEnd = w.sb.Len() }()
+
+	switch n.Kind {
+	case NodeText:
+		w.write(escapeMarkdown(n.Text, w.lineStart()))
+	case NodeCode:
+		ticks := strings.Repeat("`", longestRun(n.Text, '`')+1)
+		if strings.HasPrefix(n.Text, "`") || strings.HasSuffix(n.Text, "`") {
+			w.write(ticks + " " + n.Text + " " + ticks)
+			return
+		}
+		w.write(ticks + n.Text + ticks)
+	case NodeEmphasis:
+		w.write("*")
+		w.inlines(n.Children)
+		w.write("*")
+	case NodeStrong:
+		w.write("**")
+		w.inlines(n.Children)
+		w.write("**")
+	case NodeLink:
+		w.write("[")
+		w.inlines(n.Children)
+		w.write("](" + n.URL + ")")
+	case NodeImage:
+		alt := escapeMarkdown(n.Alt, false)
+		if n.Title != "" {
+			w.write("![" + alt + "](" + n.URL + " " + quoteTitle(n.Title) + ")")
+			return
+		}
+		w.write("![" + alt + "](" + n.URL + ")")
+	}
+}
+
+func longestRun(s string, c byte) int {
+	longest, run := 0, 0
+	for i := 0; i < len(s); i++ {
+		if s[i] != c {
+			run = 0
+			continue
+		}
+		run++
+		longest = max(longest, run)
+	}
+	return longest
+}
@@ -0,0 +1,117 @@
+package convert
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestConvertHTMLToDocument(t *testing.T) {
+	doc, err := ConvertHTMLToDocument(`<html><head><title>Title</title></head><body><main>
+<h1>Hello</h1>
+<p>Some <b>bold</b> and <a href="/x">link</a>.</p>
+<pre><code class="language-go">func main() {
+
+
+	fmt.Println("a")
+}
+</code></pre>
+<h2>List</h2>
+<ul><li>one<ul><li>nested</li></ul></li><li>two</li></ul>
+<table><tr><th>a</th><th>b</th></tr><tr><td>1</td><td><img src="i.png" alt="alt"></td></tr></table>
+</main></body></html>`, "https://example.com/a/")
+	if err != nil {
+		t.Fatalf("ConvertHTMLToDocument() error = %v", err)
+	}
+
+	if doc.Title != "Title" {
+		t.Errorf("ConvertHTMLToDocument() title = %q", doc.Title)
+	}
+
+	if len(doc.Children) != 1 || doc.Children[0].Kind != NodeSection || doc.Children[0].Title != "Hello" {
+		t.Fatalf("ConvertHTMLToDocument() unexpected root sections")
+	}
+
+	kinds := map[NodeKind]int{}
+	doc.Walk(func(n *Node) bool {
+		kinds[n.Kind]++
+		if n.MarkdownStart < 0 || n.MarkdownEnd > len(doc.Markdown) || n.MarkdownStart > n.MarkdownEnd {
+			t.Errorf("invalid offsets %d:%d for %s", n.MarkdownStart, n.MarkdownEnd, n.Kind)
+			return false
+		}
+
+		switch n.Kind {
+		case NodeCodeBlock:
+			if n.Language != "go" {
+				t.Errorf("code block language = %q", n.Language)
+			}
+			if !strings.Contains(n.Text, "{\n\n\n\tfmt") {
+				t.Errorf("code block whitespace not preserved: %q", n.Text)
+			}
+			if !strings.Contains(doc.Markdown[n.MarkdownStart:n.MarkdownEnd], n.Text) {
+				t.Errorf("code block offsets do not cover the code")
+			}
+		case NodeLink:
+			if n.URL != "https://example.com/x" || doc.Markdown[n.MarkdownStart:n.MarkdownEnd] != "[link](https://example.com/x)" {
+				t.Errorf("link = %q, %q", n.URL, doc.Markdown[n.MarkdownStart:n.MarkdownEnd])
+			}
+		case NodeImage:
+			if n.URL != "https://example.com/a/i.png" || n.Alt != "alt" {
+				t.Errorf("image = %q, %q", n.URL, n.Alt)
+			}
+		}
+		return true
+	})
+
+	for _, kind := range []NodeKind{NodeSection, NodeParagraph, NodeList, NodeListItem, NodeTable, NodeTableRow, NodeTableCell, NodeCodeBlock, NodeImage, NodeLink} {
+		if kinds[kind] == 0 {
+			t.Errorf("ConvertHTMLToDocument() missing %s node", kind)
+		}
+	}
+
+	if kinds[NodeList] != 2 {
+		t.Errorf("ConvertHTMLToDocument() nested list not preserved")
+	}
+}
+
+func TestRenderEscapes(t *testing.T) {
+	tests := []struct {
+		name string
+		html string
+		want string
+	}{
+		{"emphasis", `<p>2 * 3 and *not em* or snake_case</p>`, `2 * 3 and \*not em\* or snake\_case`},
+		{"code and links", "<p>a `tick` and [not](a link)</p>", "a \\`tick\\` and \\[not\\](a link)"},
+		{"heading", `<p># not a heading</p>`, `\# not a heading`},
+		{"list", `<p>1. not a list<br>- nor this</p>`, "1\\. not a list\n\\- nor this"},
+		{"quote", `<p>&gt; not a quote</p>`, `\> not a quote`},
+		{"html", `<p>&lt;b&gt; and &amp;amp; stay text</p>`, `\<b> and \&amp; stay text`},
+		{"link text", `<p><a href="https://example.com/">*star*</a></p>`, `[\*star\*](https://example.com/)`},
+		{"dollars", `<p>$5 and $10 for *x*</p>`, `$5 and $10 for \*x\*`},
+		{"math", `<p>*x* is <script type="math/tex">a_1 * b_2</script></p>`, `\*x\* is $a_1 * b_2$`},
+	}
+	for _, tt := range tests {
+		doc, err := ConvertHTMLToDocument("<html><body>"+tt.html+"</body></html>", "https://example.com/")
+		if err != nil {
+			t.Fatalf("%s: ConvertHTMLToDocument() error = %v", tt.name, err)
+		}
+		if got := strings.TrimSuffix(doc.Markdown, "\n"); got != tt.want {
+			t.Errorf("%s: markdown = %q, want %q", tt.name, got, tt.want)
+		}
+
+		// the text of the tree is not escaped
+		doc.Walk(func(n *Node) bool {
+			if n.Kind == NodeText && strings.Contains(n.Text, `\`) {
+				t.Errorf("%s: text node %q is escaped", tt.name, n.Text)
+			}
+			return true
+		})
+	}
+
+	doc := &Document{Children: []*Node{{Kind: NodeParagraph, Children: []*Node{
+		{Kind: NodeImage, URL: "https://example.com/i.png", Alt: "a [b]", Title: `say "café" \o/`},
+	}}}}
+	doc.Render()
+	if want := `![a \[b\]](https://example.com/i.png "say \"café\" \\o/")` + "\n"; doc.Markdown != want {
+		t.Errorf("image markdown = %q, want %q", doc.Markdown, want)
+	}
+}
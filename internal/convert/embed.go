@@ -0,0 +1,157 @@
+package convert
+
+import (
+	"html"
+	"net/url"
+	"path"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// rewriteEmbeds replaces embedded content that the sanitizer would drop
+// with links, and figures with captioned images.
+func rewriteEmbeds(s *goquery.Selection) {
+	s.Find("blockquote.twitter-tweet, blockquote.twitter-video").Each(func(i int, s *goquery.Selection) {
+		var status string
+		s.Find("a[href]").Each(func(i int, a *goquery.Selection) {
+			href, _ := a.Attr("href")
+			if strings.Contains(href, "/status/") {
+				status = href
+			}
+		})
+		if status == "" {
+			return
+		}
+		s.Find("a[href]").Last().Remove()
+		s.AppendHtml("<p>" + embedLink(status, tweetLabel(status)) + "</p>")
+	})
+
+	s.Find("iframe[src], embed[src]").Each(func(i int, s *goquery.Selection) {
+		src, _ := s.Attr("src")
+		title, _ := s.Attr("title")
+		href, label := embedTarget(src, title)
+		if href == "" {
+			s.Remove()
+			return
+		}
+		s.ReplaceWithHtml("<p>" + embedLink(href, label) + "</p>")
+	})
+
+	s.Find("script[src]").Each(func(i int, s *goquery.Selection) {
+		src, _ := s.Attr("src")
+		u, err := url.Parse(src)
+		if err != nil || u.Host != "gist.github.com" {
+			return
+		}
+		u.Path = strings.TrimSuffix(u.Path, ".js")
+		u.RawQuery = ""
+		s.ReplaceWithHtml("<p>" + embedLink(u.String(), "GitHub Gist: "+strings.Trim(u.Path, "/")) + "</p>")
+	})
+
+	s.Find(".codepen[data-slug-hash]").Each(func(i int, s *goquery.Selection) {
+		hash, _ := s.Attr("data-slug-hash")
+		user, _ := s.Attr("data-user")
+		title, _ := s.Attr("data-pen-title")
+		if user == "" {
+			user = "anon"
+		}
+		if title == "" {
+			title = user + "/" + hash
+		}
+		s.ReplaceWithHtml("<p>" + embedLink("https://codepen.io/"+user+"/pen/"+hash, "CodePen: "+title) + "</p>")
+	})
+
+	s.Find("video, audio").Each(func(i int, s *goquery.Selection) {
+		src, ok := s.Attr("src")
+		if !ok {
+			src, ok = s.Find("source[src]").First().Attr("src")
+		}
+		if !ok || src == "" {
+			s.Remove()
+			return
+		}
+
+		kind := "Video"
+		if goquery.NodeName(s) == "audio" {
+			kind = "Audio"
+		}
+		title, _ := s.Attr("title")
+		if title == "" {
+			title = path.Base(strings.SplitN(src, "?", 2)[0])
+		}
+		s.ReplaceWithHtml("<p>" + embedLink(src, kind+": "+title) + "</p>")
+	})
+
+	s.Find("figure").Each(func(i int, s *goquery.Selection) {
+		img := s.Find("img[src]").First()
+		if img.Length() == 0 {
+			return
+		}
+		caption := strings.Join(strings.Fields(s.Find("figcaption").First().Text()), " ")
+		if caption == "" {
+			return
+		}
+
+		src, _ := img.Attr("src")
+		alt, _ := img.Attr("alt")
+		if alt == "" {
+			alt = caption
+		}
+		s.ReplaceWithHtml(`<p><img src="` + html.EscapeString(src) + `" alt="` + html.EscapeString(alt) + `" title="` + html.EscapeString(caption) + `"></p>` +
+			"<p><em>" + html.EscapeString(caption) + "</em></p>")
+	})
+}
+
+// embedTarget returns the canonical URL and a descriptive label for an embedded frame.
+func embedTarget(src, title string) (href, label string) {
+	u, err := url.Parse(src)
+	if err != nil || u.Host == "" {
+		return "", ""
+	}
+	if u.Scheme == "" {
+		u.Scheme = "https"
+	}
+
+	host := strings.TrimPrefix(u.Host, "www.")
+	segments := strings.Split(strings.Trim(u.Path, "/"), "/")
+
+	describe := func(kind, fallback string) string {
+		if title != "" {
+			return kind + ": " + title
+		}
+		return kind + ": " + fallback
+	}
+
+	switch {
+	case (host == "youtube.com" || host == "youtube-nocookie.com") && len(segments) == 2 && segments[0] == "embed":
+		return "https://www.youtube.com/watch?v=" + segments[1], describe("YouTube video", segments[1])
+	case host == "player.vimeo.com" && len(segments) == 2 && segments[0] == "video":
+		return "https://vimeo.com/" + segments[1], describe("Vimeo video", segments[1])
+	case host == "codepen.io" && len(segments) == 3 && segments[1] == "embed":
+		return "https://codepen.io/" + segments[0] + "/pen/" + segments[2], describe("CodePen", segments[0]+"/"+segments[2])
+	case host == "gist.github.com":
+		u.Path = strings.TrimSuffix(u.Path, ".pibb")
+		return u.String(), describe("GitHub Gist", strings.Trim(u.Path, "/"))
+	case host == "platform.twitter.com" || host == "platform.x.com":
+		return "", ""
+	}
+
+	return u.String(), describe("Embedded content", u.Host)
+}
+
+func tweetLabel(status string) string {
+	u, err := url.Parse(status)
+	if err != nil {
+		return "Tweet"
+	}
+	user, _, _ := strings.Cut(strings.Trim(u.Path, "/"), "/")
+	if user == "" {
+		return "Tweet"
+	}
+	return "Tweet by @" + user
+}
+
+func embedLink(href, label string) string {
+	return `<a href="` + html.EscapeString(href) + `">` + html.EscapeString(label) + `</a>`
+}
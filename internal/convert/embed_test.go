@@ -0,0 +1,50 @@
+package convert
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestConvertEmbeds(t *testing.T) {
+	tests := []struct {
+		name string
+		html string
+		want string
+	}{
+		{"youtube", `<iframe src="https://www.youtube.com/embed/abc123?rel=0" title="Talk"></iframe>`, "[YouTube video: Talk](https://www.youtube.com/watch?v=abc123)"},
+		{"gist", `<script src="https://gist.github.com/bob/deadbeef.js"></script>`, "[GitHub Gist: bob/deadbeef](https://gist.github.com/bob/deadbeef)"},
+		{"codepen", `<iframe src="https://codepen.io/amy/embed/xyz"></iframe>`, "[CodePen: amy/xyz](https://codepen.io/amy/pen/xyz)"},
+		{"tweet", `<blockquote class="twitter-tweet"><p>hi</p><a href="https://twitter.com/bob/status/42">Jan 1</a></blockquote>`, "[Tweet by @bob](https://twitter.com/bob/status/42)"},
+		{"video", `<video><source src="/clip.mp4"></video>`, "[Video: clip.mp4](https://example.com/clip.mp4)"},
+		{"figure", `<figure><img src="/a.png"><figcaption>A chart</figcaption></figure>`, `![A chart](https://example.com/a.png "A chart")`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			md, err := ConvertHTMLToMarkdown("<html><body>"+tt.html+"</body></html>", "https://example.com/")
+			if err != nil {
+				t.Fatalf("ConvertHTMLToMarkdown() error = %v", err)
+			}
+			if !strings.Contains(md, tt.want) {
+				t.Errorf("ConvertHTMLToMarkdown() = %q, want %q", md, tt.want)
+			}
+		})
+	}
+}
+
+func TestConvertPolicy(t *testing.T) {
+	html := `<html><body><p>text <img src="/a.png" alt="image"></p></body></html>`
+
+	md, err := ConvertHTMLToMarkdown(html, "https://example.com/", WithPolicy(PolicyText))
+	if err != nil {
+		t.Fatalf("ConvertHTMLToMarkdown() error = %v", err)
+	}
+	if strings.Contains(md, "a.png") {
+		t.Errorf("ConvertHTMLToMarkdown() text policy kept image: %q", md)
+	}
+
+	_, err = ConvertHTMLToMarkdown(html, "https://example.com/", WithPolicy("unknown"))
+	if err != ErrUnknownPolicy {
+		t.Errorf("ConvertHTMLToMarkdown() error = %v, want ErrUnknownPolicy", err)
+	}
+}
@@ -0,0 +1,134 @@
+package convert
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// escapeMarkdown escapes the characters of text that markdown would read as syntax.
+// Like the smart escaping of the markdown converter, characters are only escaped
+// where they could start an emphasis, a code span, a link, an html tag or an entity,
+// or at the start of a line, a heading, a quote, a list item or a break. TeX math is kept as it is.
+// bol reports whether text starts at the start of a line.
+func escapeMarkdown(text string, bol bool) string {
+	var sb strings.Builder
+	sb.Grow(len(text))
+
+	for i := 0; i < len(text); i++ {
+		c := text[i]
+		if bol {
+			bol = false
+			if n := lineMarker(text[i:]); n > 0 {
+				sb.WriteString(text[i : i+n-1])
+				sb.WriteByte('\\')
+				sb.WriteByte(text[i+n-1])
+				i += n - 1
+				continue
+			}
+		}
+
+		if n := mathSpan(text[i:]); n > 0 {
+			sb.WriteString(text[i : i+n])
+			i += n - 1
+			continue
+		}
+
+		var next rune
+		if i+1 < len(text) {
+			next, _ = utf8.DecodeRuneInString(text[i+1:])
+		}
+		switch c {
+		case '\\', '`', '[', ']':
+			sb.WriteByte('\\')
+		case '*', '_':
+			// text around the run is unknown, so only a delimiter between spaces is safe
+			prev, _ := utf8.DecodeLastRuneInString(text[:i])
+			if i == 0 || next == 0 || !unicode.IsSpace(prev) || !unicode.IsSpace(next) {
+				sb.WriteByte('\\')
+			}
+		case '<':
+			if next == '/' || next == '!' || next == '?' || unicode.IsLetter(next) {
+				sb.WriteByte('\\')
+			}
+		case '&':
+			if next == '#' || unicode.IsLetter(next) || unicode.IsDigit(next) {
+				sb.WriteByte('\\')
+			}
+		case '\n':
+			bol = true
+		}
+		sb.WriteByte(c)
+	}
+
+	return sb.String()
+}
+
+// mathSpan returns the length of the TeX math at the start of s, or 0 if s does not start with math.
+// Math is delimited like in the markdown math extensions: $$ around display math, and $ around
+// inline math that does not start or end with a space and is not followed by a digit.
+// Its source is kept as it is, since markdown does not read escapes in math.
+func mathSpan(s string) int {
+	if strings.HasPrefix(s, "$$") {
+		if end := strings.Index(s[2:], "$$"); end > 0 {
+			return end + 4
+		}
+		return 0
+	}
+	if len(s) < 3 || s[0] != '$' || s[1] == ' ' || s[1] == '\n' {
+		return 0
+	}
+	for i := 2; i < len(s); i++ {
+		switch {
+		case s[i] == '\n' && s[i-1] == '\n':
+			return 0
+		case s[i] == '$' && s[i-1] != ' ' && s[i-1] != '\\' && (i+1 == len(s) || s[i+1] < '0' || s[i+1] > '9'):
+			return i + 1
+		}
+	}
+	return 0
+}
+
+// lineMarker returns the length of the block marker line starts with, up to and
+// including the character to escape, or 0 if the line does not start with a marker.
+func lineMarker(line string) int {
+	if i := strings.IndexByte(line, '\n'); i >= 0 {
+		line = line[:i]
+	}
+	if line == "" {
+		return 0
+	}
+	end := func(i int) bool { return i >= len(line) || line[i] == ' ' || line[i] == '\t' }
+
+	switch line[0] {
+	case '#':
+		n := len(line) - len(strings.TrimLeft(line, "#"))
+		if n <= 6 && end(n) {
+			return 1
+		}
+	case '>':
+		return 1
+	case '-', '+', '*':
+		if end(1) {
+			return 1
+		}
+		fallthrough
+	case '=':
+		// a break or the underline of a heading
+		if strings.Trim(line, string(line[0])+" \t") == "" {
+			return 1
+		}
+	case '0', '1', '2', '3', '4', '5', '6', '7', '8', '9':
+		n := len(line) - len(strings.TrimLeft(line, "0123456789"))
+		if n < len(line) && n <= 9 && (line[n] == '.' || line[n] == ')') && end(n+1) {
+			return n + 1
+		}
+	}
+	return 0
+}
+
+// quoteTitle quotes the title of a link or an image for markdown.
+func quoteTitle(title string) string {
+	r := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", " ")
+	return `"` + r.Replace(title) + `"`
+}
@@ -0,0 +1,94 @@
+package convert
+
+import (
+	"mime"
+	"net/url"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+type FeedType string
+
+const (
+	FeedRSS  FeedType = "rss"
+	FeedAtom FeedType = "atom"
+	FeedJSON FeedType = "json"
+)
+
+var feedTypes = map[string]FeedType{
+	"application/rss+xml":   FeedRSS,
+	"application/rdf+xml":   FeedRSS,
+	"application/atom+xml":  FeedAtom,
+	"application/feed+json": FeedJSON,
+}
+
+// Feed is a feed advertised by a page with <link rel="alternate">.
+type Feed struct {
+	URL   string   `json:"url"`
+	Title string   `json:"title,omitempty"`
+	Type  FeedType `json:"type"`
+}
+
+// discoverFeeds returns the RSS, Atom and JSON feeds advertised in the head of doc.
+// Feed URLs are resolved against the <base> of the page or curl.
+func discoverFeeds(doc *goquery.Document, curl string) []Feed {
+	base, err := url.Parse(curl)
+	if err != nil {
+		return nil
+	}
+	if href, ok := doc.Find("base[href]").First().Attr("href"); ok {
+		if u, err := base.Parse(strings.TrimSpace(href)); err == nil {
+			base = u
+		}
+	}
+
+	var feeds []Feed
+	seen := make(map[string]bool)
+	doc.Find("link[rel][href]").Each(func(i int, s *goquery.Selection) {
+		rel, _ := s.Attr("rel")
+		alternate := false
+		for _, r := range strings.Fields(strings.ToLower(rel)) {
+			alternate = alternate || r == "alternate"
+		}
+		if !alternate {
+			return
+		}
+
+		typ, _ := s.Attr("type")
+		mediaType, _, _ := mime.ParseMediaType(typ)
+		kind, ok := feedTypes[mediaType]
+		if !ok {
+			return
+		}
+
+		href, _ := s.Attr("href")
+		u, err := base.Parse(strings.TrimSpace(href))
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+			return
+		}
+		u.Fragment = ""
+		if seen[u.String()] {
+			return
+		}
+		seen[u.String()] = true
+
+		title, _ := s.Attr("title")
+		feeds = append(feeds, Feed{
+			URL:   u.String(),
+			Title: strings.TrimSpace(title),
+			Type:  kind,
+		})
+	})
+
+	return feeds
+}
+
+// DiscoverFeeds returns the feeds advertised by the html page at curl.
+func DiscoverFeeds(html string, curl string) ([]Feed, error) {
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(html))
+	if err != nil {
+		return nil, err
+	}
+	return discoverFeeds(doc, curl), nil
+}
@@ -0,0 +1,41 @@
+package convert
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDiscoverFeeds(t *testing.T) {
+	html := `<html><head>
+<title>Blog</title>
+<link rel="alternate" type="application/rss+xml" title="Posts" href="/feed.xml">
+<link rel="alternate" type="application/atom+xml; charset=utf-8" href="atom.xml">
+<link rel="alternate" type="application/feed+json" title="JSON" href="https://cdn.example.com/feed.json">
+<link rel="alternate" type="application/rss+xml" href="/feed.xml#dup">
+<link rel="alternate" type="application/json" href="/wp-json/wp/v2/posts/1">
+<link rel="alternate" hreflang="ko" href="/ko/">
+<link rel="stylesheet" type="text/css" href="/style.css">
+</head><body><p>hello</p></body></html>`
+
+	want := []Feed{
+		{URL: "https://example.com/feed.xml", Title: "Posts", Type: FeedRSS},
+		{URL: "https://example.com/blog/atom.xml", Type: FeedAtom},
+		{URL: "https://cdn.example.com/feed.json", Title: "JSON", Type: FeedJSON},
+	}
+
+	doc, err := ConvertHTMLToDocument(html, "https://example.com/blog/post")
+	if err != nil {
+		t.Fatalf("ConvertHTMLToDocument() error = %v", err)
+	}
+	if !reflect.DeepEqual(doc.Feeds, want) {
+		t.Errorf("Feeds = %+v, want %+v", doc.Feeds, want)
+	}
+
+	feeds, err := DiscoverFeeds(`<head><base href="https://example.org/b/"><link rel="alternate" type="application/rss+xml" href="rss"></head>`, "https://example.com/")
+	if err != nil {
+		t.Fatalf("DiscoverFeeds() error = %v", err)
+	}
+	if len(feeds) != 1 || feeds[0].URL != "https://example.org/b/rss" {
+		t.Errorf("DiscoverFeeds() = %+v", feeds)
+	}
+}
@@ -10,10 +10,15 @@ import (
 	"github.com/microcosm-cc/bluemonday"
 )
 
+// Version is the version of the conversion output.
+// It is incremented by changes that alter the markdown of existing documents, so they can be reprocessed.
+const Version = 2
+
 var htmlSanitizerPolicy = bluemonday.UGCPolicy().
 	AddSpaceWhenStrippingTag(true).
 	AllowRelativeURLs(true).
-	AllowStyles().Globally()
+	AllowStyles().Globally().
+	AllowAttrs("class").Matching(codeClassPattern).OnElements("code", "pre")
 
 func CleanHTML(html string) string {
 	return htmlSanitizerPolicy.Sanitize(html)
@@ -41,10 +46,36 @@ func convertURL(target, reference string) string {
 	return u.String()
 }
 
-func ConvertHTMLToMarkdown(html string, curl string) (string, error) {
+type prepared struct {
+	doc      *goquery.Document
+	title    string
+	feeds    []Feed
+	verbatim verbatim
+}
+
+func prepareHTML(html string, curl string, o *options) (*prepared, error) {
+	policy, err := getPolicy(o.policy)
+	if err != nil {
+		return nil, err
+	}
+
 	doc, err := goquery.NewDocumentFromReader(strings.NewReader(html))
 	if err != nil {
-		return "", err
+		return nil, err
+	}
+
+	p := &prepared{
+		title: strings.TrimSpace(doc.Find("head > title").First().Text()),
+		feeds: discoverFeeds(doc, curl),
+	}
+
+	rewriteMath(doc.Selection, &p.verbatim)
+	rewriteCode(doc.Selection)
+	rewriteEmbeds(doc.Selection)
+
+	html, err = doc.Html()
+	if err != nil {
+		return nil, err
 	}
 
 	if doc.Find("main").Length() > 0 {
@@ -57,11 +88,11 @@ func ConvertHTMLToMarkdown(html string, curl string) (string, error) {
 		})
 	}
 
-	cleaned := CleanHTML(html)
+	cleaned := policy.Sanitize(html)
 
 	doc, err = goquery.NewDocumentFromReader(strings.NewReader(cleaned))
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
 	doc.Find("img").Each(func(i int, s *goquery.Selection) {
@@ -78,7 +109,17 @@ func ConvertHTMLToMarkdown(html string, curl string) (string, error) {
 		}
 	})
 
-	cleaned, err = doc.Html()
+	p.doc = doc
+	return p, nil
+}
+
+func ConvertHTMLToMarkdown(html string, curl string, opts ...Option) (string, error) {
+	p, err := prepareHTML(html, curl, newOptions(opts))
+	if err != nil {
+		return "", err
+	}
+
+	cleaned, err := p.doc.Html()
 	if err != nil {
 		return "", err
 	}
@@ -88,17 +129,43 @@ func ConvertHTMLToMarkdown(html string, curl string) (string, error) {
 		return "", err
 	}
 
-	converted = strings.ReplaceAll(converted, "\r\n", "\n")
-	converted = strings.ReplaceAll(converted, "\n\n\n", "\n\n")
-	converted = strings.ReplaceAll(converted, "\u200b", "")
+	return p.verbatim.restore(cleanMarkdown(converted)), nil
+}
+
+// cleanMarkdown normalizes line endings and blank lines outside of fenced code blocks.
+func cleanMarkdown(md string) string {
+	md = strings.ReplaceAll(md, "\r\n", "\n")
+	md = strings.ReplaceAll(md, "\u200b", "")
+
+	lines := strings.Split(md, "\n")
+	out := lines[:0]
+	var fence string
+	blank := false
+	for _, line := range lines {
+		trimmed := strings.TrimLeft(line, " ")
+		if fence != "" {
+			out = append(out, line)
+			if strings.HasPrefix(trimmed, fence) && strings.TrimRight(trimmed, "`~") == "" {
+				fence = ""
+			}
+			continue
+		}
 
-	for strings.Contains(converted, " \n") {
-		converted = strings.ReplaceAll(converted, " \n", "\n")
-	}
+		line = strings.TrimRight(line, " ")
+		if line == "" {
+			if !blank {
+				out = append(out, line)
+			}
+			blank = true
+			continue
+		}
+		blank = false
 
-	for strings.Contains(converted, "\n\n\n") {
-		converted = strings.ReplaceAll(converted, "\n\n\n", "\n\n")
+		if strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~") {
+			fence = trimmed[:len(trimmed)-len(strings.TrimLeft(trimmed, trimmed[:1]))]
+		}
+		out = append(out, line)
 	}
 
-	return converted, nil
+	return strings.Join(out, "\n")
 }
@@ -0,0 +1,111 @@
+package convert
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+const (
+	placeholderStart = "\ue000"
+	placeholderEnd   = "\ue001"
+)
+
+// verbatim holds text that must survive sanitizing and markdown conversion unchanged.
+type verbatim []string
+
+// add stores s and returns the placeholder that replaces it in the html.
+func (v *verbatim) add(s string) string {
+	*v = append(*v, s)
+	return placeholderStart + strconv.Itoa(len(*v)-1) + placeholderEnd
+}
+
+func (v verbatim) restore(s string) string {
+	if len(v) == 0 || !strings.Contains(s, placeholderStart) {
+		return s
+	}
+
+	var sb strings.Builder
+	for {
+		start := strings.Index(s, placeholderStart)
+		if start < 0 {
+			break
+		}
+		end := strings.Index(s[start:], placeholderEnd)
+		if end < 0 {
+			break
+		}
+		end += start
+
+		i, err := strconv.Atoi(s[start+len(placeholderStart) : end])
+		if err != nil || i < 0 || i >= len(v) {
+			sb.WriteString(s[:end+len(placeholderEnd)])
+		} else {
+			sb.WriteString(s[:start])
+			sb.WriteString(v[i])
+		}
+		s = s[end+len(placeholderEnd):]
+	}
+	sb.WriteString(s)
+	return sb.String()
+}
+
+// rewriteMath replaces rendered KaTeX, MathJax and MathML formulas with their TeX source.
+func rewriteMath(s *goquery.Selection, v *verbatim) {
+	replace := func(s *goquery.Selection, tex string, display bool) {
+		tex = strings.TrimSpace(tex)
+		if tex == "" {
+			s.Remove()
+			return
+		}
+		if display {
+			s.ReplaceWithHtml("<div>" + v.add("$$\n"+tex+"\n$$") + "</div>")
+			return
+		}
+		s.ReplaceWithHtml(v.add("$" + tex + "$"))
+	}
+
+	// KaTeX
+	s.Find(".katex-display").Each(func(i int, s *goquery.Selection) {
+		replace(s, texAnnotation(s), true)
+	})
+	s.Find(".katex").Each(func(i int, s *goquery.Selection) {
+		replace(s, texAnnotation(s), false)
+	})
+
+	// MathJax 2 keeps the source in script tags next to the rendered output.
+	s.Find(".MathJax_Preview, .MathJax, .MathJax_Display, .MathJax_SVG, .MathJax_SVG_Display, .MathJax_CHTML").Remove()
+	s.Find(`script[type^="math/tex"]`).Each(func(i int, s *goquery.Selection) {
+		t, _ := s.Attr("type")
+		replace(s, s.Text(), strings.Contains(t, "mode=display"))
+	})
+
+	// MathJax 3
+	s.Find("mjx-container").Each(func(i int, s *goquery.Selection) {
+		d, _ := s.Attr("display")
+		replace(s, texAnnotation(s), d == "true")
+	})
+
+	// MathML, including the wikipedia variant with the source in alttext.
+	s.Find("math").Each(func(i int, s *goquery.Selection) {
+		tex := texAnnotation(s)
+		if tex == "" {
+			tex, _ = s.Attr("alttext")
+			tex = strings.TrimSpace(tex)
+			if inner, ok := strings.CutPrefix(tex, `{\displaystyle`); ok {
+				tex = strings.TrimSuffix(strings.TrimSpace(inner), "}")
+			}
+		}
+		if tex == "" {
+			tex = s.Text()
+		}
+		d, _ := s.Attr("display")
+		replace(s, tex, d == "block")
+	})
+	s.Find(".mwe-math-fallback-image-inline, .mwe-math-fallback-image-display").Remove()
+}
+
+func texAnnotation(s *goquery.Selection) string {
+	return s.Find(`annotation[encoding="application/x-tex"]`).First().Text()
+}
@@ -0,0 +1,66 @@
+package convert
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestConvertMath(t *testing.T) {
+	tests := []struct {
+		name string
+		html string
+		want string
+	}{
+		{"katex", `<p>x <span class="katex"><annotation encoding="application/x-tex">e^{i\pi}+1=0</annotation><span class="katex-html">e</span></span></p>`, `$e^{i\pi}+1=0$`},
+		{"katex display", `<span class="katex-display"><span class="katex"><annotation encoding="application/x-tex">\int_0^1 x\,dx</annotation></span></span>`, "$$\n\\int_0^1 x\\,dx\n$$"},
+		{"mathjax", `<p>x <span class="MathJax">a</span><script type="math/tex">a_1 * b_2</script></p>`, `$a_1 * b_2$`},
+		{"mathjax display", `<script type="math/tex; mode=display">\sum_i i</script>`, "$$\n\\sum_i i\n$$"},
+		{"mathml alttext", `<p><math alttext="{\displaystyle x^{2}}"><mi>x</mi></math></p>`, `$x^{2}$`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			html := "<html><body>" + tt.html + "</body></html>"
+
+			md, err := ConvertHTMLToMarkdown(html, "https://example.com/")
+			if err != nil {
+				t.Fatalf("ConvertHTMLToMarkdown() error = %v", err)
+			}
+			if !strings.Contains(md, tt.want) {
+				t.Errorf("ConvertHTMLToMarkdown() = %q, want %q", md, tt.want)
+			}
+
+			doc, err := ConvertHTMLToDocument(html, "https://example.com/")
+			if err != nil {
+				t.Fatalf("ConvertHTMLToDocument() error = %v", err)
+			}
+			if !strings.Contains(doc.Markdown, tt.want) {
+				t.Errorf("ConvertHTMLToDocument() = %q, want %q", doc.Markdown, tt.want)
+			}
+		})
+	}
+}
+
+func TestConvertMathInCode(t *testing.T) {
+	katex := `<span class="katex"><annotation encoding="application/x-tex">a^2</annotation></span>`
+	html := "<html><body><pre><code>x = " + katex + "</code></pre><p>see <code>" + katex + "</code></p></body></html>"
+
+	doc, err := ConvertHTMLToDocument(html, "https://example.com/")
+	if err != nil {
+		t.Fatalf("ConvertHTMLToDocument() error = %v", err)
+	}
+	if strings.ContainsAny(doc.Markdown, placeholderStart+placeholderEnd) {
+		t.Errorf("ConvertHTMLToDocument() = %q, has placeholders", doc.Markdown)
+	}
+
+	var code []string
+	doc.Walk(func(n *Node) bool {
+		if n.Kind == NodeCodeBlock || n.Kind == NodeCode {
+			code = append(code, n.Text)
+		}
+		return true
+	})
+	if len(code) != 2 || !strings.Contains(code[0], "$a^2$") || !strings.Contains(code[1], "$a^2$") {
+		t.Errorf("code = %q, want the TeX source of the math", code)
+	}
+}
@@ -0,0 +1,107 @@
+package convert
+
+import (
+	"errors"
+	"regexp"
+	"sort"
+	"sync"
+
+	"github.com/microcosm-cc/bluemonday"
+)
+
+var ErrUnknownPolicy = errors.New("convert: unknown sanitization policy")
+
+const (
+	// PolicyDefault keeps user generated content, including images and styles.
+	PolicyDefault = "default"
+	// PolicyText keeps the document structure and links, but drops images and styles.
+	PolicyText = "text"
+	// PolicyStrict strips every tag and keeps only the text.
+	PolicyStrict = "strict"
+)
+
+var codeClassPattern = regexp.MustCompile(`^(language|lang)-[\w+#.-]+$`)
+
+var (
+	policiesMu sync.RWMutex
+	policies   = map[string]*bluemonday.Policy{
+		PolicyDefault: htmlSanitizerPolicy,
+		PolicyText:    textPolicy(),
+		PolicyStrict:  bluemonday.StrictPolicy().AddSpaceWhenStrippingTag(true),
+	}
+)
+
+func textPolicy() *bluemonday.Policy {
+	p := bluemonday.NewPolicy()
+	p.AllowStandardURLs()
+	p.AllowRelativeURLs(true)
+	p.AddSpaceWhenStrippingTag(true)
+	p.AllowElements(
+		"article", "aside", "section", "details", "summary", "main",
+		"h1", "h2", "h3", "h4", "h5", "h6", "hgroup",
+		"blockquote", "br", "div", "hr", "p", "span",
+		"abbr", "cite", "code", "del", "em", "i", "b", "ins", "kbd",
+		"mark", "pre", "q", "samp", "small", "strong", "sub", "sup", "var",
+	)
+	p.AllowAttrs("href").OnElements("a")
+	p.AllowAttrs("class").Matching(codeClassPattern).OnElements("code", "pre")
+	p.AllowLists()
+	p.AllowTables()
+	return p
+}
+
+// RegisterPolicy registers a named sanitization policy.
+// Registering an existing name replaces the policy.
+func RegisterPolicy(name string, p *bluemonday.Policy) {
+	policiesMu.Lock()
+	policies[name] = p
+	policiesMu.Unlock()
+}
+
+// Policies returns the names of the registered sanitization policies.
+func Policies() []string {
+	policiesMu.RLock()
+	defer policiesMu.RUnlock()
+
+	names := make([]string, 0, len(policies))
+	for name := range policies {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func getPolicy(name string) (*bluemonday.Policy, error) {
+	if name == "" {
+		name = PolicyDefault
+	}
+
+	policiesMu.RLock()
+	p, ok := policies[name]
+	policiesMu.RUnlock()
+	if !ok {
+		return nil, ErrUnknownPolicy
+	}
+	return p, nil
+}
+
+type options struct {
+	policy string
+}
+
+type Option func(*options)
+
+// WithPolicy selects the named sanitization policy.
+func WithPolicy(name string) Option {
+	return func(o *options) {
+		o.policy = name
+	}
+}
+
+func newOptions(opts []Option) *options {
+	o := &options{}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
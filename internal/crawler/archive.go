@@ -0,0 +1,109 @@
+package crawler
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/chromedp/cdproto/network"
+	"github.com/chromedp/chromedp"
+	"github.com/rs/zerolog/log"
+	"gosuda.org/jimin/internal/warc"
+)
+
+// WithWARC writes the fetched responses and rendered pages to w.
+func WithWARC(w *warc.Writer) CrawlerOption {
+	return func(c *Crawler) {
+		c.warc = w
+	}
+}
+
+// documentCapture records the main document response of a navigation.
+type documentCapture struct {
+	mu       sync.Mutex
+	id       network.RequestID
+	response *network.Response
+	body     []byte
+	private  bool
+}
+
+func (d *documentCapture) listen(ctx context.Context) {
+	chromedp.ListenTarget(ctx, func(ev interface{}) {
+		e, ok := ev.(*network.EventResponseReceived)
+		if !ok || e.Type != network.ResourceTypeDocument {
+			return
+		}
+
+		d.mu.Lock()
+		if d.id == "" {
+			d.id = e.RequestID
+			d.response = e.Response
+		}
+		d.mu.Unlock()
+	})
+}
+
+func (d *documentCapture) actions() []chromedp.Action {
+	return []chromedp.Action{
+		chromedp.ActionFunc(func(ctx context.Context) error {
+			d.mu.Lock()
+			id := d.id
+			d.mu.Unlock()
+			if id == "" {
+				return nil
+			}
+
+			body, err := network.GetResponseBody(id).Do(ctx)
+			if err != nil {
+				// the body may be evicted from the browser cache, the rendered page is still archived.
+				log.Warn().Err(err).Msg("crawler: failed to get the document response body")
+				return nil
+			}
+			d.body = body
+			return nil
+		}),
+	}
+}
+
+// write archives the document response and the rendered page.
+// Both records are of the final URL of the document, which differs from the page URL after redirects.
+func (d *documentCapture) write(w *warc.Writer, page *Page) error {
+	d.mu.Lock()
+	resp := d.response
+	d.mu.Unlock()
+
+	target := page.URL
+	if resp != nil && resp.URL != "" {
+		target = resp.URL
+	}
+
+	var responseID string
+	if resp != nil && d.body != nil {
+		header := make(http.Header, len(resp.Headers))
+		for k, v := range resp.Headers {
+			switch strings.ToLower(k) {
+			// the browser returns the decoded body
+			case "content-encoding", "content-length", "transfer-encoding":
+				continue
+			case "set-cookie":
+				if d.private {
+					continue
+				}
+			}
+			for _, line := range strings.Split(fmt.Sprint(v), "\n") {
+				header.Add(k, line)
+			}
+		}
+
+		var err error
+		responseID, err = w.WriteResponse(target, page.FetchedAt, int(resp.Status), header, d.body)
+		if err != nil {
+			return err
+		}
+	}
+
+	_, err := w.WriteConversion(target, page.FetchedAt, responseID, "text/html; charset=utf-8", []byte(page.HTML))
+	return err
+}
@@ -0,0 +1,69 @@
+package crawler
+
+import (
+	"bytes"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/chromedp/cdproto/network"
+	"gosuda.org/jimin/internal/warc"
+)
+
+func TestDocumentCaptureWrite(t *testing.T) {
+	page := &Page{URL: "http://example.com/old", HTML: "<p>rendered</p>", FetchedAt: time.Now()}
+
+	tests := []struct {
+		name    string
+		capture *documentCapture
+		want    []string
+	}{
+		{
+			"redirected",
+			&documentCapture{
+				response: &network.Response{URL: "https://example.com/new", Status: 200, Headers: network.Headers{"Content-Type": "text/html"}},
+				body:     []byte("<p>hello</p>"),
+			},
+			[]string{"https://example.com/new", "https://example.com/new"},
+		},
+		{
+			"evicted body",
+			&documentCapture{response: &network.Response{URL: "https://example.com/new", Status: 200}},
+			[]string{"https://example.com/new"},
+		},
+		{"no response", &documentCapture{}, []string{"http://example.com/old"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := tt.capture.write(warc.NewWriter(&buf, false), page); err != nil {
+				t.Fatalf("write() error = %v", err)
+			}
+
+			r, err := warc.NewReader(&buf)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var targets []string
+			for {
+				rec, err := r.Next()
+				if err == io.EOF {
+					break
+				}
+				if err != nil {
+					t.Fatalf("Next() error = %v", err)
+				}
+				targets = append(targets, rec.TargetURI())
+			}
+
+			if len(targets) != len(tt.want) {
+				t.Fatalf("records of %q, want %q", targets, tt.want)
+			}
+			for i := range targets {
+				if targets[i] != tt.want[i] {
+					t.Errorf("record %d target = %q, want %q", i, targets[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
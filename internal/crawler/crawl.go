@@ -2,60 +2,202 @@ package crawler
 
 import (
 	"context"
+	"errors"
+	"net/http"
 	"sync"
 	"time"
 
+	"github.com/chromedp/cdproto/network"
 	"github.com/chromedp/chromedp"
+	"github.com/rs/zerolog/log"
+	"gosuda.org/jimin/internal/blob"
+	"gosuda.org/jimin/internal/warc"
+)
+
+var (
+	ErrTimeout         = errors.New("crawler: timeout")
+	ErrNoSnapshotStore = errors.New("crawler: snapshots requested without a snapshot store")
+	ErrClosed          = errors.New("crawler: closed")
 )
 
 type Crawler struct {
-	sema    chan struct{}
-	wg      sync.WaitGroup
-	timeout time.Duration
+	sema     chan struct{}
+	wg       sync.WaitGroup
+	timeout  time.Duration
+	store    blob.Store
+	warc     *warc.Writer
+	profiles Profiles
+	proxies  *ProxyRouter
 }
 
-func NewCrawler(concurrency int, timeout time.Duration) *Crawler {
-	return &Crawler{
+type CrawlerOption func(*Crawler)
+
+// WithSnapshotStore sets the store for page snapshots.
+func WithSnapshotStore(store blob.Store) CrawlerOption {
+	return func(c *Crawler) {
+		c.store = store
+	}
+}
+
+func NewCrawler(concurrency int, timeout time.Duration, opts ...CrawlerOption) *Crawler {
+	c := &Crawler{
 		sema:    make(chan struct{}, concurrency),
 		timeout: timeout,
 	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
 }
+
+// Options controls how a single page is crawled.
+type Options struct {
+	Wait WaitOptions `json:"wait"`
+	// Script is evaluated after waiting and before the page is captured,
+	// e.g. to expand collapsed sections. A returned promise is awaited.
+	Script    string          `json:"script,omitempty"`
+	Snapshots SnapshotOptions `json:"snapshots"`
+}
+
+type Page struct {
+	URL       string     `json:"url"`
+	HTML      string     `json:"html"`
+	Snapshots []Snapshot `json:"snapshots,omitempty"`
+	FetchedAt time.Time  `json:"fetched_at"`
+}
+
+// CrawlPage returns the rendered html of url.
+// An empty string is returned if the page could not be crawled in time.
 func (c *Crawler) CrawlPage(url string) (string, error) {
-	c.sema <- struct{}{}
-	c.wg.Add(1)
+	page, err := c.Crawl(context.Background(), url, Options{})
+	if err != nil {
+		return "", nil
+	}
+	return page.HTML, nil
+}
 
-	result := make(chan string)
-	url = convertURL(url)
+// Crawl renders url in a browser and returns the page.
+func (c *Crawler) Crawl(ctx context.Context, url string, opts Options) (*Page, error) {
+	if opts.Snapshots.enabled() && c.store == nil {
+		return nil, ErrNoSnapshotStore
+	}
 
-	go func() {
-		defer func() {
-			<-c.sema
-			c.wg.Done()
-			close(result)
-		}()
+	select {
+	case c.sema <- struct{}{}:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	c.wg.Add(1)
+	defer func() {
+		<-c.sema
+		c.wg.Done()
+	}()
+
+	timeoutCtx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
 
-		ctx, cancel := chromedp.NewContext(context.Background())
-		defer cancel()
+	page := &Page{
+		URL:       convertURL(url),
+		FetchedAt: time.Now(),
+	}
 
-		var html string
+	proxy := c.proxies.route(hostname(page.URL))
+	browserCtx, cancel, err := newBrowser(timeoutCtx, proxy)
+	if err != nil {
+		return nil, err
+	}
+	defer cancel()
 
-		err := chromedp.Run(ctx,
-			chromedp.Navigate(url),
-			chromedp.InnerHTML("html", &html),
-		)
+	var release func(err error)
+	if proxy != nil {
+		release, err = proxy.acquire(timeoutCtx)
 		if err != nil {
-			return
+			return nil, err
 		}
-		result <- html
-	}()
+	}
 
-	select {
-	case <-time.After(c.timeout):
-		return "", nil
-	case html, ok := <-result:
-		if !ok {
-			return "", nil
+	var captures snapshotCaptures
+	var document documentCapture
+	var actions []chromedp.Action
+	if c.warc != nil || opts.Wait.NetworkIdle {
+		actions = append(actions, network.Enable())
+	}
+	if c.warc != nil {
+		document.listen(browserCtx)
+		// session cookies set by authenticated responses are not archived.
+		document.private = c.profiles.Match(hostname(page.URL)) != nil
+	}
+	actions = append(actions, requestActions(browserCtx, c.profiles, proxy.auth())...)
+	actions = append(actions, chromedp.Navigate(page.URL))
+	actions = append(actions, waitActions(browserCtx, opts)...)
+	actions = append(actions, chromedp.InnerHTML("html", &page.HTML))
+	if c.warc != nil {
+		actions = append(actions, document.actions()...)
+	}
+	actions = append(actions, captures.actions(opts.Snapshots)...)
+
+	err = chromedp.Run(browserCtx, actions...)
+	if release != nil {
+		release(err)
+	}
+	if err != nil {
+		if ctx.Err() == nil && errors.Is(timeoutCtx.Err(), context.DeadlineExceeded) {
+			return nil, ErrTimeout
 		}
-		return html, nil
+		return nil, err
+	}
+
+	page.Snapshots, err = captures.store(ctx, c.store)
+	if err != nil {
+		return nil, err
+	}
+
+	if c.warc != nil {
+		if err := document.write(c.warc, page); err != nil {
+			log.Error().Err(err).Str("url", page.URL).Msg("crawler: failed to write warc records")
+		}
+	}
+
+	return page, nil
+}
+
+// newBrowser returns the context of a new browser for a crawl, launched by the shared allocator of proxy if it is not nil.
+// The browser is closed once ctx is done or the returned function is called.
+func newBrowser(ctx context.Context, proxy *proxyState) (context.Context, context.CancelFunc, error) {
+	if proxy == nil {
+		browserCtx, cancel := chromedp.NewContext(ctx)
+		return browserCtx, cancel, nil
 	}
+
+	alloc := proxy.allocator()
+	if alloc == nil || alloc.Err() != nil {
+		return nil, nil, ErrClosed
+	}
+	browserCtx, cancel := chromedp.NewContext(alloc)
+	stop := context.AfterFunc(ctx, cancel)
+	return browserCtx, func() {
+		stop()
+		cancel()
+	}, nil
+}
+
+// Transport returns a round tripper for fetching outside the browser
+// that applies the crawl profiles and proxies of the crawler.
+func (c *Crawler) Transport(base *http.Transport) http.RoundTripper {
+	return c.profiles.Transport(c.proxies.Transport(base))
+}
+
+// Close stops the browser allocators of the crawler. Crawls through proxies fail once it is closed.
+func (c *Crawler) Close() {
+	c.proxies.Close()
+}
+
+// ProxyHealth returns the health of the proxies of the crawler.
+func (c *Crawler) ProxyHealth() []ProxyHealth {
+	return c.proxies.Health()
+}
+
+// Wait blocks until all in-flight crawls are finished.
+func (c *Crawler) Wait() {
+	c.wg.Wait()
 }
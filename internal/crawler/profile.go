@@ -0,0 +1,372 @@
+package crawler
+
+import (
+	"bufio"
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/chromedp/cdproto/cdp"
+	"github.com/chromedp/cdproto/fetch"
+	"github.com/chromedp/cdproto/network"
+	"github.com/chromedp/chromedp"
+	"github.com/rs/zerolog/log"
+)
+
+var ErrInvalidCookieFile = errors.New("crawler: invalid cookie file")
+
+// Secret is a credential that is redacted when it is printed, logged or marshaled.
+type Secret string
+
+const redacted = "[REDACTED]"
+
+func (s Secret) String() string   { return redacted }
+func (s Secret) GoString() string { return redacted }
+
+func (s Secret) MarshalJSON() ([]byte, error) {
+	return []byte(strconv.Quote(redacted)), nil
+}
+
+func (s Secret) MarshalText() ([]byte, error) {
+	return []byte(redacted), nil
+}
+
+// Reveal returns the secret value.
+func (s Secret) Reveal() string {
+	return string(s)
+}
+
+type Cookie struct {
+	Name  string `json:"name"`
+	Value Secret `json:"value"`
+	// Domain defaults to the hosts of the profile.
+	Domain   string `json:"domain,omitempty"`
+	Path     string `json:"path,omitempty"`
+	Secure   bool   `json:"secure,omitempty"`
+	HTTPOnly bool   `json:"http_only,omitempty"`
+}
+
+type BasicAuth struct {
+	Username string `json:"username"`
+	Password Secret `json:"password"`
+}
+
+// Profile holds the credentials injected into requests to its hosts.
+// The headers and the basic auth are only sent over https unless AllowInsecure is set,
+// and secure cookies are only sent over https.
+type Profile struct {
+	Name      string            `json:"name"`
+	Hosts     []string          `json:"hosts"`
+	Headers   map[string]Secret `json:"headers,omitempty"`
+	Cookies   []Cookie          `json:"cookies,omitempty"`
+	BasicAuth *BasicAuth        `json:"basic_auth,omitempty"`
+	// CookieFile is a cookie jar in the Netscape cookies.txt format.
+	CookieFile string `json:"cookie_file,omitempty"`
+	// AllowInsecure sends the headers and the basic auth over plain http too.
+	AllowInsecure bool `json:"allow_insecure,omitempty"`
+}
+
+func (p *Profile) matches(host string) bool {
+	for _, pattern := range p.Hosts {
+		if MatchHost(pattern, host) {
+			return true
+		}
+	}
+	return false
+}
+
+// header returns the headers injected into requests to the profile hosts with scheme.
+func (p *Profile) header(scheme string) http.Header {
+	h := make(http.Header)
+	if scheme != "https" && !p.AllowInsecure {
+		return h
+	}
+	for k, v := range p.Headers {
+		h.Set(k, v.Reveal())
+	}
+	if p.BasicAuth != nil {
+		token := base64.StdEncoding.EncodeToString([]byte(p.BasicAuth.Username + ":" + p.BasicAuth.Password.Reveal()))
+		h.Set("Authorization", "Basic "+token)
+	}
+	return h
+}
+
+// cookies returns the cookies of the profile with their domains resolved.
+func (p *Profile) cookies() []Cookie {
+	var cookies []Cookie
+	for _, c := range p.Cookies {
+		if c.Domain != "" {
+			cookies = append(cookies, c)
+			continue
+		}
+		for _, pattern := range p.Hosts {
+			c := c
+			if suffix, ok := strings.CutPrefix(pattern, "*."); ok {
+				c.Domain = "." + suffix
+			} else if pattern != "*" {
+				c.Domain = pattern
+			} else {
+				continue
+			}
+			cookies = append(cookies, c)
+		}
+	}
+	return cookies
+}
+
+func cookieMatches(c Cookie, host, path string) bool {
+	domain := strings.ToLower(c.Domain)
+	host = strings.ToLower(host)
+	if suffix, ok := strings.CutPrefix(domain, "."); ok {
+		if host != suffix && !strings.HasSuffix(host, domain) {
+			return false
+		}
+	} else if host != domain {
+		return false
+	}
+
+	return c.Path == "" || strings.HasPrefix(path, c.Path)
+}
+
+// LoadCookieFile reads a Netscape cookies.txt file.
+func LoadCookieFile(name string) ([]Cookie, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var cookies []Cookie
+	s := bufio.NewScanner(f)
+	for s.Scan() {
+		line := strings.TrimSpace(s.Text())
+
+		httpOnly := false
+		if rest, ok := strings.CutPrefix(line, "#HttpOnly_"); ok {
+			line, httpOnly = rest, true
+		}
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		fields := strings.Split(line, "\t")
+		if len(fields) != 7 {
+			return nil, ErrInvalidCookieFile
+		}
+
+		expires, err := strconv.ParseInt(fields[4], 10, 64)
+		if err != nil {
+			return nil, ErrInvalidCookieFile
+		}
+		if expires != 0 && time.Unix(expires, 0).Before(time.Now()) {
+			continue
+		}
+
+		domain := fields[0]
+		if strings.EqualFold(fields[1], "TRUE") && !strings.HasPrefix(domain, ".") {
+			domain = "." + domain
+		}
+
+		cookies = append(cookies, Cookie{
+			Name:     fields[5],
+			Value:    Secret(fields[6]),
+			Domain:   domain,
+			Path:     fields[2],
+			Secure:   strings.EqualFold(fields[3], "TRUE"),
+			HTTPOnly: httpOnly,
+		})
+	}
+	if err := s.Err(); err != nil {
+		return nil, err
+	}
+
+	return cookies, nil
+}
+
+type Profiles []Profile
+
+// LoadProfiles resolves the cookie files of the profiles.
+func LoadProfiles(profiles []Profile) (Profiles, error) {
+	out := make(Profiles, len(profiles))
+	for i, p := range profiles {
+		if p.CookieFile != "" {
+			cookies, err := LoadCookieFile(p.CookieFile)
+			if err != nil {
+				return nil, fmt.Errorf("crawler: profile %q: %w", p.Name, err)
+			}
+			p.Cookies = append(append([]Cookie(nil), p.Cookies...), cookies...)
+		}
+		out[i] = p
+	}
+	return out, nil
+}
+
+// Match returns the first profile whose hosts match host.
+func (ps Profiles) Match(host string) *Profile {
+	for i := range ps {
+		if ps[i].matches(host) {
+			return &ps[i]
+		}
+	}
+	return nil
+}
+
+// Transport returns a round tripper that adds the profile credentials to requests to the profile hosts.
+func (ps Profiles) Transport(base http.RoundTripper) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &profileTransport{profiles: ps, base: base}
+}
+
+type profileTransport struct {
+	profiles Profiles
+	base     http.RoundTripper
+}
+
+func (t *profileTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	p := t.profiles.Match(req.URL.Hostname())
+	if p == nil {
+		return t.base.RoundTrip(req)
+	}
+
+	req = req.Clone(req.Context())
+	for k, v := range p.header(req.URL.Scheme) {
+		req.Header[k] = v
+	}
+	for _, c := range p.cookies() {
+		if cookieMatches(c, req.URL.Hostname(), req.URL.Path) && (!c.Secure || req.URL.Scheme == "https") {
+			req.AddCookie(&http.Cookie{Name: c.Name, Value: c.Value.Reveal()})
+		}
+	}
+
+	return t.base.RoundTrip(req)
+}
+
+// WithProfiles injects the profile credentials into the browser requests to the profile hosts.
+func WithProfiles(profiles Profiles) CrawlerOption {
+	return func(c *Crawler) {
+		c.profiles = profiles
+	}
+}
+
+// requestActions sets the profile cookies and intercepts requests to add the profile headers.
+// Headers are only added to requests to the hosts of the profile, never to third parties.
+// The authentication challenges of the proxy are answered with proxyAuth if it is not nil.
+func requestActions(ctx context.Context, profiles Profiles, proxyAuth *url.Userinfo) []chromedp.Action {
+	if len(profiles) == 0 && proxyAuth == nil {
+		return nil
+	}
+
+	var cookies []*network.CookieParam
+	intercept := proxyAuth != nil
+	for i := range profiles {
+		for _, c := range profiles[i].cookies() {
+			if c.Path == "" {
+				c.Path = "/"
+			}
+			cookies = append(cookies, &network.CookieParam{
+				Name:     c.Name,
+				Value:    c.Value.Reveal(),
+				Domain:   c.Domain,
+				Path:     c.Path,
+				Secure:   c.Secure,
+				HTTPOnly: c.HTTPOnly,
+			})
+		}
+		intercept = intercept || len(profiles[i].Headers) > 0 || profiles[i].BasicAuth != nil
+	}
+
+	var actions []chromedp.Action
+	if len(cookies) > 0 {
+		actions = append(actions, network.Enable(), network.SetCookies(cookies))
+	}
+
+	if intercept {
+		var answered sync.Map
+		chromedp.ListenTarget(ctx, func(ev interface{}) {
+			switch e := ev.(type) {
+			case *fetch.EventRequestPaused:
+				go func() {
+					c := chromedp.FromContext(ctx)
+					ectx := cdp.WithExecutor(ctx, c.Target)
+
+					continueReq := fetch.ContinueRequest(e.RequestID)
+					if u, err := url.Parse(e.Request.URL); err == nil {
+						if p := profiles.Match(u.Hostname()); p != nil {
+							continueReq = continueReq.WithHeaders(mergeHeaders(e.Request.Headers, p.header(u.Scheme)))
+						}
+					}
+
+					if err := continueReq.Do(ectx); err != nil && ctx.Err() == nil {
+						log.Warn().Err(err).Msg("crawler: failed to continue intercepted request")
+					}
+				}()
+			case *fetch.EventAuthRequired:
+				// a request challenged again was refused the credentials
+				_, retry := answered.LoadOrStore(e.RequestID, struct{}{})
+				resp := proxyAuthResponse(e.AuthChallenge, proxyAuth, retry)
+
+				go func() {
+					c := chromedp.FromContext(ctx)
+					ectx := cdp.WithExecutor(ctx, c.Target)
+					if err := fetch.ContinueWithAuth(e.RequestID, resp).Do(ectx); err != nil && ctx.Err() == nil {
+						log.Warn().Err(err).Msg("crawler: failed to answer an authentication challenge")
+					}
+				}()
+			}
+		})
+		actions = append(actions, fetch.Enable().WithHandleAuthRequests(proxyAuth != nil))
+	}
+
+	return actions
+}
+
+// proxyAuthResponse answers an authentication challenge with the proxy credentials.
+// The challenges of servers are left to the browser, and a proxy refusing the credentials is not asked again.
+func proxyAuthResponse(challenge *fetch.AuthChallenge, proxyAuth *url.Userinfo, retry bool) *fetch.AuthChallengeResponse {
+	if challenge == nil || challenge.Source != fetch.AuthChallengeSourceProxy || proxyAuth == nil {
+		return &fetch.AuthChallengeResponse{Response: fetch.AuthChallengeResponseResponseDefault}
+	}
+	if retry {
+		return &fetch.AuthChallengeResponse{Response: fetch.AuthChallengeResponseResponseCancelAuth}
+	}
+	password, _ := proxyAuth.Password()
+	return &fetch.AuthChallengeResponse{
+		Response: fetch.AuthChallengeResponseResponseProvideCredentials,
+		Username: proxyAuth.Username(),
+		Password: password,
+	}
+}
+
+func mergeHeaders(orig network.Headers, extra http.Header) []*fetch.HeaderEntry {
+	var entries []*fetch.HeaderEntry
+	for k, v := range orig {
+		if _, ok := extra[http.CanonicalHeaderKey(k)]; ok {
+			continue
+		}
+		entries = append(entries, &fetch.HeaderEntry{Name: k, Value: fmt.Sprint(v)})
+	}
+	for k, vs := range extra {
+		for _, v := range vs {
+			entries = append(entries, &fetch.HeaderEntry{Name: k, Value: v})
+		}
+	}
+	return entries
+}
+
+func hostname(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return ""
+	}
+	return u.Hostname()
+}
@@ -0,0 +1,136 @@
+package crawler
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestProfileTransport(t *testing.T) {
+	var got http.Header
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.Header.Clone()
+	}))
+	defer srv.Close()
+
+	profiles := Profiles{{
+		Name:      "intranet",
+		Hosts:     []string{"127.0.0.1"},
+		Headers:   map[string]Secret{"X-Api-Key": "key"},
+		Cookies:   []Cookie{{Name: "session", Value: "abc"}},
+		BasicAuth: &BasicAuth{Username: "user", Password: "pass"},
+	}}
+	client := &http.Client{Transport: profiles.Transport(nil)}
+
+	resp, err := client.Get(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	// credentials are kept off plain http unless the profile allows it
+	if got.Get("X-Api-Key") != "" || got.Get("Authorization") != "" {
+		t.Errorf("credentials sent over http: %v", got)
+	}
+	if got.Get("Cookie") != "session=abc" {
+		t.Errorf("Cookie = %q", got.Get("Cookie"))
+	}
+
+	profiles[0].AllowInsecure = true
+	client = &http.Client{Transport: profiles.Transport(nil)}
+	resp, err = client.Get(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	if got.Get("X-Api-Key") != "key" {
+		t.Errorf("X-Api-Key = %q", got.Get("X-Api-Key"))
+	}
+	if !strings.HasPrefix(got.Get("Authorization"), "Basic ") {
+		t.Errorf("Authorization = %q", got.Get("Authorization"))
+	}
+
+	// requests to other hosts are not modified
+	profiles[0].Hosts = []string{"example.com"}
+	client = &http.Client{Transport: profiles.Transport(nil)}
+	resp, err = client.Get(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	if got.Get("X-Api-Key") != "" || got.Get("Cookie") != "" || got.Get("Authorization") != "" {
+		t.Errorf("credentials leaked to an unmatched host: %v", got)
+	}
+
+	// over https
+	tls := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.Header.Clone()
+	}))
+	defer tls.Close()
+	profiles[0].Hosts, profiles[0].AllowInsecure = []string{"127.0.0.1"}, false
+	client = tls.Client()
+	client.Transport = profiles.Transport(client.Transport)
+	resp, err = client.Get(tls.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	if got.Get("X-Api-Key") != "key" || !strings.HasPrefix(got.Get("Authorization"), "Basic ") {
+		t.Errorf("credentials not sent over https: %v", got)
+	}
+}
+
+func TestLoadCookieFile(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "cookies.txt")
+	data := "# Netscape HTTP Cookie File\n" +
+		"example.com\tTRUE\t/\tTRUE\t0\tsid\tsecret\n" +
+		"#HttpOnly_wiki.example.com\tFALSE\t/docs\tFALSE\t0\ttoken\tvalue\n" +
+		"old.example.com\tFALSE\t/\tFALSE\t1\texpired\tx\n"
+	if err := os.WriteFile(name, []byte(data), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	cookies, err := LoadCookieFile(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cookies) != 2 {
+		t.Fatalf("len(cookies) = %d, want 2", len(cookies))
+	}
+
+	if c := cookies[0]; c.Domain != ".example.com" || !c.Secure || c.Value.Reveal() != "secret" {
+		t.Errorf("cookies[0] = %+v", c)
+	}
+	if c := cookies[1]; c.Domain != "wiki.example.com" || c.Path != "/docs" || !c.HTTPOnly {
+		t.Errorf("cookies[1] = %+v", c)
+	}
+
+	if !cookieMatches(cookies[0], "www.example.com", "/") || cookieMatches(cookies[1], "example.com", "/docs") {
+		t.Error("unexpected cookie domain matching")
+	}
+}
+
+func TestSecretRedacted(t *testing.T) {
+	p := Profile{
+		Headers:   map[string]Secret{"Authorization": "Bearer token"},
+		BasicAuth: &BasicAuth{Username: "user", Password: "hunter2"},
+	}
+
+	b, err := json.Marshal(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range []string{string(b), fmt.Sprintf("%v %+v", p, *p.BasicAuth)} {
+		if strings.Contains(s, "token") || strings.Contains(s, "hunter2") {
+			t.Errorf("secret leaked: %s", s)
+		}
+	}
+}
@@ -0,0 +1,103 @@
+package crawler
+
+import (
+	"bytes"
+	"context"
+
+	"github.com/chromedp/cdproto/page"
+	"github.com/chromedp/chromedp"
+	"gosuda.org/jimin/internal/blob"
+)
+
+type SnapshotKind string
+
+const (
+	SnapshotScreenshot SnapshotKind = "screenshot"
+	SnapshotPDF        SnapshotKind = "pdf"
+	SnapshotMHTML      SnapshotKind = "mhtml"
+)
+
+// SnapshotOptions selects the archival copies captured with a page.
+type SnapshotOptions struct {
+	Screenshot bool `json:"screenshot"`
+	PDF        bool `json:"pdf"`
+	MHTML      bool `json:"mhtml"`
+}
+
+func (o SnapshotOptions) enabled() bool {
+	return o.Screenshot || o.PDF || o.MHTML
+}
+
+// Snapshot is an archival copy of a page as it was rendered at crawl time.
+type Snapshot struct {
+	Kind        SnapshotKind `json:"kind"`
+	ContentType string       `json:"content_type"`
+	Blob        blob.Blob    `json:"blob"`
+}
+
+type snapshotCaptures struct {
+	screenshot []byte
+	pdf        []byte
+	mhtml      string
+}
+
+func (s *snapshotCaptures) actions(o SnapshotOptions) []chromedp.Action {
+	var actions []chromedp.Action
+
+	if o.Screenshot {
+		// quality 100 captures a lossless png
+		actions = append(actions, chromedp.FullScreenshot(&s.screenshot, 100))
+	}
+
+	if o.PDF {
+		actions = append(actions, chromedp.ActionFunc(func(ctx context.Context) error {
+			data, _, err := page.PrintToPDF().WithPrintBackground(true).Do(ctx)
+			if err != nil {
+				return err
+			}
+			s.pdf = data
+			return nil
+		}))
+	}
+
+	if o.MHTML {
+		actions = append(actions, chromedp.ActionFunc(func(ctx context.Context) error {
+			data, err := page.CaptureSnapshot().WithFormat(page.CaptureSnapshotFormatMhtml).Do(ctx)
+			if err != nil {
+				return err
+			}
+			s.mhtml = data
+			return nil
+		}))
+	}
+
+	return actions
+}
+
+func (s *snapshotCaptures) store(ctx context.Context, store blob.Store) ([]Snapshot, error) {
+	var snapshots []Snapshot
+
+	put := func(kind SnapshotKind, contentType string, data []byte) error {
+		if len(data) == 0 {
+			return nil
+		}
+		b, err := store.Put(ctx, bytes.NewReader(data))
+		if err != nil {
+			return err
+		}
+		snapshots = append(snapshots, Snapshot{Kind: kind, ContentType: contentType, Blob: b})
+		return nil
+	}
+
+	if err := put(SnapshotScreenshot, "image/png", s.screenshot); err != nil {
+		return nil, err
+	}
+	if err := put(SnapshotPDF, "application/pdf", s.pdf); err != nil {
+		return nil, err
+	}
+	if err := put(SnapshotMHTML, "multipart/related", []byte(s.mhtml)); err != nil {
+		return nil, err
+	}
+
+	return snapshots, nil
+}
@@ -0,0 +1,91 @@
+package crawler
+
+import (
+	"context"
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	"gosuda.org/jimin/internal/blob"
+)
+
+func TestSnapshotActions(t *testing.T) {
+	tests := []struct {
+		opts SnapshotOptions
+		want int
+	}{
+		{SnapshotOptions{}, 0},
+		{SnapshotOptions{Screenshot: true}, 1},
+		{SnapshotOptions{PDF: true, MHTML: true}, 2},
+		{SnapshotOptions{Screenshot: true, PDF: true, MHTML: true}, 3},
+	}
+	for _, tt := range tests {
+		var s snapshotCaptures
+		if got := len(s.actions(tt.opts)); got != tt.want {
+			t.Errorf("actions(%+v) = %d actions, want %d", tt.opts, got, tt.want)
+		}
+		if tt.opts.enabled() != (tt.want > 0) {
+			t.Errorf("%+v.enabled() = %v", tt.opts, tt.opts.enabled())
+		}
+	}
+}
+
+func TestSnapshotStore(t *testing.T) {
+	ctx := context.Background()
+	store, err := blob.NewFSStore(t.TempDir(), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := snapshotCaptures{
+		screenshot: []byte("\x89PNG\r\n\x1a\n"),
+		mhtml:      "From: <Saved by Blink>\r\n",
+	}
+	snapshots, err := s.store(ctx, store)
+	if err != nil {
+		t.Fatalf("store() error = %v", err)
+	}
+
+	want := []struct {
+		kind        SnapshotKind
+		contentType string
+		data        string
+	}{
+		{SnapshotScreenshot, "image/png", string(s.screenshot)},
+		{SnapshotMHTML, "multipart/related", s.mhtml},
+	}
+	if len(snapshots) != len(want) {
+		t.Fatalf("store() = %+v, want the screenshot and mhtml snapshots", snapshots)
+	}
+	for i, w := range want {
+		got := snapshots[i]
+		if got.Kind != w.kind || got.ContentType != w.contentType || got.Blob.Size != int64(len(w.data)) {
+			t.Errorf("snapshot %d = %+v, want a %s of %s", i, got, w.kind, w.contentType)
+			continue
+		}
+
+		r, err := store.Open(ctx, got.Blob.Key)
+		if err != nil {
+			t.Fatalf("Open(%s) error = %v", got.Kind, err)
+		}
+		data, _ := io.ReadAll(r)
+		r.Close()
+		if string(data) != w.data {
+			t.Errorf("%s blob = %q, want %q", got.Kind, data, w.data)
+		}
+	}
+
+	var none snapshotCaptures
+	if snapshots, err := none.store(ctx, store); err != nil || len(snapshots) != 0 {
+		t.Errorf("store() without captures = %+v, %v", snapshots, err)
+	}
+}
+
+func TestCrawlSnapshotsWithoutStore(t *testing.T) {
+	c := NewCrawler(1, time.Second)
+	_, err := c.Crawl(context.Background(), "https://example.com", Options{Snapshots: SnapshotOptions{PDF: true}})
+	if !errors.Is(err, ErrNoSnapshotStore) {
+		t.Errorf("Crawl() error = %v, want %v", err, ErrNoSnapshotStore)
+	}
+}
@@ -1,6 +1,9 @@
 package crawler
 
-import "net/url"
+import (
+	"net/url"
+	"strings"
+)
 
 func convertURL(path string) string {
 	u, err := url.Parse(path)
@@ -16,3 +19,20 @@ func convertURL(path string) string {
 
 	return path
 }
+
+// MatchHost reports whether host matches pattern.
+// A pattern of the form "*.example.com" matches example.com and its subdomains.
+func MatchHost(pattern, host string) bool {
+	pattern = strings.ToLower(pattern)
+	host = strings.ToLower(host)
+
+	if pattern == "*" {
+		return true
+	}
+
+	if suffix, ok := strings.CutPrefix(pattern, "*."); ok {
+		return host == suffix || strings.HasSuffix(host, "."+suffix)
+	}
+
+	return host == pattern
+}
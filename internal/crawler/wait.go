@@ -0,0 +1,156 @@
+package crawler
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/chromedp/cdproto/network"
+	"github.com/chromedp/cdproto/runtime"
+	"github.com/chromedp/chromedp"
+)
+
+const (
+	_DEFAULT_IDLE_TIME    = time.Millisecond * 500
+	_DEFAULT_SCROLL_DELAY = time.Millisecond * 500
+	_NETWORK_POLL         = time.Millisecond * 50
+)
+
+// WaitOptions controls when a rendered page is captured.
+// The strategies run in field order: delay, selector, network idle and scrolling.
+type WaitOptions struct {
+	// Delay is a fixed delay after navigation in milliseconds.
+	Delay int `json:"delay_ms,omitempty"`
+	// Selector waits until an element matching the CSS selector is visible.
+	Selector string `json:"selector,omitempty"`
+	// NetworkIdle waits until no requests are in flight for IdleTime milliseconds.
+	NetworkIdle bool `json:"network_idle,omitempty"`
+	IdleTime    int  `json:"idle_ms,omitempty"`
+	// MaxScrolls scrolls to the bottom of the page up to MaxScrolls times to trigger lazy loading.
+	// Scrolling stops early once the page height stops growing.
+	MaxScrolls  int `json:"max_scrolls,omitempty"`
+	ScrollDelay int `json:"scroll_delay_ms,omitempty"`
+}
+
+func millis(ms int, fallback time.Duration) time.Duration {
+	if ms <= 0 {
+		return fallback
+	}
+	return time.Duration(ms) * time.Millisecond
+}
+
+// networkTracker counts the requests in flight.
+type networkTracker struct {
+	mu       sync.Mutex
+	inflight map[network.RequestID]struct{}
+	last     time.Time
+}
+
+func (t *networkTracker) listen(ctx context.Context) {
+	t.inflight = make(map[network.RequestID]struct{})
+	t.last = time.Now()
+
+	chromedp.ListenTarget(ctx, func(ev interface{}) {
+		t.mu.Lock()
+		defer t.mu.Unlock()
+
+		switch e := ev.(type) {
+		case *network.EventRequestWillBeSent:
+			t.inflight[e.RequestID] = struct{}{}
+		case *network.EventLoadingFinished:
+			delete(t.inflight, e.RequestID)
+		case *network.EventLoadingFailed:
+			delete(t.inflight, e.RequestID)
+		default:
+			return
+		}
+		t.last = time.Now()
+	})
+}
+
+func (t *networkTracker) idle(d time.Duration) chromedp.Action {
+	return chromedp.ActionFunc(func(ctx context.Context) error {
+		ticker := time.NewTicker(_NETWORK_POLL)
+		defer ticker.Stop()
+
+		for {
+			t.mu.Lock()
+			idle := len(t.inflight) == 0 && time.Since(t.last) >= d
+			t.mu.Unlock()
+			if idle {
+				return nil
+			}
+
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-ticker.C:
+			}
+		}
+	})
+}
+
+const scrollScript = `(() => { window.scrollTo(0, document.documentElement.scrollHeight); return document.documentElement.scrollHeight; })()`
+
+func autoScroll(max int, delay time.Duration, settle chromedp.Action) chromedp.Action {
+	return chromedp.ActionFunc(func(ctx context.Context) error {
+		var height float64
+		for i := 0; i < max; i++ {
+			var h float64
+			if err := chromedp.Evaluate(scrollScript, &h).Do(ctx); err != nil {
+				return err
+			}
+			if i > 0 && h <= height {
+				return nil
+			}
+			height = h
+
+			if err := chromedp.Sleep(delay).Do(ctx); err != nil {
+				return err
+			}
+			if settle != nil {
+				if err := settle.Do(ctx); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+}
+
+// waitActions returns the actions that run between navigation and capture.
+func waitActions(ctx context.Context, opts Options) []chromedp.Action {
+	w := opts.Wait
+	var actions []chromedp.Action
+
+	if w.Delay > 0 {
+		actions = append(actions, chromedp.Sleep(millis(w.Delay, 0)))
+	}
+
+	if w.Selector != "" {
+		actions = append(actions, chromedp.WaitVisible(w.Selector, chromedp.ByQuery))
+	}
+
+	var settle chromedp.Action
+	if w.NetworkIdle {
+		tracker := &networkTracker{}
+		tracker.listen(ctx)
+		settle = tracker.idle(millis(w.IdleTime, _DEFAULT_IDLE_TIME))
+		actions = append(actions, settle)
+	}
+
+	if w.MaxScrolls > 0 {
+		actions = append(actions, autoScroll(w.MaxScrolls, millis(w.ScrollDelay, _DEFAULT_SCROLL_DELAY), settle))
+	}
+
+	if opts.Script != "" {
+		actions = append(actions, chromedp.Evaluate(opts.Script, nil, func(p *runtime.EvaluateParams) *runtime.EvaluateParams {
+			return p.WithAwaitPromise(true)
+		}))
+		if settle != nil {
+			actions = append(actions, settle)
+		}
+	}
+
+	return actions
+}
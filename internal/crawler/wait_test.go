@@ -0,0 +1,160 @@
+package crawler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os/exec"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/chromedp/cdproto/network"
+	"github.com/chromedp/chromedp"
+)
+
+func TestWaitActions(t *testing.T) {
+	ctx, cancel := chromedp.NewContext(context.Background())
+	defer cancel()
+
+	tests := []struct {
+		name string
+		opts Options
+		want int
+	}{
+		{"none", Options{}, 0},
+		{"delay", Options{Wait: WaitOptions{Delay: 100}}, 1},
+		{"selector", Options{Wait: WaitOptions{Selector: "#main"}}, 1},
+		{"network idle", Options{Wait: WaitOptions{NetworkIdle: true}}, 1},
+		{"scroll", Options{Wait: WaitOptions{MaxScrolls: 3}}, 1},
+		{"script", Options{Script: "1"}, 1},
+		// the network settles again after the script
+		{"script after network idle", Options{Wait: WaitOptions{NetworkIdle: true}, Script: "1"}, 3},
+		{"all", Options{Wait: WaitOptions{Delay: 100, Selector: "#main", NetworkIdle: true, MaxScrolls: 3}, Script: "1"}, 6},
+	}
+	for _, tt := range tests {
+		if got := len(waitActions(ctx, tt.opts)); got != tt.want {
+			t.Errorf("%s: waitActions() = %d actions, want %d", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestNetworkIdle(t *testing.T) {
+	tests := []struct {
+		name     string
+		inflight int
+		since    time.Duration
+		want     error
+	}{
+		{"idle", 0, time.Second, nil},
+		{"request in flight", 1, time.Second, context.DeadlineExceeded},
+		{"recent request", 0, 0, context.DeadlineExceeded},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tracker := &networkTracker{inflight: make(map[network.RequestID]struct{}), last: time.Now().Add(-tt.since)}
+			for i := range tt.inflight {
+				tracker.inflight[network.RequestID(fmt.Sprint(i))] = struct{}{}
+			}
+
+			ctx, cancel := context.WithTimeout(context.Background(), _NETWORK_POLL*4)
+			defer cancel()
+			if err := tracker.idle(time.Second / 2).Do(ctx); !errors.Is(err, tt.want) {
+				t.Errorf("idle() error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+// browserAvailable reports whether a browser chromedp can start is installed.
+func browserAvailable() bool {
+	for _, name := range []string{"headless-shell", "chromium", "chromium-browser", "google-chrome", "google-chrome-stable", "chrome"} {
+		if _, err := exec.LookPath(name); err == nil {
+			return true
+		}
+	}
+	return false
+}
+
+const waitPage = `<html><body>
+<div id="main">ready</div>
+<script>
+	setTimeout(() => {
+		const p = document.createElement("p");
+		p.id = "late";
+		p.textContent = "late content";
+		document.body.appendChild(p);
+	}, 300);
+	fetch("/slow").then(r => r.text()).then(t => {
+		const p = document.createElement("p");
+		p.textContent = t;
+		document.body.appendChild(p);
+	});
+</script>
+</body></html>`
+
+func TestWaitStrategies(t *testing.T) {
+	if testing.Short() || !browserAvailable() {
+		t.Skip("no browser to render the pages")
+	}
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/":
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+			w.Write([]byte(waitPage))
+		case "/slow":
+			time.Sleep(time.Second)
+			w.Write([]byte("fetched content"))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	tests := []struct {
+		name    string
+		opts    Options
+		want    []string
+		missing []string
+		err     error
+	}{
+		{"none", Options{}, []string{"ready"}, []string{"late content", "fetched content"}, nil},
+		{"delay", Options{Wait: WaitOptions{Delay: 400}}, []string{"late content"}, []string{"fetched content"}, nil},
+		{"selector", Options{Wait: WaitOptions{Selector: "#late"}}, []string{"late content"}, []string{"fetched content"}, nil},
+		{"network idle", Options{Wait: WaitOptions{NetworkIdle: true, IdleTime: 200}}, []string{"fetched content"}, nil, nil},
+		{"selector timeout", Options{Wait: WaitOptions{Selector: "#never"}}, nil, nil, ErrTimeout},
+		{"script", Options{Script: `new Promise(resolve => setTimeout(() => {
+			document.getElementById("main").textContent = "expanded";
+			resolve();
+		}, 100))`}, []string{"expanded"}, []string{"ready"}, nil},
+		{"script error", Options{Script: `(() => { throw new Error("boom"); })()`}, nil, nil, errors.New("boom")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := NewCrawler(1, time.Second*5)
+			page, err := c.Crawl(context.Background(), srv.URL+"/", tt.opts)
+			if tt.err != nil {
+				if err == nil || (!errors.Is(err, tt.err) && !strings.Contains(err.Error(), tt.err.Error())) {
+					t.Fatalf("Crawl() error = %v, want %v", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Crawl() error = %v", err)
+			}
+			for _, s := range tt.want {
+				if !strings.Contains(page.HTML, s) {
+					t.Errorf("Crawl() html is missing %q: %s", s, page.HTML)
+				}
+			}
+			for _, s := range tt.missing {
+				if strings.Contains(page.HTML, s) {
+					t.Errorf("Crawl() html has %q before it was waited for: %s", s, page.HTML)
+				}
+			}
+		})
+	}
+}
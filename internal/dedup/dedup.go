@@ -0,0 +1,281 @@
+package dedup
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"gosuda.org/jimin/database"
+)
+
+const (
+	_DEFAULT_THRESHOLD = 3
+	// _MAX_THRESHOLD is the largest distance the bands are guaranteed to find.
+	_MAX_THRESHOLD = _BANDS - 1
+)
+
+type DB interface {
+	database.DBTX
+	Begin(ctx context.Context) (pgx.Tx, error)
+}
+
+type IDGenerator interface {
+	Generate(ctx context.Context) (int64, error)
+}
+
+type Config struct {
+	DB  DB
+	IDs IDGenerator
+	// Threshold is the largest Hamming distance between fingerprints of near-duplicates, at most 3.
+	Threshold int
+}
+
+// Deduper clusters the near-duplicate documents of a workspace.
+// Each cluster has one canonical document and the others are linked to it
+// with DUPLICATE relations in ws_relations.
+type Deduper struct {
+	cfg Config
+	q   *database.Queries
+}
+
+func New(cfg Config) *Deduper {
+	if cfg.Threshold <= 0 {
+		cfg.Threshold = _DEFAULT_THRESHOLD
+	}
+	if cfg.Threshold > _MAX_THRESHOLD {
+		cfg.Threshold = _MAX_THRESHOLD
+	}
+	return &Deduper{cfg: cfg, q: database.New(cfg.DB)}
+}
+
+type candidate struct {
+	id        int64
+	url       string
+	createdAt time.Time
+}
+
+// preferred reports whether a is a better canonical document than b.
+// Desktop pages are preferred over mobile and AMP pages, https over http,
+// shorter URLs over longer ones and older documents over newer ones.
+func preferred(a, b candidate) bool {
+	if ma, mb := mobile(a.url), mobile(b.url); ma != mb {
+		return mb
+	}
+	if sa, sb := strings.HasPrefix(a.url, "https:"), strings.HasPrefix(b.url, "https:"); sa != sb {
+		return sa
+	}
+	if len(a.url) != len(b.url) {
+		return len(a.url) < len(b.url)
+	}
+	if !a.createdAt.Equal(b.createdAt) {
+		return a.createdAt.Before(b.createdAt)
+	}
+	return a.id < b.id
+}
+
+func mobile(rawURL string) bool {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return false
+	}
+	host := strings.ToLower(u.Hostname())
+	if strings.HasPrefix(host, "m.") || strings.HasPrefix(host, "mobile.") || strings.HasPrefix(host, "amp.") {
+		return true
+	}
+	path := strings.ToLower(u.Path)
+	return strings.HasPrefix(path, "/amp/") || strings.HasSuffix(path, "/amp") || strings.HasSuffix(path, ".amp") ||
+		u.Query().Has("amp")
+}
+
+// Update fingerprints the converted markdown of doc and links it into its cluster.
+// The cluster is the union of doc and the clusters of its near-duplicates,
+// and the preferred document of the cluster becomes the canonical document.
+// It returns the id of the canonical document, which is doc.ID if doc has no duplicates.
+func (d *Deduper) Update(ctx context.Context, doc database.Document, markdown string) (int64, error) {
+	fp := SimHash(markdown)
+	bands := Bands(fp)
+
+	canonical := doc.ID
+	err := pgx.BeginFunc(ctx, d.cfg.DB, func(tx pgx.Tx) error {
+		q := d.q.WithTx(tx)
+
+		err := q.SetDocumentSimhash(ctx, database.SetDocumentSimhashParams{
+			ID:        doc.ID,
+			Simhash:   int64(fp),
+			SimhashB0: bands[0],
+			SimhashB1: bands[1],
+			SimhashB2: bands[2],
+			SimhashB3: bands[3],
+		})
+		if err != nil {
+			return err
+		}
+
+		// the cluster of doc is recomputed from its new content
+		err = q.DeleteDuplicateRelations(ctx, database.DeleteDuplicateRelationsParams{WsID: doc.WsID, ObjectID: doc.ID})
+		if err != nil {
+			return err
+		}
+		if fp == 0 {
+			return nil
+		}
+
+		rows, err := q.ListSimhashCandidates(ctx, database.ListSimhashCandidatesParams{
+			WsID:      doc.WsID,
+			ID:        doc.ID,
+			SimhashB0: bands[0],
+			SimhashB1: bands[1],
+			SimhashB2: bands[2],
+			SimhashB3: bands[3],
+		})
+		if err != nil {
+			return err
+		}
+
+		roots := map[int64]candidate{doc.ID: {id: doc.ID, url: doc.Url, createdAt: doc.CreatedAt.Time}}
+		for _, row := range rows {
+			if Distance(fp, uint64(row.Simhash)) > d.cfg.Threshold {
+				continue
+			}
+
+			root, err := d.root(ctx, q, doc.WsID, candidate{id: row.ID, url: row.Url, createdAt: row.CreatedAt.Time})
+			if err != nil {
+				return err
+			}
+			roots[root.id] = root
+		}
+		if len(roots) == 1 {
+			return nil
+		}
+
+		best := roots[doc.ID]
+		for _, c := range roots {
+			if preferred(c, best) {
+				best = c
+			}
+		}
+		canonical = best.id
+
+		for _, c := range roots {
+			if c.id == best.id {
+				continue
+			}
+			if err := d.link(ctx, q, doc.WsID, c.id, best.id); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return canonical, nil
+}
+
+// root returns the canonical document of the cluster of c.
+func (d *Deduper) root(ctx context.Context, q *database.Queries, wsID int64, c candidate) (candidate, error) {
+	target, err := q.GetDuplicateTarget(ctx, database.GetDuplicateTargetParams{WsID: wsID, ObjectID: c.id})
+	if errors.Is(err, pgx.ErrNoRows) {
+		return c, nil
+	}
+	if err != nil {
+		return candidate{}, err
+	}
+
+	doc, err := q.GetDocument(ctx, target)
+	if err != nil {
+		return candidate{}, err
+	}
+	return candidate{id: doc.ID, url: doc.Url, createdAt: doc.CreatedAt.Time}, nil
+}
+
+// link makes the cluster of id a part of the cluster of canonical.
+func (d *Deduper) link(ctx context.Context, q *database.Queries, wsID, id, canonical int64) error {
+	err := q.RetargetDuplicates(ctx, database.RetargetDuplicatesParams{WsID: wsID, OldTargetID: id, CanonicalID: canonical})
+	if err != nil {
+		return err
+	}
+	err = q.DeleteDuplicateRelations(ctx, database.DeleteDuplicateRelationsParams{WsID: wsID, ObjectID: id})
+	if err != nil {
+		return err
+	}
+
+	relID, err := d.cfg.IDs.Generate(ctx)
+	if err != nil {
+		return err
+	}
+	return q.CreateRelation(ctx, database.CreateRelationParams{
+		ID:       relID,
+		WsID:     wsID,
+		ObjectID: id,
+		Relation: database.RelationTypeDUPLICATE,
+		TargetID: canonical,
+	})
+}
+
+// Canonical returns the canonical document of id, which is id itself if it is not a duplicate.
+func (d *Deduper) Canonical(ctx context.Context, wsID, id int64) (int64, error) {
+	target, err := d.q.GetDuplicateTarget(ctx, database.GetDuplicateTargetParams{WsID: wsID, ObjectID: id})
+	if errors.Is(err, pgx.ErrNoRows) {
+		return id, nil
+	}
+	return target, err
+}
+
+// Duplicates returns the documents linked to the canonical document id.
+func (d *Deduper) Duplicates(ctx context.Context, wsID, id int64) ([]int64, error) {
+	return d.q.ListDuplicates(ctx, database.ListDuplicatesParams{WsID: wsID, TargetID: id})
+}
+
+// Targets returns the canonical documents of the duplicates in ids.
+// Documents that are not duplicates are left out.
+func (d *Deduper) Targets(ctx context.Context, wsID int64, ids []int64) (map[int64]int64, error) {
+	targets := make(map[int64]int64)
+	if len(ids) == 0 {
+		return targets, nil
+	}
+
+	rows, err := d.q.ListDuplicateTargets(ctx, database.ListDuplicateTargetsParams{WsID: wsID, ObjectIds: ids})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, row := range rows {
+		targets[row.ObjectID] = row.TargetID
+	}
+	return targets, nil
+}
+
+// Collapse replaces the duplicates in ids with their canonical documents and
+// removes repeated ids, keeping the order of the first occurrences.
+// Search results are collapsed so each cluster is returned once.
+func (d *Deduper) Collapse(ctx context.Context, wsID int64, ids []int64) ([]int64, error) {
+	if len(ids) == 0 {
+		return ids, nil
+	}
+
+	targets, err := d.Targets(ctx, wsID, ids)
+	if err != nil {
+		return nil, err
+	}
+	return collapse(ids, targets), nil
+}
+
+func collapse(ids []int64, targets map[int64]int64) []int64 {
+	out := make([]int64, 0, len(ids))
+	seen := make(map[int64]bool, len(ids))
+	for _, id := range ids {
+		if t, ok := targets[id]; ok {
+			id = t
+		}
+		if !seen[id] {
+			seen[id] = true
+			out = append(out, id)
+		}
+	}
+	return out
+}
@@ -0,0 +1,41 @@
+package dedup
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestPreferred(t *testing.T) {
+	old := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name string
+		a, b candidate
+	}{
+		{"desktop over mobile", candidate{id: 2, url: "https://example.com/post/long-title"}, candidate{id: 1, url: "https://m.example.com/post"}},
+		{"canonical over amp", candidate{id: 2, url: "https://example.com/post/long-title"}, candidate{id: 1, url: "https://example.com/post/amp"}},
+		{"https over http", candidate{id: 2, url: "https://example.com/post/long"}, candidate{id: 1, url: "http://example.com/post"}},
+		{"shorter url", candidate{id: 2, url: "https://example.com/post"}, candidate{id: 1, url: "https://example.com/post?page=1"}},
+		{"older document", candidate{id: 2, url: "https://example.com/a", createdAt: old}, candidate{id: 1, url: "https://example.com/b", createdAt: old.Add(time.Hour)}},
+		{"lower id", candidate{id: 1, url: "https://example.com/a"}, candidate{id: 2, url: "https://example.com/b"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if !preferred(tt.a, tt.b) {
+				t.Errorf("preferred(%s, %s) = false, want true", tt.a.url, tt.b.url)
+			}
+			if preferred(tt.b, tt.a) {
+				t.Errorf("preferred(%s, %s) = true, want false", tt.b.url, tt.a.url)
+			}
+		})
+	}
+}
+
+func TestCollapse(t *testing.T) {
+	got := collapse([]int64{5, 3, 7, 1, 9}, map[int64]int64{3: 1, 7: 5, 9: 2})
+	want := []int64{5, 1, 2}
+	if !slices.Equal(got, want) {
+		t.Errorf("collapse() = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,83 @@
+package dedup
+
+import (
+	"hash/fnv"
+	"math/bits"
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+const (
+	_SHINGLE_SIZE = 3
+	// _MIN_TOKENS is the number of words below which documents are not fingerprinted,
+	// short pages share too much boilerplate to be compared.
+	_MIN_TOKENS = 20
+	_BANDS      = 4
+)
+
+var (
+	markdownLink = regexp.MustCompile(`\]\([^)]*\)`)
+	bareURL      = regexp.MustCompile(`https?://\S+`)
+)
+
+// tokens returns the lowercased words of markdown without link targets and syntax.
+func tokens(markdown string) []string {
+	markdown = markdownLink.ReplaceAllString(markdown, "]")
+	markdown = bareURL.ReplaceAllString(markdown, " ")
+
+	return strings.FieldsFunc(strings.ToLower(markdown), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// SimHash returns the 64 bit SimHash of the word shingles of markdown.
+// Similar documents have fingerprints within a small Hamming distance.
+// Documents with fewer than 20 words have no fingerprint and 0 is returned.
+func SimHash(markdown string) uint64 {
+	words := tokens(markdown)
+	if len(words) < _MIN_TOKENS {
+		return 0
+	}
+
+	var weights [64]int
+	h := fnv.New64a()
+	for i := 0; i+_SHINGLE_SIZE <= len(words); i++ {
+		h.Reset()
+		for _, t := range words[i : i+_SHINGLE_SIZE] {
+			h.Write([]byte(t))
+			h.Write([]byte{0})
+		}
+		sum := h.Sum64()
+		for b := range weights {
+			if sum&(1<<uint(b)) != 0 {
+				weights[b]++
+			} else {
+				weights[b]--
+			}
+		}
+	}
+
+	var fp uint64
+	for b, w := range weights {
+		if w > 0 {
+			fp |= 1 << uint(b)
+		}
+	}
+	return fp
+}
+
+// Distance returns the Hamming distance between two fingerprints.
+func Distance(a, b uint64) int {
+	return bits.OnesCount64(a ^ b)
+}
+
+// Bands splits a fingerprint into four 16 bit bands.
+// Fingerprints within a distance of 3 share at least one band.
+func Bands(fp uint64) [_BANDS]int32 {
+	var bands [_BANDS]int32
+	for i := range bands {
+		bands[i] = int32(fp >> (16 * uint(i)) & 0xffff)
+	}
+	return bands
+}
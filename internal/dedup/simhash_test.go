@@ -0,0 +1,72 @@
+package dedup
+
+import (
+	"strings"
+	"testing"
+)
+
+const article = `# Release notes
+
+The new release improves the crawler, adds scheduled recrawls with conditional requests,
+stores every version of a document and checks the links of crawled pages for link rot.
+Feeds advertised by pages are suggested to the workspace. See [the changelog](https://example.com/changelog) for details.`
+
+func TestSimHash(t *testing.T) {
+	fp := SimHash(article)
+	if fp == 0 {
+		t.Fatal("SimHash() = 0")
+	}
+	if got := SimHash(article); got != fp {
+		t.Errorf("SimHash() = %x, want %x", got, fp)
+	}
+
+	// link targets and formatting do not change the fingerprint
+	reformatted := strings.ReplaceAll(article, "https://example.com/changelog", "https://example.com/changelog?utm_source=feed")
+	reformatted = strings.ReplaceAll(reformatted, "# Release notes", "**RELEASE NOTES**")
+	if got := SimHash(reformatted); got != fp {
+		t.Errorf("SimHash(reformatted) = %x, want %x", got, fp)
+	}
+
+	edited := strings.Replace(article, "checks the links", "verifies the links", 1)
+	if d := Distance(SimHash(edited), fp); d > 16 {
+		t.Errorf("Distance(edited) = %d, want a small distance", d)
+	}
+
+	other := `Cooking pasta takes a large pot of salted boiling water, a few minutes of patience
+and a colander. Stir now and then so the noodles do not stick together, taste one before draining
+and keep a cup of the starchy water for the sauce you will make afterwards with garlic and oil.`
+	if d := Distance(SimHash(other), fp); d < 10 {
+		t.Errorf("Distance(other) = %d, want a large distance", d)
+	}
+}
+
+func TestSimHashShort(t *testing.T) {
+	if got := SimHash("Page not found"); got != 0 {
+		t.Errorf("SimHash() = %x, want 0", got)
+	}
+}
+
+func TestBands(t *testing.T) {
+	fp := uint64(0x0123456789abcdef)
+	bands := Bands(fp)
+	want := [4]int32{0xcdef, 0x89ab, 0x4567, 0x0123}
+	if bands != want {
+		t.Fatalf("Bands() = %x, want %x", bands, want)
+	}
+
+	// any three flipped bits leave one band intact
+	for _, flips := range [][]uint{{0, 16, 32}, {15, 31, 63}, {1, 2, 3}, {17, 40, 60}} {
+		other := fp
+		for _, b := range flips {
+			other ^= 1 << b
+		}
+		ob := Bands(other)
+		shared := false
+		for i := range bands {
+			shared = shared || bands[i] == ob[i]
+		}
+		if !shared {
+			t.Errorf("Bands() with bits %v flipped share no band", flips)
+		}
+	}
+}
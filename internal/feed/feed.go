@@ -0,0 +1,126 @@
+package feed
+
+import (
+	"context"
+	"errors"
+
+	"github.com/jackc/pgx/v5"
+	"gosuda.org/jimin/database"
+	"gosuda.org/jimin/internal/convert"
+)
+
+var ErrNotFound = errors.New("feed: not found")
+
+type IDGenerator interface {
+	Generate(ctx context.Context) (int64, error)
+}
+
+// Registry keeps the feed suggestions and subscriptions of workspaces.
+type Registry struct {
+	q   *database.Queries
+	ids IDGenerator
+}
+
+func NewRegistry(db database.DBTX, ids IDGenerator) *Registry {
+	return &Registry{q: database.New(db), ids: ids}
+}
+
+// Discovered records the feeds advertised by page as suggestions for the workspace.
+// If subscribe is set, new and suggested feeds are subscribed instead.
+// Feeds dismissed by the workspace are not suggested again.
+func (r *Registry) Discovered(ctx context.Context, wsID int64, page string, feeds []convert.Feed, subscribe bool) ([]database.Feed, error) {
+	status := database.FeedStatusSUGGESTED
+	if subscribe {
+		status = database.FeedStatusSUBSCRIBED
+	}
+
+	var out []database.Feed
+	for _, f := range feeds {
+		feed, err := r.q.GetFeedByURL(ctx, database.GetFeedByURLParams{WsID: wsID, Url: f.URL})
+		switch {
+		case errors.Is(err, pgx.ErrNoRows):
+			feed, err = r.create(ctx, wsID, page, f, status)
+		case err == nil && subscribe && feed.Status == database.FeedStatusSUGGESTED:
+			feed, err = r.q.SetFeedStatus(ctx, database.SetFeedStatusParams{ID: feed.ID, Status: status})
+		}
+		if err != nil {
+			return out, err
+		}
+		out = append(out, feed)
+	}
+
+	return out, nil
+}
+
+func (r *Registry) create(ctx context.Context, wsID int64, page string, f convert.Feed, status database.FeedStatus) (database.Feed, error) {
+	id, err := r.ids.Generate(ctx)
+	if err != nil {
+		return database.Feed{}, err
+	}
+
+	feed, err := r.q.CreateFeed(ctx, database.CreateFeedParams{
+		ID:             id,
+		WsID:           wsID,
+		Url:            f.URL,
+		Title:          f.Title,
+		Type:           string(f.Type),
+		Status:         status,
+		DiscoveredFrom: page,
+	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		// discovered concurrently
+		return r.q.GetFeedByURL(ctx, database.GetFeedByURLParams{WsID: wsID, Url: f.URL})
+	}
+	return feed, err
+}
+
+// Suggestions returns the feeds suggested to the workspace.
+func (r *Registry) Suggestions(ctx context.Context, wsID int64) ([]database.Feed, error) {
+	return r.q.ListFeeds(ctx, database.ListFeedsParams{WsID: wsID, Status: database.FeedStatusSUGGESTED})
+}
+
+// Subscriptions returns the feeds the workspace is subscribed to.
+func (r *Registry) Subscriptions(ctx context.Context, wsID int64) ([]database.Feed, error) {
+	return r.q.ListFeeds(ctx, database.ListFeedsParams{WsID: wsID, Status: database.FeedStatusSUBSCRIBED})
+}
+
+// List returns the feeds of the workspace with status,
+// or its suggestions followed by its subscriptions if status is empty.
+func (r *Registry) List(ctx context.Context, wsID int64, status database.FeedStatus) ([]database.Feed, error) {
+	if status != "" {
+		return r.q.ListFeeds(ctx, database.ListFeedsParams{WsID: wsID, Status: status})
+	}
+
+	suggested, err := r.Suggestions(ctx, wsID)
+	if err != nil {
+		return nil, err
+	}
+	subscribed, err := r.Subscriptions(ctx, wsID)
+	if err != nil {
+		return nil, err
+	}
+	return append(suggested, subscribed...), nil
+}
+
+// Subscribe subscribes the workspace to a feed for a user, or for no user if userID is zero.
+// The feed is fetched shortly after, and then as long as the user may add documents to the workspace.
+func (r *Registry) Subscribe(ctx context.Context, wsID, userID, id int64) (database.Feed, error) {
+	return r.setStatus(ctx, wsID, id, database.FeedStatusSUBSCRIBED, userID)
+}
+
+// Dismiss dismisses a suggested feed or cancels a subscription.
+func (r *Registry) Dismiss(ctx context.Context, wsID, id int64) (database.Feed, error) {
+	return r.setStatus(ctx, wsID, id, database.FeedStatusDISMISSED, 0)
+}
+
+func (r *Registry) setStatus(ctx context.Context, wsID, id int64, status database.FeedStatus, userID int64) (database.Feed, error) {
+	feed, err := r.q.GetFeed(ctx, id)
+	if errors.Is(err, pgx.ErrNoRows) || (err == nil && feed.WsID != wsID) {
+		return database.Feed{}, ErrNotFound
+	}
+	if err != nil {
+		return database.Feed{}, err
+	}
+
+	return r.q.SetFeedStatus(ctx, database.SetFeedStatusParams{ID: id, Status: status, SubscribedBy: userID})
+}
@@ -0,0 +1,156 @@
+package feed
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"net/url"
+	"strings"
+
+	"golang.org/x/net/html/charset"
+)
+
+var ErrInvalidFeed = errors.New("feed: not an RSS, Atom or JSON feed")
+
+// Entry is an item of a feed.
+type Entry struct {
+	URL   string
+	Title string
+}
+
+type xmlFeed struct {
+	XMLName xml.Name
+	// RSS 2.0
+	Channel struct {
+		Items []xmlEntry `xml:"item"`
+	} `xml:"channel"`
+	// RSS 1.0 items are siblings of the channel
+	Items []xmlEntry `xml:"item"`
+	// Atom
+	Entries []xmlEntry `xml:"entry"`
+}
+
+type xmlEntry struct {
+	Title string    `xml:"title"`
+	Links []xmlLink `xml:"link"`
+	GUID  struct {
+		Value       string `xml:",chardata"`
+		IsPermaLink string `xml:"isPermaLink,attr"`
+	} `xml:"guid"`
+}
+
+// xmlLink is the text of an RSS link or the href of an Atom link.
+type xmlLink struct {
+	Href  string `xml:"href,attr"`
+	Rel   string `xml:"rel,attr"`
+	Value string `xml:",chardata"`
+}
+
+type jsonFeed struct {
+	Version string `json:"version"`
+	Items   []struct {
+		URL         string `json:"url"`
+		ExternalURL string `json:"external_url"`
+		Title       string `json:"title"`
+	} `json:"items"`
+}
+
+// Parse returns the entries of an RSS, Atom or JSON feed, in the order of the feed.
+// Entry URLs are resolved against feedURL, and entries without an http or https URL are left out.
+func Parse(feedURL string, body []byte) ([]Entry, error) {
+	base, err := url.Parse(feedURL)
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []Entry
+	if trimmed := bytes.TrimSpace(body); bytes.HasPrefix(trimmed, []byte("{")) {
+		entries, err = parseJSON(trimmed)
+	} else {
+		entries, err = parseXML(body)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	out := entries[:0]
+	for _, e := range entries {
+		ref := strings.TrimSpace(e.URL)
+		if ref == "" {
+			continue
+		}
+		u, err := base.Parse(ref)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+			continue
+		}
+		u.Fragment = ""
+		out = append(out, Entry{URL: u.String(), Title: strings.TrimSpace(e.Title)})
+	}
+	return out, nil
+}
+
+func parseJSON(body []byte) ([]Entry, error) {
+	var f jsonFeed
+	if err := json.Unmarshal(body, &f); err != nil || !strings.HasPrefix(f.Version, "https://jsonfeed.org/version/") {
+		return nil, ErrInvalidFeed
+	}
+
+	entries := make([]Entry, 0, len(f.Items))
+	for _, item := range f.Items {
+		u := item.URL
+		if u == "" {
+			u = item.ExternalURL
+		}
+		entries = append(entries, Entry{URL: u, Title: item.Title})
+	}
+	return entries, nil
+}
+
+func parseXML(body []byte) ([]Entry, error) {
+	d := xml.NewDecoder(bytes.NewReader(body))
+	d.CharsetReader = charset.NewReaderLabel
+	d.Strict = false
+
+	var f xmlFeed
+	if err := d.Decode(&f); err != nil {
+		return nil, ErrInvalidFeed
+	}
+
+	var items []xmlEntry
+	switch f.XMLName.Local {
+	case "rss":
+		items = f.Channel.Items
+	case "RDF":
+		items = f.Items
+	case "feed":
+		items = f.Entries
+	default:
+		return nil, ErrInvalidFeed
+	}
+
+	entries := make([]Entry, 0, len(items))
+	for _, item := range items {
+		entries = append(entries, Entry{URL: item.link(), Title: item.Title})
+	}
+	return entries, nil
+}
+
+// link returns the alternate link of an Atom entry, the link of an RSS item,
+// or the guid of an RSS item without a link unless it is not a permalink.
+func (e xmlEntry) link() string {
+	for _, l := range e.Links {
+		if l.Href != "" && (l.Rel == "" || l.Rel == "alternate") {
+			return l.Href
+		}
+	}
+	for _, l := range e.Links {
+		if l.Href == "" && strings.TrimSpace(l.Value) != "" {
+			return l.Value
+		}
+	}
+	if e.GUID.IsPermaLink != "false" {
+		return e.GUID.Value
+	}
+	return ""
+}
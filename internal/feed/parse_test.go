@@ -0,0 +1,89 @@
+package feed
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+const rss2 = `<?xml version="1.0" encoding="UTF-8"?>
+<rss version="2.0" xmlns:atom="http://www.w3.org/2005/Atom">
+<channel>
+	<title>Blog</title>
+	<link>https://example.com/</link>
+	<atom:link href="https://example.com/feed.xml" rel="self" type="application/rss+xml"/>
+	<item><title> First </title><link>https://example.com/posts/1</link></item>
+	<item><title>Relative</title><link>/posts/2#comments</link></item>
+	<item><title>Permalink</title><guid>https://example.com/posts/3</guid></item>
+	<item><title>Not a permalink</title><guid isPermaLink="false">post-4</guid></item>
+	<item><title>Mail</title><link>mailto:editor@example.com</link></item>
+</channel>
+</rss>`
+
+const rss1 = `<?xml version="1.0"?>
+<rdf:RDF xmlns:rdf="http://www.w3.org/1999/02/22-rdf-syntax-ns#" xmlns="http://purl.org/rss/1.0/">
+<channel rdf:about="https://example.com/"><title>Blog</title></channel>
+<item rdf:about="https://example.com/posts/1"><title>First</title><link>https://example.com/posts/1</link></item>
+</rdf:RDF>`
+
+const atom = `<?xml version="1.0" encoding="ISO-8859-1"?>
+<feed xmlns="http://www.w3.org/2005/Atom">
+	<title>Blog</title>
+	<link href="https://example.com/"/>
+	<entry>
+		<title>Caf` + "\xe9" + `</title>
+		<link rel="replies" href="https://example.com/posts/1/comments"/>
+		<link rel="alternate" href="https://example.com/posts/1"/>
+	</entry>
+	<entry><title>Relative</title><link href="posts/2"/></entry>
+</feed>`
+
+const jsonFeed1 = `{
+	"version": "https://jsonfeed.org/version/1.1",
+	"title": "Blog",
+	"items": [
+		{"id": "1", "url": "https://example.com/posts/1", "title": "First"},
+		{"id": "2", "external_url": "https://other.example.com/article", "title": "Linked"},
+		{"id": "3", "content_text": "no url"}
+	]
+}`
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want []Entry
+		err  error
+	}{
+		{"rss 2.0", rss2, []Entry{
+			{URL: "https://example.com/posts/1", Title: "First"},
+			{URL: "https://example.com/posts/2", Title: "Relative"},
+			{URL: "https://example.com/posts/3", Title: "Permalink"},
+		}, nil},
+		{"rss 1.0", rss1, []Entry{{URL: "https://example.com/posts/1", Title: "First"}}, nil},
+		{"atom", atom, []Entry{
+			{URL: "https://example.com/posts/1", Title: "Café"},
+			{URL: "https://example.com/feeds/posts/2", Title: "Relative"},
+		}, nil},
+		{"json feed", jsonFeed1, []Entry{
+			{URL: "https://example.com/posts/1", Title: "First"},
+			{URL: "https://other.example.com/article", Title: "Linked"},
+		}, nil},
+		{"empty rss", `<rss version="2.0"><channel><title>Empty</title></channel></rss>`, []Entry{}, nil},
+		{"html", `<!DOCTYPE html><html><body>not a feed</body></html>`, nil, ErrInvalidFeed},
+		{"other xml", `<sitemap><url><loc>https://example.com/</loc></url></sitemap>`, nil, ErrInvalidFeed},
+		{"other json", `{"items": [{"url": "https://example.com/"}]}`, nil, ErrInvalidFeed},
+		{"garbage", "\x00\x01", nil, ErrInvalidFeed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Parse("https://example.com/feeds/main.xml", []byte(tt.body))
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("Parse() error = %v, want %v", err, tt.err)
+			}
+			if tt.err == nil && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Parse() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,271 @@
+package feed
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/rs/zerolog/log"
+	"gosuda.org/jimin/database"
+	"gosuda.org/jimin/internal/rbac"
+)
+
+const (
+	_DEFAULT_CONCURRENCY    = 4
+	_DEFAULT_BATCH_SIZE     = 64
+	_DEFAULT_POLL_INTERVAL  = time.Minute
+	_DEFAULT_INTERVAL       = time.Hour
+	_DEFAULT_RETRY_INTERVAL = time.Hour * 6
+	_DEFAULT_LEASE          = time.Minute * 10
+	_DEFAULT_MAX_ENTRIES    = 100
+	_DEFAULT_TIMEOUT        = time.Second * 30
+
+	_MAX_FEED_BYTES = 10 << 20
+)
+
+// Adder adds the pages linked by feed entries to the crawl.
+type Adder interface {
+	// Add returns the document of url, creating it if needed. The source is resolved from url if empty.
+	Add(ctx context.Context, wsID int64, source, url string) (database.Document, error)
+}
+
+// Authorizer checks that the user who subscribed to a feed may still add documents to its workspace.
+type Authorizer interface {
+	Authorize(ctx context.Context, userID, wsID int64, perm rbac.Permission) error
+}
+
+type PollerConfig struct {
+	DB    database.DBTX
+	Adder Adder
+	// Authz checks the feeds subscribed by users before each fetch. Without it, and for the feeds of no user, feeds are always fetched.
+	Authz  Authorizer
+	Client *http.Client
+	// Interval is the time between fetches of a subscribed feed.
+	Interval time.Duration
+	// RetryInterval is the time before a feed that failed to fetch is fetched again.
+	RetryInterval time.Duration
+	// Lease is how long a claimed batch is held before other pollers may claim its feeds again.
+	Lease time.Duration
+	// MaxEntries is the number of the first entries of a feed added on each fetch.
+	MaxEntries int
+
+	Concurrency  int
+	BatchSize    int
+	PollInterval time.Duration
+}
+
+// Poller periodically fetches the subscribed feeds and adds the pages of their entries
+// to the workspaces, so the pages are crawled and indexed like any other document.
+type Poller struct {
+	cfg PollerConfig
+	q   *database.Queries
+}
+
+func NewPoller(cfg PollerConfig) *Poller {
+	if cfg.Client == nil {
+		cfg.Client = &http.Client{Timeout: _DEFAULT_TIMEOUT}
+	}
+	if cfg.Interval <= 0 {
+		cfg.Interval = _DEFAULT_INTERVAL
+	}
+	if cfg.RetryInterval <= 0 {
+		cfg.RetryInterval = _DEFAULT_RETRY_INTERVAL
+	}
+	if cfg.Lease <= 0 {
+		cfg.Lease = _DEFAULT_LEASE
+	}
+	if cfg.MaxEntries <= 0 {
+		cfg.MaxEntries = _DEFAULT_MAX_ENTRIES
+	}
+	if cfg.Concurrency <= 0 {
+		cfg.Concurrency = _DEFAULT_CONCURRENCY
+	}
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = _DEFAULT_BATCH_SIZE
+	}
+	if cfg.PollInterval <= 0 {
+		cfg.PollInterval = _DEFAULT_POLL_INTERVAL
+	}
+	return &Poller{cfg: cfg, q: database.New(cfg.DB)}
+}
+
+func timestamptz(t time.Time) pgtype.Timestamptz {
+	return pgtype.Timestamptz{Time: t, Valid: true}
+}
+
+// Run fetches the due feeds until ctx is canceled.
+func (p *Poller) Run(ctx context.Context) error {
+	ticker := time.NewTicker(p.cfg.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		for {
+			n, err := p.RunOnce(ctx)
+			if err != nil {
+				if ctx.Err() != nil {
+					return ctx.Err()
+				}
+				log.Error().Err(err).Msg("feed: failed to claim due feeds")
+			}
+			if n < p.cfg.BatchSize {
+				break
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// RunOnce claims a batch of due subscribed feeds, fetches them and returns the number of feeds fetched.
+func (p *Poller) RunOnce(ctx context.Context) (int, error) {
+	now := time.Now()
+	feeds, err := p.q.ClaimDueFeeds(ctx, database.ClaimDueFeedsParams{
+		LeaseUntil:  timestamptz(now.Add(p.cfg.Lease)),
+		NextFetchAt: timestamptz(now),
+		PageSize:    int32(p.cfg.BatchSize),
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	sema := make(chan struct{}, p.cfg.Concurrency)
+	var wg sync.WaitGroup
+	for _, f := range feeds {
+		sema <- struct{}{}
+		wg.Add(1)
+		go func(f database.Feed) {
+			defer func() {
+				<-sema
+				wg.Done()
+			}()
+
+			if err := p.Poll(ctx, f); err != nil && ctx.Err() == nil {
+				log.Warn().Err(err).Int64("feed", f.ID).Str("url", f.Url).Msg("feed: failed to record feed fetch")
+			}
+		}(f)
+	}
+	wg.Wait()
+
+	return len(feeds), nil
+}
+
+// Poll fetches f, adds the pages of its entries to its workspace and schedules its next fetch.
+// Failed fetches are recorded on the feed and retried after the retry interval.
+// A feed whose subscriber may no longer add documents is unsubscribed and suggested again instead.
+func (p *Poller) Poll(ctx context.Context, f database.Feed) error {
+	now := time.Now()
+	err := p.authorize(ctx, f)
+	if errors.Is(err, rbac.ErrNotMember) || errors.Is(err, rbac.ErrForbidden) {
+		log.Warn().Int64("feed", f.ID).Int64("user", f.SubscribedBy).Msg("feed: subscriber may no longer add documents")
+		_, err = p.q.SetFeedStatus(ctx, database.SetFeedStatusParams{ID: f.ID, Status: database.FeedStatusSUGGESTED})
+		return err
+	}
+
+	var res fetchResult
+	if err == nil {
+		res, err = fetch(ctx, p.cfg.Client, f)
+	}
+	if err == nil {
+		err = p.add(ctx, f, res.entries)
+	}
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+
+	params := database.UpdateFeedFetchParams{
+		ID:            f.ID,
+		Etag:          res.etag,
+		LastModified:  res.lastModified,
+		LastFetchedAt: timestamptz(now),
+		NextFetchAt:   timestamptz(now.Add(p.cfg.Interval)),
+	}
+	if err != nil {
+		params.Etag, params.LastModified = f.Etag, f.LastModified
+		params.FetchError = err.Error()
+		params.NextFetchAt = timestamptz(now.Add(p.cfg.RetryInterval))
+	}
+	return p.q.UpdateFeedFetch(ctx, params)
+}
+
+// authorize checks the subscriber of f on every fetch, so that a subscription does not outlive the permission.
+func (p *Poller) authorize(ctx context.Context, f database.Feed) error {
+	if p.cfg.Authz == nil || f.SubscribedBy == 0 {
+		return nil
+	}
+	return p.cfg.Authz.Authorize(ctx, f.SubscribedBy, f.WsID, rbac.AddDocuments)
+}
+
+// add adds the pages of the first entries to the workspace of f.
+// Pages already in the workspace are left as they are.
+func (p *Poller) add(ctx context.Context, f database.Feed, entries []Entry) error {
+	if len(entries) > p.cfg.MaxEntries {
+		entries = entries[:p.cfg.MaxEntries]
+	}
+	for _, e := range entries {
+		if _, err := p.cfg.Adder.Add(ctx, f.WsID, "", e.URL); err != nil {
+			return fmt.Errorf("feed: failed to add %s: %w", e.URL, err)
+		}
+	}
+	return nil
+}
+
+type fetchResult struct {
+	entries      []Entry
+	etag         string
+	lastModified string
+}
+
+// fetch requests f conditionally on the validators of its last fetch.
+// An unmodified feed has no entries and keeps its validators.
+func fetch(ctx context.Context, client *http.Client, f database.Feed) (fetchResult, error) {
+	res := fetchResult{etag: f.Etag, lastModified: f.LastModified}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, f.Url, nil)
+	if err != nil {
+		return res, err
+	}
+	req.Header.Set("Accept", "application/rss+xml, application/atom+xml, application/feed+json, application/xml;q=0.9, */*;q=0.8")
+	if f.Etag != "" {
+		req.Header.Set("If-None-Match", f.Etag)
+	}
+	if f.LastModified != "" {
+		req.Header.Set("If-Modified-Since", f.LastModified)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return res, err
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusNotModified:
+		return res, nil
+	case resp.StatusCode != http.StatusOK:
+		return res, fmt.Errorf("feed: unexpected status %s", resp.Status)
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, _MAX_FEED_BYTES))
+	if err != nil {
+		return res, err
+	}
+	entries, err := Parse(resp.Request.URL.String(), body)
+	if err != nil {
+		return res, err
+	}
+
+	return fetchResult{
+		entries:      entries,
+		etag:         resp.Header.Get("ETag"),
+		lastModified: resp.Header.Get("Last-Modified"),
+	}, nil
+}
@@ -0,0 +1,195 @@
+package feed
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgtype"
+	"gosuda.org/jimin/database"
+	"gosuda.org/jimin/internal/rbac"
+)
+
+// fetchDB records the fetch updates and the status changes of the poller.
+type fetchDB struct {
+	updates  [][]any
+	statuses [][]any
+}
+
+func (db *fetchDB) Exec(_ context.Context, _ string, args ...any) (pgconn.CommandTag, error) {
+	db.updates = append(db.updates, args)
+	return pgconn.CommandTag{}, nil
+}
+
+func (db *fetchDB) Query(context.Context, string, ...any) (pgx.Rows, error) {
+	return nil, errors.New("unexpected query")
+}
+
+func (db *fetchDB) QueryRow(_ context.Context, _ string, args ...any) pgx.Row {
+	db.statuses = append(db.statuses, args)
+	return statusRow{}
+}
+
+type statusRow struct{}
+
+func (statusRow) Scan(...any) error { return nil }
+
+// fakeAuthz lets user 3 add documents to workspace 2 unless err is set.
+type fakeAuthz struct {
+	err error
+}
+
+func (a fakeAuthz) Authorize(ctx context.Context, userID, wsID int64, perm rbac.Permission) error {
+	if userID != 3 || wsID != 2 || perm != rbac.AddDocuments {
+		return rbac.ErrNotMember
+	}
+	return a.err
+}
+
+type fakeAdder struct {
+	urls []string
+	err  error
+}
+
+func (a *fakeAdder) Add(_ context.Context, wsID int64, source, url string) (database.Document, error) {
+	if a.err != nil {
+		return database.Document{}, a.err
+	}
+	a.urls = append(a.urls, url)
+	return database.Document{WsID: wsID, Source: source, Url: url}, nil
+}
+
+func TestPoll(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/feed.xml":
+			if r.Header.Get("If-None-Match") == `"v1"` {
+				w.WriteHeader(http.StatusNotModified)
+				return
+			}
+			w.Header().Set("ETag", `"v1"`)
+			w.Header().Set("Last-Modified", "Mon, 19 Oct 2026 12:00:00 GMT")
+			w.Header().Set("Content-Type", "application/rss+xml")
+			w.Write([]byte(`<rss version="2.0"><channel>
+				<item><link>/posts/1</link></item>
+				<item><link>/posts/2</link></item>
+				<item><link>/posts/3</link></item>
+			</channel></rss>`))
+		case "/page.html":
+			w.Write([]byte("<html></html>"))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	tests := []struct {
+		name      string
+		feed      database.Feed
+		addErr    error
+		want      []string
+		etag      string
+		fetchErr  string
+		nextAfter time.Duration
+	}{
+		{"new entries", database.Feed{Url: srv.URL + "/feed.xml"},
+			nil, []string{srv.URL + "/posts/1", srv.URL + "/posts/2"}, `"v1"`, "", time.Hour},
+		{"not modified", database.Feed{Url: srv.URL + "/feed.xml", Etag: `"v1"`},
+			nil, nil, `"v1"`, "", time.Hour},
+		{"not a feed", database.Feed{Url: srv.URL + "/page.html", Etag: `"v0"`},
+			nil, nil, `"v0"`, ErrInvalidFeed.Error(), time.Hour * 6},
+		{"missing", database.Feed{Url: srv.URL + "/gone.xml"},
+			nil, nil, "", "404", time.Hour * 6},
+		{"add failure", database.Feed{Url: srv.URL + "/feed.xml"},
+			errors.New("database is down"), nil, "", "database is down", time.Hour * 6},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := &fetchDB{}
+			adder := &fakeAdder{err: tt.addErr}
+			p := NewPoller(PollerConfig{DB: db, Adder: adder, MaxEntries: 2})
+
+			start := time.Now()
+			tt.feed.ID, tt.feed.WsID = 1, 2
+			if err := p.Poll(context.Background(), tt.feed); err != nil {
+				t.Fatalf("Poll() error = %v", err)
+			}
+			if !reflect.DeepEqual(adder.urls, tt.want) {
+				t.Errorf("Poll() added %v, want %v", adder.urls, tt.want)
+			}
+			if len(db.updates) != 1 {
+				t.Fatalf("Poll() recorded %d fetches, want 1", len(db.updates))
+			}
+
+			args := db.updates[0]
+			id, etag, fetchErr, next := args[0].(int64), args[1].(string), args[3].(string), args[5].(pgtype.Timestamptz).Time
+			if id != tt.feed.ID || etag != tt.etag {
+				t.Errorf("Poll() recorded feed %d with etag %q, want feed %d with %q", id, etag, tt.feed.ID, tt.etag)
+			}
+			if (tt.fetchErr == "") != (fetchErr == "") || !strings.Contains(fetchErr, tt.fetchErr) {
+				t.Errorf("Poll() recorded error %q, want %q", fetchErr, tt.fetchErr)
+			}
+			if next.Before(start.Add(tt.nextAfter)) || next.After(time.Now().Add(tt.nextAfter)) {
+				t.Errorf("Poll() scheduled the next fetch at %v, want %v later", next, tt.nextAfter)
+			}
+		})
+	}
+}
+
+func TestPollAuthorizes(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`<rss version="2.0"><channel><item><link>/posts/1</link></item></channel></rss>`))
+	}))
+	defer srv.Close()
+
+	errDB := errors.New("connection lost")
+	tests := []struct {
+		name         string
+		subscribedBy int64
+		authz        error
+		added        bool
+		fetchErr     string
+		suggested    bool
+	}{
+		{"subscriber", 3, nil, true, "", false},
+		{"former member", 4, nil, false, "", true},
+		{"demoted member", 3, rbac.ErrForbidden, false, "", true},
+		{"no subscriber", 0, rbac.ErrForbidden, true, "", false},
+		{"authorization failure", 3, errDB, false, errDB.Error(), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := &fetchDB{}
+			adder := &fakeAdder{}
+			p := NewPoller(PollerConfig{DB: db, Adder: adder, Authz: fakeAuthz{err: tt.authz}})
+
+			f := database.Feed{ID: 1, WsID: 2, Url: srv.URL, Status: database.FeedStatusSUBSCRIBED, SubscribedBy: tt.subscribedBy}
+			if err := p.Poll(context.Background(), f); err != nil {
+				t.Fatalf("Poll() error = %v", err)
+			}
+			if added := len(adder.urls) > 0; added != tt.added {
+				t.Errorf("Poll() added %v, want added %v", adder.urls, tt.added)
+			}
+
+			if tt.suggested {
+				if len(db.updates) != 0 || len(db.statuses) != 1 || db.statuses[0][1] != database.FeedStatusSUGGESTED || db.statuses[0][2] != int64(0) {
+					t.Errorf("Poll() recorded fetches %v and statuses %v, want the feed suggested again", db.updates, db.statuses)
+				}
+				return
+			}
+			if len(db.updates) != 1 || len(db.statuses) != 0 {
+				t.Fatalf("Poll() recorded fetches %v and statuses %v, want one fetch", db.updates, db.statuses)
+			}
+			if fetchErr := db.updates[0][3].(string); fetchErr != tt.fetchErr {
+				t.Errorf("Poll() recorded error %q, want %q", fetchErr, tt.fetchErr)
+			}
+		})
+	}
+}
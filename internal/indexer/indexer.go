@@ -1,5 +1,172 @@
 package indexer
 
-func SplitChunk() {
-	
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	_DEFAULT_CHUNK_SIZE    = 2000
+	_DEFAULT_CHUNK_OVERLAP = 200
+)
+
+// Chunk is a part of a markdown document.
+// Start and End are the byte offsets of the chunk in the document, including the overlap.
+type Chunk struct {
+	Heading string `json:"heading,omitempty"`
+	Content string `json:"content"`
+	Start   int    `json:"start"`
+	End     int    `json:"end"`
+}
+
+type block struct {
+	heading    string
+	start, end int
+}
+
+// SplitChunk splits markdown into chunks of at most size bytes along paragraphs.
+// A chunk starts with up to overlap bytes of the end of the previous chunk
+// and records the closest heading above it. Paragraphs larger than size are split at line
+// and word boundaries. size <= 0 and overlap < 0 select the defaults.
+func SplitChunk(markdown string, size, overlap int) []Chunk {
+	if size <= 0 {
+		size = _DEFAULT_CHUNK_SIZE
+	}
+	if overlap < 0 {
+		overlap = _DEFAULT_CHUNK_OVERLAP
+	}
+	if overlap >= size/2 {
+		overlap = size / 2
+	}
+
+	var chunks []Chunk
+	var cur []block
+	flush := func() {
+		if len(cur) == 0 {
+			return
+		}
+		start, end := cur[0].start, cur[len(cur)-1].end
+		// sections start fresh, other chunks continue the previous one
+		if len(chunks) > 0 && overlap > 0 && !isHeading(markdown[cur[0].start:cur[0].end]) {
+			start = overlapStart(markdown, chunks[len(chunks)-1].Start, start, overlap)
+		}
+		chunks = append(chunks, Chunk{
+			Heading: cur[0].heading,
+			Content: strings.TrimSpace(markdown[start:end]),
+			Start:   start,
+			End:     end,
+		})
+		cur = cur[:0]
+	}
+
+	for _, b := range blocks(markdown, size) {
+		if len(cur) > 0 && (b.end-cur[0].start > size || isHeading(markdown[b.start:b.end])) {
+			flush()
+		}
+		cur = append(cur, b)
+	}
+	flush()
+
+	return chunks
+}
+
+// overlapStart returns the offset of the overlap of a chunk starting at start
+// with the previous chunk starting at prevStart, aligned to a word boundary.
+func overlapStart(markdown string, prevStart, start, overlap int) int {
+	o := start - overlap
+	if o < prevStart {
+		o = prevStart
+	}
+	if i := strings.IndexAny(markdown[o:start], " \n"); i >= 0 {
+		return o + i + 1
+	}
+	return start
+}
+
+func isHeading(s string) bool {
+	s = strings.TrimLeft(s, " ")
+	n := 0
+	for n < len(s) && s[n] == '#' {
+		n++
+	}
+	return n > 0 && n <= 6 && (n == len(s) || s[n] == ' ')
+}
+
+// blocks returns the paragraphs of markdown, split further to fit size.
+// Fenced code blocks are kept together unless they exceed size.
+func blocks(markdown string, size int) []block {
+	var out []block
+	var heading string
+	start := -1
+	fenced := false
+
+	add := func(end int) {
+		if start < 0 {
+			return
+		}
+		for _, b := range splitBlock(markdown, start, end, size) {
+			b.heading = heading
+			out = append(out, b)
+		}
+		start = -1
+	}
+
+	pos := 0
+	for pos < len(markdown) {
+		end := strings.IndexByte(markdown[pos:], '\n')
+		if end < 0 {
+			end = len(markdown)
+		} else {
+			end += pos
+		}
+		line := markdown[pos:end]
+		trimmed := strings.TrimSpace(line)
+
+		switch {
+		case strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~"):
+			if start < 0 {
+				start = pos
+			}
+			fenced = !fenced
+		case fenced:
+		case trimmed == "":
+			add(pos)
+		case isHeading(line):
+			add(pos)
+			heading = strings.TrimSpace(strings.TrimLeft(trimmed, "#"))
+			start = pos
+			add(end)
+		default:
+			if start < 0 {
+				start = pos
+			}
+		}
+
+		pos = end + 1
+	}
+	add(len(markdown))
+
+	return out
+}
+
+// splitBlock splits markdown[start:end] at line, then word boundaries into parts of at most size bytes.
+func splitBlock(markdown string, start, end, size int) []block {
+	var out []block
+	for end-start > size {
+		cut := start + size
+		for !utf8.RuneStart(markdown[cut]) {
+			cut--
+		}
+		if i := strings.LastIndexByte(markdown[start:cut], '\n'); i > 0 {
+			cut = start + i + 1
+		} else if i := strings.LastIndexByte(markdown[start:cut], ' '); i > 0 {
+			cut = start + i + 1
+		}
+		out = append(out, block{start: start, end: cut})
+		start = cut
+	}
+	if strings.TrimSpace(markdown[start:end]) != "" {
+		out = append(out, block{start: start, end: end})
+	}
+	return out
 }
@@ -0,0 +1,64 @@
+package indexer
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSplitChunk(t *testing.T) {
+	md := "# Intro\n\nFirst paragraph of the introduction.\n\nSecond paragraph.\n\n" +
+		"## Usage\n\n```go\nfunc main() {\n\n\tprintln(\"hi\")\n}\n```\n\nRun it.\n"
+
+	chunks := SplitChunk(md, 60, 0)
+	want := []struct{ heading, prefix string }{
+		{"Intro", "# Intro\n\nFirst paragraph"},
+		{"Intro", "Second paragraph."},
+		{"Usage", "## Usage\n\n```go"},
+		{"Usage", "Run it."},
+	}
+	if len(chunks) != len(want) {
+		t.Fatalf("SplitChunk() = %d chunks %q, want %d", len(chunks), chunks, len(want))
+	}
+	for i, w := range want {
+		c := chunks[i]
+		if c.Heading != w.heading || !strings.HasPrefix(c.Content, w.prefix) {
+			t.Errorf("chunk %d = %q (%q), want prefix %q (%q)", i, c.Content, c.Heading, w.prefix, w.heading)
+		}
+		if len(c.Content) > 60 {
+			t.Errorf("chunk %d has %d bytes, want at most 60", i, len(c.Content))
+		}
+		if strings.TrimSpace(md[c.Start:c.End]) != c.Content {
+			t.Errorf("chunk %d offsets [%d:%d] do not match its content", i, c.Start, c.End)
+		}
+	}
+
+	// the code block is not split at its blank line
+	if !strings.Contains(chunks[2].Content, "println") {
+		t.Errorf("code block was split: %q", chunks[2].Content)
+	}
+}
+
+func TestSplitChunkLongParagraph(t *testing.T) {
+	md := strings.Repeat("word ", 100)
+	chunks := SplitChunk(md, 100, 20)
+	if len(chunks) < 5 {
+		t.Fatalf("SplitChunk() = %d chunks, want at least 5", len(chunks))
+	}
+	for i, c := range chunks {
+		if len(md[c.Start:c.End]) > 120 {
+			t.Errorf("chunk %d has %d bytes, want at most size and overlap", i, c.End-c.Start)
+		}
+		if strings.HasPrefix(c.Content, "ord") {
+			t.Errorf("chunk %d starts inside a word: %q", i, c.Content)
+		}
+		if i > 0 && c.Start >= chunks[i-1].End {
+			t.Errorf("chunk %d does not overlap the previous chunk", i)
+		}
+	}
+}
+
+func TestSplitChunkEmpty(t *testing.T) {
+	if chunks := SplitChunk("\n\n  \n", 0, -1); len(chunks) != 0 {
+		t.Errorf("SplitChunk() = %q, want no chunks", chunks)
+	}
+}
@@ -0,0 +1,5 @@
+package indexer
+
+// Version is the version of the chunker.
+// It is incremented by changes that alter the chunks of existing documents, so they can be reprocessed.
+const Version = 1
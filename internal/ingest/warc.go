@@ -0,0 +1,118 @@
+package ingest
+
+import (
+	"bytes"
+	"io"
+	"mime"
+	"net/http"
+	"time"
+
+	"golang.org/x/net/html/charset"
+	"gosuda.org/jimin/internal/convert"
+	"gosuda.org/jimin/internal/warc"
+)
+
+// Document is a converted page ready for indexing.
+type Document struct {
+	URL       string    `json:"url"`
+	Markdown  string    `json:"markdown"`
+	FetchedAt time.Time `json:"fetched_at"`
+}
+
+// Page is an html page stored in a web archive.
+type Page struct {
+	URL       string
+	HTML      string
+	FetchedAt time.Time
+}
+
+// ImportWARC replays the html pages stored in a .warc, .warc.gz or .wacz file through the converter.
+// A rendered page stored as a conversion record replaces the raw response it refers to.
+func ImportWARC(name string, fn func(*Document) error, opts ...convert.Option) error {
+	return WalkPages(name, func(p *Page) error {
+		md, err := convert.ConvertHTMLToMarkdown(p.HTML, p.URL, opts...)
+		if err != nil {
+			return err
+		}
+		return fn(&Document{URL: p.URL, Markdown: md, FetchedAt: p.FetchedAt})
+	})
+}
+
+// WalkPages calls fn for each html page stored in a .warc, .warc.gz or .wacz file.
+// A rendered page stored as a conversion record replaces the raw response it refers to.
+func WalkPages(name string, fn func(*Page) error) error {
+	var pending *Page
+
+	emit := func() error {
+		if pending == nil {
+			return nil
+		}
+		p := pending
+		pending = nil
+		return fn(p)
+	}
+
+	err := warc.Walk(name, func(r *warc.Record) error {
+		page, ok := archivedHTML(r)
+		if !ok {
+			return nil
+		}
+
+		if r.Type() == warc.TypeConversion && pending != nil && pending.URL == page.URL {
+			pending = page
+			return nil
+		}
+
+		if err := emit(); err != nil {
+			return err
+		}
+		pending = page
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	return emit()
+}
+
+func archivedHTML(r *warc.Record) (*Page, bool) {
+	var contentType string
+	var body io.Reader
+
+	switch r.Type() {
+	case warc.TypeResponse:
+		resp, err := r.HTTPResponse()
+		if err != nil || resp.StatusCode != http.StatusOK {
+			return nil, false
+		}
+		defer resp.Body.Close()
+		contentType = resp.Header.Get("Content-Type")
+		body = resp.Body
+	case warc.TypeResource, warc.TypeConversion:
+		contentType = r.Header.Get("Content-Type")
+		body = bytes.NewReader(r.Block)
+	default:
+		return nil, false
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	if mediaType != "text/html" && mediaType != "application/xhtml+xml" {
+		return nil, false
+	}
+
+	decoded, err := charset.NewReader(body, contentType)
+	if err != nil {
+		return nil, false
+	}
+	html, err := io.ReadAll(decoded)
+	if err != nil {
+		return nil, false
+	}
+
+	return &Page{
+		URL:       r.TargetURI(),
+		HTML:      string(html),
+		FetchedAt: r.Date(),
+	}, true
+}
@@ -0,0 +1,48 @@
+package ingest
+
+import (
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"gosuda.org/jimin/internal/warc"
+)
+
+func TestImportWARC(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "test.warc.gz")
+	f, err := os.Create(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	w := warc.NewWriter(f, true)
+	header := http.Header{"Content-Type": {"text/html; charset=utf-8"}}
+	id, _ := w.WriteResponse("https://example.com/a", time.Now(), http.StatusOK, header, []byte("<p>raw</p>"))
+	w.WriteConversion("https://example.com/a", time.Now(), id, "text/html", []byte("<p>rendered</p>"))
+	w.WriteResponse("https://example.com/b", time.Now(), http.StatusOK, header, []byte("<p>second</p>"))
+	w.WriteResponse("https://example.com/c", time.Now(), http.StatusNotFound, header, []byte("<p>missing</p>"))
+	w.WriteResponse("https://example.com/d.png", time.Now(), http.StatusOK, http.Header{"Content-Type": {"image/png"}}, []byte("png"))
+	f.Close()
+
+	var docs []*Document
+	err = ImportWARC(name, func(d *Document) error {
+		docs = append(docs, d)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("ImportWARC() error = %v", err)
+	}
+
+	if len(docs) != 2 {
+		t.Fatalf("ImportWARC() imported %d documents, want 2", len(docs))
+	}
+	if docs[0].URL != "https://example.com/a" || strings.TrimSpace(docs[0].Markdown) != "rendered" {
+		t.Errorf("ImportWARC() first document = %q %q", docs[0].URL, docs[0].Markdown)
+	}
+	if docs[1].URL != "https://example.com/b" || strings.TrimSpace(docs[1].Markdown) != "second" {
+		t.Errorf("ImportWARC() second document = %q %q", docs[1].URL, docs[1].Markdown)
+	}
+}
@@ -0,0 +1,127 @@
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/rs/zerolog/log"
+)
+
+var ErrStopped = errors.New("lifecycle: group is stopped")
+
+type component struct {
+	name string
+	stop func(ctx context.Context) error
+}
+
+// Group tracks the started components of a process and stops them
+// in the reverse order of their start, so each component stops before the ones it depends on.
+type Group struct {
+	mu         sync.Mutex
+	components []component
+	stopped    bool
+
+	failed   chan struct{}
+	failOnce sync.Once
+	err      error
+}
+
+func New() *Group {
+	return &Group{failed: make(chan struct{})}
+}
+
+// Add registers a started component with the function stopping it.
+// It fails with ErrStopped once the group is stopped.
+func (g *Group) Add(name string, stop func(ctx context.Context) error) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.stopped {
+		return ErrStopped
+	}
+	g.components = append(g.components, component{name: name, stop: stop})
+	return nil
+}
+
+// Go runs a worker until it returns or it is stopped by canceling its context.
+// A worker returning before it is stopped fails the group.
+func (g *Group) Go(name string, run func(ctx context.Context) error) error {
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+
+	err := g.Add(name, func(stopCtx context.Context) error {
+		cancel()
+		select {
+		case <-done:
+			return nil
+		case <-stopCtx.Done():
+			return fmt.Errorf("did not stop: %w", stopCtx.Err())
+		}
+	})
+	if err != nil {
+		cancel()
+		return err
+	}
+
+	go func() {
+		defer close(done)
+		err := run(ctx)
+		if ctx.Err() != nil {
+			// stopped
+			if err != nil && !errors.Is(err, context.Canceled) {
+				log.Warn().Err(err).Str("component", name).Msg("lifecycle: component stopped with an error")
+			}
+			return
+		}
+		if err == nil {
+			err = errors.New("returned unexpectedly")
+		}
+		g.Fail(fmt.Errorf("%s: %w", name, err))
+	}()
+	return nil
+}
+
+// Fail records the first failure of a component and closes Failed.
+func (g *Group) Fail(err error) {
+	g.failOnce.Do(func() {
+		g.err = err
+		close(g.failed)
+	})
+}
+
+// Failed is closed when a component fails.
+func (g *Group) Failed() <-chan struct{} {
+	return g.failed
+}
+
+// Err returns the first failure of a component after Failed is closed.
+func (g *Group) Err() error {
+	select {
+	case <-g.failed:
+		return g.err
+	default:
+		return nil
+	}
+}
+
+// Stop stops the components in the reverse order of their start.
+// Every component is stopped even if a previous one fails, and ctx bounds the whole shutdown.
+// It returns the errors of the components that did not stop cleanly.
+func (g *Group) Stop(ctx context.Context) error {
+	g.mu.Lock()
+	components := g.components
+	g.components = nil
+	g.stopped = true
+	g.mu.Unlock()
+
+	var errs []error
+	for i := len(components) - 1; i >= 0; i-- {
+		c := components[i]
+		log.Debug().Str("component", c.name).Msg("lifecycle: stopping")
+		if err := c.stop(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", c.name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
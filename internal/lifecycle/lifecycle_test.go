@@ -0,0 +1,109 @@
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestStopOrder(t *testing.T) {
+	g := New()
+	var stopped []string
+	for _, name := range []string{"database", "ids", "server"} {
+		g.Add(name, func(ctx context.Context) error {
+			stopped = append(stopped, name)
+			return nil
+		})
+	}
+
+	if err := g.Stop(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"server", "ids", "database"}; !slices.Equal(stopped, want) {
+		t.Errorf("stopped %v, want %v", stopped, want)
+	}
+	if err := g.Add("late", func(context.Context) error { return nil }); !errors.Is(err, ErrStopped) {
+		t.Errorf("Add() after Stop() error = %v, want %v", err, ErrStopped)
+	}
+}
+
+func TestStopErrors(t *testing.T) {
+	g := New()
+	var stopped []string
+	g.Add("database", func(ctx context.Context) error {
+		stopped = append(stopped, "database")
+		return nil
+	})
+	g.Add("crawler", func(ctx context.Context) error {
+		return errors.New("browser crashed")
+	})
+
+	err := g.Stop(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "crawler: browser crashed") {
+		t.Errorf("Stop() error = %v, want the crawler error", err)
+	}
+	if !slices.Equal(stopped, []string{"database"}) {
+		t.Error("Stop() does not stop the components after a failed one")
+	}
+}
+
+func TestGoStops(t *testing.T) {
+	g := New()
+	canceled := make(chan struct{})
+	g.Go("worker", func(ctx context.Context) error {
+		<-ctx.Done()
+		close(canceled)
+		return ctx.Err()
+	})
+
+	if err := g.Stop(context.Background()); err != nil {
+		t.Fatalf("Stop() error = %v", err)
+	}
+	select {
+	case <-canceled:
+	default:
+		t.Error("Stop() returned before the worker stopped")
+	}
+	if g.Err() != nil {
+		t.Errorf("Err() = %v after a clean stop", g.Err())
+	}
+}
+
+func TestGoTimeout(t *testing.T) {
+	g := New()
+	release := make(chan struct{})
+	defer close(release)
+	g.Go("stuck", func(ctx context.Context) error {
+		<-release
+		return nil
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	err := g.Stop(ctx)
+	if !errors.Is(err, context.DeadlineExceeded) || !strings.Contains(err.Error(), "stuck") {
+		t.Errorf("Stop() error = %v, want a deadline error of stuck", err)
+	}
+}
+
+func TestGoFails(t *testing.T) {
+	g := New()
+	g.Go("worker", func(ctx context.Context) error {
+		return errors.New("connection lost")
+	})
+
+	select {
+	case <-g.Failed():
+	case <-time.After(time.Second):
+		t.Fatal("Failed() is not closed when a worker fails")
+	}
+	if err := g.Err(); err == nil || err.Error() != "worker: connection lost" {
+		t.Errorf("Err() = %v", err)
+	}
+	if err := g.Stop(context.Background()); err != nil {
+		t.Errorf("Stop() error = %v", err)
+	}
+}
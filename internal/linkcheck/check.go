@@ -0,0 +1,177 @@
+package linkcheck
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"golang.org/x/net/html"
+	"gosuda.org/jimin/database"
+	"gosuda.org/jimin/internal/convert"
+)
+
+const (
+	_USER_AGENT    = "Mozilla/5.0 (compatible; jimin-linkcheck/1.0)"
+	_MAX_BODY_SIZE = 256 << 10
+	// _THIN_PAGE_WORDS is the number of words under which a page has too little content to be more than a parking page.
+	_THIN_PAGE_WORDS = 200
+)
+
+// parkingHosts are the hosts parked domains redirect to.
+var parkingHosts = []string{
+	"sedoparking.com", "parkingcrew.net", "bodis.com", "above.com",
+	"parklogic.com", "dan.com", "afternic.com", "hugedomains.com",
+	"undeveloped.com", "sav.com", "domainmarket.com", "parked.com",
+}
+
+// parkingPhrases are phrases found on parked domain pages.
+var parkingPhrases = [][]byte{
+	[]byte("this domain is for sale"),
+	[]byte("this domain may be for sale"),
+	[]byte("the domain name is for sale"),
+	[]byte("buy this domain"),
+	[]byte("domain is parked"),
+	[]byte("parked free, courtesy of"),
+	[]byte("sedoparking"),
+	[]byte("parkingcrew"),
+}
+
+type Result struct {
+	Status database.LinkStatus
+	Code   int
+	Err    error
+}
+
+// Check requests url and classifies the response.
+// 404, 410 and unresolvable hosts are broken, parked domain pages are parked
+// and other failures are inconclusive errors.
+func Check(ctx context.Context, client *http.Client, url string) Result {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return Result{Status: database.LinkStatusBROKEN, Err: err}
+	}
+	req.Header.Set("User-Agent", _USER_AGENT)
+
+	resp, err := client.Do(req)
+	if err != nil {
+		var dnsErr *net.DNSError
+		if errors.As(err, &dnsErr) && dnsErr.IsNotFound {
+			return Result{Status: database.LinkStatusBROKEN, Err: err}
+		}
+		return Result{Status: database.LinkStatusERROR, Err: err}
+	}
+	defer resp.Body.Close()
+
+	r := Result{Code: resp.StatusCode}
+	switch {
+	case resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusGone:
+		r.Status = database.LinkStatusBROKEN
+	case resp.StatusCode >= 200 && resp.StatusCode < 300:
+		body, err := io.ReadAll(io.LimitReader(resp.Body, _MAX_BODY_SIZE))
+		if err != nil {
+			return Result{Status: database.LinkStatusERROR, Code: resp.StatusCode, Err: err}
+		}
+		r.Status = database.LinkStatusOK
+		if parked(req.URL.Hostname(), resp.Request.URL.Hostname(), body) {
+			r.Status = database.LinkStatusPARKED
+		}
+	default:
+		r.Status = database.LinkStatusERROR
+	}
+
+	return r
+}
+
+// parked reports whether the page of host, served by final after redirects, is a parked domain page.
+// Links redirected to another host are parked if that host is a parking service. Pages offering
+// the domain for sale are parked if they are thin or titled with the domain itself, so that
+// pages about domain parking and links to the parking services themselves are not.
+func parked(host, final string, body []byte) bool {
+	host, final = strings.ToLower(host), strings.ToLower(final)
+	if final != host {
+		for _, h := range parkingHosts {
+			if final == h || strings.HasSuffix(final, "."+h) {
+				return true
+			}
+		}
+	}
+
+	lower := bytes.ToLower(body)
+	offered := false
+	for _, p := range parkingPhrases {
+		if bytes.Contains(lower, p) {
+			offered = true
+			break
+		}
+	}
+	if !offered {
+		return false
+	}
+
+	title, words := pageText(body)
+	return words < _THIN_PAGE_WORDS || strings.Contains(strings.ToLower(title), strings.TrimPrefix(host, "www."))
+}
+
+// pageText returns the title of an html page and the number of words of its visible text.
+func pageText(body []byte) (title string, words int) {
+	z := html.NewTokenizer(bytes.NewReader(body))
+	var skip, inTitle bool
+	for {
+		tt := z.Next()
+		switch tt {
+		case html.ErrorToken:
+			return strings.TrimSpace(title), words
+		case html.StartTagToken, html.EndTagToken:
+			name, _ := z.TagName()
+			start := tt == html.StartTagToken
+			switch string(name) {
+			case "script", "style", "noscript", "template":
+				skip = start
+			case "title":
+				inTitle = start
+			}
+		case html.TextToken:
+			switch {
+			case inTitle:
+				title += string(z.Text())
+			case !skip:
+				words += len(bytes.Fields(z.Text()))
+			}
+		}
+	}
+}
+
+// Outlinks returns the unique http links of doc to other pages.
+func Outlinks(doc *convert.Document) []string {
+	self, _ := url.Parse(doc.URL)
+
+	var links []string
+	seen := make(map[string]bool)
+	doc.Walk(func(n *convert.Node) bool {
+		if n.Kind != convert.NodeLink {
+			return true
+		}
+
+		u, err := url.Parse(n.URL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+			return true
+		}
+		u.Fragment = ""
+		if self != nil && u.Host == self.Host && u.Path == self.Path && u.RawQuery == self.RawQuery {
+			return true
+		}
+
+		if s := u.String(); !seen[s] {
+			seen[s] = true
+			links = append(links, s)
+		}
+		return true
+	})
+
+	return links
+}
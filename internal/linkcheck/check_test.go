@@ -0,0 +1,96 @@
+package linkcheck
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"gosuda.org/jimin/database"
+	"gosuda.org/jimin/internal/convert"
+)
+
+func TestCheck(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/ok", func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "<html><body>Welcome</body></html>")
+	})
+	mux.HandleFunc("/gone", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusGone)
+	})
+	mux.HandleFunc("/parked", func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "<html><body><h1>This Domain Is For Sale</h1></body></html>")
+	})
+	mux.HandleFunc("/article", func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "<html><head><title>How domain parking works</title></head><body><p>"+
+			strings.Repeat("Parking pages ask visitors to buy this domain. ", 50)+"</p></body></html>")
+	})
+	mux.HandleFunc("/error", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	tests := []struct {
+		path string
+		want database.LinkStatus
+		code int
+	}{
+		{"/ok", database.LinkStatusOK, http.StatusOK},
+		{"/missing", database.LinkStatusBROKEN, http.StatusNotFound},
+		{"/gone", database.LinkStatusBROKEN, http.StatusGone},
+		{"/parked", database.LinkStatusPARKED, http.StatusOK},
+		{"/article", database.LinkStatusOK, http.StatusOK},
+		{"/error", database.LinkStatusERROR, http.StatusServiceUnavailable},
+	}
+
+	for _, tt := range tests {
+		r := Check(context.Background(), srv.Client(), srv.URL+tt.path)
+		if r.Status != tt.want || r.Code != tt.code {
+			t.Errorf("Check(%s) = %v %d, want %v %d", tt.path, r.Status, r.Code, tt.want, tt.code)
+		}
+	}
+}
+
+func TestParked(t *testing.T) {
+	article := "<html><head><title>Blog</title></head><body><p>" + strings.Repeat("Sedo and parkingcrew show a buy this domain banner. ", 40) + "</p></body></html>"
+
+	tests := []struct {
+		name        string
+		host, final string
+		body        string
+		want        bool
+	}{
+		{"redirected to a parking service", "old.example", "www.sedoparking.com", "<html></html>", true},
+		{"link to a parking service", "dan.com", "dan.com", "<html><body>Sell your domain with us</body></html>", false},
+		{"thin page for sale", "old.example", "old.example", "<html><body><h1>This domain is for sale</h1><script src=x></script></body></html>", true},
+		{"page titled with the domain", "www.old.example", "www.old.example", "<html><head><title>old.example is for sale</title></head><body>" + article + "</body></html>", true},
+		{"article about parking", "blog.example", "blog.example", article, false},
+		{"thin page", "old.example", "old.example", "<html><body>Welcome</body></html>", false},
+	}
+	for _, tt := range tests {
+		if got := parked(tt.host, tt.final, []byte(tt.body)); got != tt.want {
+			t.Errorf("%s: parked() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestOutlinks(t *testing.T) {
+	html := `<html><body>
+<p><a href="/a">a</a> <a href="https://other.com/b#x">b</a> <a href="https://other.com/b">b</a></p>
+<p><a href="#top">top</a> <a href="mailto:me@example.com">mail</a></p>
+</body></html>`
+
+	doc, err := convert.ConvertHTMLToDocument(html, "https://example.com/page")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{"https://example.com/a", "https://other.com/b"}
+	if got := Outlinks(doc); !reflect.DeepEqual(got, want) {
+		t.Errorf("Outlinks() = %v, want %v", got, want)
+	}
+}
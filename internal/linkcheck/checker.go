@@ -0,0 +1,271 @@
+package linkcheck
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/rs/zerolog/log"
+	"gosuda.org/jimin/database"
+)
+
+const (
+	_DEFAULT_CONCURRENCY    = 8
+	_DEFAULT_BATCH_SIZE     = 128
+	_DEFAULT_POLL_INTERVAL  = time.Minute
+	_DEFAULT_INTERVAL       = time.Hour * 24 * 7
+	_DEFAULT_RETRY_INTERVAL = time.Hour * 24
+	_DEFAULT_LEASE          = time.Minute * 30
+	_DEFAULT_MAX_FAILURES   = 3
+	_DEFAULT_TIMEOUT        = time.Second * 30
+)
+
+type DB interface {
+	database.DBTX
+	Begin(ctx context.Context) (pgx.Tx, error)
+}
+
+type IDGenerator interface {
+	Generate(ctx context.Context) (int64, error)
+}
+
+type Config struct {
+	DB     DB
+	IDs    IDGenerator
+	Client *http.Client
+	// Interval is the time between checks of a link that was reachable or broken.
+	Interval time.Duration
+	// RetryInterval is the time before an inconclusive check is retried,
+	// and before a broken or parked link is checked again until it reaches MaxFailures.
+	RetryInterval time.Duration
+	// Lease is how long a claimed batch is held before other checkers may claim its links again.
+	Lease time.Duration
+	// MaxFailures is the number of consecutive failed checks of its source before a document is flagged broken or parked.
+	MaxFailures int
+
+	Concurrency  int
+	BatchSize    int
+	PollInterval time.Duration
+}
+
+// Checker periodically verifies the source URLs and outlinks of documents.
+type Checker struct {
+	cfg Config
+	q   *database.Queries
+}
+
+func NewChecker(cfg Config) *Checker {
+	if cfg.Client == nil {
+		cfg.Client = &http.Client{Timeout: _DEFAULT_TIMEOUT}
+	}
+	if cfg.Interval <= 0 {
+		cfg.Interval = _DEFAULT_INTERVAL
+	}
+	if cfg.RetryInterval <= 0 {
+		cfg.RetryInterval = _DEFAULT_RETRY_INTERVAL
+	}
+	if cfg.Lease <= 0 {
+		cfg.Lease = _DEFAULT_LEASE
+	}
+	if cfg.MaxFailures <= 0 {
+		cfg.MaxFailures = _DEFAULT_MAX_FAILURES
+	}
+	if cfg.Concurrency <= 0 {
+		cfg.Concurrency = _DEFAULT_CONCURRENCY
+	}
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = _DEFAULT_BATCH_SIZE
+	}
+	if cfg.PollInterval <= 0 {
+		cfg.PollInterval = _DEFAULT_POLL_INTERVAL
+	}
+	return &Checker{cfg: cfg, q: database.New(cfg.DB)}
+}
+
+func timestamptz(t time.Time) pgtype.Timestamptz {
+	return pgtype.Timestamptz{Time: t, Valid: true}
+}
+
+// Track registers the source URL and the outlinks of doc for checking.
+// Outlinks that are no longer linked from doc are removed.
+func (c *Checker) Track(ctx context.Context, doc database.Document, outlinks []string) error {
+	return pgx.BeginFunc(ctx, c.cfg.DB, func(tx pgx.Tx) error {
+		q := c.q.WithTx(tx)
+
+		add := func(url string, kind database.LinkKind) error {
+			id, err := c.cfg.IDs.Generate(ctx)
+			if err != nil {
+				return err
+			}
+			return q.CreateLink(ctx, database.CreateLinkParams{
+				ID:          id,
+				WsID:        doc.WsID,
+				DocumentID:  doc.ID,
+				Url:         url,
+				Kind:        kind,
+				NextCheckAt: timestamptz(time.Now()),
+			})
+		}
+
+		if err := add(doc.Url, database.LinkKindSOURCE); err != nil {
+			return err
+		}
+		for _, url := range outlinks {
+			if url == doc.Url {
+				continue
+			}
+			if err := add(url, database.LinkKindOUTLINK); err != nil {
+				return err
+			}
+		}
+
+		return q.DeleteStaleOutlinks(ctx, database.DeleteStaleOutlinksParams{
+			DocumentID: doc.ID,
+			Urls:       append([]string{}, outlinks...),
+		})
+	})
+}
+
+// Run checks the due links until ctx is canceled.
+func (c *Checker) Run(ctx context.Context) error {
+	ticker := time.NewTicker(c.cfg.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		for {
+			n, err := c.RunOnce(ctx)
+			if err != nil {
+				if ctx.Err() != nil {
+					return ctx.Err()
+				}
+				log.Error().Err(err).Msg("linkcheck: failed to claim due links")
+			}
+			if n < c.cfg.BatchSize {
+				break
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// RunOnce claims a batch of due links, checks them and returns the number of links checked.
+// The claimed links are not due for other checkers until the lease expires or they are checked.
+func (c *Checker) RunOnce(ctx context.Context) (int, error) {
+	now := time.Now()
+	links, err := c.q.ClaimDueLinks(ctx, database.ClaimDueLinksParams{
+		LeaseUntil:  timestamptz(now.Add(c.cfg.Lease)),
+		NextCheckAt: timestamptz(now),
+		PageSize:    int32(c.cfg.BatchSize),
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	sema := make(chan struct{}, c.cfg.Concurrency)
+	var wg sync.WaitGroup
+	for _, link := range links {
+		sema <- struct{}{}
+		wg.Add(1)
+		go func(link database.Link) {
+			defer func() {
+				<-sema
+				wg.Done()
+			}()
+
+			if err := c.check(ctx, link); err != nil && ctx.Err() == nil {
+				log.Warn().Err(err).Int64("link", link.ID).Msg("linkcheck: failed to record link status")
+			}
+		}(link)
+	}
+	wg.Wait()
+
+	return len(links), nil
+}
+
+func (c *Checker) check(ctx context.Context, link database.Link) error {
+	now := time.Now()
+	r := Check(ctx, c.cfg.Client, link.Url)
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+
+	failures, next, flag := c.outcome(link, r, now)
+
+	var msg string
+	if r.Err != nil {
+		msg = r.Err.Error()
+	}
+
+	id, err := c.cfg.IDs.Generate(ctx)
+	if err != nil {
+		return err
+	}
+
+	return pgx.BeginFunc(ctx, c.cfg.DB, func(tx pgx.Tx) error {
+		q := c.q.WithTx(tx)
+
+		err := q.CreateLinkCheck(ctx, database.CreateLinkCheckParams{
+			ID:         id,
+			LinkID:     link.ID,
+			Status:     r.Status,
+			StatusCode: int32(r.Code),
+			Error:      msg,
+			CheckedAt:  timestamptz(now),
+		})
+		if err != nil {
+			return err
+		}
+
+		err = q.UpdateLinkStatus(ctx, database.UpdateLinkStatusParams{
+			ID:            link.ID,
+			Status:        r.Status,
+			StatusCode:    int32(r.Code),
+			Failures:      failures,
+			LastCheckedAt: timestamptz(now),
+			NextCheckAt:   timestamptz(next),
+		})
+		if err != nil {
+			return err
+		}
+
+		if flag {
+			return q.SetDocumentLinkStatus(ctx, database.SetDocumentLinkStatusParams{ID: link.DocumentID, LinkStatus: r.Status})
+		}
+		return nil
+	})
+}
+
+// outcome returns the consecutive failures of link after the check r, the time of its next check
+// and whether the status of its document is set to the status of the check.
+// Inconclusive checks are retried and keep the last known status of the document.
+// A broken or parked link is retried until it failed MaxFailures times in a row,
+// and only then flags its document, so a single failed check does not.
+func (c *Checker) outcome(link database.Link, r Result, now time.Time) (failures int32, next time.Time, flag bool) {
+	if r.Status == database.LinkStatusOK {
+		return 0, now.Add(c.cfg.Interval), link.Kind == database.LinkKindSOURCE
+	}
+
+	failures = link.Failures + 1
+	if r.Status == database.LinkStatusERROR || failures < int32(c.cfg.MaxFailures) {
+		return failures, now.Add(c.cfg.RetryInterval), false
+	}
+	return failures, now.Add(c.cfg.Interval), link.Kind == database.LinkKindSOURCE
+}
+
+// Broken returns the broken and parked links of a workspace with ids greater than after.
+func (c *Checker) Broken(ctx context.Context, wsID, after int64, limit int) ([]database.Link, error) {
+	return c.q.ListBrokenLinks(ctx, database.ListBrokenLinksParams{WsID: wsID, ID: after, Limit: int32(limit)})
+}
+
+// History returns the most recent checks of a link.
+func (c *Checker) History(ctx context.Context, linkID int64, limit int) ([]database.LinkCheck, error) {
+	return c.q.ListLinkChecks(ctx, database.ListLinkChecksParams{LinkID: linkID, Limit: int32(limit)})
+}
@@ -0,0 +1,94 @@
+package linkcheck
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgtype"
+	"gosuda.org/jimin/database"
+)
+
+var errQuery = errors.New("query failed")
+
+// claimDB records the queries of the checker and fails them.
+type claimDB struct {
+	sql  string
+	args []any
+}
+
+func (db *claimDB) Exec(context.Context, string, ...any) (pgconn.CommandTag, error) {
+	return pgconn.CommandTag{}, errQuery
+}
+
+func (db *claimDB) Query(_ context.Context, sql string, args ...any) (pgx.Rows, error) {
+	db.sql, db.args = sql, args
+	return nil, errQuery
+}
+
+func (db *claimDB) QueryRow(context.Context, string, ...any) pgx.Row { return nil }
+
+func (db *claimDB) Begin(context.Context) (pgx.Tx, error) { return nil, errQuery }
+
+func TestRunOnceClaims(t *testing.T) {
+	db := &claimDB{}
+	c := NewChecker(Config{DB: db, Lease: time.Minute, BatchSize: 8})
+
+	start := time.Now()
+	if _, err := c.RunOnce(context.Background()); !errors.Is(err, errQuery) {
+		t.Fatalf("RunOnce() error = %v, want %v", err, errQuery)
+	}
+	if !strings.Contains(db.sql, "UPDATE links") || !strings.Contains(db.sql, "FOR UPDATE SKIP LOCKED") {
+		t.Fatalf("RunOnce() does not claim the due links: %s", db.sql)
+	}
+	if len(db.args) != 3 {
+		t.Fatalf("RunOnce() args = %v", db.args)
+	}
+
+	lease, due := db.args[0].(pgtype.Timestamptz).Time, db.args[1].(pgtype.Timestamptz).Time
+	if due.Before(start) || due.After(time.Now()) {
+		t.Errorf("claimed links due by %v, want due by the time of the claim", due)
+	}
+	if got := lease.Sub(due); got != time.Minute {
+		t.Errorf("lease = %v, want %v", got, time.Minute)
+	}
+	if got := db.args[2].(int32); got != 8 {
+		t.Errorf("page size = %d, want 8", got)
+	}
+}
+
+func TestOutcome(t *testing.T) {
+	c := NewChecker(Config{Interval: time.Hour * 24, RetryInterval: time.Hour, MaxFailures: 3})
+	source := database.Link{Kind: database.LinkKindSOURCE}
+	failed := func(link database.Link, n int32) database.Link {
+		link.Failures = n
+		return link
+	}
+
+	tests := []struct {
+		name     string
+		link     database.Link
+		status   database.LinkStatus
+		failures int32
+		next     time.Duration
+		flag     bool
+	}{
+		{"reachable", failed(source, 2), database.LinkStatusOK, 0, time.Hour * 24, true},
+		{"first failure", source, database.LinkStatusBROKEN, 1, time.Hour, false},
+		{"second failure", failed(source, 1), database.LinkStatusPARKED, 2, time.Hour, false},
+		{"consecutive failures", failed(source, 2), database.LinkStatusBROKEN, 3, time.Hour * 24, true},
+		{"inconclusive", failed(source, 5), database.LinkStatusERROR, 6, time.Hour, false},
+		{"broken outlink", database.Link{Kind: database.LinkKindOUTLINK, Failures: 2}, database.LinkStatusBROKEN, 3, time.Hour * 24, false},
+	}
+	now := time.Now()
+	for _, tt := range tests {
+		failures, next, flag := c.outcome(tt.link, Result{Status: tt.status}, now)
+		if failures != tt.failures || next.Sub(now) != tt.next || flag != tt.flag {
+			t.Errorf("%s: outcome() = %d, %v, %v, want %d, %v, %v", tt.name, failures, next.Sub(now), flag, tt.failures, tt.next, tt.flag)
+		}
+	}
+}
@@ -0,0 +1,60 @@
+package mail
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"net/mail"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// File writes emails as .eml files to a directory instead of sending them, for development and tests.
+type File struct {
+	dir  string
+	from *mail.Address
+}
+
+func NewFile(dir, from string) (*File, error) {
+	a, err := address(from)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, err
+	}
+	return &File{dir: dir, from: a}, nil
+}
+
+func (f *File) Send(ctx context.Context, m Message) error {
+	to, err := address(m.To)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	msg, err := encode(f.from, to, m, now)
+	if err != nil {
+		return err
+	}
+
+	var suffix [4]byte
+	rand.Read(suffix[:])
+	name := now.UTC().Format("20060102T150405.000000000Z") + "-" + hex.EncodeToString(suffix[:]) + ".eml"
+	// the emails hold sign in links
+	return os.WriteFile(filepath.Join(f.dir, name), msg, 0o600)
+}
+
+// Log logs emails instead of sending them, for local development.
+// The logs hold the sign in links of the emails.
+type Log struct{}
+
+func (Log) Send(ctx context.Context, m Message) error {
+	if _, err := address(m.To); err != nil {
+		return err
+	}
+	log.Info().Str("to", m.To).Str("subject", m.Subject).Str("text", m.Text).Msg("mail: not sent")
+	return nil
+}
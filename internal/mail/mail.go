@@ -0,0 +1,73 @@
+// Package mail sends the plain text emails of the server through a pluggable Mailer.
+package mail
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"mime"
+	"mime/quotedprintable"
+	"net/mail"
+	"strings"
+	"time"
+)
+
+var ErrInvalidAddress = errors.New("mail: invalid address")
+
+// Message is a plain text email.
+type Message struct {
+	To      string
+	Subject string
+	Text    string
+}
+
+// Mailer sends emails.
+type Mailer interface {
+	Send(ctx context.Context, m Message) error
+}
+
+// address parses a single address, rejecting the ones that would inject headers.
+func address(s string) (*mail.Address, error) {
+	if strings.ContainsAny(s, "\r\n") {
+		return nil, fmt.Errorf("%w: %q", ErrInvalidAddress, s)
+	}
+	a, err := mail.ParseAddress(s)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %q: %w", ErrInvalidAddress, s, err)
+	}
+	return a, nil
+}
+
+// encode returns m as an RFC 5322 message from from.
+func encode(from *mail.Address, to *mail.Address, m Message, date time.Time) ([]byte, error) {
+	var id [16]byte
+	rand.Read(id[:])
+	domain := from.Address[strings.LastIndexByte(from.Address, '@')+1:]
+
+	var b bytes.Buffer
+	header := func(k, v string) {
+		b.WriteString(k + ": " + v + "\r\n")
+	}
+	header("From", from.String())
+	header("To", to.String())
+	header("Subject", mime.QEncoding.Encode("utf-8", strings.Join(strings.Fields(m.Subject), " ")))
+	header("Date", date.Format(time.RFC1123Z))
+	header("Message-ID", "<"+hex.EncodeToString(id[:])+"@"+domain+">")
+	header("MIME-Version", "1.0")
+	header("Content-Type", `text/plain; charset="utf-8"`)
+	header("Content-Transfer-Encoding", "quoted-printable")
+	b.WriteString("\r\n")
+
+	w := quotedprintable.NewWriter(&b)
+	text := strings.ReplaceAll(strings.ReplaceAll(m.Text, "\r\n", "\n"), "\n", "\r\n")
+	if _, err := w.Write([]byte(text)); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
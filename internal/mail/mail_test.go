@@ -0,0 +1,170 @@
+package mail
+
+import (
+	"bufio"
+	"context"
+	"encoding/base64"
+	"errors"
+	"io"
+	"mime"
+	"net"
+	"net/mail"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestEncode(t *testing.T) {
+	from, _ := address("Jimin <noreply@jimin.example>")
+	to, _ := address("someone@example.com")
+	msg, err := encode(from, to, Message{Subject: "이메일 확인\r\nBcc: evil@example.com", Text: "Hello,\nhttps://jimin.example/verify?token=" + strings.Repeat("a", 100) + "\n"}, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	m, err := mail.ReadMessage(strings.NewReader(string(msg)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.Header.Get("Bcc") != "" {
+		t.Error("the subject injects a header")
+	}
+	subject, err := new(mime.WordDecoder).DecodeHeader(m.Header.Get("Subject"))
+	if err != nil || subject != "이메일 확인 Bcc: evil@example.com" {
+		t.Errorf("subject = %q, %v", subject, err)
+	}
+	if !strings.HasSuffix(m.Header.Get("Message-ID"), "@jimin.example>") {
+		t.Errorf("message id = %q", m.Header.Get("Message-ID"))
+	}
+	body, _ := io.ReadAll(m.Body)
+	for _, line := range strings.Split(string(body), "\r\n") {
+		if len(line) > 76 {
+			t.Errorf("body line of %d bytes", len(line))
+		}
+	}
+
+	if _, err := address("someone@example.com\r\nBcc: evil@example.com"); !errors.Is(err, ErrInvalidAddress) {
+		t.Errorf("address with a header error = %v, want %v", err, ErrInvalidAddress)
+	}
+}
+
+func TestFile(t *testing.T) {
+	dir := t.TempDir()
+	f, err := NewFile(dir, "noreply@jimin.example")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Send(context.Background(), Message{To: "someone@example.com", Subject: "Hi", Text: "Hello"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Send(context.Background(), Message{To: "not an address", Subject: "Hi"}); !errors.Is(err, ErrInvalidAddress) {
+		t.Errorf("invalid recipient error = %v, want %v", err, ErrInvalidAddress)
+	}
+
+	files, _ := filepath.Glob(filepath.Join(dir, "*.eml"))
+	if len(files) != 1 {
+		t.Fatalf("files = %v", files)
+	}
+	b, _ := os.ReadFile(files[0])
+	if !strings.Contains(string(b), "To: <someone@example.com>\r\n") || !strings.HasSuffix(string(b), "\r\n\r\nHello") {
+		t.Errorf("file = %q", b)
+	}
+}
+
+// smtpServer accepts one email over plain text SMTP with the login user:pass.
+func smtpServer(t *testing.T) (addr string, received chan string) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { l.Close() })
+	received = make(chan string, 1)
+
+	go func() {
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		r := bufio.NewReader(conn)
+		reply := func(s string) { io.WriteString(conn, s+"\r\n") }
+
+		var log []string
+		reply("220 localhost ESMTP")
+		for {
+			line, err := r.ReadString('\n')
+			if err != nil {
+				return
+			}
+			line = strings.TrimRight(line, "\r\n")
+			log = append(log, line)
+			switch cmd := strings.ToUpper(strings.Fields(line + " ")[0]); cmd {
+			case "EHLO":
+				reply("250-localhost")
+				reply("250 AUTH PLAIN")
+			case "AUTH":
+				if cred, _ := base64.StdEncoding.DecodeString(strings.Fields(line)[2]); string(cred) != "\x00user\x00pass" {
+					reply("535 invalid credentials")
+					continue
+				}
+				reply("235 ok")
+			case "MAIL", "RCPT":
+				reply("250 ok")
+			case "DATA":
+				reply("354 go ahead")
+				var data strings.Builder
+				for {
+					l, err := r.ReadString('\n')
+					if err != nil || l == ".\r\n" {
+						break
+					}
+					data.WriteString(l)
+				}
+				log = append(log, data.String())
+				reply("250 queued")
+			case "QUIT":
+				reply("221 bye")
+				received <- strings.Join(log, "\n")
+				return
+			default:
+				reply("502 unknown command")
+			}
+		}
+	}()
+	return l.Addr().String(), received
+}
+
+func TestSMTP(t *testing.T) {
+	addr, received := smtpServer(t)
+	s, err := NewSMTP(SMTPConfig{Addr: addr, TLS: TLSNone, Username: "user", Password: "pass", From: "noreply@jimin.example"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Send(context.Background(), Message{To: "Someone <someone@example.com>", Subject: "Hi", Text: "Hello"}); err != nil {
+		t.Fatal(err)
+	}
+
+	log := <-received
+	for _, want := range []string{"MAIL FROM:<noreply@jimin.example>", "RCPT TO:<someone@example.com>", "Subject: Hi\r\n", "\r\n\r\nHello"} {
+		if !strings.Contains(log, want) {
+			t.Errorf("session has no %q:\n%s", want, log)
+		}
+	}
+}
+
+func TestSMTPRequiresStartTLS(t *testing.T) {
+	addr, _ := smtpServer(t)
+	s, err := NewSMTP(SMTPConfig{Addr: addr, From: "noreply@jimin.example"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Send(context.Background(), Message{To: "someone@example.com", Subject: "Hi"}); err == nil || !strings.Contains(err.Error(), "STARTTLS") {
+		t.Errorf("send without STARTTLS error = %v", err)
+	}
+
+	if _, err := NewSMTP(SMTPConfig{Addr: addr, TLS: "ssl", From: "noreply@jimin.example"}); err == nil {
+		t.Error("unknown TLS mode is accepted")
+	}
+}
@@ -0,0 +1,135 @@
+package mail
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/smtp"
+	"time"
+)
+
+const _DEFAULT_TIMEOUT = time.Second * 30
+
+// TLS modes of an SMTP server.
+const (
+	// TLSStartTLS upgrades the connection with STARTTLS, and fails if the server does not offer it.
+	TLSStartTLS = "starttls"
+	// TLSImplicit connects over TLS, usually to port 465.
+	TLSImplicit = "implicit"
+	// TLSNone sends in plain text. Only for local relays.
+	TLSNone = "none"
+)
+
+type SMTPConfig struct {
+	// Addr is the host:port of the server.
+	Addr string
+	// TLS is the TLS mode, starttls if empty.
+	TLS      string
+	Username string
+	Password string
+	// From is the sender address of every email.
+	From    string
+	Timeout time.Duration
+}
+
+// SMTP sends emails through an SMTP server, over a new connection for each.
+type SMTP struct {
+	cfg  SMTPConfig
+	host string
+}
+
+func NewSMTP(cfg SMTPConfig) (*SMTP, error) {
+	if cfg.TLS == "" {
+		cfg.TLS = TLSStartTLS
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = _DEFAULT_TIMEOUT
+	}
+	switch cfg.TLS {
+	case TLSStartTLS, TLSImplicit, TLSNone:
+	default:
+		return nil, fmt.Errorf("mail: unknown TLS mode %q", cfg.TLS)
+	}
+	host, _, err := net.SplitHostPort(cfg.Addr)
+	if err != nil {
+		return nil, fmt.Errorf("mail: invalid server address %q: %w", cfg.Addr, err)
+	}
+	if _, err := address(cfg.From); err != nil {
+		return nil, err
+	}
+	return &SMTP{cfg: cfg, host: host}, nil
+}
+
+func (s *SMTP) Send(ctx context.Context, m Message) error {
+	from, err := address(s.cfg.From)
+	if err != nil {
+		return err
+	}
+	to, err := address(m.To)
+	if err != nil {
+		return err
+	}
+	msg, err := encode(from, to, m, time.Now())
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, s.cfg.Timeout)
+	defer cancel()
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "tcp", s.cfg.Addr)
+	if err != nil {
+		return err
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+	// the client does not watch ctx, closing the connection interrupts it
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	defer stop()
+
+	tlsConfig := &tls.Config{ServerName: s.host}
+	if s.cfg.TLS == TLSImplicit {
+		conn = tls.Client(conn, tlsConfig)
+	}
+	c, err := smtp.NewClient(conn, s.host)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer c.Close()
+
+	if s.cfg.TLS == TLSStartTLS {
+		if ok, _ := c.Extension("STARTTLS"); !ok {
+			return fmt.Errorf("mail: %s does not support STARTTLS", s.cfg.Addr)
+		}
+		if err := c.StartTLS(tlsConfig); err != nil {
+			return err
+		}
+	}
+	if s.cfg.Username != "" {
+		// PlainAuth refuses to send the password without TLS, except to localhost
+		if err := c.Auth(smtp.PlainAuth("", s.cfg.Username, s.cfg.Password, s.host)); err != nil {
+			return err
+		}
+	}
+
+	if err := c.Mail(from.Address); err != nil {
+		return err
+	}
+	if err := c.Rcpt(to.Address); err != nil {
+		return err
+	}
+	w, err := c.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(msg); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return c.Quit()
+}
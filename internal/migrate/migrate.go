@@ -0,0 +1,244 @@
+package migrate
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+)
+
+var (
+	ErrDirty            = errors.New("migrate: database is dirty, fix the failed migration and force its version")
+	ErrInvalidMigration = errors.New("migrate: invalid migration file name")
+	ErrUnknownVersion   = errors.New("migrate: unknown version")
+	ErrIrreversible     = errors.New("migrate: migration has no down script")
+)
+
+// _LOCK_KEY is the key of the advisory lock held while migrating ("jimin" in ascii).
+const _LOCK_KEY = 0x6a696d696e
+
+// Migration is a pair of up and down scripts named like go-migrate: <version>_<name>.(up|down).sql.
+type Migration struct {
+	Version int64
+	Name    string
+	Up      string
+	Down    string
+}
+
+// Load reads the migrations of fsys ordered by version.
+func Load(fsys fs.FS) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := make(map[int64]*Migration)
+	for _, e := range entries {
+		if e.IsDir() || !strings.HasSuffix(e.Name(), ".sql") {
+			continue
+		}
+
+		base := strings.TrimSuffix(e.Name(), ".sql")
+		base, direction, ok := cutLast(base, ".")
+		if !ok || (direction != "up" && direction != "down") {
+			return nil, fmt.Errorf("%w: %s", ErrInvalidMigration, e.Name())
+		}
+		v, name, _ := strings.Cut(base, "_")
+		version, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s", ErrInvalidMigration, e.Name())
+		}
+
+		script, err := fs.ReadFile(fsys, e.Name())
+		if err != nil {
+			return nil, err
+		}
+
+		m, ok := byVersion[version]
+		if !ok {
+			m = &Migration{Version: version, Name: name}
+			byVersion[version] = m
+		}
+		if direction == "up" {
+			m.Up = string(script)
+		} else {
+			m.Down = string(script)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		migrations = append(migrations, *m)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return migrations, nil
+}
+
+func cutLast(s, sep string) (before, after string, found bool) {
+	if i := strings.LastIndex(s, sep); i >= 0 {
+		return s[:i], s[i+len(sep):], true
+	}
+	return s, "", false
+}
+
+// Version returns the applied version recorded in the go-migrate schema_migrations table.
+// The version is 0 if no migration was applied.
+func Version(ctx context.Context, conn *pgx.Conn) (version int64, dirty bool, err error) {
+	var exists bool
+	err = conn.QueryRow(ctx, "SELECT to_regclass('schema_migrations') IS NOT NULL").Scan(&exists)
+	if err != nil || !exists {
+		return 0, false, err
+	}
+
+	err = conn.QueryRow(ctx, "SELECT version, dirty FROM schema_migrations LIMIT 1").Scan(&version, &dirty)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return 0, false, nil
+	}
+	return version, dirty, err
+}
+
+// locked runs fn holding the session advisory lock of the migrations,
+// so the replicas starting at once migrate one at a time.
+func locked(ctx context.Context, conn *pgx.Conn, fn func() error) error {
+	if _, err := conn.Exec(ctx, "SELECT pg_advisory_lock($1)", _LOCK_KEY); err != nil {
+		return fmt.Errorf("migrate: failed to lock: %w", err)
+	}
+	// the lock outlives a canceled ctx on a pooled connection, so it is released regardless
+	defer conn.Exec(context.WithoutCancel(ctx), "SELECT pg_advisory_unlock($1)", _LOCK_KEY)
+
+	_, err := conn.Exec(ctx, "CREATE TABLE IF NOT EXISTS schema_migrations (version BIGINT NOT NULL PRIMARY KEY, dirty BOOLEAN NOT NULL)")
+	if err != nil {
+		return err
+	}
+	return fn()
+}
+
+// setVersion records version, or no version if it is 0.
+func setVersion(ctx context.Context, conn *pgx.Conn, version int64, dirty bool) error {
+	return pgx.BeginFunc(ctx, conn, func(tx pgx.Tx) error {
+		if _, err := tx.Exec(ctx, "DELETE FROM schema_migrations"); err != nil {
+			return err
+		}
+		if version == 0 {
+			return nil
+		}
+		_, err := tx.Exec(ctx, "INSERT INTO schema_migrations (version, dirty) VALUES ($1, $2)", version, dirty)
+		return err
+	})
+}
+
+// exec runs a migration script.
+func exec(ctx context.Context, conn *pgx.Conn, m Migration, script string) error {
+	// the scripts hold several statements, which only the simple protocol runs at once
+	if _, err := conn.PgConn().Exec(ctx, script).ReadAll(); err != nil {
+		return fmt.Errorf("migrate: %d_%s: %w", m.Version, m.Name, err)
+	}
+	return nil
+}
+
+// index returns the index of the migration of version, or -1 for version 0.
+func index(migrations []Migration, version int64) (int, error) {
+	if version == 0 {
+		return -1, nil
+	}
+	for i, m := range migrations {
+		if m.Version == version {
+			return i, nil
+		}
+	}
+	return 0, fmt.Errorf("%w: %d", ErrUnknownVersion, version)
+}
+
+// Up applies the migrations newer than the applied version in order and returns the applied migrations.
+// A migration is recorded as dirty while it runs, so a failed migration stops later runs.
+func Up(ctx context.Context, conn *pgx.Conn, migrations []Migration) ([]Migration, error) {
+	var applied []Migration
+	err := locked(ctx, conn, func() error {
+		current, dirty, err := Version(ctx, conn)
+		if err != nil {
+			return err
+		}
+		if dirty {
+			return fmt.Errorf("%w: version %d", ErrDirty, current)
+		}
+
+		for _, m := range migrations {
+			if m.Version <= current {
+				continue
+			}
+
+			if err := setVersion(ctx, conn, m.Version, true); err != nil {
+				return err
+			}
+			if err := exec(ctx, conn, m, m.Up); err != nil {
+				return err
+			}
+			if err := setVersion(ctx, conn, m.Version, false); err != nil {
+				return err
+			}
+			applied = append(applied, m)
+		}
+		return nil
+	})
+	return applied, err
+}
+
+// Down reverts the last n applied migrations in reverse order and returns the reverted migrations.
+// A migration is recorded as dirty while it is reverted, so a failed revert stops later runs.
+func Down(ctx context.Context, conn *pgx.Conn, migrations []Migration, n int) ([]Migration, error) {
+	var reverted []Migration
+	err := locked(ctx, conn, func() error {
+		current, dirty, err := Version(ctx, conn)
+		if err != nil {
+			return err
+		}
+		if dirty {
+			return fmt.Errorf("%w: version %d", ErrDirty, current)
+		}
+		i, err := index(migrations, current)
+		if err != nil {
+			return err
+		}
+
+		for ; i >= 0 && len(reverted) < n; i-- {
+			m := migrations[i]
+			if m.Down == "" {
+				return fmt.Errorf("%w: %d_%s", ErrIrreversible, m.Version, m.Name)
+			}
+			var previous int64
+			if i > 0 {
+				previous = migrations[i-1].Version
+			}
+
+			if err := setVersion(ctx, conn, m.Version, true); err != nil {
+				return err
+			}
+			if err := exec(ctx, conn, m, m.Down); err != nil {
+				return err
+			}
+			if err := setVersion(ctx, conn, previous, false); err != nil {
+				return err
+			}
+			reverted = append(reverted, m)
+		}
+		return nil
+	})
+	return reverted, err
+}
+
+// Force records version as the clean applied version without running any script,
+// to recover from a failed migration once the database is fixed by hand.
+// Version 0 records that no migration is applied.
+func Force(ctx context.Context, conn *pgx.Conn, migrations []Migration, version int64) error {
+	if _, err := index(migrations, version); err != nil {
+		return err
+	}
+	return locked(ctx, conn, func() error {
+		return setVersion(ctx, conn, version, false)
+	})
+}
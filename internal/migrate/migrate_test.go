@@ -0,0 +1,53 @@
+package migrate
+
+import (
+	"errors"
+	"testing"
+	"testing/fstest"
+)
+
+func TestLoad(t *testing.T) {
+	fsys := fstest.MapFS{
+		"261020_add_feeds.up.sql":         {Data: []byte("CREATE TABLE feeds ();")},
+		"261020_add_feeds.down.sql":       {Data: []byte("DROP TABLE feeds;")},
+		"241105_initialize_schema.up.sql": {Data: []byte("CREATE TABLE users ();")},
+		"README.md":                       {Data: []byte("not a migration")},
+	}
+
+	migrations, err := Load(fsys)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(migrations) != 2 {
+		t.Fatalf("Load() returned %d migrations, want 2", len(migrations))
+	}
+
+	if m := migrations[0]; m.Version != 241105 || m.Name != "initialize_schema" || m.Up == "" || m.Down != "" {
+		t.Errorf("migrations[0] = %+v", m)
+	}
+	if m := migrations[1]; m.Version != 261020 || m.Name != "add_feeds" || m.Up != "CREATE TABLE feeds ();" || m.Down != "DROP TABLE feeds;" {
+		t.Errorf("migrations[1] = %+v", m)
+	}
+}
+
+func TestLoadInvalid(t *testing.T) {
+	for _, name := range []string{"add_feeds.up.sql", "261020_add_feeds.sql", "261020_add_feeds.sideways.sql"} {
+		_, err := Load(fstest.MapFS{name: {Data: []byte("SELECT 1;")}})
+		if !errors.Is(err, ErrInvalidMigration) {
+			t.Errorf("Load(%s) error = %v, want %v", name, err, ErrInvalidMigration)
+		}
+	}
+}
+
+func TestIndex(t *testing.T) {
+	migrations := []Migration{{Version: 241105}, {Version: 261020}}
+
+	for version, want := range map[int64]int{0: -1, 241105: 0, 261020: 1} {
+		if i, err := index(migrations, version); err != nil || i != want {
+			t.Errorf("index(%d) = %d, %v, want %d", version, i, err, want)
+		}
+	}
+	if _, err := index(migrations, 261019); !errors.Is(err, ErrUnknownVersion) {
+		t.Errorf("index(261019) error = %v, want %v", err, ErrUnknownVersion)
+	}
+}
@@ -0,0 +1,210 @@
+// Package oidc signs users in with OpenID Connect providers, with the authorization code flow and PKCE.
+package oidc
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	_DEFAULT_TIMEOUT = time.Second * 10
+	_MAX_BODY_BYTES  = 1 << 20
+)
+
+var (
+	ErrDiscovery    = errors.New("oidc: invalid provider metadata")
+	ErrExchange     = errors.New("oidc: code exchange failed")
+	ErrInvalidToken = errors.New("oidc: invalid id token")
+)
+
+var defaultScopes = []string{"openid", "email", "profile"}
+
+type Config struct {
+	// Issuer is the issuer URL, whose metadata is discovered at /.well-known/openid-configuration.
+	Issuer       string
+	ClientID     string
+	ClientSecret string
+	// RedirectURL is the callback URL registered with the provider.
+	RedirectURL string
+	// Scopes are the requested scopes, openid, email and profile if empty.
+	Scopes []string
+	Client *http.Client
+}
+
+// Provider is an OpenID Connect provider. Its metadata and keys are fetched on first use.
+type Provider struct {
+	cfg Config
+
+	mu   sync.Mutex
+	meta *metadata
+	keys *keySet
+}
+
+type metadata struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+func New(cfg Config) *Provider {
+	if len(cfg.Scopes) == 0 {
+		cfg.Scopes = defaultScopes
+	}
+	if cfg.Client == nil {
+		cfg.Client = &http.Client{Timeout: _DEFAULT_TIMEOUT}
+	}
+	return &Provider{cfg: cfg}
+}
+
+// Flow holds the secrets of a login from its redirect to its callback.
+type Flow struct {
+	// State binds the callback to the login it completes.
+	State string
+	// Nonce binds the id token to the login.
+	Nonce string
+	// Verifier is the PKCE code verifier, sent to the provider as its S256 challenge.
+	Verifier string
+}
+
+func random() string {
+	var b [32]byte
+	rand.Read(b[:])
+	return base64.RawURLEncoding.EncodeToString(b[:])
+}
+
+// NewFlow returns the random secrets of a new login.
+func NewFlow() Flow {
+	return Flow{State: random(), Nonce: random(), Verifier: random()}
+}
+
+// Challenge returns the S256 PKCE challenge of the verifier.
+func (f Flow) Challenge() string {
+	h := sha256.Sum256([]byte(f.Verifier))
+	return base64.RawURLEncoding.EncodeToString(h[:])
+}
+
+func (p *Provider) getJSON(ctx context.Context, u string, v any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	resp, err := p.cfg.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("GET %s: %s", u, resp.Status)
+	}
+	return json.NewDecoder(io.LimitReader(resp.Body, _MAX_BODY_BYTES)).Decode(v)
+}
+
+// metadata returns the discovered metadata of the provider.
+func (p *Provider) metadata(ctx context.Context) (*metadata, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.meta != nil {
+		return p.meta, nil
+	}
+
+	var m metadata
+	if err := p.getJSON(ctx, strings.TrimSuffix(p.cfg.Issuer, "/")+"/.well-known/openid-configuration", &m); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrDiscovery, err)
+	}
+	if m.Issuer != p.cfg.Issuer {
+		return nil, fmt.Errorf("%w: issuer %q is not %q", ErrDiscovery, m.Issuer, p.cfg.Issuer)
+	}
+	if m.AuthorizationEndpoint == "" || m.TokenEndpoint == "" || m.JWKSURI == "" {
+		return nil, fmt.Errorf("%w: missing endpoints", ErrDiscovery)
+	}
+	p.meta = &m
+	return p.meta, nil
+}
+
+// AuthURL returns the URL of the provider the user is redirected to for the login of f.
+func (p *Provider) AuthURL(ctx context.Context, f Flow) (string, error) {
+	m, err := p.metadata(ctx)
+	if err != nil {
+		return "", err
+	}
+	u, err := url.Parse(m.AuthorizationEndpoint)
+	if err != nil {
+		return "", fmt.Errorf("%w: %w", ErrDiscovery, err)
+	}
+
+	q := u.Query()
+	q.Set("response_type", "code")
+	q.Set("client_id", p.cfg.ClientID)
+	q.Set("redirect_uri", p.cfg.RedirectURL)
+	q.Set("scope", strings.Join(p.cfg.Scopes, " "))
+	q.Set("state", f.State)
+	q.Set("nonce", f.Nonce)
+	q.Set("code_challenge", f.Challenge())
+	q.Set("code_challenge_method", "S256")
+	u.RawQuery = q.Encode()
+	return u.String(), nil
+}
+
+type tokenResponse struct {
+	IDToken          string `json:"id_token"`
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description"`
+}
+
+// Exchange redeems the authorization code of the callback of f and returns the verified claims of its id token.
+func (p *Provider) Exchange(ctx context.Context, code string, f Flow) (*Claims, error) {
+	m, err := p.metadata(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	form := url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {p.cfg.RedirectURL},
+		"code_verifier": {f.Verifier},
+	}
+	if p.cfg.ClientSecret == "" {
+		form.Set("client_id", p.cfg.ClientID)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, m.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	if p.cfg.ClientSecret != "" {
+		req.SetBasicAuth(url.QueryEscape(p.cfg.ClientID), url.QueryEscape(p.cfg.ClientSecret))
+	}
+
+	resp, err := p.cfg.Client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrExchange, err)
+	}
+	defer resp.Body.Close()
+
+	var t tokenResponse
+	if err := json.NewDecoder(io.LimitReader(resp.Body, _MAX_BODY_BYTES)).Decode(&t); err != nil {
+		return nil, fmt.Errorf("%w: %s: %w", ErrExchange, resp.Status, err)
+	}
+	if t.Error != "" {
+		return nil, fmt.Errorf("%w: %s: %s", ErrExchange, t.Error, t.ErrorDescription)
+	}
+	if resp.StatusCode != http.StatusOK || t.IDToken == "" {
+		return nil, fmt.Errorf("%w: %s without an id token", ErrExchange, resp.Status)
+	}
+	return p.Verify(ctx, t.IDToken, f.Nonce)
+}
@@ -0,0 +1,285 @@
+package oidc
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// issuer is an in-process OpenID Connect provider that signs every user in as its claims.
+type issuer struct {
+	*httptest.Server
+	key *rsa.PrivateKey
+	kid string
+
+	mu     sync.Mutex
+	codes  map[string]grant
+	claims map[string]any
+	// tamper modifies the claims of the next id tokens.
+	tamper func(claims map[string]any)
+}
+
+// grant is an issued authorization code.
+type grant struct {
+	clientID, redirectURI string
+	challenge, nonce      string
+}
+
+func newIssuer(t *testing.T) *issuer {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	is := &issuer{key: key, kid: "key-1", codes: map[string]grant{}, claims: map[string]any{
+		"sub": "user-1", "email": "someone@example.com", "email_verified": true, "name": "Someone",
+	}}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]string{
+			"issuer":                 is.URL,
+			"authorization_endpoint": is.URL + "/authorize",
+			"token_endpoint":         is.URL + "/token",
+			"jwks_uri":               is.URL + "/jwks",
+		})
+	})
+	mux.HandleFunc("GET /jwks", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]any{"keys": []map[string]string{{
+			"kty": "RSA",
+			"kid": is.kid,
+			"use": "sig",
+			"n":   base64.RawURLEncoding.EncodeToString(is.key.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(is.key.E)).Bytes()),
+		}}})
+	})
+	mux.HandleFunc("GET /authorize", func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		if q.Get("response_type") != "code" || q.Get("code_challenge_method") != "S256" || !strings.Contains(q.Get("scope"), "openid") {
+			http.Error(w, "invalid request", http.StatusBadRequest)
+			return
+		}
+		code := random()
+		is.mu.Lock()
+		is.codes[code] = grant{clientID: q.Get("client_id"), redirectURI: q.Get("redirect_uri"), challenge: q.Get("code_challenge"), nonce: q.Get("nonce")}
+		is.mu.Unlock()
+
+		u, _ := url.Parse(q.Get("redirect_uri"))
+		u.RawQuery = url.Values{"code": {code}, "state": {q.Get("state")}}.Encode()
+		http.Redirect(w, r, u.String(), http.StatusFound)
+	})
+	mux.HandleFunc("POST /token", func(w http.ResponseWriter, r *http.Request) {
+		fail := func(code string) {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": code})
+		}
+
+		is.mu.Lock()
+		g, ok := is.codes[r.PostFormValue("code")]
+		delete(is.codes, r.PostFormValue("code"))
+		is.mu.Unlock()
+		id, secret, _ := r.BasicAuth()
+		h := sha256.Sum256([]byte(r.PostFormValue("code_verifier")))
+		switch {
+		case r.PostFormValue("grant_type") != "authorization_code" || !ok:
+			fail("invalid_grant")
+		case id != g.clientID || secret != "secret":
+			fail("invalid_client")
+		case r.PostFormValue("redirect_uri") != g.redirectURI:
+			fail("invalid_grant")
+		case base64.RawURLEncoding.EncodeToString(h[:]) != g.challenge:
+			fail("invalid_grant")
+		default:
+			json.NewEncoder(w).Encode(map[string]string{"access_token": "access", "token_type": "Bearer", "id_token": is.token(g.clientID, g.nonce)})
+		}
+	})
+	is.Server = httptest.NewServer(mux)
+	t.Cleanup(is.Close)
+	return is
+}
+
+// token returns an id token signed by the issuer.
+func (is *issuer) token(clientID, nonce string) string {
+	now := time.Now()
+	claims := map[string]any{"iss": is.URL, "aud": clientID, "iat": now.Unix(), "exp": now.Add(time.Hour).Unix(), "nonce": nonce}
+	is.mu.Lock()
+	for k, v := range is.claims {
+		claims[k] = v
+	}
+	if is.tamper != nil {
+		is.tamper(claims)
+	}
+	is.mu.Unlock()
+
+	enc := func(v any) string {
+		b, _ := json.Marshal(v)
+		return base64.RawURLEncoding.EncodeToString(b)
+	}
+	signed := enc(map[string]string{"alg": "RS256", "typ": "JWT", "kid": is.kid}) + "." + enc(claims)
+	digest := sha256.Sum256([]byte(signed))
+	sig, _ := rsa.SignPKCS1v15(rand.Reader, is.key, crypto.SHA256, digest[:])
+	return signed + "." + base64.RawURLEncoding.EncodeToString(sig)
+}
+
+// login runs the redirect of the user to the issuer and returns the callback URL.
+func login(t *testing.T, p *Provider, f Flow) *url.URL {
+	t.Helper()
+	authURL, err := p.AuthURL(context.Background(), f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }}
+	resp, err := client.Get(authURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	callback, err := resp.Location()
+	if err != nil {
+		t.Fatalf("authorize = %s: %v", resp.Status, err)
+	}
+	return callback
+}
+
+func newProvider(is *issuer) *Provider {
+	return New(Config{Issuer: is.URL, ClientID: "client", ClientSecret: "secret", RedirectURL: "https://jimin.example/callback"})
+}
+
+func TestLogin(t *testing.T) {
+	is := newIssuer(t)
+	p := newProvider(is)
+	f := NewFlow()
+
+	callback := login(t, p, f)
+	if got := callback.Query().Get("state"); got != f.State {
+		t.Errorf("callback state = %q, want %q", got, f.State)
+	}
+	claims, err := p.Exchange(context.Background(), callback.Query().Get("code"), f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if claims.Subject != "user-1" || claims.Email != "someone@example.com" || !claims.EmailVerified || claims.Name != "Someone" {
+		t.Errorf("claims = %+v", claims)
+	}
+
+	// codes are single use
+	if _, err := p.Exchange(context.Background(), callback.Query().Get("code"), f); !errors.Is(err, ErrExchange) {
+		t.Errorf("second exchange error = %v, want %v", err, ErrExchange)
+	}
+}
+
+func TestPKCE(t *testing.T) {
+	is := newIssuer(t)
+	p := newProvider(is)
+	f := NewFlow()
+
+	callback := login(t, p, f)
+	f.Verifier = NewFlow().Verifier
+	if _, err := p.Exchange(context.Background(), callback.Query().Get("code"), f); !errors.Is(err, ErrExchange) {
+		t.Errorf("exchange with another verifier error = %v, want %v", err, ErrExchange)
+	}
+}
+
+func TestInvalidTokens(t *testing.T) {
+	tests := map[string]func(claims map[string]any){
+		"nonce":         func(c map[string]any) { c["nonce"] = "replayed" },
+		"issuer":        func(c map[string]any) { c["iss"] = "https://evil.example" },
+		"audience":      func(c map[string]any) { c["aud"] = "another-client" },
+		"expired":       func(c map[string]any) { c["exp"] = time.Now().Add(-time.Hour).Unix() },
+		"future":        func(c map[string]any) { c["iat"] = time.Now().Add(time.Hour).Unix() },
+		"azp":           func(c map[string]any) { c["aud"] = []string{"client", "another-client"} },
+		"subject":       func(c map[string]any) { delete(c, "sub") },
+		"email_boolean": nil,
+	}
+
+	for name, tamper := range tests {
+		t.Run(name, func(t *testing.T) {
+			is := newIssuer(t)
+			is.tamper = tamper
+			if tamper == nil {
+				is.claims["email_verified"] = "true"
+			}
+			p := newProvider(is)
+			f := NewFlow()
+
+			claims, err := p.Exchange(context.Background(), login(t, p, f).Query().Get("code"), f)
+			if tamper == nil {
+				if err != nil || !claims.EmailVerified {
+					t.Errorf("string email_verified = %v, %v", claims, err)
+				}
+				return
+			}
+			if !errors.Is(err, ErrInvalidToken) {
+				t.Errorf("error = %v, want %v", err, ErrInvalidToken)
+			}
+		})
+	}
+}
+
+func TestSignature(t *testing.T) {
+	is := newIssuer(t)
+	p := newProvider(is)
+	token := is.token("client", "nonce")
+	parts := strings.Split(token, ".")
+
+	if _, err := p.Verify(context.Background(), token, "nonce"); err != nil {
+		t.Fatal(err)
+	}
+
+	none := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"none","kid":"key-1"}`))
+	hs := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"HS256","kid":"key-1"}`))
+	claims, _ := base64.RawURLEncoding.DecodeString(parts[1])
+	forged := base64.RawURLEncoding.EncodeToString([]byte(strings.Replace(string(claims), "user-1", "user-2", 1)))
+	for name, token := range map[string]string{
+		"none":    none + "." + parts[1] + ".",
+		"hmac":    hs + "." + parts[1] + "." + parts[2],
+		"forged":  parts[0] + "." + forged + "." + parts[2],
+		"garbage": "not a token",
+	} {
+		if _, err := p.Verify(context.Background(), token, "nonce"); !errors.Is(err, ErrInvalidToken) {
+			t.Errorf("%s token error = %v, want %v", name, err, ErrInvalidToken)
+		}
+	}
+}
+
+func TestKeyRotation(t *testing.T) {
+	is := newIssuer(t)
+	p := newProvider(is)
+	if _, err := p.Verify(context.Background(), is.token("client", "nonce"), "nonce"); err != nil {
+		t.Fatal(err)
+	}
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	is.mu.Lock()
+	is.key, is.kid = key, "key-2"
+	is.mu.Unlock()
+
+	// the keys are refetched at most once a minute
+	p.keys.fetched = time.Now().Add(-_KEYS_REFRESH_INTERVAL)
+	if _, err := p.Verify(context.Background(), is.token("client", "nonce"), "nonce"); err != nil {
+		t.Errorf("token of a rotated key: %v", err)
+	}
+}
+
+func TestDiscovery(t *testing.T) {
+	is := newIssuer(t)
+	p := New(Config{Issuer: is.URL + "/other", ClientID: "client"})
+	if _, err := p.AuthURL(context.Background(), NewFlow()); !errors.Is(err, ErrDiscovery) {
+		t.Errorf("unknown issuer error = %v, want %v", err, ErrDiscovery)
+	}
+}
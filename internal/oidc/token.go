@@ -0,0 +1,270 @@
+package oidc
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"slices"
+	"strings"
+	"time"
+)
+
+const (
+	// _CLOCK_SKEW is the difference tolerated between the clocks of the provider and the server.
+	_CLOCK_SKEW = time.Minute
+	// _KEYS_REFRESH_INTERVAL limits the refetches of the keys for unknown key ids.
+	_KEYS_REFRESH_INTERVAL = time.Minute
+)
+
+// Claims are the claims of an id token identifying the user.
+type Claims struct {
+	Issuer        string   `json:"iss"`
+	Subject       string   `json:"sub"`
+	Audience      audience `json:"aud"`
+	AuthorizedBy  string   `json:"azp"`
+	Expiry        int64    `json:"exp"`
+	IssuedAt      int64    `json:"iat"`
+	Nonce         string   `json:"nonce"`
+	Email         string   `json:"email"`
+	EmailVerified boolean  `json:"email_verified"`
+	Name          string   `json:"name"`
+}
+
+// audience is a single audience or a list of audiences.
+type audience []string
+
+func (a *audience) UnmarshalJSON(b []byte) error {
+	var s string
+	if json.Unmarshal(b, &s) == nil {
+		*a = audience{s}
+		return nil
+	}
+	return json.Unmarshal(b, (*[]string)(a))
+}
+
+// boolean is a boolean claim, which some providers send as a string.
+type boolean bool
+
+func (v *boolean) UnmarshalJSON(b []byte) error {
+	var s string
+	if json.Unmarshal(b, &s) == nil {
+		*v = s == "true"
+		return nil
+	}
+	return json.Unmarshal(b, (*bool)(v))
+}
+
+type header struct {
+	Alg string `json:"alg"`
+	Kid string `json:"kid"`
+}
+
+// Verify checks the signature, issuer, audience, expiry and nonce of an id token and returns its claims.
+func (p *Provider) Verify(ctx context.Context, token, nonce string) (*Claims, error) {
+	m, err := p.metadata(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("%w: not a JWS", ErrInvalidToken)
+	}
+	var h header
+	if err := decodeSegment(parts[0], &h); err != nil {
+		return nil, err
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidToken, err)
+	}
+	key, err := p.key(ctx, m, h.Kid)
+	if err != nil {
+		return nil, err
+	}
+	if err := verifySignature(h.Alg, key, parts[0]+"."+parts[1], sig); err != nil {
+		return nil, err
+	}
+
+	var c Claims
+	if err := decodeSegment(parts[1], &c); err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	switch {
+	case c.Issuer != m.Issuer:
+		return nil, fmt.Errorf("%w: issuer %q", ErrInvalidToken, c.Issuer)
+	case !slices.Contains(c.Audience, p.cfg.ClientID):
+		return nil, fmt.Errorf("%w: audience %q", ErrInvalidToken, c.Audience)
+	case len(c.Audience) > 1 && c.AuthorizedBy != p.cfg.ClientID:
+		return nil, fmt.Errorf("%w: authorized party %q", ErrInvalidToken, c.AuthorizedBy)
+	case now.After(time.Unix(c.Expiry, 0).Add(_CLOCK_SKEW)):
+		return nil, fmt.Errorf("%w: expired", ErrInvalidToken)
+	case time.Unix(c.IssuedAt, 0).After(now.Add(_CLOCK_SKEW)):
+		return nil, fmt.Errorf("%w: issued in the future", ErrInvalidToken)
+	case c.Nonce != nonce:
+		return nil, fmt.Errorf("%w: nonce mismatch", ErrInvalidToken)
+	case c.Subject == "":
+		return nil, fmt.Errorf("%w: no subject", ErrInvalidToken)
+	}
+	return &c, nil
+}
+
+func decodeSegment(s string, v any) error {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrInvalidToken, err)
+	}
+	if err := json.Unmarshal(b, v); err != nil {
+		return fmt.Errorf("%w: %w", ErrInvalidToken, err)
+	}
+	return nil
+}
+
+// verifySignature verifies a signature with the asymmetric algorithms of OpenID Connect.
+// The none and HMAC algorithms are rejected.
+func verifySignature(alg string, key crypto.PublicKey, signed string, sig []byte) error {
+	digest := sha256.Sum256([]byte(signed))
+	switch alg {
+	case "RS256":
+		if k, ok := key.(*rsa.PublicKey); ok && rsa.VerifyPKCS1v15(k, crypto.SHA256, digest[:], sig) == nil {
+			return nil
+		}
+	case "PS256":
+		if k, ok := key.(*rsa.PublicKey); ok && rsa.VerifyPSS(k, crypto.SHA256, digest[:], sig, nil) == nil {
+			return nil
+		}
+	case "ES256":
+		k, ok := key.(*ecdsa.PublicKey)
+		if ok && k.Curve == elliptic.P256() && len(sig) == 64 {
+			r, s := new(big.Int).SetBytes(sig[:32]), new(big.Int).SetBytes(sig[32:])
+			if ecdsa.Verify(k, digest[:], r, s) {
+				return nil
+			}
+		}
+	default:
+		return fmt.Errorf("%w: unsupported algorithm %q", ErrInvalidToken, alg)
+	}
+	return fmt.Errorf("%w: bad signature", ErrInvalidToken)
+}
+
+// keySet is the signing keys of a provider by key id.
+type keySet struct {
+	keys    map[string]crypto.PublicKey
+	fetched time.Time
+}
+
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+// key returns the signing key kid, refetching the keys of the provider if it rotated them.
+func (p *Provider) key(ctx context.Context, m *metadata, kid string) (crypto.PublicKey, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.keys != nil {
+		if k, ok := p.keys.lookup(kid); ok || time.Since(p.keys.fetched) < _KEYS_REFRESH_INTERVAL {
+			if !ok {
+				return nil, fmt.Errorf("%w: unknown key %q", ErrInvalidToken, kid)
+			}
+			return k, nil
+		}
+	}
+
+	var set struct {
+		Keys []jwk `json:"keys"`
+	}
+	if err := p.getJSON(ctx, m.JWKSURI, &set); err != nil {
+		return nil, fmt.Errorf("%w: keys: %w", ErrDiscovery, err)
+	}
+	ks := &keySet{keys: make(map[string]crypto.PublicKey), fetched: time.Now()}
+	for _, k := range set.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+		if pub, err := k.publicKey(); err == nil {
+			ks.keys[k.Kid] = pub
+		}
+	}
+	p.keys = ks
+
+	k, ok := ks.lookup(kid)
+	if !ok {
+		return nil, fmt.Errorf("%w: unknown key %q", ErrInvalidToken, kid)
+	}
+	return k, nil
+}
+
+// lookup returns the key kid, or the only key if the token names none.
+func (ks *keySet) lookup(kid string) (crypto.PublicKey, bool) {
+	if kid == "" && len(ks.keys) == 1 {
+		for _, k := range ks.keys {
+			return k, true
+		}
+	}
+	k, ok := ks.keys[kid]
+	return k, ok
+}
+
+func (k jwk) publicKey() (crypto.PublicKey, error) {
+	decode := func(s string) (*big.Int, error) {
+		b, err := base64.RawURLEncoding.DecodeString(s)
+		if err != nil {
+			return nil, err
+		}
+		return new(big.Int).SetBytes(b), nil
+	}
+
+	switch k.Kty {
+	case "RSA":
+		n, err := decode(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decode(k.E)
+		if err != nil {
+			return nil, err
+		}
+		if !e.IsInt64() || e.Int64() > 1<<31 {
+			return nil, fmt.Errorf("oidc: invalid RSA exponent")
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case "EC":
+		if k.Crv != "P-256" {
+			return nil, fmt.Errorf("oidc: unsupported curve %q", k.Crv)
+		}
+		x, err := base64.RawURLEncoding.DecodeString(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := base64.RawURLEncoding.DecodeString(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		if len(x) != 32 || len(y) != 32 {
+			return nil, fmt.Errorf("oidc: invalid P-256 point")
+		}
+		// ecdh validates the uncompressed point
+		if _, err := ecdh.P256().NewPublicKey(append(append([]byte{4}, x...), y...)); err != nil {
+			return nil, err
+		}
+		return &ecdsa.PublicKey{Curve: elliptic.P256(), X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}, nil
+	}
+	return nil, fmt.Errorf("oidc: unsupported key type %q", k.Kty)
+}
@@ -0,0 +1,284 @@
+package pipeline
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"gosuda.org/jimin/internal/asset"
+	"gosuda.org/jimin/internal/convert"
+	"gosuda.org/jimin/internal/indexer"
+)
+
+type StageKind string
+
+const (
+	StageFetch     StageKind = "fetch"
+	StageExtract   StageKind = "extract"
+	StageClean     StageKind = "clean"
+	StageChunk     StageKind = "chunk"
+	StageEmbed     StageKind = "embed"
+	StageSummarize StageKind = "summarize"
+	StageTag       StageKind = "tag"
+)
+
+var (
+	ErrUnknownStage   = errors.New("pipeline: unknown stage")
+	ErrMissingModel   = errors.New("pipeline: stage requires a model role")
+	ErrInvalidOptions = errors.New("pipeline: invalid stage options")
+	ErrStageOrder     = errors.New("pipeline: stages are out of order")
+)
+
+// StageConfig declares a stage of a pipeline.
+type StageConfig struct {
+	Stage StageKind `json:"stage"`
+	// Name identifies the stage in traces, it defaults to the stage kind.
+	Name string `json:"name,omitempty"`
+	// Model is the model role used by the embed, summarize and tag stages.
+	Model   string          `json:"model,omitempty"`
+	Options json.RawMessage `json:"options,omitempty"`
+	// Retries is the number of times a failed attempt is retried.
+	Retries int `json:"retries,omitempty"`
+	// Backoff is the delay in milliseconds before the first retry, doubled for each further retry.
+	Backoff int `json:"backoff,omitempty"`
+	// Timeout limits each attempt in seconds, 0 disables the limit.
+	Timeout int `json:"timeout,omitempty"`
+}
+
+// Config declares the ordered stages of a pipeline.
+type Config struct {
+	Stages []StageConfig `json:"stages"`
+}
+
+// Default is the pipeline of source types without a declared pipeline.
+var Default = Config{Stages: []StageConfig{
+	{Stage: StageFetch},
+	{Stage: StageExtract},
+	{Stage: StageClean},
+	{Stage: StageChunk},
+}}
+
+// Item is a document flowing through a pipeline. Each stage fills in its part.
+type Item struct {
+	URL string
+	// Policy is the sanitization policy of the source, used unless the extract stage sets one.
+	Policy string
+
+	ContentType string
+	Raw         []byte
+
+	Document *convert.Document
+	Title    string
+	Markdown string
+	// Assets are the images of the document captured by the extract stage.
+	Assets []asset.Asset
+
+	Chunks []indexer.Chunk
+	// Embeddings are the embeddings of Chunks, in the same order.
+	Embeddings [][]float32
+	Summary    string
+	Tags       []string
+}
+
+// Stage is a step of a pipeline.
+type Stage interface {
+	Run(ctx context.Context, item *Item) error
+}
+
+type StageFunc func(ctx context.Context, item *Item) error
+
+func (f StageFunc) Run(ctx context.Context, item *Item) error {
+	return f(ctx, item)
+}
+
+// Generator generates text with a language model.
+type Generator interface {
+	Generate(ctx context.Context, instruction, input string) (string, error)
+}
+
+// Embedder embeds texts with an embedding model.
+type Embedder interface {
+	Embed(ctx context.Context, texts []string) ([][]float32, error)
+}
+
+// Capturer stores the images of a document and points the document at the stored copies.
+type Capturer interface {
+	CaptureDocument(ctx context.Context, doc *convert.Document) ([]asset.Asset, error)
+}
+
+// Env provides the dependencies of the stages.
+type Env struct {
+	Client    *http.Client
+	Generator func(role string) (Generator, error)
+	Embedder  func(role string) (Embedder, error)
+	// Capturer captures the images of the extracted documents. Images are left in place if it is nil.
+	Capturer Capturer
+}
+
+type permanentError struct {
+	err error
+}
+
+func (e *permanentError) Error() string { return e.err.Error() }
+func (e *permanentError) Unwrap() error { return e.err }
+
+// Permanent marks err as a failure that is not retried.
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &permanentError{err: err}
+}
+
+func isPermanent(err error) bool {
+	var p *permanentError
+	return errors.As(err, &p) || errors.Is(err, context.Canceled)
+}
+
+// Trace records the execution of a stage.
+type Trace struct {
+	Stage    string        `json:"stage"`
+	Kind     StageKind     `json:"kind"`
+	Attempts int           `json:"attempts"`
+	Start    time.Time     `json:"start"`
+	Duration time.Duration `json:"duration"`
+	Error    string        `json:"error,omitempty"`
+}
+
+type stage struct {
+	cfg StageConfig
+	run Stage
+}
+
+// Pipeline executes its stages in order, retrying each stage on its own.
+type Pipeline struct {
+	stages []stage
+}
+
+// New builds the stages declared by cfg.
+func New(cfg Config, env Env) (*Pipeline, error) {
+	if env.Client == nil {
+		env.Client = http.DefaultClient
+	}
+
+	p := &Pipeline{}
+	last := -1
+	for _, sc := range cfg.Stages {
+		build, ok := builders[sc.Stage]
+		if !ok {
+			return nil, fmt.Errorf("%w: %q", ErrUnknownStage, sc.Stage)
+		}
+		// stages may be left out but not reordered, since each consumes the output of the previous ones
+		order := stageOrder(sc.Stage)
+		if order < last {
+			return nil, fmt.Errorf("%w: %s after %s", ErrStageOrder, sc.Stage, p.stages[len(p.stages)-1].cfg.Stage)
+		}
+		last = order
+		if sc.Name == "" {
+			sc.Name = string(sc.Stage)
+		}
+
+		run, err := build(sc, env)
+		if err != nil {
+			return nil, fmt.Errorf("pipeline: stage %s: %w", sc.Name, err)
+		}
+		p.stages = append(p.stages, stage{cfg: sc, run: run})
+	}
+	return p, nil
+}
+
+func stageOrder(kind StageKind) int {
+	for i, k := range []StageKind{StageFetch, StageExtract, StageClean, StageChunk, StageEmbed, StageSummarize, StageTag} {
+		if k == kind {
+			return i
+		}
+	}
+	return -1
+}
+
+// Select returns a pipeline of the stages of p with the given kinds.
+func (p *Pipeline) Select(kinds ...StageKind) *Pipeline {
+	sub := &Pipeline{}
+	for _, s := range p.stages {
+		for _, k := range kinds {
+			if s.cfg.Stage == k {
+				sub.stages = append(sub.stages, s)
+				break
+			}
+		}
+	}
+	return sub
+}
+
+// Has reports whether p has a stage of the kind.
+func (p *Pipeline) Has(kind StageKind) bool {
+	for _, s := range p.stages {
+		if s.cfg.Stage == kind {
+			return true
+		}
+	}
+	return false
+}
+
+// Run executes the stages on item and returns their traces.
+// It stops at the first stage that fails after its retries.
+func (p *Pipeline) Run(ctx context.Context, item *Item) ([]Trace, error) {
+	traces := make([]Trace, 0, len(p.stages))
+	for _, s := range p.stages {
+		t, err := s.execute(ctx, item)
+		traces = append(traces, t)
+
+		ev := log.Debug()
+		if err != nil {
+			ev = log.Warn().Err(err)
+		}
+		ev.Str("stage", t.Stage).Str("url", item.URL).Int("attempts", t.Attempts).Dur("duration", t.Duration).Msg("pipeline: stage finished")
+
+		if err != nil {
+			return traces, fmt.Errorf("pipeline: stage %s: %w", s.cfg.Name, err)
+		}
+	}
+	return traces, nil
+}
+
+func (s stage) execute(ctx context.Context, item *Item) (Trace, error) {
+	t := Trace{Stage: s.cfg.Name, Kind: s.cfg.Stage, Start: time.Now()}
+	backoff := time.Duration(s.cfg.Backoff) * time.Millisecond
+
+	var err error
+	for t.Attempts = 1; ; t.Attempts++ {
+		err = s.attempt(ctx, item)
+		if err == nil || isPermanent(err) || t.Attempts > s.cfg.Retries {
+			break
+		}
+
+		select {
+		case <-ctx.Done():
+			err = ctx.Err()
+		case <-time.After(backoff):
+		}
+		if ctx.Err() != nil {
+			break
+		}
+		backoff *= 2
+	}
+
+	t.Duration = time.Since(t.Start)
+	if err != nil {
+		t.Error = err.Error()
+	}
+	return t, err
+}
+
+func (s stage) attempt(ctx context.Context, item *Item) error {
+	if s.cfg.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, time.Duration(s.cfg.Timeout)*time.Second)
+		defer cancel()
+	}
+	return s.run.Run(ctx, item)
+}
@@ -0,0 +1,228 @@
+package pipeline
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"slices"
+	"strings"
+	"testing"
+
+	"gosuda.org/jimin/internal/asset"
+	"gosuda.org/jimin/internal/convert"
+)
+
+type fakeModel struct {
+	calls int
+	fail  int
+}
+
+func (m *fakeModel) Generate(ctx context.Context, instruction, input string) (string, error) {
+	m.calls++
+	if m.calls <= m.fail {
+		return "", errors.New("unavailable")
+	}
+	return "Go, Testing, go,  #pipelines\nextra", nil
+}
+
+func (m *fakeModel) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	m.calls++
+	out := make([][]float32, len(texts))
+	for i, t := range texts {
+		out[i] = []float32{float32(len(t))}
+	}
+	return out, nil
+}
+
+func testEnv(m *fakeModel) Env {
+	return Env{
+		Generator: func(role string) (Generator, error) { return m, nil },
+		Embedder:  func(role string) (Embedder, error) { return m, nil },
+	}
+}
+
+func TestPipeline(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Write([]byte(`<html><head><title>Guide</title></head><body><article>
+			<h1>Guide</h1><p>Share this page</p><p>The first paragraph explains the pipeline.</p>
+			<p>The second paragraph explains the stages.</p></article></body></html>`))
+	}))
+	defer srv.Close()
+
+	var cfg Config
+	err := json.Unmarshal([]byte(`{"stages": [
+		{"stage": "fetch"},
+		{"stage": "extract"},
+		{"stage": "clean", "options": {"drop_lines": ["^Share this"]}},
+		{"stage": "chunk", "options": {"size": 60, "overlap": 0}},
+		{"stage": "embed", "model": "embedder", "options": {"batch_size": 2}},
+		{"stage": "tag", "model": "tagger", "retries": 2, "options": {"max_tags": 3}}
+	]}`), &cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	env := testEnv(&fakeModel{})
+	env.Generator = func(role string) (Generator, error) { return &fakeModel{fail: 1}, nil }
+	env.Client = srv.Client()
+	p, err := New(cfg, env)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	item := &Item{URL: srv.URL}
+	traces, err := p.Run(context.Background(), item)
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	if len(traces) != 6 {
+		t.Fatalf("Run() = %d traces, want 6", len(traces))
+	}
+	if tr := traces[5]; tr.Stage != "tag" || tr.Attempts != 2 || tr.Error != "" {
+		t.Errorf("tag trace = %+v, want 2 attempts without error", tr)
+	}
+
+	if item.Title != "Guide" {
+		t.Errorf("Title = %q, want Guide", item.Title)
+	}
+	if strings.Contains(item.Markdown, "Share this") {
+		t.Errorf("Markdown = %q, want the share line dropped", item.Markdown)
+	}
+	if len(item.Chunks) < 2 || len(item.Embeddings) != len(item.Chunks) {
+		t.Errorf("got %d chunks and %d embeddings", len(item.Chunks), len(item.Embeddings))
+	}
+	if want := []string{"go", "testing", "pipelines"}; !slices.Equal(item.Tags, want) {
+		t.Errorf("Tags = %q, want %q", item.Tags, want)
+	}
+}
+
+func TestPipelineRetries(t *testing.T) {
+	attempts := 0
+	p := &Pipeline{stages: []stage{{
+		cfg: StageConfig{Name: "flaky", Retries: 2, Backoff: 1},
+		run: StageFunc(func(ctx context.Context, item *Item) error {
+			attempts++
+			return errors.New("transient")
+		}),
+	}, {
+		cfg: StageConfig{Name: "never"},
+		run: StageFunc(func(ctx context.Context, item *Item) error {
+			t.Error("stage after a failed stage ran")
+			return nil
+		}),
+	}}}
+
+	traces, err := p.Run(context.Background(), &Item{})
+	if err == nil || attempts != 3 || len(traces) != 1 || traces[0].Attempts != 3 {
+		t.Errorf("Run() = %+v, %v after %d attempts, want failure after 3 attempts", traces, err, attempts)
+	}
+
+	attempts = 0
+	p.stages[0].run = StageFunc(func(ctx context.Context, item *Item) error {
+		attempts++
+		return Permanent(ErrUnsupportedContent)
+	})
+	if _, err := p.Run(context.Background(), &Item{}); !errors.Is(err, ErrUnsupportedContent) || attempts != 1 {
+		t.Errorf("Run() = %v after %d attempts, want a permanent failure after 1 attempt", err, attempts)
+	}
+}
+
+func TestNewErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  Config
+		err  error
+	}{
+		{"unknown stage", Config{Stages: []StageConfig{{Stage: "translate"}}}, ErrUnknownStage},
+		{"out of order", Config{Stages: []StageConfig{{Stage: StageChunk}, {Stage: StageExtract}}}, ErrStageOrder},
+		{"missing model", Config{Stages: []StageConfig{{Stage: StageSummarize}}}, ErrMissingModel},
+		{"unknown option", Config{Stages: []StageConfig{{Stage: StageChunk, Options: json.RawMessage(`{"sise": 10}`)}}}, ErrInvalidOptions},
+		{"invalid pattern", Config{Stages: []StageConfig{{Stage: StageClean, Options: json.RawMessage(`{"drop_lines": ["("]}`)}}}, ErrInvalidOptions},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := New(tt.cfg, testEnv(&fakeModel{})); !errors.Is(err, tt.err) {
+				t.Errorf("New() error = %v, want %v", err, tt.err)
+			}
+		})
+	}
+}
+
+func TestSelect(t *testing.T) {
+	p, err := New(Default, Env{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sub := p.Select(StageChunk, StageEmbed)
+	if len(sub.stages) != 1 || !sub.Has(StageChunk) || sub.Has(StageFetch) {
+		t.Errorf("Select() = %+v, want the chunk stage", sub.stages)
+	}
+}
+
+func TestFetchSkipsKnownContent(t *testing.T) {
+	p, err := New(Config{Stages: []StageConfig{{Stage: StageFetch}, {Stage: StageExtract}}}, Env{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	item := &Item{URL: "http://invalid.invalid/", ContentType: "text/html", Raw: []byte("<h1>Cached</h1><p>Kept from the crawl.</p>")}
+	if _, err := p.Run(context.Background(), item); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if !strings.Contains(item.Markdown, "Kept from the crawl.") {
+		t.Errorf("Markdown = %q", item.Markdown)
+	}
+
+	item = &Item{URL: "http://invalid.invalid/", ContentType: "application/pdf", Raw: []byte("%PDF")}
+	if _, err := p.Run(context.Background(), item); !errors.Is(err, ErrUnsupportedContent) {
+		t.Errorf("Run() error = %v, want %v", err, ErrUnsupportedContent)
+	}
+}
+
+type fakeCapturer struct{}
+
+func (fakeCapturer) CaptureDocument(ctx context.Context, doc *convert.Document) ([]asset.Asset, error) {
+	var assets []asset.Asset
+	doc.Walk(func(n *convert.Node) bool {
+		if n.Kind == convert.NodeImage {
+			assets = append(assets, asset.Asset{Source: n.URL, URL: "/v1/assets/key", Caption: "a diagram"})
+			n.URL, n.Alt = "/v1/assets/key", "a diagram"
+		}
+		return true
+	})
+	doc.Render()
+	return assets, nil
+}
+
+func TestExtractCapturesImages(t *testing.T) {
+	p, err := New(Config{Stages: []StageConfig{{Stage: StageExtract}, {Stage: StageClean}}}, Env{Capturer: fakeCapturer{}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	item := &Item{URL: "https://example.com/", ContentType: "text/html", Raw: []byte(`<p>Overview</p><p><img src="/d.png"></p>`)}
+	if _, err := p.Run(context.Background(), item); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if len(item.Assets) != 1 || item.Assets[0].Source != "https://example.com/d.png" {
+		t.Errorf("Assets = %+v", item.Assets)
+	}
+	if !strings.Contains(item.Markdown, "![a diagram](/v1/assets/key)") {
+		t.Errorf("Markdown = %q, want the captured image", item.Markdown)
+	}
+}
+
+func TestClean(t *testing.T) {
+	md := "Title  \r\n\r\n\r\n\r\nText\t\n```\ncode  \n\n\n\n```\nAccept cookies\n"
+	got := clean(md, []*regexp.Regexp{regexp.MustCompile("(?i)^accept cookies")})
+	want := "Title\n\nText\n```\ncode  \n\n```"
+	if got != want {
+		t.Errorf("clean() = %q, want %q", got, want)
+	}
+}
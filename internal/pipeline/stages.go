@@ -0,0 +1,375 @@
+package pipeline
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"golang.org/x/net/html/charset"
+	"gosuda.org/jimin/internal/convert"
+	"gosuda.org/jimin/internal/indexer"
+)
+
+const (
+	_USER_AGENT            = "Mozilla/5.0 (compatible; jimin/1.0)"
+	_DEFAULT_MAX_BYTES     = 16 << 20
+	_DEFAULT_EMBED_BATCH   = 32
+	_DEFAULT_SUMMARY_INPUT = 16000
+	_DEFAULT_MAX_TAGS      = 5
+
+	_SUMMARIZE_INSTRUCTION = "Summarize the document in at most three sentences. Reply with the summary only."
+	_TAG_INSTRUCTION       = "List up to %d short topic tags for the document, separated by commas. Reply with the tags only."
+)
+
+var ErrUnsupportedContent = errors.New("pipeline: unsupported content type")
+
+type builder func(sc StageConfig, env Env) (Stage, error)
+
+var builders = map[StageKind]builder{
+	StageFetch:     buildFetch,
+	StageExtract:   buildExtract,
+	StageClean:     buildClean,
+	StageChunk:     buildChunk,
+	StageEmbed:     buildEmbed,
+	StageSummarize: buildSummarize,
+	StageTag:       buildTag,
+}
+
+func decodeOptions(sc StageConfig, v any) error {
+	if len(sc.Options) == 0 {
+		return nil
+	}
+	dec := json.NewDecoder(bytes.NewReader(sc.Options))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(v); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidOptions, err)
+	}
+	return nil
+}
+
+type fetchOptions struct {
+	UserAgent string `json:"user_agent,omitempty"`
+	MaxBytes  int64  `json:"max_bytes,omitempty"`
+}
+
+// buildFetch downloads the item unless its raw content is already known, e.g. from a crawl.
+func buildFetch(sc StageConfig, env Env) (Stage, error) {
+	opts := fetchOptions{UserAgent: _USER_AGENT, MaxBytes: _DEFAULT_MAX_BYTES}
+	if err := decodeOptions(sc, &opts); err != nil {
+		return nil, err
+	}
+
+	return StageFunc(func(ctx context.Context, item *Item) error {
+		if item.Raw != nil {
+			return nil
+		}
+
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, item.URL, nil)
+		if err != nil {
+			return Permanent(err)
+		}
+		req.Header.Set("User-Agent", opts.UserAgent)
+
+		resp, err := env.Client.Do(req)
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+
+		if resp.StatusCode != http.StatusOK {
+			err := fmt.Errorf("pipeline: unexpected status %s", resp.Status)
+			// server errors and rate limits may be transient
+			if resp.StatusCode >= 500 || resp.StatusCode == http.StatusTooManyRequests {
+				return err
+			}
+			return Permanent(err)
+		}
+
+		raw, err := io.ReadAll(io.LimitReader(resp.Body, opts.MaxBytes))
+		if err != nil {
+			return err
+		}
+		item.Raw = raw
+		item.ContentType = resp.Header.Get("Content-Type")
+		return nil
+	}), nil
+}
+
+type extractOptions struct {
+	Policy string `json:"policy,omitempty"`
+}
+
+// buildExtract converts the raw html of the item to markdown and captures its images.
+func buildExtract(sc StageConfig, env Env) (Stage, error) {
+	var opts extractOptions
+	if err := decodeOptions(sc, &opts); err != nil {
+		return nil, err
+	}
+
+	return StageFunc(func(ctx context.Context, item *Item) error {
+		if item.ContentType != "" {
+			mediaType, _, _ := mime.ParseMediaType(item.ContentType)
+			if mediaType != "text/html" && mediaType != "application/xhtml+xml" {
+				return Permanent(ErrUnsupportedContent)
+			}
+		}
+
+		r, err := charset.NewReader(bytes.NewReader(item.Raw), item.ContentType)
+		if err != nil {
+			return Permanent(err)
+		}
+		html, err := io.ReadAll(r)
+		if err != nil {
+			return Permanent(err)
+		}
+
+		policy := opts.Policy
+		if policy == "" {
+			policy = item.Policy
+		}
+		doc, err := convert.ConvertHTMLToDocument(string(html), item.URL, convert.WithPolicy(policy))
+		if err != nil {
+			return Permanent(err)
+		}
+
+		if env.Capturer != nil {
+			assets, err := env.Capturer.CaptureDocument(ctx, doc)
+			if err != nil {
+				return err
+			}
+			item.Assets = assets
+		}
+
+		item.Document = doc
+		item.Title = doc.Title
+		item.Markdown = doc.Markdown
+		return nil
+	}), nil
+}
+
+type cleanOptions struct {
+	// DropLines are patterns of lines to remove, e.g. cookie banners and share buttons.
+	DropLines []string `json:"drop_lines,omitempty"`
+}
+
+var blankLines = regexp.MustCompile(`\n{3,}`)
+
+// buildClean normalizes the whitespace of the markdown and drops unwanted lines.
+func buildClean(sc StageConfig, env Env) (Stage, error) {
+	var opts cleanOptions
+	if err := decodeOptions(sc, &opts); err != nil {
+		return nil, err
+	}
+
+	drop := make([]*regexp.Regexp, 0, len(opts.DropLines))
+	for _, p := range opts.DropLines {
+		re, err := regexp.Compile(p)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidOptions, err)
+		}
+		drop = append(drop, re)
+	}
+
+	return StageFunc(func(ctx context.Context, item *Item) error {
+		item.Markdown = clean(item.Markdown, drop)
+		return nil
+	}), nil
+}
+
+func clean(markdown string, drop []*regexp.Regexp) string {
+	lines := strings.Split(strings.ReplaceAll(markdown, "\r\n", "\n"), "\n")
+	out := lines[:0]
+	fenced := false
+	for _, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~") {
+			fenced = !fenced
+		}
+		if fenced {
+			out = append(out, line)
+			continue
+		}
+
+		line = strings.TrimRight(line, " \t")
+		dropped := false
+		for _, re := range drop {
+			if re.MatchString(trimmed) {
+				dropped = true
+				break
+			}
+		}
+		if !dropped {
+			out = append(out, line)
+		}
+	}
+
+	return strings.TrimSpace(blankLines.ReplaceAllString(strings.Join(out, "\n"), "\n\n"))
+}
+
+type chunkOptions struct {
+	Size    int `json:"size,omitempty"`
+	Overlap int `json:"overlap,omitempty"`
+}
+
+// buildChunk splits the markdown into chunks.
+func buildChunk(sc StageConfig, env Env) (Stage, error) {
+	opts := chunkOptions{Overlap: -1}
+	if err := decodeOptions(sc, &opts); err != nil {
+		return nil, err
+	}
+
+	return StageFunc(func(ctx context.Context, item *Item) error {
+		item.Chunks = indexer.SplitChunk(item.Markdown, opts.Size, opts.Overlap)
+		item.Embeddings = nil
+		return nil
+	}), nil
+}
+
+type embedOptions struct {
+	BatchSize int `json:"batch_size,omitempty"`
+}
+
+// buildEmbed embeds the chunks with the model of the stage.
+func buildEmbed(sc StageConfig, env Env) (Stage, error) {
+	opts := embedOptions{BatchSize: _DEFAULT_EMBED_BATCH}
+	if err := decodeOptions(sc, &opts); err != nil {
+		return nil, err
+	}
+	if sc.Model == "" || env.Embedder == nil {
+		return nil, ErrMissingModel
+	}
+	embedder, err := env.Embedder(sc.Model)
+	if err != nil {
+		return nil, err
+	}
+
+	return StageFunc(func(ctx context.Context, item *Item) error {
+		texts := make([]string, len(item.Chunks))
+		for i, c := range item.Chunks {
+			texts[i] = c.Content
+			if c.Heading != "" && !strings.HasPrefix(c.Content, "#") {
+				texts[i] = c.Heading + "\n\n" + c.Content
+			}
+		}
+
+		// a retry starts over, so a failed batch does not leave the embeddings misaligned
+		embeddings := make([][]float32, 0, len(texts))
+		for start := 0; start < len(texts); start += opts.BatchSize {
+			end := min(start+opts.BatchSize, len(texts))
+			batch, err := embedder.Embed(ctx, texts[start:end])
+			if err != nil {
+				return err
+			}
+			if len(batch) != end-start {
+				return Permanent(fmt.Errorf("pipeline: got %d embeddings for %d chunks", len(batch), end-start))
+			}
+			embeddings = append(embeddings, batch...)
+		}
+		item.Embeddings = embeddings
+		return nil
+	}), nil
+}
+
+type generateOptions struct {
+	Instruction string `json:"instruction,omitempty"`
+	// MaxInput is the number of bytes of the markdown given to the model.
+	MaxInput int `json:"max_input,omitempty"`
+	MaxTags  int `json:"max_tags,omitempty"`
+}
+
+func generator(sc StageConfig, env Env, opts *generateOptions) (Generator, error) {
+	if err := decodeOptions(sc, opts); err != nil {
+		return nil, err
+	}
+	if opts.MaxInput <= 0 {
+		opts.MaxInput = _DEFAULT_SUMMARY_INPUT
+	}
+	if sc.Model == "" || env.Generator == nil {
+		return nil, ErrMissingModel
+	}
+	return env.Generator(sc.Model)
+}
+
+// input returns the title and the beginning of the markdown of item.
+func input(item *Item, maxInput int) string {
+	md := item.Markdown
+	if len(md) > maxInput {
+		md = strings.ToValidUTF8(md[:maxInput], "")
+	}
+	if item.Title == "" {
+		return md
+	}
+	return item.Title + "\n\n" + md
+}
+
+// buildSummarize summarizes the item with the model of the stage.
+func buildSummarize(sc StageConfig, env Env) (Stage, error) {
+	opts := generateOptions{Instruction: _SUMMARIZE_INSTRUCTION}
+	gen, err := generator(sc, env, &opts)
+	if err != nil {
+		return nil, err
+	}
+
+	return StageFunc(func(ctx context.Context, item *Item) error {
+		if strings.TrimSpace(item.Markdown) == "" {
+			return nil
+		}
+		summary, err := gen.Generate(ctx, opts.Instruction, input(item, opts.MaxInput))
+		if err != nil {
+			return err
+		}
+		item.Summary = strings.TrimSpace(summary)
+		return nil
+	}), nil
+}
+
+// buildTag tags the item with topics chosen by the model of the stage.
+func buildTag(sc StageConfig, env Env) (Stage, error) {
+	opts := generateOptions{MaxTags: _DEFAULT_MAX_TAGS}
+	gen, err := generator(sc, env, &opts)
+	if err != nil {
+		return nil, err
+	}
+	if opts.MaxTags <= 0 {
+		opts.MaxTags = _DEFAULT_MAX_TAGS
+	}
+	if opts.Instruction == "" {
+		opts.Instruction = fmt.Sprintf(_TAG_INSTRUCTION, opts.MaxTags)
+	}
+
+	return StageFunc(func(ctx context.Context, item *Item) error {
+		if strings.TrimSpace(item.Markdown) == "" {
+			return nil
+		}
+		out, err := gen.Generate(ctx, opts.Instruction, input(item, opts.MaxInput))
+		if err != nil {
+			return err
+		}
+		item.Tags = parseTags(out, opts.MaxTags)
+		return nil
+	}), nil
+}
+
+func parseTags(s string, max int) []string {
+	var tags []string
+	seen := make(map[string]bool)
+	for _, t := range strings.FieldsFunc(s, func(r rune) bool { return r == ',' || r == '\n' }) {
+		t = strings.ToLower(strings.Trim(strings.TrimSpace(t), "#*-•.\"'` "))
+		if t == "" || seen[t] {
+			continue
+		}
+		seen[t] = true
+		tags = append(tags, t)
+		if len(tags) == max {
+			break
+		}
+	}
+	return tags
+}
@@ -11,6 +11,7 @@ import (
 
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/rs/zerolog/log"
+	"gosuda.org/jimin/database"
 	"gosuda.org/jimin/internal/randflake/sparx64"
 )
 
@@ -34,8 +35,10 @@ type RandFlake struct {
 	poolMutex  sync.Mutex
 	sourcePool []*source
 
-	box  *sparx64.Sparx64
-	stop chan struct{}
+	box    *sparx64.Sparx64
+	stop   chan struct{}
+	done   chan struct{}
+	closed bool
 }
 
 func NewRandFlake(db *pgxpool.Pool, secret []byte) (*RandFlake, error) {
@@ -51,13 +54,83 @@ func NewRandFlake(db *pgxpool.Pool, secret []byte) (*RandFlake, error) {
 	return randflake, nil
 }
 
-func (g *RandFlake) worker() {
+// Start starts the worker renewing the leases before they expire.
+func (g *RandFlake) Start() {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.stop != nil || g.closed {
+		return
+	}
+
+	g.stop = make(chan struct{})
+	g.done = make(chan struct{})
+	go g.worker(g.stop, g.done)
+}
+
+// Close stops the lease worker and releases the leases, so other processes can lease their ranges.
+// The generators must be closed first.
+func (g *RandFlake) Close(ctx context.Context) error {
+	g.mu.Lock()
+	stop, done := g.stop, g.done
+	g.stop, g.done = nil, nil
+	g.mu.Unlock()
+
+	if stop != nil {
+		close(stop)
+		select {
+		case <-done:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.closed {
+		return nil
+	}
+	g.closed = true
+
+	g.poolMutex.Lock()
+	g.sourcePool = nil
+	g.poolMutex.Unlock()
+
+	// ids of the current second may still be in use, a new holder of the ranges must not repeat them
+	select {
+	case <-time.After(time.Until(time.Now().Truncate(time.Second).Add(time.Second))):
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	c, err := g.db.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer c.Release()
+
+	var errs []error
+	q := database.New(c)
+	for _, l := range g.leases {
+		err := q.ReleaseRandflakeRangeLease(ctx, database.ReleaseRandflakeRangeLeaseParams{
+			ID:          l.node.ID,
+			LeaseHolder: l.node.LeaseHolder,
+		})
+		errs = append(errs, err)
+	}
+	g.leases = nil
+
+	return errors.Join(errs...)
+}
+
+func (g *RandFlake) worker(stop <-chan struct{}, done chan<- struct{}) {
+	defer close(done)
+
 	ticker := time.NewTicker(_LEASE_RENEWAL_INTERVAL)
 	defer ticker.Stop()
 
 	for {
 		select {
-		case <-g.stop:
+		case <-stop:
 			return
 		case <-ticker.C:
 			{
@@ -148,6 +221,9 @@ type source struct {
 func (g *RandFlake) newSource(ctx context.Context) (*source, error) {
 	g.mu.Lock()
 	defer g.mu.Unlock()
+	if g.closed {
+		return nil, ErrClosed
+	}
 	var free int64 = -1
 	var lease *lease
 
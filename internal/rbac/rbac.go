@@ -0,0 +1,220 @@
+// Package rbac decides what the members of a workspace may do.
+// Every member has a built-in role, and custom roles grant them more permissions.
+// It also decides what users may do on other users, which only the admins of the server may do freely.
+package rbac
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"gosuda.org/jimin/database"
+)
+
+// Permission is an action on a workspace or on users.
+type Permission string
+
+const (
+	// ViewWorkspace views the workspace with its members and roles.
+	ViewWorkspace Permission = "workspace.view"
+	// UpdateWorkspace renames the workspace.
+	UpdateWorkspace Permission = "workspace.update"
+	// ManageMembers adds and removes members and changes their roles, up to the role of the member doing it.
+	// Only owners make or remove owners and admins.
+	ManageMembers Permission = "members.manage"
+	// ManageRoles creates, changes and deletes custom roles.
+	ManageRoles Permission = "roles.manage"
+	// ManageAPIKeys creates and revokes the API keys of the workspace.
+	ManageAPIKeys Permission = "api_keys.manage"
+	// ViewDocuments views the documents and searches them.
+	ViewDocuments Permission = "documents.view"
+	// AddDocuments adds pages to crawl.
+	AddDocuments Permission = "documents.add"
+	// ReprocessDocuments starts and cancels reprocessing jobs.
+	ReprocessDocuments Permission = "documents.reprocess"
+	// Ask asks questions answered from the documents.
+	Ask Permission = "search.ask"
+	// ManageOwners makes and removes owners. Only owners have it, custom roles cannot grant it.
+	ManageOwners Permission = "owners.manage"
+)
+
+// Permissions on users. Workspace roles do not grant them.
+const (
+	// ViewUsers views the user itself and the users sharing a workspace with it.
+	ViewUsers Permission = "users.view"
+	// UpdateUsers changes the user itself.
+	UpdateUsers Permission = "users.update"
+	// ManageUsers creates users, and views and changes every user. Only admins have it.
+	ManageUsers Permission = "users.manage"
+)
+
+// UserPermissions are the permissions on users. Admins have all of them on every user.
+var UserPermissions = []Permission{ViewUsers, UpdateUsers, ManageUsers}
+
+// Permissions are the permissions custom roles grant.
+var Permissions = []Permission{
+	ViewWorkspace, UpdateWorkspace, ManageMembers, ManageRoles, ManageAPIKeys,
+	ViewDocuments, AddDocuments, ReprocessDocuments, Ask,
+}
+
+// Built-in roles, from the most to the least permissive.
+const (
+	Owner  = database.WsMemberRoleOWNER
+	Admin  = database.WsMemberRoleADMIN
+	Editor = database.WsMemberRoleEDITOR
+	Viewer = database.WsMemberRoleVIEWER
+)
+
+// roles are the permissions of the built-in roles.
+var roles = map[database.WsMemberRole][]Permission{
+	Owner: append(slices.Clone(Permissions), ManageOwners),
+	Admin: Permissions,
+	Editor: {
+		ViewWorkspace,
+		ViewDocuments, AddDocuments, ReprocessDocuments, Ask,
+	},
+	Viewer: {
+		ViewWorkspace,
+		ViewDocuments, Ask,
+	},
+}
+
+var (
+	ErrNotMember         = errors.New("rbac: not a member of the workspace")
+	ErrForbidden         = errors.New("rbac: permission denied")
+	ErrUnknownRole       = errors.New("rbac: unknown role")
+	ErrUnknownPermission = errors.New("rbac: unknown permission")
+	ErrLastOwner         = errors.New("rbac: a workspace needs an owner")
+)
+
+// ParseRole returns the built-in role named name, in any case.
+func ParseRole(name string) (database.WsMemberRole, error) {
+	role := database.WsMemberRole(strings.ToUpper(name))
+	if _, ok := roles[role]; !ok {
+		return "", ErrUnknownRole
+	}
+	return role, nil
+}
+
+// IsBuiltIn reports whether name is the name of a built-in role, which custom roles cannot take.
+func IsBuiltIn(name string) bool {
+	_, err := ParseRole(strings.TrimSpace(name))
+	return err == nil
+}
+
+// CheckPermissions returns ErrUnknownPermission if perms hold a permission custom roles cannot grant.
+func CheckPermissions(perms []string) error {
+	for _, p := range perms {
+		if !slices.Contains(Permissions, Permission(p)) {
+			return ErrUnknownPermission
+		}
+	}
+	return nil
+}
+
+// Access is what a member may do in a workspace.
+type Access struct {
+	MemberID int64
+	Role     database.WsMemberRole
+	// Granted are the permissions of the custom roles of the member.
+	Granted []Permission
+}
+
+// Allows reports whether the built-in role or a custom role of the member grants perm.
+func (a Access) Allows(perm Permission) bool {
+	if slices.Contains(roles[a.Role], perm) {
+		return true
+	}
+	// owners.manage is never granted by custom roles, whatever the database holds
+	return perm != ManageOwners && slices.Contains(a.Granted, perm)
+}
+
+// ranks are the built-in roles, from the least to the most permissive.
+var ranks = []database.WsMemberRole{Viewer, Editor, Admin, Owner}
+
+// Grants reports whether the member may give or take the built-in role role.
+// Members give and take only the roles at or below their own, and only owners those of owners and admins.
+func (a Access) Grants(role database.WsMemberRole) bool {
+	if role == Owner || role == Admin {
+		return a.Role == Owner
+	}
+	return slices.Index(ranks, a.Role) >= slices.Index(ranks, role)
+}
+
+// Authorizer authorizes the actions of users on workspaces.
+type Authorizer struct {
+	q *database.Queries
+}
+
+func New(db database.DBTX) *Authorizer {
+	return &Authorizer{q: database.New(db)}
+}
+
+// Access returns what a user may do in a workspace, or ErrNotMember.
+func (a *Authorizer) Access(ctx context.Context, userID, wsID int64) (Access, error) {
+	m, err := a.q.GetMemberAccess(ctx, database.GetMemberAccessParams{WsID: wsID, UserID: userID})
+	if errors.Is(err, pgx.ErrNoRows) {
+		return Access{}, ErrNotMember
+	}
+	if err != nil {
+		return Access{}, err
+	}
+
+	access := Access{MemberID: m.ID, Role: m.Role, Granted: make([]Permission, len(m.Permissions))}
+	for i, p := range m.Permissions {
+		access.Granted[i] = Permission(p)
+	}
+	return access, nil
+}
+
+// Authorize returns nil if a user may do perm in a workspace,
+// ErrNotMember if the user is not a member and ErrForbidden if no role of the member grants perm.
+// For the permissions on users, wsID is the user acted on, or zero for none in particular,
+// and ErrNotMember is returned if the users share no workspace.
+// It is the one check of every action on a workspace or a user, by the API,
+// and by the reprocessing jobs and feed subscriptions acting for a user in the background.
+func (a *Authorizer) Authorize(ctx context.Context, userID, wsID int64, perm Permission) error {
+	if slices.Contains(UserPermissions, perm) {
+		return a.authorizeUser(ctx, userID, wsID, perm)
+	}
+
+	access, err := a.Access(ctx, userID, wsID)
+	if err != nil {
+		return err
+	}
+	if !access.Allows(perm) {
+		return ErrForbidden
+	}
+	return nil
+}
+
+// authorizeUser authorizes a user permission of userID on the user targetID.
+func (a *Authorizer) authorizeUser(ctx context.Context, userID, targetID int64, perm Permission) error {
+	user, err := a.q.GetUserByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if user.IsAdmin {
+		return nil
+	}
+	if perm == ManageUsers {
+		return ErrForbidden
+	}
+	if targetID == 0 || targetID == userID {
+		return nil
+	}
+
+	shared, err := a.q.SharesWorkspace(ctx, database.SharesWorkspaceParams{UserID: userID, OtherID: targetID})
+	if err != nil {
+		return err
+	}
+	if !shared {
+		return ErrNotMember
+	}
+	if perm != ViewUsers {
+		return ErrForbidden
+	}
+	return nil
+}
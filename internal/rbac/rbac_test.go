@@ -0,0 +1,231 @@
+package rbac
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"gosuda.org/jimin/database"
+)
+
+// fakeDB answers GetMemberAccess with the member of user 1 in workspace 1.
+type fakeDB struct {
+	role  database.WsMemberRole
+	perms []string
+	err   error
+}
+
+func (db fakeDB) Exec(context.Context, string, ...interface{}) (pgconn.CommandTag, error) {
+	return pgconn.CommandTag{}, errors.ErrUnsupported
+}
+
+func (db fakeDB) Query(context.Context, string, ...interface{}) (pgx.Rows, error) {
+	return nil, errors.ErrUnsupported
+}
+
+func (db fakeDB) QueryRow(ctx context.Context, sql string, args ...interface{}) pgx.Row {
+	if db.err == nil && (args[0] != int64(1) || args[1] != int64(1)) {
+		return row{db: fakeDB{err: pgx.ErrNoRows}}
+	}
+	return row{db: db}
+}
+
+type row struct {
+	db fakeDB
+}
+
+func (r row) Scan(dest ...any) error {
+	if r.db.err != nil {
+		return r.db.err
+	}
+	*dest[0].(*int64) = 7
+	*dest[1].(*database.WsMemberRole) = r.db.role
+	*dest[2].(*[]string) = r.db.perms
+	return nil
+}
+
+func TestAuthorize(t *testing.T) {
+	errDB := errors.New("connection lost")
+	tests := []struct {
+		name         string
+		db           fakeDB
+		userID, wsID int64
+		perm         Permission
+		want         error
+	}{
+		{"owner views", fakeDB{role: Owner}, 1, 1, ViewDocuments, nil},
+		{"owner manages owners", fakeDB{role: Owner}, 1, 1, ManageOwners, nil},
+		{"admin manages members", fakeDB{role: Admin}, 1, 1, ManageMembers, nil},
+		{"admin manages keys", fakeDB{role: Admin}, 1, 1, ManageAPIKeys, nil},
+		{"admin manages owners", fakeDB{role: Admin}, 1, 1, ManageOwners, ErrForbidden},
+		{"editor adds documents", fakeDB{role: Editor}, 1, 1, AddDocuments, nil},
+		{"editor reprocesses", fakeDB{role: Editor}, 1, 1, ReprocessDocuments, nil},
+		{"editor renames", fakeDB{role: Editor}, 1, 1, UpdateWorkspace, ErrForbidden},
+		{"editor manages members", fakeDB{role: Editor}, 1, 1, ManageMembers, ErrForbidden},
+		{"viewer views", fakeDB{role: Viewer}, 1, 1, ViewWorkspace, nil},
+		{"viewer asks", fakeDB{role: Viewer}, 1, 1, Ask, nil},
+		{"viewer adds documents", fakeDB{role: Viewer}, 1, 1, AddDocuments, ErrForbidden},
+		{"viewer with a custom role", fakeDB{role: Viewer, perms: []string{"documents.add"}}, 1, 1, AddDocuments, nil},
+		{"custom role of other permissions", fakeDB{role: Viewer, perms: []string{"roles.manage"}}, 1, 1, ManageMembers, ErrForbidden},
+		{"custom role granting owners", fakeDB{role: Admin, perms: []string{"owners.manage"}}, 1, 1, ManageOwners, ErrForbidden},
+		{"unknown role", fakeDB{role: "GUEST"}, 1, 1, ViewWorkspace, ErrForbidden},
+		{"another user", fakeDB{role: Owner}, 2, 1, ViewWorkspace, ErrNotMember},
+		{"another workspace", fakeDB{role: Owner}, 1, 2, ViewWorkspace, ErrNotMember},
+		{"database error", fakeDB{err: errDB}, 1, 1, ViewWorkspace, errDB},
+	}
+	for _, tt := range tests {
+		err := New(tt.db).Authorize(context.Background(), tt.userID, tt.wsID, tt.perm)
+		if !errors.Is(err, tt.want) {
+			t.Errorf("%s: Authorize(%s) = %v, want %v", tt.name, tt.perm, err, tt.want)
+		}
+	}
+}
+
+// usersDB answers GetUserByID with user 1, an admin if admin is set,
+// and SharesWorkspace with whether user 1 shares a workspace with user 2.
+type usersDB struct {
+	fakeDB
+	admin, shared bool
+}
+
+func (db usersDB) QueryRow(ctx context.Context, sql string, args ...interface{}) pgx.Row {
+	if db.err != nil {
+		return row{db: db.fakeDB}
+	}
+	return usersRow{db: db, args: args}
+}
+
+type usersRow struct {
+	db   usersDB
+	args []any
+}
+
+func (r usersRow) Scan(dest ...any) error {
+	if len(dest) == 1 {
+		*dest[0].(*bool) = r.db.shared && r.args[0] == int64(1) && r.args[1] == int64(2)
+		return nil
+	}
+	if r.args[0] != int64(1) {
+		return pgx.ErrNoRows
+	}
+	*dest[0].(*int64) = 1
+	*dest[len(dest)-1].(*bool) = r.db.admin
+	return nil
+}
+
+func TestAuthorizeUser(t *testing.T) {
+	errDB := errors.New("connection lost")
+	tests := []struct {
+		name     string
+		db       usersDB
+		targetID int64
+		perm     Permission
+		want     error
+	}{
+		{"views itself", usersDB{}, 1, ViewUsers, nil},
+		{"views users", usersDB{}, 0, ViewUsers, nil},
+		{"views a coworker", usersDB{shared: true}, 2, ViewUsers, nil},
+		{"views a stranger", usersDB{}, 2, ViewUsers, ErrNotMember},
+		{"updates itself", usersDB{}, 1, UpdateUsers, nil},
+		{"updates a coworker", usersDB{shared: true}, 2, UpdateUsers, ErrForbidden},
+		{"updates a stranger", usersDB{}, 2, UpdateUsers, ErrNotMember},
+		{"manages users", usersDB{}, 0, ManageUsers, ErrForbidden},
+		{"manages itself", usersDB{}, 1, ManageUsers, ErrForbidden},
+		{"admin views a stranger", usersDB{admin: true}, 2, ViewUsers, nil},
+		{"admin updates a stranger", usersDB{admin: true}, 2, UpdateUsers, nil},
+		{"admin manages users", usersDB{admin: true}, 0, ManageUsers, nil},
+		{"database error", usersDB{fakeDB: fakeDB{err: errDB}}, 1, ViewUsers, errDB},
+	}
+	for _, tt := range tests {
+		err := New(tt.db).Authorize(context.Background(), 1, tt.targetID, tt.perm)
+		if !errors.Is(err, tt.want) {
+			t.Errorf("%s: Authorize(%s) = %v, want %v", tt.name, tt.perm, err, tt.want)
+		}
+	}
+
+	// workspace roles never grant the permissions on users
+	if err := CheckPermissions([]string{string(ManageUsers)}); err != ErrUnknownPermission {
+		t.Errorf("CheckPermissions(%s) = %v, want %v", ManageUsers, err, ErrUnknownPermission)
+	}
+}
+
+func TestRoles(t *testing.T) {
+	// every role has the permissions of the roles below it
+	order := []database.WsMemberRole{Owner, Admin, Editor, Viewer}
+	for i := 1; i < len(order); i++ {
+		for _, p := range roles[order[i]] {
+			if !(Access{Role: order[i-1]}).Allows(p) {
+				t.Errorf("%s lacks %s of %s", order[i-1], p, order[i])
+			}
+		}
+	}
+	for _, p := range Permissions {
+		if !(Access{Role: Admin}).Allows(p) {
+			t.Errorf("admin lacks %s", p)
+		}
+	}
+
+	tests := []struct {
+		name string
+		want database.WsMemberRole
+		err  error
+	}{
+		{"owner", Owner, nil},
+		{"Editor", Editor, nil},
+		{"VIEWER", Viewer, nil},
+		{"guest", "", ErrUnknownRole},
+		{"", "", ErrUnknownRole},
+	}
+	for _, tt := range tests {
+		if got, err := ParseRole(tt.name); got != tt.want || err != tt.err {
+			t.Errorf("ParseRole(%q) = %q, %v, want %q, %v", tt.name, got, err, tt.want, tt.err)
+		}
+	}
+	if !IsBuiltIn(" Admin ") || IsBuiltIn("reviewer") {
+		t.Error("built-in role names are not recognized")
+	}
+}
+
+func TestGrants(t *testing.T) {
+	tests := []struct {
+		role  database.WsMemberRole
+		grant database.WsMemberRole
+		want  bool
+	}{
+		{Owner, Owner, true},
+		{Owner, Admin, true},
+		{Admin, Admin, false},
+		{Admin, Owner, false},
+		{Admin, Editor, true},
+		{Admin, Viewer, true},
+		{Editor, Editor, true},
+		{Editor, Admin, false},
+		{Viewer, Editor, false},
+		{Viewer, Viewer, true},
+		{"GUEST", Viewer, false},
+	}
+	for _, tt := range tests {
+		if got := (Access{Role: tt.role}).Grants(tt.grant); got != tt.want {
+			t.Errorf("%s Grants(%s) = %v, want %v", tt.role, tt.grant, got, tt.want)
+		}
+	}
+}
+
+func TestCheckPermissions(t *testing.T) {
+	tests := []struct {
+		perms []string
+		want  error
+	}{
+		{nil, nil},
+		{[]string{"documents.view", "search.ask"}, nil},
+		{[]string{"owners.manage"}, ErrUnknownPermission},
+		{[]string{"documents.view", "documents.delete"}, ErrUnknownPermission},
+	}
+	for _, tt := range tests {
+		if err := CheckPermissions(tt.perms); err != tt.want {
+			t.Errorf("CheckPermissions(%q) = %v, want %v", tt.perms, err, tt.want)
+		}
+	}
+}
@@ -0,0 +1,98 @@
+package recrawl
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+)
+
+const _DEFAULT_MAX_BODY = 32 << 20
+
+var (
+	ErrUnexpectedCode = errors.New("recrawl: unexpected status code")
+	ErrTooLarge       = errors.New("recrawl: response too large")
+)
+
+// Validators are the cache validators of the last fetched version of a page.
+type Validators struct {
+	ETag         string
+	LastModified string
+}
+
+type Response struct {
+	// NotModified is set if the server answered a conditional request with 304 Not Modified.
+	NotModified bool
+	Status      int
+	Header      http.Header
+	Body        []byte
+	Validators  Validators
+}
+
+// Fetch requests url with the validators as conditional headers.
+func Fetch(ctx context.Context, client *http.Client, url string, v Validators) (*Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	if v.ETag != "" {
+		req.Header.Set("If-None-Match", v.ETag)
+	}
+	if v.LastModified != "" {
+		req.Header.Set("If-Modified-Since", v.LastModified)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	r := &Response{
+		Status: resp.StatusCode,
+		Header: resp.Header,
+		Validators: Validators{
+			ETag:         resp.Header.Get("ETag"),
+			LastModified: resp.Header.Get("Last-Modified"),
+		},
+	}
+
+	if resp.StatusCode == http.StatusNotModified {
+		r.NotModified = true
+		// a 304 may omit the validators
+		if r.Validators.ETag == "" {
+			r.Validators.ETag = v.ETag
+		}
+		if r.Validators.LastModified == "" {
+			r.Validators.LastModified = v.LastModified
+		}
+		return r, nil
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return r, ErrUnexpectedCode
+	}
+
+	r.Body, err = io.ReadAll(io.LimitReader(resp.Body, _DEFAULT_MAX_BODY+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(r.Body) > _DEFAULT_MAX_BODY {
+		return nil, ErrTooLarge
+	}
+
+	return r, nil
+}
+
+// ContentHash returns the hash of the extracted content of a page.
+// Trailing whitespace is ignored so that insignificant reformatting does not create a new version.
+func ContentHash(content string) string {
+	lines := strings.Split(strings.TrimSpace(content), "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimRight(line, " \t\r")
+	}
+	sum := sha256.Sum256([]byte(strings.Join(lines, "\n")))
+	return hex.EncodeToString(sum[:])
+}
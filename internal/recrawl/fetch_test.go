@@ -0,0 +1,46 @@
+package recrawl
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestFetchConditional(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("ETag", `"v1"`)
+		w.Header().Set("Last-Modified", "Mon, 19 Oct 2026 10:00:00 GMT")
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Write([]byte("<p>hello</p>"))
+	}))
+	defer srv.Close()
+
+	resp, err := Fetch(context.Background(), srv.Client(), srv.URL, Validators{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.NotModified || string(resp.Body) != "<p>hello</p>" || resp.Validators.ETag != `"v1"` {
+		t.Fatalf("resp = %+v", resp)
+	}
+
+	resp, err = Fetch(context.Background(), srv.Client(), srv.URL, resp.Validators)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !resp.NotModified || resp.Body != nil {
+		t.Fatalf("resp = %+v, want not modified", resp)
+	}
+}
+
+func TestContentHash(t *testing.T) {
+	if ContentHash("# Title\n\nbody  \n") != ContentHash("# Title\n\nbody") {
+		t.Error("trailing whitespace changed the hash")
+	}
+	if ContentHash("body") == ContentHash("body!") {
+		t.Error("different content has the same hash")
+	}
+}
@@ -0,0 +1,413 @@
+package recrawl
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/rs/zerolog/log"
+	"gosuda.org/jimin/database"
+	"gosuda.org/jimin/internal/asset"
+	"gosuda.org/jimin/internal/blob"
+	"gosuda.org/jimin/internal/crawler"
+)
+
+const (
+	_DEFAULT_CONCURRENCY   = 4
+	_DEFAULT_BATCH_SIZE    = 64
+	_DEFAULT_POLL_INTERVAL = time.Minute
+	_DEFAULT_TIMEOUT       = time.Second * 30
+	_DEFAULT_LEASE         = time.Minute * 30
+)
+
+type DB interface {
+	database.DBTX
+	Begin(ctx context.Context) (pgx.Tx, error)
+}
+
+type IDGenerator interface {
+	Generate(ctx context.Context) (int64, error)
+}
+
+// Content is the extracted content of a page.
+type Content struct {
+	Title    string
+	Markdown string
+	// Raw is the page the content was extracted from, kept to reprocess the page later.
+	Raw            []byte
+	RawContentType string
+	// ConverterVersion and ChunkerVersion are the versions that processed the page.
+	ConverterVersion int32
+	ChunkerVersion   int32
+	// Assets are the images captured from the page, recorded with the version.
+	Assets []asset.Asset
+	// Snapshots are the archival copies of the rendered page, recorded with the version.
+	Snapshots []crawler.Snapshot
+}
+
+type Config struct {
+	DB  DB
+	IDs IDGenerator
+	// Client is used for the conditional requests.
+	Client *http.Client
+	// Blobs stores the raw content of the versions. Raw content is not kept if it is nil.
+	Blobs blob.Store
+	// Extract extracts the content of a modified page, e.g. by rendering it in a browser.
+	Extract func(ctx context.Context, doc database.Document, resp *Response) (*Content, error)
+	// Schedule returns the schedule of a document. The adaptive schedule is used if it is nil.
+	Schedule func(doc database.Document) Schedule
+	// Changed is called after a new version of a document is stored, e.g. to reindex it.
+	Changed func(ctx context.Context, doc database.Document, version database.DocumentVersion) error
+
+	Concurrency  int
+	BatchSize    int
+	PollInterval time.Duration
+	// Lease is how long a claimed batch is held before other recrawlers may claim its documents again.
+	// It should cover crawling a whole batch, since a crawl only reschedules its own document.
+	Lease time.Duration
+}
+
+type Recrawler struct {
+	cfg Config
+	q   *database.Queries
+}
+
+func New(cfg Config) *Recrawler {
+	if cfg.Client == nil {
+		cfg.Client = &http.Client{Timeout: _DEFAULT_TIMEOUT}
+	}
+	if cfg.Schedule == nil {
+		cfg.Schedule = func(database.Document) Schedule { return Schedule{} }
+	}
+	if cfg.Concurrency <= 0 {
+		cfg.Concurrency = _DEFAULT_CONCURRENCY
+	}
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = _DEFAULT_BATCH_SIZE
+	}
+	if cfg.PollInterval <= 0 {
+		cfg.PollInterval = _DEFAULT_POLL_INTERVAL
+	}
+	if cfg.Lease <= 0 {
+		cfg.Lease = _DEFAULT_LEASE
+	}
+	return &Recrawler{cfg: cfg, q: database.New(cfg.DB)}
+}
+
+func timestamptz(t time.Time) pgtype.Timestamptz {
+	return pgtype.Timestamptz{Time: t, Valid: true}
+}
+
+// Add registers url for recrawling and schedules its first crawl immediately.
+// The existing document is returned if url is already registered in the workspace.
+func (r *Recrawler) Add(ctx context.Context, wsID int64, source, url string) (database.Document, error) {
+	return r.add(ctx, wsID, source, url, timestamptz(time.Now()))
+}
+
+func (r *Recrawler) add(ctx context.Context, wsID int64, source, url string, next pgtype.Timestamptz) (database.Document, error) {
+	doc, err := r.q.GetDocumentByURL(ctx, database.GetDocumentByURLParams{WsID: wsID, Url: url})
+	if err == nil || !errors.Is(err, pgx.ErrNoRows) {
+		return doc, err
+	}
+
+	id, err := r.cfg.IDs.Generate(ctx)
+	if err != nil {
+		return database.Document{}, err
+	}
+
+	doc = database.Document{WsID: wsID, Source: source, Url: url}
+	return r.q.CreateDocument(ctx, database.CreateDocumentParams{
+		ID:              id,
+		WsID:            wsID,
+		Source:          source,
+		Url:             url,
+		RecrawlInterval: int64(r.cfg.Schedule(doc).Initial() / time.Second),
+		NextCrawlAt:     next,
+	})
+}
+
+// Ingest stores content imported from outside the crawl, e.g. from a file or a web archive,
+// as the current version of url and reports whether it changed.
+// Documents of urls that cannot be fetched, like file urls, are never recrawled.
+func (r *Recrawler) Ingest(ctx context.Context, wsID int64, source, url string, content *Content, fetchedAt time.Time) (database.Document, bool, error) {
+	next := timestamptz(time.Now())
+	if !strings.HasPrefix(url, "http://") && !strings.HasPrefix(url, "https://") {
+		next = pgtype.Timestamptz{InfinityModifier: pgtype.Infinity, Valid: true}
+	}
+
+	doc, err := r.add(ctx, wsID, source, url, next)
+	if err != nil {
+		return database.Document{}, false, err
+	}
+
+	hash := ContentHash(content.Markdown)
+	if hash == doc.ContentHash {
+		return doc, false, nil
+	}
+
+	version, err := r.createVersion(ctx, doc, content, hash, Validators{}, fetchedAt)
+	if err != nil {
+		return doc, false, err
+	}
+	doc.CurrentVersionID = version.ID
+	doc.Title = version.Title
+	doc.ContentHash = hash
+
+	if r.cfg.Changed != nil {
+		if err := r.cfg.Changed(ctx, doc, version); err != nil {
+			return doc, true, err
+		}
+	}
+	return doc, true, nil
+}
+
+// Run crawls the due documents until ctx is canceled.
+func (r *Recrawler) Run(ctx context.Context) error {
+	ticker := time.NewTicker(r.cfg.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		for {
+			n, err := r.RunOnce(ctx)
+			if err != nil {
+				if ctx.Err() != nil {
+					return ctx.Err()
+				}
+				log.Error().Err(err).Msg("recrawl: failed to claim due documents")
+			}
+			// keep going while full batches are due
+			if n < r.cfg.BatchSize {
+				break
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// RunOnce claims a batch of due documents, crawls them and returns the number of documents crawled.
+// Claiming pushes the next crawl of the documents past the lease, so concurrent recrawlers
+// never crawl the same document and a document left by a stopped recrawler is picked up again.
+func (r *Recrawler) RunOnce(ctx context.Context) (int, error) {
+	now := time.Now()
+	docs, err := r.q.ClaimDueDocuments(ctx, database.ClaimDueDocumentsParams{
+		LeaseUntil:  timestamptz(now.Add(r.cfg.Lease)),
+		NextCrawlAt: timestamptz(now),
+		PageSize:    int32(r.cfg.BatchSize),
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	sema := make(chan struct{}, r.cfg.Concurrency)
+	var wg sync.WaitGroup
+	for _, doc := range docs {
+		sema <- struct{}{}
+		wg.Add(1)
+		go func(doc database.Document) {
+			defer func() {
+				<-sema
+				wg.Done()
+			}()
+
+			if _, err := r.Crawl(ctx, doc); err != nil {
+				log.Warn().Err(err).Int64("document", doc.ID).Str("url", doc.Url).Msg("recrawl: failed to crawl document")
+			}
+		}(doc)
+	}
+	wg.Wait()
+
+	return len(docs), nil
+}
+
+// Crawl fetches doc and stores a new version if its content changed.
+// Failed crawls are rescheduled like unchanged pages, so the interval backs off.
+func (r *Recrawler) Crawl(ctx context.Context, doc database.Document) (bool, error) {
+	now := time.Now()
+
+	validators := Validators{ETag: doc.Etag, LastModified: doc.LastModified}
+	if doc.CurrentVersionID == 0 {
+		validators = Validators{}
+	}
+
+	changed, validators, crawlErr := r.crawl(ctx, doc, validators, now)
+	if ctx.Err() != nil {
+		return false, ctx.Err()
+	}
+
+	next, interval, err := r.cfg.Schedule(doc).Next(now, time.Duration(doc.RecrawlInterval)*time.Second, changed)
+	if err != nil {
+		return changed, err
+	}
+
+	unchanged := doc.UnchangedCount + 1
+	if changed {
+		unchanged = 0
+	}
+
+	err = r.q.UpdateDocumentCrawl(ctx, database.UpdateDocumentCrawlParams{
+		ID:              doc.ID,
+		Etag:            validators.ETag,
+		LastModified:    validators.LastModified,
+		RecrawlInterval: int64(interval / time.Second),
+		UnchangedCount:  unchanged,
+		LastCrawledAt:   timestamptz(now),
+		NextCrawlAt:     timestamptz(next),
+	})
+	if err != nil {
+		return changed, err
+	}
+
+	return changed, crawlErr
+}
+
+func (r *Recrawler) crawl(ctx context.Context, doc database.Document, validators Validators, now time.Time) (bool, Validators, error) {
+	resp, err := Fetch(ctx, r.cfg.Client, doc.Url, validators)
+	if err != nil {
+		return false, validators, err
+	}
+	if resp.NotModified {
+		return false, resp.Validators, nil
+	}
+
+	content, err := r.cfg.Extract(ctx, doc, resp)
+	if err != nil {
+		return false, validators, err
+	}
+
+	hash := ContentHash(content.Markdown)
+	if hash == doc.ContentHash {
+		return false, resp.Validators, nil
+	}
+
+	version, err := r.createVersion(ctx, doc, content, hash, resp.Validators, now)
+	if err != nil {
+		return false, validators, err
+	}
+
+	if r.cfg.Changed != nil {
+		doc.CurrentVersionID = version.ID
+		doc.Title = version.Title
+		doc.ContentHash = hash
+		if err := r.cfg.Changed(ctx, doc, version); err != nil {
+			log.Error().Err(err).Int64("document", doc.ID).Msg("recrawl: failed to handle changed document")
+		}
+	}
+
+	return true, resp.Validators, nil
+}
+
+func (r *Recrawler) createVersion(ctx context.Context, doc database.Document, content *Content, hash string, v Validators, now time.Time) (database.DocumentVersion, error) {
+	var rawKey string
+	if r.cfg.Blobs != nil && len(content.Raw) > 0 {
+		b, err := r.cfg.Blobs.Put(ctx, bytes.NewReader(content.Raw))
+		if err != nil {
+			return database.DocumentVersion{}, err
+		}
+		rawKey = b.Key
+	}
+
+	id, err := r.cfg.IDs.Generate(ctx)
+	if err != nil {
+		return database.DocumentVersion{}, err
+	}
+
+	var version database.DocumentVersion
+	err = pgx.BeginFunc(ctx, r.cfg.DB, func(tx pgx.Tx) error {
+		q := r.q.WithTx(tx)
+
+		var err error
+		version, err = q.CreateDocumentVersion(ctx, database.CreateDocumentVersionParams{
+			ID:               id,
+			DocumentID:       doc.ID,
+			Title:            content.Title,
+			Content:          content.Markdown,
+			ContentHash:      hash,
+			Etag:             v.ETag,
+			LastModified:     v.LastModified,
+			FetchedAt:        timestamptz(now),
+			RawKey:           rawKey,
+			RawContentType:   content.RawContentType,
+			ConverterVersion: content.ConverterVersion,
+			ChunkerVersion:   content.ChunkerVersion,
+		})
+		if err != nil {
+			return err
+		}
+		if err := CreateAssets(ctx, q, r.cfg.IDs, doc, version.ID, content.Assets); err != nil {
+			return err
+		}
+		if err := CreateSnapshots(ctx, q, r.cfg.IDs, doc, version.ID, content.Snapshots); err != nil {
+			return err
+		}
+
+		return q.SetDocumentVersion(ctx, database.SetDocumentVersionParams{
+			ID:               doc.ID,
+			CurrentVersionID: version.ID,
+			Title:            content.Title,
+			ContentHash:      hash,
+			LastChangedAt:    timestamptz(now),
+		})
+	})
+
+	return version, err
+}
+
+// CreateAssets records the assets captured from a version of doc.
+func CreateAssets(ctx context.Context, q *database.Queries, ids IDGenerator, doc database.Document, versionID int64, assets []asset.Asset) error {
+	for _, a := range assets {
+		id, err := ids.Generate(ctx)
+		if err != nil {
+			return err
+		}
+		err = q.CreateDocumentAsset(ctx, database.CreateDocumentAssetParams{
+			ID:          id,
+			WsID:        doc.WsID,
+			DocumentID:  doc.ID,
+			VersionID:   versionID,
+			SourceUrl:   a.Source,
+			BlobKey:     a.Blob.Key,
+			ContentType: a.ContentType,
+			Size:        a.Blob.Size,
+			Caption:     a.Caption,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// CreateSnapshots records the snapshots of the page of a version of doc.
+func CreateSnapshots(ctx context.Context, q *database.Queries, ids IDGenerator, doc database.Document, versionID int64, snapshots []crawler.Snapshot) error {
+	for _, s := range snapshots {
+		id, err := ids.Generate(ctx)
+		if err != nil {
+			return err
+		}
+		err = q.CreateDocumentSnapshot(ctx, database.CreateDocumentSnapshotParams{
+			ID:          id,
+			WsID:        doc.WsID,
+			DocumentID:  doc.ID,
+			VersionID:   versionID,
+			Kind:        string(s.Kind),
+			BlobKey:     s.Blob.Key,
+			ContentType: s.ContentType,
+			Size:        s.Blob.Size,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
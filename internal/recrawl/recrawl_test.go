@@ -0,0 +1,75 @@
+package recrawl
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+var errQuery = errors.New("query failed")
+
+// claimDB records the queries of the recrawler and fails them.
+type claimDB struct {
+	sql  string
+	args []any
+}
+
+func (db *claimDB) Exec(context.Context, string, ...any) (pgconn.CommandTag, error) {
+	return pgconn.CommandTag{}, errQuery
+}
+
+func (db *claimDB) Query(_ context.Context, sql string, args ...any) (pgx.Rows, error) {
+	db.sql, db.args = sql, args
+	return nil, errQuery
+}
+
+func (db *claimDB) QueryRow(context.Context, string, ...any) pgx.Row { return nil }
+
+func (db *claimDB) Begin(context.Context) (pgx.Tx, error) { return nil, errQuery }
+
+func TestRunOnceClaims(t *testing.T) {
+	tests := []struct {
+		name  string
+		cfg   Config
+		lease time.Duration
+		batch int32
+	}{
+		{"defaults", Config{}, _DEFAULT_LEASE, _DEFAULT_BATCH_SIZE},
+		{"configured", Config{Lease: time.Minute, BatchSize: 8}, time.Minute, 8},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := &claimDB{}
+			tt.cfg.DB = db
+			r := New(tt.cfg)
+
+			start := time.Now()
+			if _, err := r.RunOnce(context.Background()); !errors.Is(err, errQuery) {
+				t.Fatalf("RunOnce() error = %v, want %v", err, errQuery)
+			}
+			if !strings.Contains(db.sql, "UPDATE documents") || !strings.Contains(db.sql, "FOR UPDATE SKIP LOCKED") {
+				t.Fatalf("RunOnce() does not claim the due documents: %s", db.sql)
+			}
+			if len(db.args) != 3 {
+				t.Fatalf("RunOnce() args = %v", db.args)
+			}
+
+			lease, due := db.args[0].(pgtype.Timestamptz).Time, db.args[1].(pgtype.Timestamptz).Time
+			if due.Before(start) || due.After(time.Now()) {
+				t.Errorf("claimed documents due by %v, want due by the time of the claim", due)
+			}
+			if got := lease.Sub(due); got != tt.lease {
+				t.Errorf("lease = %v, want %v", got, tt.lease)
+			}
+			if got := db.args[2].(int32); got != tt.batch {
+				t.Errorf("page size = %d, want %d", got, tt.batch)
+			}
+		})
+	}
+}
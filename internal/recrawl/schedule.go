@@ -0,0 +1,241 @@
+package recrawl
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	_DEFAULT_MIN_INTERVAL = time.Hour
+	_DEFAULT_MAX_INTERVAL = time.Hour * 24 * 30
+	_DEFAULT_INTERVAL     = time.Hour * 24
+)
+
+var ErrInvalidCron = errors.New("recrawl: invalid cron expression")
+
+// Schedule decides when a page is crawled again.
+// With a cron expression pages are crawled at the scheduled times.
+// Otherwise the interval adapts to how often the page changes:
+// it is halved when the page changed and grows by half when it did not.
+type Schedule struct {
+	Cron string `json:"cron,omitempty"`
+	// MinInterval and MaxInterval bound the adaptive interval in seconds.
+	MinInterval int `json:"min_interval,omitempty"`
+	MaxInterval int `json:"max_interval,omitempty"`
+}
+
+func (s Schedule) bounds() (time.Duration, time.Duration) {
+	min := time.Duration(s.MinInterval) * time.Second
+	if min <= 0 {
+		min = _DEFAULT_MIN_INTERVAL
+	}
+	max := time.Duration(s.MaxInterval) * time.Second
+	if max <= 0 {
+		max = _DEFAULT_MAX_INTERVAL
+	}
+	if max < min {
+		max = min
+	}
+	return min, max
+}
+
+// Initial returns the interval of a page that has not been crawled before.
+func (s Schedule) Initial() time.Duration {
+	min, max := s.bounds()
+	return clamp(_DEFAULT_INTERVAL, min, max)
+}
+
+// Next returns the next crawl time and the adapted interval after a crawl at now.
+func (s Schedule) Next(now time.Time, interval time.Duration, changed bool) (time.Time, time.Duration, error) {
+	min, max := s.bounds()
+	if interval <= 0 {
+		interval = s.Initial()
+	}
+
+	if changed {
+		interval /= 2
+	} else {
+		interval += interval / 2
+	}
+	interval = clamp(interval, min, max)
+
+	if s.Cron != "" {
+		c, err := ParseCron(s.Cron)
+		if err != nil {
+			return time.Time{}, 0, err
+		}
+		// an expression that never fires falls back to the adaptive interval
+		if next := c.Next(now); !next.IsZero() {
+			return next, interval, nil
+		}
+	}
+
+	return now.Add(interval), interval, nil
+}
+
+func clamp(d, min, max time.Duration) time.Duration {
+	if d < min {
+		return min
+	}
+	if d > max {
+		return max
+	}
+	return d
+}
+
+// Cron is a parsed five field cron expression: minute, hour, day of month, month and day of week.
+type Cron struct {
+	minute, hour, dom, month, dow uint64
+	// domStar and dowStar record unrestricted day fields.
+	// If both day fields are restricted, a time matches either of them.
+	domStar, dowStar bool
+}
+
+var cronMacros = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+var monthNames = []string{"jan", "feb", "mar", "apr", "may", "jun", "jul", "aug", "sep", "oct", "nov", "dec"}
+var dowNames = []string{"sun", "mon", "tue", "wed", "thu", "fri", "sat"}
+
+// ParseCron parses a five field cron expression or one of the @yearly, @monthly,
+// @weekly, @daily and @hourly macros. Times are evaluated in the location of the given time.
+func ParseCron(expr string) (*Cron, error) {
+	expr = strings.TrimSpace(expr)
+	if macro, ok := cronMacros[strings.ToLower(expr)]; ok {
+		expr = macro
+	}
+
+	fields := strings.Fields(expr)
+	if len(fields) != 5 {
+		return nil, ErrInvalidCron
+	}
+
+	var c Cron
+	var err error
+	if c.minute, err = parseCronField(fields[0], 0, 59, nil); err != nil {
+		return nil, err
+	}
+	if c.hour, err = parseCronField(fields[1], 0, 23, nil); err != nil {
+		return nil, err
+	}
+	if c.dom, err = parseCronField(fields[2], 1, 31, nil); err != nil {
+		return nil, err
+	}
+	if c.month, err = parseCronField(fields[3], 1, 12, monthNames); err != nil {
+		return nil, err
+	}
+	if c.dow, err = parseCronField(fields[4], 0, 7, dowNames); err != nil {
+		return nil, err
+	}
+	// 7 is sunday as well
+	if c.dow&(1<<7) != 0 {
+		c.dow |= 1
+	}
+	c.domStar = fields[2] == "*" || fields[2] == "?"
+	c.dowStar = fields[4] == "*" || fields[4] == "?"
+
+	return &c, nil
+}
+
+func parseCronField(field string, min, max int, names []string) (uint64, error) {
+	var bits uint64
+	for _, part := range strings.Split(field, ",") {
+		step := 1
+		if rng, s, ok := strings.Cut(part, "/"); ok {
+			n, err := strconv.Atoi(s)
+			if err != nil || n <= 0 {
+				return 0, ErrInvalidCron
+			}
+			part, step = rng, n
+		}
+
+		lo, hi := min, max
+		switch {
+		case part == "*" || part == "?":
+		case strings.Contains(part, "-"):
+			a, b, _ := strings.Cut(part, "-")
+			var err error
+			if lo, err = cronValue(a, min, names); err != nil {
+				return 0, err
+			}
+			if hi, err = cronValue(b, min, names); err != nil {
+				return 0, err
+			}
+		default:
+			v, err := cronValue(part, min, names)
+			if err != nil {
+				return 0, err
+			}
+			lo = v
+			if step == 1 {
+				hi = v
+			}
+		}
+
+		if lo < min || hi > max || lo > hi {
+			return 0, ErrInvalidCron
+		}
+		for v := lo; v <= hi; v += step {
+			bits |= 1 << uint(v)
+		}
+	}
+	return bits, nil
+}
+
+func cronValue(s string, min int, names []string) (int, error) {
+	for i, name := range names {
+		if strings.EqualFold(s, name) {
+			return i + min, nil
+		}
+	}
+	v, err := strconv.Atoi(s)
+	if err != nil {
+		return 0, ErrInvalidCron
+	}
+	return v, nil
+}
+
+func (c *Cron) dayMatches(t time.Time) bool {
+	dom := c.dom&(1<<uint(t.Day())) != 0
+	dow := c.dow&(1<<uint(t.Weekday())) != 0
+	if c.domStar || c.dowStar {
+		return dom && dow
+	}
+	return dom || dow
+}
+
+// Next returns the first scheduled time after t, or the zero time if there is none within five years.
+func (c *Cron) Next(t time.Time) time.Time {
+	t = t.Truncate(time.Minute).Add(time.Minute)
+	limit := t.AddDate(5, 0, 0)
+
+	for t.Before(limit) {
+		if c.month&(1<<uint(t.Month())) == 0 {
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, t.Location())
+			continue
+		}
+		if !c.dayMatches(t) {
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, t.Location())
+			continue
+		}
+		if c.hour&(1<<uint(t.Hour())) == 0 {
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, t.Location())
+			continue
+		}
+		if c.minute&(1<<uint(t.Minute())) == 0 {
+			t = t.Add(time.Minute)
+			continue
+		}
+		return t
+	}
+	return time.Time{}
+}
@@ -0,0 +1,90 @@
+package recrawl
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestCronNext(t *testing.T) {
+	from := time.Date(2026, time.October, 19, 10, 30, 0, 0, time.UTC) // monday
+
+	tests := []struct {
+		expr string
+		want time.Time
+	}{
+		{"*/15 * * * *", time.Date(2026, time.October, 19, 10, 45, 0, 0, time.UTC)},
+		{"0 * * * *", time.Date(2026, time.October, 19, 11, 0, 0, 0, time.UTC)},
+		{"@daily", time.Date(2026, time.October, 20, 0, 0, 0, 0, time.UTC)},
+		{"0 9 * * mon-fri", time.Date(2026, time.October, 20, 9, 0, 0, 0, time.UTC)},
+		{"0 9 * * 0", time.Date(2026, time.October, 25, 9, 0, 0, 0, time.UTC)},
+		{"0 9 * * 7", time.Date(2026, time.October, 25, 9, 0, 0, 0, time.UTC)},
+		{"30 2 1 * *", time.Date(2026, time.November, 1, 2, 30, 0, 0, time.UTC)},
+		{"0 0 1 jan *", time.Date(2027, time.January, 1, 0, 0, 0, 0, time.UTC)},
+		// both day fields restricted: either matches
+		{"0 0 25 * 3", time.Date(2026, time.October, 21, 0, 0, 0, 0, time.UTC)},
+		{"0 12 29 2 *", time.Date(2028, time.February, 29, 12, 0, 0, 0, time.UTC)},
+	}
+
+	for _, tt := range tests {
+		c, err := ParseCron(tt.expr)
+		if err != nil {
+			t.Errorf("ParseCron(%q): %v", tt.expr, err)
+			continue
+		}
+		if got := c.Next(from); !got.Equal(tt.want) {
+			t.Errorf("ParseCron(%q).Next = %v, want %v", tt.expr, got, tt.want)
+		}
+	}
+}
+
+func TestParseCronInvalid(t *testing.T) {
+	for _, expr := range []string{"", "* * * *", "60 * * * *", "* 24 * * *", "* * 0 * *", "*/0 * * * *", "5-1 * * * *", "* * * foo *"} {
+		if _, err := ParseCron(expr); !errors.Is(err, ErrInvalidCron) {
+			t.Errorf("ParseCron(%q) err = %v", expr, err)
+		}
+	}
+}
+
+func TestScheduleAdaptive(t *testing.T) {
+	s := Schedule{MinInterval: 3600, MaxInterval: 3600 * 48}
+	now := time.Now()
+
+	next, interval, err := s.Next(now, 0, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if interval != time.Hour*36 || !next.Equal(now.Add(interval)) {
+		t.Errorf("unchanged: interval = %v, next = %v", interval, next)
+	}
+
+	_, interval, _ = s.Next(now, interval, false)
+	if interval != time.Hour*48 {
+		t.Errorf("interval = %v, want the max interval", interval)
+	}
+
+	_, interval, _ = s.Next(now, time.Hour*24, true)
+	if interval != time.Hour*12 {
+		t.Errorf("changed: interval = %v, want 12h", interval)
+	}
+
+	_, interval, _ = s.Next(now, time.Hour, true)
+	if interval != time.Hour {
+		t.Errorf("interval = %v, want the min interval", interval)
+	}
+}
+
+func TestScheduleCron(t *testing.T) {
+	now := time.Date(2026, time.October, 19, 10, 30, 0, 0, time.UTC)
+	next, _, err := Schedule{Cron: "@hourly"}.Next(now, time.Hour, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := time.Date(2026, time.October, 19, 11, 0, 0, 0, time.UTC); !next.Equal(want) {
+		t.Errorf("next = %v, want %v", next, want)
+	}
+
+	if _, _, err := (Schedule{Cron: "bad"}).Next(now, time.Hour, true); !errors.Is(err, ErrInvalidCron) {
+		t.Errorf("err = %v", err)
+	}
+}
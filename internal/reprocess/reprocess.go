@@ -0,0 +1,402 @@
+package reprocess
+
+import (
+	"context"
+	"errors"
+	"io"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/rs/zerolog/log"
+	"gosuda.org/jimin/database"
+	"gosuda.org/jimin/internal/blob"
+	"gosuda.org/jimin/internal/crawler"
+	"gosuda.org/jimin/internal/rbac"
+	"gosuda.org/jimin/internal/recrawl"
+)
+
+const (
+	_DEFAULT_CONCURRENCY   = 4
+	_DEFAULT_BATCH_SIZE    = 32
+	_DEFAULT_POLL_INTERVAL = time.Second * 10
+	_DEFAULT_LEASE_TIMEOUT = time.Minute * 10
+)
+
+var (
+	ErrNotFound     = errors.New("reprocess: job not found")
+	ErrFinished     = errors.New("reprocess: job already finished")
+	ErrNoRawContent = errors.New("reprocess: raw content is not retained")
+)
+
+type DB interface {
+	database.DBTX
+	Begin(ctx context.Context) (pgx.Tx, error)
+}
+
+type IDGenerator interface {
+	Generate(ctx context.Context) (int64, error)
+}
+
+// Authorizer checks that the user who started a job may still reprocess the documents of its workspace.
+type Authorizer interface {
+	Authorize(ctx context.Context, userID, wsID int64, perm rbac.Permission) error
+}
+
+// Filter selects the documents of a workspace to reprocess.
+type Filter struct {
+	Source    string `json:"source,omitempty"`
+	URLPrefix string `json:"url_prefix,omitempty"`
+	// Stale selects only the documents processed by an older converter or chunker.
+	Stale bool `json:"stale,omitempty"`
+}
+
+type Config struct {
+	DB    DB
+	IDs   IDGenerator
+	Blobs blob.Store
+	// Authz checks the jobs started by users before they run. Without it, and for the jobs of no user, jobs always run.
+	Authz Authorizer
+	// Convert converts the raw content of a document again.
+	Convert func(ctx context.Context, doc database.Document, raw []byte, contentType string) (*recrawl.Content, error)
+	// Changed is called after a document is converted again, e.g. to chunk and embed it again.
+	// It is called even if the markdown did not change, since the chunker may have.
+	Changed func(ctx context.Context, doc database.Document, version database.DocumentVersion) error
+	// ConverterVersion and ChunkerVersion are the current versions, recorded on the reprocessed versions.
+	ConverterVersion int32
+	ChunkerVersion   int32
+
+	Concurrency  int
+	BatchSize    int
+	PollInterval time.Duration
+	// LeaseTimeout is the time after which a running job without progress is resumed by another worker.
+	LeaseTimeout time.Duration
+}
+
+// Reprocessor rebuilds documents from their retained raw content in background jobs.
+// Jobs record the last processed document, so they resume where they stopped
+// when a worker is shut down or lost.
+type Reprocessor struct {
+	cfg Config
+	q   *database.Queries
+}
+
+func New(cfg Config) *Reprocessor {
+	if cfg.Concurrency <= 0 {
+		cfg.Concurrency = _DEFAULT_CONCURRENCY
+	}
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = _DEFAULT_BATCH_SIZE
+	}
+	if cfg.PollInterval <= 0 {
+		cfg.PollInterval = _DEFAULT_POLL_INTERVAL
+	}
+	if cfg.LeaseTimeout <= 0 {
+		cfg.LeaseTimeout = _DEFAULT_LEASE_TIMEOUT
+	}
+	return &Reprocessor{cfg: cfg, q: database.New(cfg.DB)}
+}
+
+func timestamptz(t time.Time) pgtype.Timestamptz {
+	return pgtype.Timestamptz{Time: t, Valid: true}
+}
+
+// Start queues a job of a user, or of no user if userID is zero, reprocessing the documents of the workspace selected by f.
+func (r *Reprocessor) Start(ctx context.Context, wsID, userID int64, f Filter) (database.ReprocessJob, error) {
+	id, err := r.cfg.IDs.Generate(ctx)
+	if err != nil {
+		return database.ReprocessJob{}, err
+	}
+
+	return r.q.CreateReprocessJob(ctx, database.CreateReprocessJobParams{
+		ID:        id,
+		WsID:      wsID,
+		Source:    f.Source,
+		UrlPrefix: f.URLPrefix,
+		StaleOnly: f.Stale,
+		CreatedBy: userID,
+	})
+}
+
+// Job returns a job of the workspace.
+func (r *Reprocessor) Job(ctx context.Context, wsID, id int64) (database.ReprocessJob, error) {
+	job, err := r.q.GetReprocessJob(ctx, id)
+	if errors.Is(err, pgx.ErrNoRows) || (err == nil && job.WsID != wsID) {
+		return database.ReprocessJob{}, ErrNotFound
+	}
+	return job, err
+}
+
+// Jobs returns the most recent jobs of the workspace.
+func (r *Reprocessor) Jobs(ctx context.Context, wsID int64, limit int) ([]database.ReprocessJob, error) {
+	return r.q.ListReprocessJobs(ctx, database.ListReprocessJobsParams{WsID: wsID, Limit: int32(limit)})
+}
+
+// Cancel cancels a pending or running job of the workspace.
+// A running job stops after its current batch.
+func (r *Reprocessor) Cancel(ctx context.Context, wsID, id int64) (database.ReprocessJob, error) {
+	if _, err := r.Job(ctx, wsID, id); err != nil {
+		return database.ReprocessJob{}, err
+	}
+
+	job, err := r.q.CancelReprocessJob(ctx, id)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return database.ReprocessJob{}, ErrFinished
+	}
+	return job, err
+}
+
+// Run processes the queued jobs until ctx is canceled.
+func (r *Reprocessor) Run(ctx context.Context) error {
+	ticker := time.NewTicker(r.cfg.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		for {
+			ok, err := r.RunOnce(ctx)
+			if err != nil {
+				if ctx.Err() != nil {
+					return ctx.Err()
+				}
+				log.Error().Err(err).Msg("reprocess: failed to run job")
+			}
+			if !ok {
+				break
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// RunOnce claims a queued or abandoned job and processes it until it is done or canceled.
+// It reports whether a job was claimed.
+func (r *Reprocessor) RunOnce(ctx context.Context) (bool, error) {
+	now := time.Now()
+	job, err := r.q.ClaimReprocessJob(ctx, database.ClaimReprocessJobParams{
+		HeartbeatAt: timestamptz(now),
+		StaleBefore: timestamptz(now.Add(-r.cfg.LeaseTimeout)),
+	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	err = r.authorize(ctx, job)
+	if err == nil {
+		err = r.process(ctx, job)
+	}
+	if err != nil && ctx.Err() == nil {
+		// a canceled context leaves the job running, so it is resumed after the lease timeout
+		ferr := r.q.FinishReprocessJob(context.WithoutCancel(ctx), database.FinishReprocessJobParams{
+			ID:     job.ID,
+			Status: database.ReprocessStatusFAILED,
+			Error:  err.Error(),
+		})
+		if ferr != nil {
+			log.Error().Err(ferr).Int64("job", job.ID).Msg("reprocess: failed to record job failure")
+		}
+	}
+	return true, err
+}
+
+// authorize fails if the user who started job may no longer reprocess, e.g. after leaving the workspace.
+// It is checked on every claim, so that a resumed job does not outlive the permission.
+func (r *Reprocessor) authorize(ctx context.Context, job database.ReprocessJob) error {
+	if r.cfg.Authz == nil || job.CreatedBy == 0 {
+		return nil
+	}
+	err := r.cfg.Authz.Authorize(ctx, job.CreatedBy, job.WsID, rbac.ReprocessDocuments)
+	if errors.Is(err, rbac.ErrNotMember) || errors.Is(err, rbac.ErrForbidden) {
+		log.Warn().Int64("job", job.ID).Int64("user", job.CreatedBy).Msg("reprocess: job creator may no longer reprocess")
+	}
+	return err
+}
+
+func (r *Reprocessor) process(ctx context.Context, job database.ReprocessJob) error {
+	for {
+		docs, err := r.q.ListReprocessDocuments(ctx, database.ListReprocessDocumentsParams{
+			WsID:             job.WsID,
+			AfterID:          job.LastDocumentID,
+			Source:           job.Source,
+			UrlPrefix:        job.UrlPrefix,
+			StaleOnly:        job.StaleOnly,
+			ConverterVersion: r.cfg.ConverterVersion,
+			ChunkerVersion:   r.cfg.ChunkerVersion,
+			PageSize:         int32(r.cfg.BatchSize),
+		})
+		if err != nil {
+			return err
+		}
+		if len(docs) == 0 {
+			return r.q.FinishReprocessJob(ctx, database.FinishReprocessJobParams{ID: job.ID, Status: database.ReprocessStatusDONE})
+		}
+
+		var failed atomic.Int32
+		sema := make(chan struct{}, r.cfg.Concurrency)
+		var wg sync.WaitGroup
+		for _, doc := range docs {
+			sema <- struct{}{}
+			wg.Add(1)
+			go func(doc database.Document) {
+				defer func() {
+					<-sema
+					wg.Done()
+				}()
+
+				if err := r.Reprocess(ctx, doc); err != nil {
+					failed.Add(1)
+					log.Warn().Err(err).Int64("job", job.ID).Int64("document", doc.ID).Msg("reprocess: failed to reprocess document")
+				}
+			}(doc)
+		}
+		wg.Wait()
+
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		job, err = r.q.UpdateReprocessProgress(ctx, database.UpdateReprocessProgressParams{
+			ID:             job.ID,
+			LastDocumentID: docs[len(docs)-1].ID,
+			Processed:      job.Processed + int32(len(docs)),
+			Failed:         job.Failed + failed.Load(),
+			HeartbeatAt:    timestamptz(time.Now()),
+		})
+		if errors.Is(err, pgx.ErrNoRows) {
+			// canceled, or claimed by another worker after the lease timed out
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// Reprocess converts the raw content of the current version of doc again.
+// A new version is stored if the markdown changed.
+func (r *Reprocessor) Reprocess(ctx context.Context, doc database.Document) error {
+	version, err := r.q.GetDocumentVersionByID(ctx, doc.CurrentVersionID)
+	if err != nil {
+		return err
+	}
+	if version.RawKey == "" || r.cfg.Blobs == nil {
+		return ErrNoRawContent
+	}
+
+	raw, err := r.readRaw(ctx, version.RawKey)
+	if err != nil {
+		return err
+	}
+
+	content, err := r.cfg.Convert(ctx, doc, raw, version.RawContentType)
+	if err != nil {
+		return err
+	}
+
+	hash := recrawl.ContentHash(content.Markdown)
+	if hash == version.ContentHash {
+		err = r.q.SetDocumentVersionProcessors(ctx, database.SetDocumentVersionProcessorsParams{
+			ID:               version.ID,
+			ConverterVersion: r.cfg.ConverterVersion,
+			ChunkerVersion:   r.cfg.ChunkerVersion,
+		})
+		version.ConverterVersion = r.cfg.ConverterVersion
+		version.ChunkerVersion = r.cfg.ChunkerVersion
+	} else {
+		version, err = r.createVersion(ctx, doc, version, content, hash)
+		doc.CurrentVersionID = version.ID
+		doc.Title = version.Title
+		doc.ContentHash = hash
+	}
+	if err != nil {
+		return err
+	}
+
+	if r.cfg.Changed != nil {
+		return r.cfg.Changed(ctx, doc, version)
+	}
+	return nil
+}
+
+func (r *Reprocessor) readRaw(ctx context.Context, key string) ([]byte, error) {
+	f, err := r.cfg.Blobs.Open(ctx, key)
+	if errors.Is(err, blob.ErrNotFound) {
+		return nil, ErrNoRawContent
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return io.ReadAll(f)
+}
+
+// createVersion stores the new conversion as a version fetched with prev.
+func (r *Reprocessor) createVersion(ctx context.Context, doc database.Document, prev database.DocumentVersion, content *recrawl.Content, hash string) (database.DocumentVersion, error) {
+	id, err := r.cfg.IDs.Generate(ctx)
+	if err != nil {
+		return database.DocumentVersion{}, err
+	}
+
+	var version database.DocumentVersion
+	err = pgx.BeginFunc(ctx, r.cfg.DB, func(tx pgx.Tx) error {
+		q := r.q.WithTx(tx)
+
+		var err error
+		version, err = q.CreateDocumentVersion(ctx, database.CreateDocumentVersionParams{
+			ID:               id,
+			DocumentID:       doc.ID,
+			Title:            content.Title,
+			Content:          content.Markdown,
+			ContentHash:      hash,
+			Etag:             prev.Etag,
+			LastModified:     prev.LastModified,
+			FetchedAt:        prev.FetchedAt,
+			RawKey:           prev.RawKey,
+			RawContentType:   prev.RawContentType,
+			ConverterVersion: r.cfg.ConverterVersion,
+			ChunkerVersion:   r.cfg.ChunkerVersion,
+		})
+		if err != nil {
+			return err
+		}
+		if err := recrawl.CreateAssets(ctx, q, r.cfg.IDs, doc, version.ID, content.Assets); err != nil {
+			return err
+		}
+		// the page is not rendered again, the snapshots of its crawl are kept
+		prevSnapshots, err := q.ListDocumentVersionSnapshots(ctx, prev.ID)
+		if err != nil {
+			return err
+		}
+		snapshots := make([]crawler.Snapshot, len(prevSnapshots))
+		for i, s := range prevSnapshots {
+			snapshots[i] = crawler.Snapshot{
+				Kind:        crawler.SnapshotKind(s.Kind),
+				ContentType: s.ContentType,
+				Blob:        blob.Blob{Key: s.BlobKey, Size: s.Size, ContentType: s.ContentType},
+			}
+		}
+		if err := recrawl.CreateSnapshots(ctx, q, r.cfg.IDs, doc, version.ID, snapshots); err != nil {
+			return err
+		}
+
+		return q.SetDocumentVersion(ctx, database.SetDocumentVersionParams{
+			ID:               doc.ID,
+			CurrentVersionID: version.ID,
+			Title:            content.Title,
+			ContentHash:      hash,
+			LastChangedAt:    doc.LastChangedAt,
+		})
+	})
+
+	return version, err
+}
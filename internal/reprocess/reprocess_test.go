@@ -0,0 +1,238 @@
+package reprocess
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgtype"
+	"gosuda.org/jimin/database"
+	"gosuda.org/jimin/internal/rbac"
+)
+
+// jobDB queues job, if any, over the documents docs, none of which has retained raw content.
+// It records the claims, the pages listed after a document id and the finished statuses.
+type jobDB struct {
+	job  *database.ReprocessJob
+	docs []database.Document
+	// takenOver makes the progress updates find the job claimed by another worker.
+	takenOver bool
+
+	mu       sync.Mutex
+	claim    []any
+	listed   []int64
+	progress []any
+	finished []database.ReprocessStatus
+}
+
+func (db *jobDB) Exec(_ context.Context, sql string, args ...any) (pgconn.CommandTag, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	if queryName(sql) == "FinishReprocessJob" {
+		db.finished = append(db.finished, args[1].(database.ReprocessStatus))
+	}
+	return pgconn.CommandTag{}, nil
+}
+
+func (db *jobDB) Query(_ context.Context, sql string, args ...any) (pgx.Rows, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	after := args[1].(int64)
+	db.listed = append(db.listed, after)
+
+	var docs []any
+	for _, doc := range db.docs {
+		if doc.ID > after {
+			docs = append(docs, doc)
+		}
+	}
+	return &structRows{rows: docs}, nil
+}
+
+func (db *jobDB) QueryRow(_ context.Context, sql string, args ...any) pgx.Row {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	switch queryName(sql) {
+	case "ClaimReprocessJob":
+		db.claim = args
+		if db.job == nil {
+			return structRow{err: pgx.ErrNoRows}
+		}
+		return structRow{v: *db.job}
+	case "UpdateReprocessProgress":
+		db.progress = args
+		if db.takenOver {
+			return structRow{err: pgx.ErrNoRows}
+		}
+		job := *db.job
+		job.LastDocumentID, job.Processed, job.Failed = args[1].(int64), args[2].(int32), args[3].(int32)
+		return structRow{v: job}
+	case "GetDocumentVersionByID":
+		return structRow{v: database.DocumentVersion{ID: args[0].(int64)}}
+	}
+	return structRow{err: errors.ErrUnsupported}
+}
+
+func (db *jobDB) Begin(context.Context) (pgx.Tx, error) { return nil, errors.ErrUnsupported }
+
+func queryName(sql string) string {
+	return strings.Fields(sql)[2]
+}
+
+// structRow scans the fields of v in order, which is the order of the columns of its table.
+type structRow struct {
+	v   any
+	err error
+}
+
+func (r structRow) Scan(dest ...any) error {
+	if r.err != nil {
+		return r.err
+	}
+	v := reflect.ValueOf(r.v)
+	for i := range dest {
+		reflect.ValueOf(dest[i]).Elem().Set(v.Field(i))
+	}
+	return nil
+}
+
+type structRows struct {
+	pgx.Rows
+	rows []any
+	i    int
+}
+
+func (r *structRows) Next() bool {
+	r.i++
+	return r.i <= len(r.rows)
+}
+
+func (r *structRows) Scan(dest ...any) error { return structRow{v: r.rows[r.i-1]}.Scan(dest...) }
+
+func (r *structRows) Close() {}
+
+func (r *structRows) Err() error { return nil }
+
+type fakeAuthz struct {
+	err error
+}
+
+func (a fakeAuthz) Authorize(ctx context.Context, userID, wsID int64, perm rbac.Permission) error {
+	if perm != rbac.ReprocessDocuments {
+		return rbac.ErrForbidden
+	}
+	return a.err
+}
+
+func documents(ids ...int64) []database.Document {
+	docs := make([]database.Document, len(ids))
+	for i, id := range ids {
+		docs[i] = database.Document{ID: id, WsID: 1, CurrentVersionID: id * 10}
+	}
+	return docs
+}
+
+func TestRunOnceClaims(t *testing.T) {
+	db := &jobDB{}
+	r := New(Config{DB: db, LeaseTimeout: time.Minute})
+
+	start := time.Now()
+	ok, err := r.RunOnce(context.Background())
+	if ok || err != nil {
+		t.Fatalf("RunOnce() without jobs = %v, %v, want false, nil", ok, err)
+	}
+	if len(db.claim) != 2 {
+		t.Fatalf("claim args = %v", db.claim)
+	}
+
+	// jobs without a heartbeat within the lease timeout are taken over
+	heartbeat, stale := db.claim[0].(pgtype.Timestamptz).Time, db.claim[1].(pgtype.Timestamptz).Time
+	if heartbeat.Before(start) || heartbeat.After(time.Now()) {
+		t.Errorf("claimed with heartbeat %v, want the time of the claim", heartbeat)
+	}
+	if got := heartbeat.Sub(stale); got != time.Minute {
+		t.Errorf("claimed jobs stale for %v, want %v", got, time.Minute)
+	}
+}
+
+func TestRunOnceResumes(t *testing.T) {
+	job := database.ReprocessJob{ID: 7, WsID: 1, Status: database.ReprocessStatusRUNNING, LastDocumentID: 2, Processed: 2}
+	db := &jobDB{job: &job, docs: documents(1, 2, 3, 4)}
+	r := New(Config{DB: db, BatchSize: 8})
+
+	ok, err := r.RunOnce(context.Background())
+	if !ok || err != nil {
+		t.Fatalf("RunOnce() = %v, %v, want true, nil", ok, err)
+	}
+
+	// the documents before the last processed one are not listed again
+	if !slices.Equal(db.listed, []int64{2, 4}) {
+		t.Errorf("listed after %v, want after [2 4]", db.listed)
+	}
+	if last, processed, failed := db.progress[1].(int64), db.progress[2].(int32), db.progress[3].(int32); last != 4 || processed != 4 || failed != 2 {
+		t.Errorf("progress = last %d, processed %d, failed %d, want 4, 4, 2", last, processed, failed)
+	}
+	if !slices.Equal(db.finished, []database.ReprocessStatus{database.ReprocessStatusDONE}) {
+		t.Errorf("finished = %v, want [DONE]", db.finished)
+	}
+}
+
+func TestRunOnceTakenOver(t *testing.T) {
+	job := database.ReprocessJob{ID: 7, WsID: 1, Status: database.ReprocessStatusRUNNING}
+	db := &jobDB{job: &job, docs: documents(1, 2), takenOver: true}
+	r := New(Config{DB: db})
+
+	ok, err := r.RunOnce(context.Background())
+	if !ok || err != nil {
+		t.Fatalf("RunOnce() = %v, %v, want true, nil", ok, err)
+	}
+	if len(db.listed) != 1 || len(db.finished) != 0 {
+		t.Errorf("kept running a job claimed by another worker: listed after %v, finished %v", db.listed, db.finished)
+	}
+}
+
+func TestRunOnceAuthorizes(t *testing.T) {
+	tests := []struct {
+		name      string
+		createdBy int64
+		authz     error
+		want      error
+		finished  database.ReprocessStatus
+	}{
+		{"member", 3, nil, nil, database.ReprocessStatusDONE},
+		{"former member", 3, rbac.ErrNotMember, rbac.ErrNotMember, database.ReprocessStatusFAILED},
+		{"demoted member", 3, rbac.ErrForbidden, rbac.ErrForbidden, database.ReprocessStatusFAILED},
+		{"no user", 0, rbac.ErrForbidden, nil, database.ReprocessStatusDONE},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			job := database.ReprocessJob{ID: 7, WsID: 1, Status: database.ReprocessStatusRUNNING, CreatedBy: tt.createdBy}
+			db := &jobDB{job: &job}
+			r := New(Config{DB: db, Authz: fakeAuthz{err: tt.authz}})
+
+			if ok, err := r.RunOnce(context.Background()); !ok || !errors.Is(err, tt.want) {
+				t.Fatalf("RunOnce() = %v, %v, want true, %v", ok, err, tt.want)
+			}
+			if tt.want != nil && len(db.listed) != 0 {
+				t.Errorf("listed the documents of an unauthorized job")
+			}
+			if !slices.Equal(db.finished, []database.ReprocessStatus{tt.finished}) {
+				t.Errorf("finished = %v, want [%s]", db.finished, tt.finished)
+			}
+		})
+	}
+}
+
+func TestReprocessWithoutRawContent(t *testing.T) {
+	r := New(Config{DB: &jobDB{}})
+	if err := r.Reprocess(context.Background(), documents(1)[0]); !errors.Is(err, ErrNoRawContent) {
+		t.Errorf("Reprocess() error = %v, want %v", err, ErrNoRawContent)
+	}
+}
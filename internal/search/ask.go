@@ -0,0 +1,60 @@
+package search
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+const (
+	_ASK_INSTRUCTION = "Answer the question using only the numbered sources. " +
+		"Cite the sources you use like [1]. If the sources do not answer the question, say so."
+	// _MAX_SOURCE_BYTES limits each source given to the model.
+	_MAX_SOURCE_BYTES = 4000
+)
+
+var ErrNoSources = errors.New("search: no sources found for the question")
+
+// Generator generates text with a language model.
+type Generator interface {
+	Generate(ctx context.Context, instruction, input string) (string, error)
+}
+
+// Answer is the answer to a question with the sources it was generated from.
+type Answer struct {
+	Question string   `json:"question"`
+	Answer   string   `json:"answer"`
+	Sources  []Result `json:"sources"`
+}
+
+// Ask searches the workspace for question and answers it from the results with gen.
+func (s *Searcher) Ask(ctx context.Context, gen Generator, wsID int64, question string, mode Mode, limit int) (*Answer, error) {
+	results, err := s.Search(ctx, wsID, question, mode, limit)
+	if err != nil {
+		return nil, err
+	}
+	if len(results) == 0 {
+		return nil, ErrNoSources
+	}
+
+	answer, err := gen.Generate(ctx, _ASK_INSTRUCTION, prompt(question, results))
+	if err != nil {
+		return nil, err
+	}
+	return &Answer{Question: question, Answer: strings.TrimSpace(answer), Sources: results}, nil
+}
+
+func prompt(question string, results []Result) string {
+	var sb strings.Builder
+	for i, r := range results {
+		content := r.Content
+		if len(content) > _MAX_SOURCE_BYTES {
+			content = strings.ToValidUTF8(content[:_MAX_SOURCE_BYTES], "")
+		}
+		fmt.Fprintf(&sb, "[%d] %s (%s)\n%s\n\n", i+1, r.Title, r.URL, content)
+	}
+	sb.WriteString("Question: ")
+	sb.WriteString(question)
+	return sb.String()
+}
@@ -0,0 +1,276 @@
+package search
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/jackc/pgx/v5"
+	"gosuda.org/jimin/database"
+)
+
+type Mode string
+
+const (
+	ModeKeyword Mode = "keyword"
+	ModeVector  Mode = "vector"
+	ModeHybrid  Mode = "hybrid"
+)
+
+const (
+	_DEFAULT_LIMIT = 10
+	// _RRF_K dampens the weight of the top ranks in reciprocal rank fusion.
+	_RRF_K = 60
+	// _CANDIDATES is the number of chunks fetched per result, since a document may match with several chunks.
+	_CANDIDATES = 4
+)
+
+var (
+	ErrUnknownMode = errors.New("search: unknown mode")
+	ErrNoEmbedder  = errors.New("search: vector search requires an embedder")
+)
+
+// Embedder embeds the query for vector search.
+type Embedder interface {
+	Embed(ctx context.Context, texts []string) ([][]float32, error)
+}
+
+// Deduper maps near-duplicate documents to the canonical documents of their clusters.
+type Deduper interface {
+	Targets(ctx context.Context, wsID int64, ids []int64) (map[int64]int64, error)
+}
+
+type Config struct {
+	DB database.DBTX
+	// Embedder enables vector search. Hybrid search falls back to keyword search if it is nil.
+	Embedder Embedder
+	// Dups collapses near-duplicate documents into one result if it is not nil.
+	Dups Deduper
+}
+
+// Result is a document matching a query with its best matching chunk.
+type Result struct {
+	DocumentID int64   `json:"document_id"`
+	URL        string  `json:"url"`
+	Title      string  `json:"title"`
+	ChunkID    int64   `json:"chunk_id"`
+	Heading    string  `json:"heading,omitempty"`
+	Content    string  `json:"content"`
+	Score      float64 `json:"score"`
+}
+
+// Searcher searches the chunks of the documents of a workspace.
+type Searcher struct {
+	cfg Config
+	q   *database.Queries
+}
+
+func New(cfg Config) *Searcher {
+	return &Searcher{cfg: cfg, q: database.New(cfg.DB)}
+}
+
+// ParseMode parses a search mode, hybrid if s is empty.
+func ParseMode(s string) (Mode, error) {
+	switch m := Mode(s); m {
+	case "":
+		return ModeHybrid, nil
+	case ModeKeyword, ModeVector, ModeHybrid:
+		return m, nil
+	}
+	return "", fmt.Errorf("%w: %q", ErrUnknownMode, s)
+}
+
+// Search returns up to limit documents of the workspace matching query, best first.
+// Keyword search matches the words of the query, vector search the meaning of the query,
+// and hybrid search fuses both rankings.
+func (s *Searcher) Search(ctx context.Context, wsID int64, query string, mode Mode, limit int) ([]Result, error) {
+	if limit <= 0 {
+		limit = _DEFAULT_LIMIT
+	}
+	if mode == ModeHybrid && s.cfg.Embedder == nil {
+		mode = ModeKeyword
+	}
+	candidates := int32(limit * _CANDIDATES)
+
+	var lists [][]Result
+	if mode == ModeKeyword || mode == ModeHybrid {
+		hits, err := s.keyword(ctx, wsID, query, candidates)
+		if err != nil {
+			return nil, err
+		}
+		lists = append(lists, hits)
+	}
+	if mode == ModeVector || mode == ModeHybrid {
+		hits, err := s.vector(ctx, wsID, query, candidates)
+		if err != nil {
+			return nil, err
+		}
+		lists = append(lists, hits)
+	}
+	if lists == nil {
+		return nil, fmt.Errorf("%w: %q", ErrUnknownMode, mode)
+	}
+
+	// a single ranking keeps its own scores
+	results := lists[0]
+	if len(lists) > 1 {
+		results = fuse(lists)
+	}
+	results = bestChunks(results)
+
+	results, err := s.collapse(ctx, wsID, results)
+	if err != nil {
+		return nil, err
+	}
+	if len(results) > limit {
+		results = results[:limit]
+	}
+	return results, nil
+}
+
+func (s *Searcher) keyword(ctx context.Context, wsID int64, query string, limit int32) ([]Result, error) {
+	rows, err := s.q.SearchChunksText(ctx, database.SearchChunksTextParams{Query: query, WsID: wsID, MaxResults: limit})
+	if err != nil {
+		return nil, err
+	}
+
+	hits := make([]Result, len(rows))
+	for i, row := range rows {
+		hits[i] = Result{
+			DocumentID: row.DocumentID,
+			URL:        row.Url,
+			Title:      row.Title,
+			ChunkID:    row.ID,
+			Heading:    row.Heading,
+			Content:    row.Content,
+			Score:      float64(row.Rank),
+		}
+	}
+	return hits, nil
+}
+
+func (s *Searcher) vector(ctx context.Context, wsID int64, query string, limit int32) ([]Result, error) {
+	if s.cfg.Embedder == nil {
+		return nil, ErrNoEmbedder
+	}
+	embeddings, err := s.cfg.Embedder.Embed(ctx, []string{query})
+	if err != nil {
+		return nil, err
+	}
+	if len(embeddings) != 1 || len(embeddings[0]) == 0 {
+		return nil, errors.New("search: embedder returned no embedding")
+	}
+
+	rows, err := s.q.SearchChunksVector(ctx, database.SearchChunksVectorParams{Embedding: embeddings[0], WsID: wsID, MaxResults: limit})
+	if err != nil {
+		return nil, err
+	}
+
+	hits := make([]Result, len(rows))
+	for i, row := range rows {
+		hits[i] = Result{
+			DocumentID: row.DocumentID,
+			URL:        row.Url,
+			Title:      row.Title,
+			ChunkID:    row.ID,
+			Heading:    row.Heading,
+			Content:    row.Content,
+			// cosine distance ranges from 0 to 2
+			Score: 1 - float64(row.Distance)/2,
+		}
+	}
+	return hits, nil
+}
+
+// fuse merges the rankings of chunks with reciprocal rank fusion.
+func fuse(lists [][]Result) []Result {
+	scores := make(map[int64]float64)
+	chunks := make(map[int64]Result)
+	for _, list := range lists {
+		for rank, hit := range list {
+			scores[hit.ChunkID] += 1 / float64(_RRF_K+rank+1)
+			chunks[hit.ChunkID] = hit
+		}
+	}
+
+	out := make([]Result, 0, len(chunks))
+	for id, hit := range chunks {
+		hit.Score = scores[id]
+		out = append(out, hit)
+	}
+	sortResults(out)
+	return out
+}
+
+func sortResults(results []Result) {
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].Score != results[j].Score {
+			return results[i].Score > results[j].Score
+		}
+		return results[i].ChunkID < results[j].ChunkID
+	})
+}
+
+// bestChunks keeps the first chunk of each document of the ranked chunks.
+func bestChunks(ranked []Result) []Result {
+	out := make([]Result, 0, len(ranked))
+	seen := make(map[int64]bool, len(ranked))
+	for _, r := range ranked {
+		if !seen[r.DocumentID] {
+			seen[r.DocumentID] = true
+			out = append(out, r)
+		}
+	}
+	return out
+}
+
+// collapse keeps the best result of each cluster of near-duplicates,
+// reported as the canonical document of the cluster.
+func (s *Searcher) collapse(ctx context.Context, wsID int64, results []Result) ([]Result, error) {
+	if s.cfg.Dups == nil || len(results) == 0 {
+		return results, nil
+	}
+
+	ids := make([]int64, len(results))
+	for i, r := range results {
+		ids[i] = r.DocumentID
+	}
+	targets, err := s.cfg.Dups.Targets(ctx, wsID, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	results = collapse(results, targets)
+	for i, r := range results {
+		target, ok := targets[r.DocumentID]
+		if !ok {
+			continue
+		}
+		doc, err := s.q.GetDocument(ctx, target)
+		if errors.Is(err, pgx.ErrNoRows) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		results[i].DocumentID, results[i].URL, results[i].Title = doc.ID, doc.Url, doc.Title
+	}
+	return results, nil
+}
+
+func collapse(results []Result, targets map[int64]int64) []Result {
+	out := make([]Result, 0, len(results))
+	seen := make(map[int64]bool, len(results))
+	for _, r := range results {
+		id := r.DocumentID
+		if t, ok := targets[id]; ok {
+			id = t
+		}
+		if !seen[id] {
+			seen[id] = true
+			out = append(out, r)
+		}
+	}
+	return out
+}
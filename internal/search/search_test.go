@@ -0,0 +1,88 @@
+package search
+
+import (
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func chunkIDs(results []Result) []int64 {
+	ids := make([]int64, len(results))
+	for i, r := range results {
+		ids[i] = r.ChunkID
+	}
+	return ids
+}
+
+func TestFuse(t *testing.T) {
+	keyword := []Result{{ChunkID: 1}, {ChunkID: 2}, {ChunkID: 3}}
+	vector := []Result{{ChunkID: 3}, {ChunkID: 4}, {ChunkID: 1}}
+
+	got := fuse([][]Result{keyword, vector})
+	// 1 and 3 are found by both with the same ranks, ties are ordered by id
+	want := []int64{1, 3, 2, 4}
+	if !slices.Equal(chunkIDs(got), want) {
+		t.Errorf("fuse() = %v, want %v", chunkIDs(got), want)
+	}
+	if got[0].Score != 1.0/61+1.0/63 {
+		t.Errorf("fuse() score = %v, want %v", got[0].Score, 1.0/61+1.0/63)
+	}
+}
+
+func TestBestChunks(t *testing.T) {
+	ranked := []Result{
+		{DocumentID: 10, ChunkID: 1},
+		{DocumentID: 20, ChunkID: 2},
+		{DocumentID: 10, ChunkID: 3},
+		{DocumentID: 30, ChunkID: 4},
+	}
+
+	got := bestChunks(ranked)
+	if want := []int64{1, 2, 4}; !slices.Equal(chunkIDs(got), want) {
+		t.Errorf("bestChunks() = %v, want %v", chunkIDs(got), want)
+	}
+}
+
+func TestCollapse(t *testing.T) {
+	results := []Result{
+		{DocumentID: 5, ChunkID: 1},
+		{DocumentID: 3, ChunkID: 2},
+		{DocumentID: 7, ChunkID: 3},
+		{DocumentID: 1, ChunkID: 4},
+	}
+
+	got := collapse(results, map[int64]int64{3: 1, 7: 5})
+	if want := []int64{1, 2}; !slices.Equal(chunkIDs(got), want) {
+		t.Errorf("collapse() = %v, want %v", chunkIDs(got), want)
+	}
+}
+
+func TestParseMode(t *testing.T) {
+	for s, want := range map[string]Mode{"": ModeHybrid, "keyword": ModeKeyword, "vector": ModeVector, "hybrid": ModeHybrid} {
+		got, err := ParseMode(s)
+		if err != nil || got != want {
+			t.Errorf("ParseMode(%q) = %q, %v, want %q", s, got, err, want)
+		}
+	}
+	if _, err := ParseMode("fuzzy"); !errors.Is(err, ErrUnknownMode) {
+		t.Errorf("ParseMode(fuzzy) error = %v, want %v", err, ErrUnknownMode)
+	}
+}
+
+func TestPrompt(t *testing.T) {
+	got := prompt("what is jimin?", []Result{
+		{Title: "Jimin", URL: "https://example.com/jimin", Content: "Jimin is a knowledge base."},
+		{Title: "Docs", URL: "https://example.com/docs", Content: strings.Repeat("a", _MAX_SOURCE_BYTES+10)},
+	})
+
+	if !strings.HasPrefix(got, "[1] Jimin (https://example.com/jimin)\nJimin is a knowledge base.\n\n[2] Docs") {
+		t.Errorf("prompt() does not number the sources:\n%s", got)
+	}
+	if strings.Contains(got, strings.Repeat("a", _MAX_SOURCE_BYTES+1)) {
+		t.Error("prompt() does not truncate long sources")
+	}
+	if !strings.HasSuffix(got, "Question: what is jimin?") {
+		t.Errorf("prompt() does not end with the question:\n%s", got)
+	}
+}
@@ -21,34 +21,86 @@ const (
 	ServerStatusRunning
 )
 
+func (s ServerStatus) String() string {
+	switch s {
+	case ServerStatusStopped:
+		return "stopped"
+	case ServerStatusStopping:
+		return "stopping"
+	case ServerStatusStarting:
+		return "starting"
+	case ServerStatusRunning:
+		return "running"
+	}
+	return "unknown"
+}
+
 var (
 	ErrAlreadyRunning  = errors.New("server is already running")
 	ErrInvalidListener = errors.New("invalid listener")
 )
 
+const (
+	_DEFAULT_READ_HEADER_TIMEOUT = time.Second * 10
+	_DEFAULT_IDLE_TIMEOUT        = time.Second * 30
+)
+
+// Options are the timeouts and limits of the connections.
+// The zero value of a field selects its default.
+type Options struct {
+	ReadHeaderTimeout time.Duration // default 10s
+	ReadTimeout       time.Duration // default none
+	WriteTimeout      time.Duration // default none
+	IdleTimeout       time.Duration // default 30s
+	MaxHeaderBytes    int           // default http.DefaultMaxHeaderBytes
+}
+
+// Server serves a router on a listener. It can be started again on a new listener once stopped.
 type Server struct {
 	mu     sync.Mutex
-	stop   chan struct{}
 	status atomic.Int32
 
 	serverID ksuid.KSUID
-	ln       net.Listener
-	srv      http.Server
+	handler  http.Handler
+	opts     Options
+
+	// the current run
+	srv     *http.Server
+	ln      net.Listener
+	ready   chan struct{} // closed once running
+	drained chan struct{} // closed once the shutdown finishes
+	done    chan struct{} // closed once stopped
 
 	errsMu sync.Mutex
 	errs   []error
 }
 
-func New() *Server {
-	g := &Server{
-		stop:     nil,
+// New returns a stopped server of router, or of an empty router if it is nil.
+func New(router http.Handler, opts Options) *Server {
+	if router == nil {
+		router = http.NewServeMux()
+	}
+	if opts.ReadHeaderTimeout <= 0 {
+		opts.ReadHeaderTimeout = _DEFAULT_READ_HEADER_TIMEOUT
+	}
+	if opts.IdleTimeout <= 0 {
+		opts.IdleTimeout = _DEFAULT_IDLE_TIMEOUT
+	}
+
+	done := make(chan struct{})
+	close(done)
+	return &Server{
 		serverID: ksuid.New(),
-		srv: http.Server{
-			IdleTimeout: time.Second * 30,
-		},
+		handler:  router,
+		opts:     opts,
+		ready:    make(chan struct{}),
+		done:     done,
 	}
+}
 
-	return g
+// ID returns the unique id of the server process.
+func (g *Server) ID() ksuid.KSUID {
+	return g.serverID
 }
 
 func (g *Server) setState(status ServerStatus) {
@@ -73,9 +125,36 @@ func (g *Server) State() ServerStatus {
 	return ServerStatus(g.status.Load())
 }
 
+// Errors returns the errors of the current or the last run.
+func (g *Server) Errors() []error {
+	g.errsMu.Lock()
+	defer g.errsMu.Unlock()
+	return append([]error(nil), g.errs...)
+}
+
+// Ready is closed once the server is running, and is replaced by a new channel when it stops.
+// A server stopped before it runs does not close it.
+func (g *Server) Ready() <-chan struct{} {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.ready
+}
+
+// Wait waits until the server is stopped and returns the errors of its run.
+// It returns immediately if the server is not started.
+func (g *Server) Wait() error {
+	g.mu.Lock()
+	done := g.done
+	g.mu.Unlock()
+
+	<-done
+	return errors.Join(g.Errors()...)
+}
+
+// Start serves on ln until the server is stopped or ln fails.
 func (g *Server) Start(ln net.Listener) error {
-	if g.State() != ServerStatusStopped {
-		return ErrAlreadyRunning
+	if ln == nil {
+		return ErrInvalidListener
 	}
 
 	g.mu.Lock()
@@ -84,45 +163,82 @@ func (g *Server) Start(ln net.Listener) error {
 		return ErrAlreadyRunning
 	}
 
-	if g.ln == nil {
-		return ErrInvalidListener
-	}
-
 	g.clearErrs()
-	g.ln = ln
-	g.stop = make(chan struct{})
+	srv := &http.Server{
+		Handler:           g.handler,
+		ReadHeaderTimeout: g.opts.ReadHeaderTimeout,
+		ReadTimeout:       g.opts.ReadTimeout,
+		WriteTimeout:      g.opts.WriteTimeout,
+		IdleTimeout:       g.opts.IdleTimeout,
+		MaxHeaderBytes:    g.opts.MaxHeaderBytes,
+	}
+	ready, drained, done := g.ready, make(chan struct{}), make(chan struct{})
+	g.srv, g.ln, g.drained, g.done = srv, ln, drained, done
 	g.setState(ServerStatusStarting)
 
 	go func() {
-		defer g.doStop()
-		if err := g.srv.Serve(g.ln); err != nil && err != http.ErrServerClosed {
+		// a server stopped while starting stays stopping
+		if g.status.CompareAndSwap(int32(ServerStatusStarting), int32(ServerStatusRunning)) {
+			close(ready)
+		}
+		err := srv.Serve(ln)
+		if errors.Is(err, http.ErrServerClosed) {
+			// Serve returns once the shutdown starts, the active requests are still running
+			<-drained
+		} else {
+			g.status.CompareAndSwap(int32(ServerStatusRunning), int32(ServerStatusStopping))
 			g.aError(err)
+			srv.Close()
 		}
+		g.finish(done)
 	}()
 
 	return nil
 }
 
-func (g *Server) doStop() error {
-	if g.State() != ServerStatusRunning {
-		return nil
+// finish records that the current run is stopped.
+func (g *Server) finish(done chan struct{}) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	select {
+	case <-g.ready:
+		g.ready = make(chan struct{})
+	default:
 	}
+	g.srv, g.ln, g.drained = nil, nil, nil
+	g.setState(ServerStatusStopped)
+	close(done)
+}
 
+// Stop stops accepting connections and waits for the active requests to finish until ctx is done.
+// The remaining connections are then closed and the error of ctx is returned.
+func (g *Server) Stop(ctx context.Context) error {
 	g.mu.Lock()
-	defer g.mu.Unlock()
+	srv, drained, done := g.srv, g.drained, g.done
+	status := g.State()
+	if status == ServerStatusStarting || status == ServerStatusRunning {
+		g.setState(ServerStatusStopping)
+	}
+	g.mu.Unlock()
 
-	if g.State() != ServerStatusRunning {
+	switch status {
+	case ServerStatusStopped:
 		return nil
+	case ServerStatusStopping:
+		// stopped by another call, or after a serve error
+		select {
+		case <-done:
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
 	}
-	g.setState(ServerStatusStopping)
-
-	close(g.stop)
 
-	err := g.srv.Shutdown(context.Background())
-	g.aError(err)
-
-	g.stop = nil
-	g.ln = nil
-
-	return nil
+	err := srv.Shutdown(ctx)
+	if err != nil {
+		srv.Close()
+	}
+	close(drained)
+	<-done
+	return err
 }
@@ -0,0 +1,278 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func listen(t *testing.T) net.Listener {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return ln
+}
+
+func get(t *testing.T, ln net.Listener) string {
+	t.Helper()
+	resp, err := http.Get("http://" + ln.Addr().String() + "/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	return string(body)
+}
+
+func ready(t *testing.T, s *Server) {
+	t.Helper()
+	select {
+	case <-s.Ready():
+	case <-time.After(time.Second):
+		t.Fatalf("server is not ready, state %v", s.State())
+	}
+}
+
+func hello() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "hello")
+	})
+}
+
+func TestStartStop(t *testing.T) {
+	s := New(hello(), Options{})
+	if s.State() != ServerStatusStopped {
+		t.Fatalf("State() = %v before Start()", s.State())
+	}
+	if err := s.Start(nil); !errors.Is(err, ErrInvalidListener) {
+		t.Errorf("Start(nil) error = %v, want %v", err, ErrInvalidListener)
+	}
+
+	ln := listen(t)
+	if err := s.Start(ln); err != nil {
+		t.Fatal(err)
+	}
+	ready(t, s)
+	if s.State() != ServerStatusRunning {
+		t.Errorf("State() = %v once ready", s.State())
+	}
+	if body := get(t, ln); body != "hello" {
+		t.Errorf("GET / = %q", body)
+	}
+
+	other := listen(t)
+	defer other.Close()
+	if err := s.Start(other); !errors.Is(err, ErrAlreadyRunning) {
+		t.Errorf("Start() while running error = %v, want %v", err, ErrAlreadyRunning)
+	}
+
+	if err := s.Stop(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if s.State() != ServerStatusStopped {
+		t.Errorf("State() = %v after Stop()", s.State())
+	}
+	if err := s.Wait(); err != nil {
+		t.Errorf("Wait() = %v after a clean stop", err)
+	}
+	select {
+	case <-s.Ready():
+		t.Error("Ready() is closed after Stop()")
+	default:
+	}
+	if err := s.Stop(context.Background()); err != nil {
+		t.Errorf("Stop() of a stopped server error = %v", err)
+	}
+}
+
+func TestRestart(t *testing.T) {
+	s := New(hello(), Options{})
+	for i := 0; i < 3; i++ {
+		ln := listen(t)
+		if err := s.Start(ln); err != nil {
+			t.Fatalf("Start() #%d error = %v", i, err)
+		}
+		ready(t, s)
+		if body := get(t, ln); body != "hello" {
+			t.Errorf("GET / #%d = %q", i, body)
+		}
+		if err := s.Stop(context.Background()); err != nil {
+			t.Fatalf("Stop() #%d error = %v", i, err)
+		}
+	}
+}
+
+func TestConcurrentStart(t *testing.T) {
+	s := New(hello(), Options{})
+	defer s.Stop(context.Background())
+
+	var started atomic.Int32
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		ln := listen(t)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			err := s.Start(ln)
+			switch {
+			case err == nil:
+				started.Add(1)
+			case errors.Is(err, ErrAlreadyRunning):
+				ln.Close()
+			default:
+				t.Errorf("Start() error = %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if n := started.Load(); n != 1 {
+		t.Errorf("%d concurrent Start() calls succeeded, want 1", n)
+	}
+	ready(t, s)
+}
+
+func TestConcurrentStop(t *testing.T) {
+	s := New(hello(), Options{})
+	if err := s.Start(listen(t)); err != nil {
+		t.Fatal(err)
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := s.Stop(context.Background()); err != nil {
+				t.Errorf("Stop() error = %v", err)
+			}
+			if s.State() != ServerStatusStopped {
+				t.Errorf("State() = %v after Stop() returned", s.State())
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func TestStopDrains(t *testing.T) {
+	entered := make(chan struct{})
+	release := make(chan struct{})
+	s := New(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(entered)
+		<-release
+		io.WriteString(w, "done")
+	}), Options{})
+	ln := listen(t)
+	if err := s.Start(ln); err != nil {
+		t.Fatal(err)
+	}
+
+	body := make(chan string, 1)
+	go func() {
+		resp, err := http.Get("http://" + ln.Addr().String() + "/")
+		if err != nil {
+			body <- err.Error()
+			return
+		}
+		defer resp.Body.Close()
+		b, _ := io.ReadAll(resp.Body)
+		body <- string(b)
+	}()
+	<-entered
+
+	stopped := make(chan error, 1)
+	go func() { stopped <- s.Stop(context.Background()) }()
+
+	// the active request keeps the server stopping
+	time.Sleep(20 * time.Millisecond)
+	if s.State() != ServerStatusStopping {
+		t.Errorf("State() = %v while a request is active", s.State())
+	}
+	close(release)
+
+	if err := <-stopped; err != nil {
+		t.Errorf("Stop() error = %v", err)
+	}
+	if b := <-body; b != "done" {
+		t.Errorf("active request got %q", b)
+	}
+}
+
+func TestStopTimeout(t *testing.T) {
+	entered := make(chan struct{})
+	release := make(chan struct{})
+	defer close(release)
+	s := New(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(entered)
+		<-release
+	}), Options{})
+	ln := listen(t)
+	if err := s.Start(ln); err != nil {
+		t.Fatal(err)
+	}
+
+	go http.Get("http://" + ln.Addr().String() + "/")
+	<-entered
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := s.Stop(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Stop() error = %v, want %v", err, context.DeadlineExceeded)
+	}
+	if s.State() != ServerStatusStopped {
+		t.Errorf("State() = %v after a timed out Stop()", s.State())
+	}
+}
+
+// failingListener fails to accept after it is closed by the test.
+type failingListener struct {
+	net.Listener
+}
+
+var errAccept = errors.New("accept failed")
+
+func (l failingListener) Accept() (net.Conn, error) {
+	c, err := l.Listener.Accept()
+	if err != nil {
+		return nil, errAccept
+	}
+	return c, nil
+}
+
+func TestServeError(t *testing.T) {
+	s := New(hello(), Options{})
+	ln := failingListener{listen(t)}
+	if err := s.Start(ln); err != nil {
+		t.Fatal(err)
+	}
+	ready(t, s)
+
+	ln.Listener.Close()
+	if err := s.Wait(); !errors.Is(err, errAccept) {
+		t.Errorf("Wait() = %v, want %v", err, errAccept)
+	}
+	if s.State() != ServerStatusStopped {
+		t.Errorf("State() = %v after a serve error", s.State())
+	}
+	if errs := s.Errors(); len(errs) != 1 {
+		t.Errorf("Errors() = %v", errs)
+	}
+
+	if err := s.Start(listen(t)); err != nil {
+		t.Fatalf("Start() after a serve error = %v", err)
+	}
+	if err := s.Stop(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if errs := s.Errors(); len(errs) != 0 {
+		t.Errorf("Errors() = %v of the previous run", errs)
+	}
+}
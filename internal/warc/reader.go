@@ -0,0 +1,155 @@
+package warc
+
+import (
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"io"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+)
+
+type Reader struct {
+	br *bufio.Reader
+}
+
+// NewReader returns a reader of the records in r.
+// Gzip compressed input is detected and decompressed.
+func NewReader(r io.Reader) (*Reader, error) {
+	br := bufio.NewReader(r)
+
+	magic, err := br.Peek(2)
+	if err == nil && magic[0] == 0x1f && magic[1] == 0x8b {
+		zr, err := gzip.NewReader(br)
+		if err != nil {
+			return nil, err
+		}
+		br = bufio.NewReader(zr)
+	}
+
+	return &Reader{br: br}, nil
+}
+
+// Next returns the next record, or io.EOF if there are no more records.
+func (r *Reader) Next() (*Record, error) {
+	var line string
+	for {
+		l, err := r.br.ReadString('\n')
+		if err != nil {
+			if err == io.EOF && strings.TrimSpace(l) == "" {
+				return nil, io.EOF
+			}
+			return nil, err
+		}
+		line = strings.TrimSpace(l)
+		if line != "" {
+			break
+		}
+	}
+
+	if line != "WARC/1.1" && line != "WARC/1.0" {
+		return nil, ErrUnsupported
+	}
+
+	rec := &Record{}
+	for {
+		l, err := r.br.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+		l = strings.TrimRight(l, "\r\n")
+		if l == "" {
+			break
+		}
+
+		// continuation line
+		if (l[0] == ' ' || l[0] == '\t') && len(rec.Header) > 0 {
+			rec.Header[len(rec.Header)-1].Value += " " + strings.TrimSpace(l)
+			continue
+		}
+
+		name, value, ok := strings.Cut(l, ":")
+		if !ok {
+			return nil, ErrInvalidRecord
+		}
+		rec.Header = append(rec.Header, Field{Name: strings.TrimSpace(name), Value: strings.TrimSpace(value)})
+	}
+
+	length, err := strconv.ParseInt(rec.Header.Get("Content-Length"), 10, 64)
+	if err != nil || length < 0 {
+		return nil, ErrInvalidRecord
+	}
+
+	var block bytes.Buffer
+	if _, err := io.CopyN(&block, r.br, length); err != nil {
+		return nil, err
+	}
+	rec.Block = block.Bytes()
+
+	return rec, nil
+}
+
+// Walk calls fn for every record of a .warc, .warc.gz or .wacz file.
+func Walk(name string, fn func(*Record) error) error {
+	if strings.EqualFold(path.Ext(name), ".wacz") {
+		return walkWACZ(name, fn)
+	}
+
+	f, err := os.Open(name)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	return walk(f, fn)
+}
+
+func walk(r io.Reader, fn func(*Record) error) error {
+	wr, err := NewReader(r)
+	if err != nil {
+		return err
+	}
+
+	for {
+		rec, err := wr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if err := fn(rec); err != nil {
+			return err
+		}
+	}
+}
+
+// walkWACZ reads the archives stored in the archive/ directory of a WACZ package.
+func walkWACZ(name string, fn func(*Record) error) error {
+	zr, err := zip.OpenReader(name)
+	if err != nil {
+		return err
+	}
+	defer zr.Close()
+
+	for _, f := range zr.File {
+		if !strings.HasPrefix(f.Name, "archive/") || !strings.Contains(f.Name, ".warc") {
+			continue
+		}
+
+		rc, err := f.Open()
+		if err != nil {
+			return err
+		}
+		err = walk(rc, fn)
+		rc.Close()
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,240 @@
+package warc
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha1"
+	"encoding/base32"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const Version = "WARC/1.1"
+
+type RecordType string
+
+const (
+	TypeWarcinfo   RecordType = "warcinfo"
+	TypeResponse   RecordType = "response"
+	TypeResource   RecordType = "resource"
+	TypeRequest    RecordType = "request"
+	TypeMetadata   RecordType = "metadata"
+	TypeRevisit    RecordType = "revisit"
+	TypeConversion RecordType = "conversion"
+)
+
+var (
+	ErrInvalidRecord = errors.New("warc: invalid record")
+	ErrUnsupported   = errors.New("warc: unsupported version")
+)
+
+type Field struct {
+	Name  string
+	Value string
+}
+
+// Header holds the named fields of a record in their original order.
+type Header []Field
+
+func (h Header) Get(name string) string {
+	for _, f := range h {
+		if strings.EqualFold(f.Name, name) {
+			return f.Value
+		}
+	}
+	return ""
+}
+
+func (h *Header) Set(name, value string) {
+	for i, f := range *h {
+		if strings.EqualFold(f.Name, name) {
+			(*h)[i].Value = value
+			return
+		}
+	}
+	*h = append(*h, Field{Name: name, Value: value})
+}
+
+type Record struct {
+	Header Header
+	Block  []byte
+}
+
+func (r *Record) Type() RecordType {
+	return RecordType(r.Header.Get("WARC-Type"))
+}
+
+func (r *Record) TargetURI() string {
+	return strings.Trim(r.Header.Get("WARC-Target-URI"), "<>")
+}
+
+func (r *Record) Date() time.Time {
+	t, _ := time.Parse(time.RFC3339Nano, r.Header.Get("WARC-Date"))
+	return t
+}
+
+// HTTPResponse parses the block of a response record.
+// The body of the returned response is decoded if it uses a gzip content encoding.
+func (r *Record) HTTPResponse() (*http.Response, error) {
+	resp, err := http.ReadResponse(bufio.NewReader(bytes.NewReader(r.Block)), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if strings.EqualFold(resp.Header.Get("Content-Encoding"), "gzip") {
+		zr, err := gzip.NewReader(resp.Body)
+		if err != nil {
+			return nil, err
+		}
+		resp.Body = zr
+		resp.Header.Del("Content-Encoding")
+	}
+
+	return resp, nil
+}
+
+func digest(b []byte) string {
+	sum := sha1.Sum(b)
+	return "sha1:" + base32.StdEncoding.EncodeToString(sum[:])
+}
+
+// HTTPResponseBlock serializes an http response for a response record.
+func HTTPResponseBlock(status int, header http.Header, body []byte) []byte {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "HTTP/1.1 %d %s\r\n", status, http.StatusText(status))
+
+	keys := make([]string, 0, len(header))
+	for k := range header {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		for _, v := range header[k] {
+			buf.WriteString(k + ": " + strings.NewReplacer("\r", " ", "\n", " ").Replace(v) + "\r\n")
+		}
+	}
+	buf.WriteString("\r\n")
+	buf.Write(body)
+
+	return buf.Bytes()
+}
+
+type Writer struct {
+	mu       sync.Mutex
+	w        io.Writer
+	compress bool
+}
+
+// NewWriter returns a writer that appends records to w.
+// If compress is set, every record is written as a separate gzip member as in .warc.gz files.
+func NewWriter(w io.Writer, compress bool) *Writer {
+	return &Writer{w: w, compress: compress}
+}
+
+// WriteRecord writes r and returns its record ID.
+// WARC-Record-ID, WARC-Date, Content-Length and WARC-Block-Digest are filled in if they are missing.
+func (w *Writer) WriteRecord(r *Record) (string, error) {
+	if r.Header.Get("WARC-Type") == "" {
+		return "", ErrInvalidRecord
+	}
+	if r.Header.Get("WARC-Record-ID") == "" {
+		r.Header.Set("WARC-Record-ID", "<urn:uuid:"+uuid.NewString()+">")
+	}
+	if r.Header.Get("WARC-Date") == "" {
+		r.Header.Set("WARC-Date", time.Now().UTC().Format(time.RFC3339))
+	}
+	if r.Header.Get("WARC-Block-Digest") == "" {
+		r.Header.Set("WARC-Block-Digest", digest(r.Block))
+	}
+	r.Header.Set("Content-Length", strconv.Itoa(len(r.Block)))
+
+	var buf bytes.Buffer
+	buf.WriteString(Version + "\r\n")
+	for _, f := range r.Header {
+		buf.WriteString(f.Name + ": " + f.Value + "\r\n")
+	}
+	buf.WriteString("\r\n")
+	buf.Write(r.Block)
+	buf.WriteString("\r\n\r\n")
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if !w.compress {
+		_, err := w.w.Write(buf.Bytes())
+		return r.Header.Get("WARC-Record-ID"), err
+	}
+
+	zw := gzip.NewWriter(w.w)
+	if _, err := zw.Write(buf.Bytes()); err != nil {
+		return "", err
+	}
+	if err := zw.Close(); err != nil {
+		return "", err
+	}
+	return r.Header.Get("WARC-Record-ID"), nil
+}
+
+// WriteInfo writes a warcinfo record with the given fields.
+func (w *Writer) WriteInfo(filename string, fields map[string]string) (string, error) {
+	keys := make([]string, 0, len(fields))
+	for k := range fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var block bytes.Buffer
+	for _, k := range keys {
+		block.WriteString(k + ": " + fields[k] + "\r\n")
+	}
+
+	return w.WriteRecord(&Record{
+		Header: Header{
+			{"WARC-Type", string(TypeWarcinfo)},
+			{"WARC-Filename", filename},
+			{"Content-Type", "application/warc-fields"},
+		},
+		Block: block.Bytes(),
+	})
+}
+
+// WriteResponse writes a response record with the http response of target.
+func (w *Writer) WriteResponse(target string, date time.Time, status int, header http.Header, body []byte) (string, error) {
+	return w.WriteRecord(&Record{
+		Header: Header{
+			{"WARC-Type", string(TypeResponse)},
+			{"WARC-Target-URI", target},
+			{"WARC-Date", date.UTC().Format(time.RFC3339)},
+			{"WARC-Payload-Digest", digest(body)},
+			{"Content-Type", "application/http;msgtype=response"},
+		},
+		Block: HTTPResponseBlock(status, header, body),
+	})
+}
+
+// WriteConversion writes a conversion record, e.g. the rendered DOM of a response.
+func (w *Writer) WriteConversion(target string, date time.Time, refersTo string, contentType string, body []byte) (string, error) {
+	r := &Record{
+		Header: Header{
+			{"WARC-Type", string(TypeConversion)},
+			{"WARC-Target-URI", target},
+			{"WARC-Date", date.UTC().Format(time.RFC3339)},
+			{"Content-Type", contentType},
+		},
+		Block: body,
+	}
+	if refersTo != "" {
+		r.Header.Set("WARC-Refers-To", refersTo)
+	}
+	return w.WriteRecord(r)
+}
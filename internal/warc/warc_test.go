@@ -0,0 +1,69 @@
+package warc
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestWriterReader(t *testing.T) {
+	for _, compress := range []bool{false, true} {
+		var buf bytes.Buffer
+		w := NewWriter(&buf, compress)
+
+		if _, err := w.WriteInfo("test.warc", map[string]string{"software": "jimin"}); err != nil {
+			t.Fatalf("WriteInfo() error = %v", err)
+		}
+
+		header := http.Header{"Content-Type": {"text/html"}}
+		id, err := w.WriteResponse("https://example.com/", time.Now(), http.StatusOK, header, []byte("<p>hello</p>"))
+		if err != nil {
+			t.Fatalf("WriteResponse() error = %v", err)
+		}
+
+		if _, err := w.WriteConversion("https://example.com/", time.Now(), id, "text/html", []byte("<p>rendered</p>")); err != nil {
+			t.Fatalf("WriteConversion() error = %v", err)
+		}
+
+		r, err := NewReader(&buf)
+		if err != nil {
+			t.Fatalf("NewReader() error = %v", err)
+		}
+
+		var records []*Record
+		for {
+			rec, err := r.Next()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				t.Fatalf("Next() error = %v", err)
+			}
+			records = append(records, rec)
+		}
+
+		if len(records) != 3 {
+			t.Fatalf("read %d records, want 3", len(records))
+		}
+		if records[0].Type() != TypeWarcinfo || records[1].Type() != TypeResponse || records[2].Type() != TypeConversion {
+			t.Errorf("unexpected record types %s, %s, %s", records[0].Type(), records[1].Type(), records[2].Type())
+		}
+		if records[2].Header.Get("WARC-Refers-To") != id {
+			t.Errorf("WARC-Refers-To = %q, want %q", records[2].Header.Get("WARC-Refers-To"), id)
+		}
+
+		resp, err := records[1].HTTPResponse()
+		if err != nil {
+			t.Fatalf("HTTPResponse() error = %v", err)
+		}
+		body, _ := io.ReadAll(resp.Body)
+		if resp.StatusCode != http.StatusOK || string(body) != "<p>hello</p>" {
+			t.Errorf("HTTPResponse() = %d %q", resp.StatusCode, body)
+		}
+		if records[1].TargetURI() != "https://example.com/" {
+			t.Errorf("TargetURI() = %q", records[1].TargetURI())
+		}
+	}
+}
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"net/http"
+	"time"
+
+	"gosuda.org/jimin/internal/crawler"
+	"gosuda.org/jimin/internal/linkcheck"
+)
+
+type LinkCheckConfig struct {
+	// Interval is the time in seconds between checks of a link.
+	Interval int `json:"interval,omitempty"`
+	// RetryInterval is the time in seconds before an inconclusive check is retried.
+	RetryInterval int `json:"retry_interval,omitempty"`
+	// MaxFailures is the number of consecutive failed checks of its source before a document is flagged.
+	MaxFailures int `json:"max_failures,omitempty"`
+	Concurrency int `json:"concurrency,omitempty"`
+}
+
+// NewLinkChecker returns the link rot checker for the config.
+// Links are fetched with the profiles and proxies of cr if it is not nil.
+func NewLinkChecker(c *Config, db linkcheck.DB, ids linkcheck.IDGenerator, cr *crawler.Crawler) *linkcheck.Checker {
+	client := &http.Client{Timeout: time.Second * 30}
+	if cr != nil {
+		client.Transport = cr.Transport(nil)
+	}
+
+	return linkcheck.NewChecker(linkcheck.Config{
+		DB:            db,
+		IDs:           ids,
+		Client:        client,
+		Interval:      time.Duration(c.LinkCheck.Interval) * time.Second,
+		RetryInterval: time.Duration(c.LinkCheck.RetryInterval) * time.Second,
+		MaxFailures:   c.LinkCheck.MaxFailures,
+		Concurrency:   c.LinkCheck.Concurrency,
+	})
+}
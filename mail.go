@@ -0,0 +1,54 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"gosuda.org/jimin/internal/mail"
+)
+
+type MailConfig struct {
+	// Type is smtp, file or log. Emails are not sent if empty.
+	Type string `json:"type,omitempty"`
+	// From is the sender address, like "Jimin <noreply@example.com>".
+	From string         `json:"from,omitempty"`
+	SMTP SMTPMailConfig `json:"smtp"`
+	// Dir is the directory the file mailer writes the emails to.
+	Dir string `json:"dir,omitempty"`
+}
+
+type SMTPMailConfig struct {
+	// Addr is the host:port of the server.
+	Addr string `json:"addr,omitempty"`
+	// TLS is starttls, implicit or none, starttls if empty.
+	TLS      string `json:"tls,omitempty"`
+	Username string `json:"username,omitempty"`
+	Password string `json:"password,omitempty"`
+	// Timeout is the time in seconds to send an email, 30 if 0.
+	Timeout int `json:"timeout,omitempty"`
+}
+
+var ErrNoMailer = errors.New("mail is not configured")
+
+// Mailer returns the configured mailer.
+func (c *Config) Mailer() (mail.Mailer, error) {
+	switch c.Mail.Type {
+	case "":
+		return nil, ErrNoMailer
+	case "smtp":
+		return mail.NewSMTP(mail.SMTPConfig{
+			Addr:     c.Mail.SMTP.Addr,
+			TLS:      c.Mail.SMTP.TLS,
+			Username: c.Mail.SMTP.Username,
+			Password: c.Mail.SMTP.Password,
+			From:     c.Mail.From,
+			Timeout:  time.Duration(c.Mail.SMTP.Timeout) * time.Second,
+		})
+	case "file":
+		return mail.NewFile(c.Mail.Dir, c.Mail.From)
+	case "log":
+		return mail.Log{}, nil
+	}
+	return nil, fmt.Errorf("unknown mail type %q", c.Mail.Type)
+}
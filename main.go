@@ -1,9 +1,13 @@
 package main
 
 import (
+	"context"
+	"os"
+	"os/signal"
+	"syscall"
+
 	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/log"
-	"gopkg.eu.org/envloader"
 
 	_ "github.com/lemon-mint/coord/provider/aistudio"
 	_ "github.com/lemon-mint/coord/provider/anthropic"
@@ -15,12 +19,10 @@ import (
 
 func main() {
 	zerolog.TimeFieldFormat = zerolog.TimeFormatUnixMs
+	log.Logger = log.Output(zerolog.ConsoleWriter{Out: os.Stderr, TimeFormat: "15:04:05"})
 
-	envloader.LoadEnvFile(".env")
-	config, err := LoadConfig("config.jsonnet")
-	if err != nil {
-		log.Fatal().Err(err).Msg("Failed to load config")
-	}
-
-	_ = config
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	code := run(ctx, os.Args[1:], os.Stdout, os.Stderr)
+	stop()
+	os.Exit(code)
 }
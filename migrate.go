@@ -0,0 +1,227 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"strconv"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"gosuda.org/jimin/internal/migrate"
+	"gosuda.org/jimin/migrations"
+)
+
+// loadMigrations loads the migrations of dir, or the embedded ones if dir is empty.
+func loadMigrations(dir string) ([]migrate.Migration, error) {
+	var fsys fs.FS = migrations.FS
+	if dir != "" {
+		fsys = os.DirFS(dir)
+	}
+	ms, err := migrate.Load(fsys)
+	if err != nil {
+		return nil, withCode(_EXIT_CONFIG, err)
+	}
+	return ms, nil
+}
+
+// migration is the connection and the migrations of a migrate command.
+type migration struct {
+	pool       *pgxpool.Pool
+	conn       *pgxpool.Conn
+	migrations []migrate.Migration
+}
+
+func (m *migration) Close() {
+	m.conn.Release()
+	m.pool.Close()
+}
+
+// migrationFlags returns the flag set of a migrate command with its -dir flag.
+func (c *cli) migrationFlags(name, args string) (*flag.FlagSet, *string) {
+	flags := c.flags(name, args)
+	return flags, flags.String("dir", "", "migrations directory (default: the embedded migrations)")
+}
+
+// openMigration loads the migrations of dir and connects to the database.
+func (c *cli) openMigration(ctx context.Context, dir string) (*migration, error) {
+	cfg, err := c.loadConfig()
+	if err != nil {
+		return nil, err
+	}
+	ms, err := loadMigrations(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	pool, err := connect(ctx, cfg)
+	if err != nil {
+		return nil, err
+	}
+	conn, err := pool.Acquire(ctx)
+	if err != nil {
+		pool.Close()
+		return nil, withCode(_EXIT_UNAVAILABLE, err)
+	}
+	return &migration{pool: pool, conn: conn, migrations: ms}, nil
+}
+
+// migrateError maps the errors of the migrate package to exit codes.
+func migrateError(err error) error {
+	if errors.Is(err, migrate.ErrDirty) || errors.Is(err, migrate.ErrUnknownVersion) || errors.Is(err, migrate.ErrIrreversible) {
+		return withCode(_EXIT_CONFLICT, err)
+	}
+	return err
+}
+
+func migrationNames(ms []migrate.Migration) []string {
+	names := make([]string, len(ms))
+	for i, m := range ms {
+		names[i] = fmt.Sprintf("%d_%s", m.Version, m.Name)
+	}
+	return names
+}
+
+// printVersion prints the version of the database after a migrate command.
+func (c *cli) printVersion(ctx context.Context, conn *pgx.Conn, key string, ms []migrate.Migration) error {
+	version, _, err := migrate.Version(ctx, conn)
+	if err != nil {
+		return err
+	}
+	return c.print(map[string]any{"version": version, key: migrationNames(ms)}, func(w io.Writer) {
+		fmt.Fprintf(w, "database is at version %d\n", version)
+	})
+}
+
+func (c *cli) migrateUp(ctx context.Context, args []string) error {
+	flags, dir := c.migrationFlags("migrate up", "")
+	if _, err := c.parse(flags, args, 0); err != nil {
+		return err
+	}
+
+	m, err := c.openMigration(ctx, *dir)
+	if err != nil {
+		return err
+	}
+	defer m.Close()
+
+	applied, err := migrate.Up(ctx, m.conn.Conn(), m.migrations)
+	if !c.json {
+		for _, name := range migrationNames(applied) {
+			fmt.Fprintf(c.stdout, "applied  %s\n", name)
+		}
+	}
+	if err != nil {
+		return migrateError(err)
+	}
+	return c.printVersion(ctx, m.conn.Conn(), "applied", applied)
+}
+
+func (c *cli) migrateDown(ctx context.Context, args []string) error {
+	flags, dir := c.migrationFlags("migrate down", "<n>")
+	pos, err := c.parse(flags, args, 1)
+	if err != nil {
+		return err
+	}
+	n, err := strconv.Atoi(pos[0])
+	if err != nil || n <= 0 {
+		return usageErrorf("migrate down: %q is not a positive number of migrations", pos[0])
+	}
+
+	m, err := c.openMigration(ctx, *dir)
+	if err != nil {
+		return err
+	}
+	defer m.Close()
+
+	reverted, err := migrate.Down(ctx, m.conn.Conn(), m.migrations, n)
+	if !c.json {
+		for _, name := range migrationNames(reverted) {
+			fmt.Fprintf(c.stdout, "reverted %s\n", name)
+		}
+	}
+	if err != nil {
+		return migrateError(err)
+	}
+	return c.printVersion(ctx, m.conn.Conn(), "reverted", reverted)
+}
+
+type migrationStatus struct {
+	Version int64  `json:"version"`
+	Name    string `json:"name"`
+	Applied bool   `json:"applied"`
+}
+
+func (c *cli) migrateStatus(ctx context.Context, args []string) error {
+	flags, dir := c.migrationFlags("migrate status", "")
+	if _, err := c.parse(flags, args, 0); err != nil {
+		return err
+	}
+
+	m, err := c.openMigration(ctx, *dir)
+	if err != nil {
+		return err
+	}
+	defer m.Close()
+
+	version, dirty, err := migrate.Version(ctx, m.conn.Conn())
+	if err != nil {
+		return err
+	}
+	statuses := make([]migrationStatus, len(m.migrations))
+	known := version == 0
+	for i, mig := range m.migrations {
+		statuses[i] = migrationStatus{Version: mig.Version, Name: mig.Name, Applied: mig.Version <= version}
+		known = known || mig.Version == version
+	}
+
+	return c.print(map[string]any{"version": version, "dirty": dirty, "migrations": statuses}, func(w io.Writer) {
+		for _, s := range statuses {
+			state := "pending"
+			switch {
+			case s.Version == version && dirty:
+				state = "dirty"
+			case s.Applied:
+				state = "applied"
+			}
+			fmt.Fprintf(w, "%-8s %d_%s\n", state, s.Version, s.Name)
+		}
+		fmt.Fprintf(w, "database is at version %d", version)
+		if dirty {
+			fmt.Fprint(w, ", dirty: fix the failed migration and run 'jimin migrate force <version>'")
+		}
+		if !known {
+			fmt.Fprint(w, ", which is not a known migration")
+		}
+		fmt.Fprintln(w)
+	})
+}
+
+func (c *cli) migrateForce(ctx context.Context, args []string) error {
+	flags, dir := c.migrationFlags("migrate force", "<version>")
+	pos, err := c.parse(flags, args, 1)
+	if err != nil {
+		return err
+	}
+	version, err := strconv.ParseInt(pos[0], 10, 64)
+	if err != nil || version < 0 {
+		return usageErrorf("migrate force: %q is not a migration version", pos[0])
+	}
+
+	m, err := c.openMigration(ctx, *dir)
+	if err != nil {
+		return err
+	}
+	defer m.Close()
+
+	if err := migrate.Force(ctx, m.conn.Conn(), m.migrations, version); err != nil {
+		return migrateError(err)
+	}
+	return c.print(map[string]any{"version": version}, func(w io.Writer) {
+		fmt.Fprintf(w, "database is at version %d\n", version)
+	})
+}
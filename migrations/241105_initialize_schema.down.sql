@@ -1,31 +1,47 @@
-DROP EXTENSION vector;
+DROP INDEX idx_randflake_nodes_lease_end;
 
-DROP TABLE users;
+DROP INDEX idx_randflake_nodes_unique_range_start_lease_end;
 
-DROP INDEX idx_users_unique_email;
+DROP INDEX idx_randflake_nodes_unique_range_start;
 
-DROP TABLE wss;
+DROP TABLE randflake_nodes;
 
-DROP TABLE ws_members;
+DROP TABLE ws_relations;
 
-DROP INDEX idx_ws_members_unique_ws_id_user_id;
+DROP TYPE relation_type;
 
-DROP INDEX idx_ws_members_ws_id;
+DROP INDEX idx_ws_role_members_ws_id;
 
-DROP TABLE ws_roles;
+DROP INDEX idx_ws_role_members_ws_id_ws_role_id;
 
-DROP INDEX idx_ws_roles_unique_ws_id_name;
+DROP INDEX idx_ws_role_members_ws_id_ws_member_id;
 
-DROP INDEX idx_ws_roles_ws_id;
+DROP INDEX idx_ws_role_members_unique_ws_id_ws_role_id_ws_member_id;
 
 DROP TABLE ws_role_members;
 
-DROP INDEX idx_ws_role_members_unique_ws_id_ws_role_id_ws_member_id;
+DROP INDEX idx_ws_roles_ws_id;
 
-DROP INDEX idx_ws_role_members_ws_id_ws_member_id;
+DROP INDEX idx_ws_roles_unique_ws_id_name;
 
-DROP INDEX idx_ws_role_members_ws_id_ws_role_id;
+DROP TABLE ws_roles;
 
-DROP INDEX idx_ws_role_members_ws_id;
+DROP INDEX idx_ws_members_ws_id;
 
-DROP TABLE randflake_nodes;
+DROP INDEX idx_ws_members_unique_ws_id_user_id;
+
+DROP TABLE ws_members;
+
+DROP TABLE wss;
+
+DROP INDEX idx_users_auth_unique_provider_subject;
+
+DROP INDEX idx_users_auth_unique_user_id_provider;
+
+DROP TABLE users_auth;
+
+DROP INDEX idx_users_unique_email;
+
+DROP TABLE users;
+
+DROP EXTENSION vector;
@@ -23,7 +23,7 @@ CREATE TABLE
         updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW ()
     );
 
-CREATE UNIQUE INDEX idx_users_auth_unique_user_id_provider ON users_auth (user_id, provider);
+CREATE UNIQUE INDEX idx_users_auth_unique_user_id_provider ON users_auth (user_id, provider_id);
 CREATE UNIQUE INDEX idx_users_auth_unique_provider_subject ON users_auth (provider_id, provider_subject);
 
 -- workspaces
@@ -0,0 +1,9 @@
+DROP INDEX idx_document_versions_unique_document_id_version;
+
+DROP TABLE document_versions;
+
+DROP INDEX idx_documents_next_crawl_at;
+
+DROP INDEX idx_documents_unique_ws_id_url;
+
+DROP TABLE documents;
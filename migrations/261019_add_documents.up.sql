@@ -0,0 +1,39 @@
+CREATE TABLE
+    documents (
+        id BIGINT PRIMARY KEY,
+        ws_id BIGINT NOT NULL,
+        source TEXT NOT NULL,
+        url TEXT NOT NULL,
+        title TEXT NOT NULL,
+        current_version_id BIGINT NOT NULL DEFAULT 0,
+        content_hash TEXT NOT NULL DEFAULT '',
+        etag TEXT NOT NULL DEFAULT '',
+        last_modified TEXT NOT NULL DEFAULT '',
+        recrawl_interval BIGINT NOT NULL,
+        unchanged_count INTEGER NOT NULL DEFAULT 0,
+        last_crawled_at TIMESTAMPTZ,
+        last_changed_at TIMESTAMPTZ,
+        next_crawl_at TIMESTAMPTZ NOT NULL,
+        created_at TIMESTAMPTZ NOT NULL DEFAULT NOW (),
+        updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW ()
+    );
+
+CREATE UNIQUE INDEX idx_documents_unique_ws_id_url ON documents (ws_id, url);
+
+CREATE INDEX idx_documents_next_crawl_at ON documents (next_crawl_at ASC);
+
+CREATE TABLE
+    document_versions (
+        id BIGINT PRIMARY KEY,
+        document_id BIGINT NOT NULL,
+        version INTEGER NOT NULL,
+        title TEXT NOT NULL,
+        content TEXT NOT NULL,
+        content_hash TEXT NOT NULL,
+        etag TEXT NOT NULL,
+        last_modified TEXT NOT NULL,
+        fetched_at TIMESTAMPTZ NOT NULL,
+        created_at TIMESTAMPTZ NOT NULL DEFAULT NOW ()
+    );
+
+CREATE UNIQUE INDEX idx_document_versions_unique_document_id_version ON document_versions (document_id, version);
@@ -0,0 +1,7 @@
+DROP INDEX idx_feeds_ws_id_status;
+
+DROP INDEX idx_feeds_unique_ws_id_url;
+
+DROP TABLE feeds;
+
+DROP TYPE feed_status;
@@ -0,0 +1,18 @@
+CREATE TYPE feed_status AS ENUM ('SUGGESTED', 'SUBSCRIBED', 'DISMISSED');
+
+CREATE TABLE
+    feeds (
+        id BIGINT PRIMARY KEY,
+        ws_id BIGINT NOT NULL,
+        url TEXT NOT NULL,
+        title TEXT NOT NULL,
+        type TEXT NOT NULL,
+        status feed_status NOT NULL,
+        discovered_from TEXT NOT NULL,
+        created_at TIMESTAMPTZ NOT NULL DEFAULT NOW (),
+        updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW ()
+    );
+
+CREATE UNIQUE INDEX idx_feeds_unique_ws_id_url ON feeds (ws_id, url);
+
+CREATE INDEX idx_feeds_ws_id_status ON feeds (ws_id, status);
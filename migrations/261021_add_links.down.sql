@@ -0,0 +1,17 @@
+DROP INDEX idx_link_checks_link_id_checked_at;
+
+DROP TABLE link_checks;
+
+DROP INDEX idx_links_ws_id_status;
+
+DROP INDEX idx_links_next_check_at;
+
+DROP INDEX idx_links_unique_document_id_url;
+
+DROP TABLE links;
+
+ALTER TABLE documents DROP COLUMN link_status;
+
+DROP TYPE link_kind;
+
+DROP TYPE link_status;
@@ -0,0 +1,39 @@
+CREATE TYPE link_status AS ENUM ('UNKNOWN', 'OK', 'BROKEN', 'PARKED', 'ERROR');
+
+CREATE TYPE link_kind AS ENUM ('SOURCE', 'OUTLINK');
+
+ALTER TABLE documents ADD COLUMN link_status link_status NOT NULL DEFAULT 'UNKNOWN';
+
+CREATE TABLE
+    links (
+        id BIGINT PRIMARY KEY,
+        ws_id BIGINT NOT NULL,
+        document_id BIGINT NOT NULL,
+        url TEXT NOT NULL,
+        kind link_kind NOT NULL,
+        status link_status NOT NULL DEFAULT 'UNKNOWN',
+        status_code INTEGER NOT NULL DEFAULT 0,
+        failures INTEGER NOT NULL DEFAULT 0,
+        last_checked_at TIMESTAMPTZ,
+        next_check_at TIMESTAMPTZ NOT NULL,
+        created_at TIMESTAMPTZ NOT NULL DEFAULT NOW (),
+        updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW ()
+    );
+
+CREATE UNIQUE INDEX idx_links_unique_document_id_url ON links (document_id, url);
+
+CREATE INDEX idx_links_next_check_at ON links (next_check_at ASC);
+
+CREATE INDEX idx_links_ws_id_status ON links (ws_id, status);
+
+CREATE TABLE
+    link_checks (
+        id BIGINT PRIMARY KEY,
+        link_id BIGINT NOT NULL,
+        status link_status NOT NULL,
+        status_code INTEGER NOT NULL,
+        error TEXT NOT NULL,
+        checked_at TIMESTAMPTZ NOT NULL
+    );
+
+CREATE INDEX idx_link_checks_link_id_checked_at ON link_checks (link_id, checked_at DESC);
@@ -0,0 +1,21 @@
+DROP INDEX idx_ws_relations_ws_id_target_id_relation;
+
+DROP INDEX idx_ws_relations_ws_id_object_id_relation;
+
+DROP INDEX idx_documents_ws_id_simhash_b3;
+
+DROP INDEX idx_documents_ws_id_simhash_b2;
+
+DROP INDEX idx_documents_ws_id_simhash_b1;
+
+DROP INDEX idx_documents_ws_id_simhash_b0;
+
+ALTER TABLE documents
+    DROP COLUMN simhash,
+    DROP COLUMN simhash_b0,
+    DROP COLUMN simhash_b1,
+    DROP COLUMN simhash_b2,
+    DROP COLUMN simhash_b3;
+
+-- enum values cannot be dropped, the duplicate relations are removed instead
+DELETE FROM ws_relations WHERE relation = 'DUPLICATE';
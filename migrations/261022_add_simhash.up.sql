@@ -0,0 +1,20 @@
+ALTER TYPE relation_type ADD VALUE 'DUPLICATE';
+
+ALTER TABLE documents
+    ADD COLUMN simhash BIGINT NOT NULL DEFAULT 0,
+    ADD COLUMN simhash_b0 INTEGER NOT NULL DEFAULT 0,
+    ADD COLUMN simhash_b1 INTEGER NOT NULL DEFAULT 0,
+    ADD COLUMN simhash_b2 INTEGER NOT NULL DEFAULT 0,
+    ADD COLUMN simhash_b3 INTEGER NOT NULL DEFAULT 0;
+
+CREATE INDEX idx_documents_ws_id_simhash_b0 ON documents (ws_id, simhash_b0);
+
+CREATE INDEX idx_documents_ws_id_simhash_b1 ON documents (ws_id, simhash_b1);
+
+CREATE INDEX idx_documents_ws_id_simhash_b2 ON documents (ws_id, simhash_b2);
+
+CREATE INDEX idx_documents_ws_id_simhash_b3 ON documents (ws_id, simhash_b3);
+
+CREATE INDEX idx_ws_relations_ws_id_object_id_relation ON ws_relations (ws_id, object_id, relation);
+
+CREATE INDEX idx_ws_relations_ws_id_target_id_relation ON ws_relations (ws_id, target_id, relation);
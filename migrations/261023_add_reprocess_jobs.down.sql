@@ -0,0 +1,15 @@
+DROP INDEX idx_documents_ws_id_id;
+
+DROP INDEX idx_reprocess_jobs_status;
+
+DROP INDEX idx_reprocess_jobs_ws_id;
+
+DROP TABLE reprocess_jobs;
+
+DROP TYPE reprocess_status;
+
+ALTER TABLE document_versions
+    DROP COLUMN raw_key,
+    DROP COLUMN raw_content_type,
+    DROP COLUMN converter_version,
+    DROP COLUMN chunker_version;
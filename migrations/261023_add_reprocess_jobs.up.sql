@@ -0,0 +1,30 @@
+ALTER TABLE document_versions
+    ADD COLUMN raw_key TEXT NOT NULL DEFAULT '',
+    ADD COLUMN raw_content_type TEXT NOT NULL DEFAULT '',
+    ADD COLUMN converter_version INTEGER NOT NULL DEFAULT 0,
+    ADD COLUMN chunker_version INTEGER NOT NULL DEFAULT 0;
+
+CREATE TYPE reprocess_status AS ENUM ('PENDING', 'RUNNING', 'DONE', 'FAILED', 'CANCELED');
+
+CREATE TABLE
+    reprocess_jobs (
+        id BIGINT PRIMARY KEY,
+        ws_id BIGINT NOT NULL,
+        source TEXT NOT NULL DEFAULT '',
+        url_prefix TEXT NOT NULL DEFAULT '',
+        stale_only BOOLEAN NOT NULL DEFAULT FALSE,
+        status reprocess_status NOT NULL DEFAULT 'PENDING',
+        last_document_id BIGINT NOT NULL DEFAULT 0,
+        processed INTEGER NOT NULL DEFAULT 0,
+        failed INTEGER NOT NULL DEFAULT 0,
+        error TEXT NOT NULL DEFAULT '',
+        heartbeat_at TIMESTAMPTZ,
+        created_at TIMESTAMPTZ NOT NULL DEFAULT NOW (),
+        updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW ()
+    );
+
+CREATE INDEX idx_reprocess_jobs_ws_id ON reprocess_jobs (ws_id);
+
+CREATE INDEX idx_reprocess_jobs_status ON reprocess_jobs (status);
+
+CREATE INDEX idx_documents_ws_id_id ON documents (ws_id, id);
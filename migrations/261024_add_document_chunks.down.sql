@@ -0,0 +1,9 @@
+DROP INDEX idx_document_chunks_ws_id;
+
+DROP INDEX idx_document_chunks_unique_document_id_seq;
+
+DROP TABLE document_chunks;
+
+ALTER TABLE document_versions
+    DROP COLUMN summary,
+    DROP COLUMN tags;
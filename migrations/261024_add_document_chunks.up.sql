@@ -0,0 +1,22 @@
+ALTER TABLE document_versions
+    ADD COLUMN summary TEXT NOT NULL DEFAULT '',
+    ADD COLUMN tags TEXT[] NOT NULL DEFAULT '{}';
+
+CREATE TABLE
+    document_chunks (
+        id BIGINT PRIMARY KEY,
+        ws_id BIGINT NOT NULL,
+        document_id BIGINT NOT NULL,
+        version_id BIGINT NOT NULL,
+        seq INTEGER NOT NULL,
+        heading TEXT NOT NULL,
+        content TEXT NOT NULL,
+        start_offset INTEGER NOT NULL,
+        end_offset INTEGER NOT NULL,
+        embedding REAL[] NOT NULL DEFAULT '{}',
+        created_at TIMESTAMPTZ NOT NULL DEFAULT NOW ()
+    );
+
+CREATE UNIQUE INDEX idx_document_chunks_unique_document_id_seq ON document_chunks (document_id, seq);
+
+CREATE INDEX idx_document_chunks_ws_id ON document_chunks (ws_id);
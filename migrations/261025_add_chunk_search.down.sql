@@ -0,0 +1 @@
+DROP INDEX idx_document_chunks_content_tsv;
@@ -0,0 +1,7 @@
+DROP INDEX idx_sessions_expires_at;
+
+DROP INDEX idx_sessions_user_id;
+
+DROP INDEX idx_sessions_unique_token_hash;
+
+DROP TABLE sessions;
@@ -0,0 +1,18 @@
+CREATE TABLE
+    sessions (
+        id BIGINT PRIMARY KEY,
+        user_id BIGINT NOT NULL,
+        token_hash BYTEA NOT NULL,
+        user_agent TEXT NOT NULL DEFAULT '',
+        ip_address TEXT NOT NULL DEFAULT '',
+        expires_at TIMESTAMPTZ NOT NULL,
+        revoked_at TIMESTAMPTZ,
+        last_seen_at TIMESTAMPTZ NOT NULL DEFAULT NOW (),
+        created_at TIMESTAMPTZ NOT NULL DEFAULT NOW ()
+    );
+
+CREATE UNIQUE INDEX idx_sessions_unique_token_hash ON sessions (token_hash);
+
+CREATE INDEX idx_sessions_user_id ON sessions (user_id);
+
+CREATE INDEX idx_sessions_expires_at ON sessions (expires_at);
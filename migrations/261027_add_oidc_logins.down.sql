@@ -0,0 +1,5 @@
+DROP INDEX idx_oidc_logins_expires_at;
+
+DROP INDEX idx_oidc_logins_unique_state_hash;
+
+DROP TABLE oidc_logins;
@@ -0,0 +1,16 @@
+CREATE TABLE
+    oidc_logins (
+        id BIGINT PRIMARY KEY,
+        state_hash BYTEA NOT NULL,
+        provider_id BIGINT NOT NULL,
+        nonce TEXT NOT NULL,
+        code_verifier TEXT NOT NULL,
+        link_user_id BIGINT NOT NULL DEFAULT 0,
+        return_to TEXT NOT NULL DEFAULT '',
+        expires_at TIMESTAMPTZ NOT NULL,
+        created_at TIMESTAMPTZ NOT NULL DEFAULT NOW ()
+    );
+
+CREATE UNIQUE INDEX idx_oidc_logins_unique_state_hash ON oidc_logins (state_hash);
+
+CREATE INDEX idx_oidc_logins_expires_at ON oidc_logins (expires_at);
@@ -0,0 +1,7 @@
+DROP INDEX idx_email_tokens_expires_at;
+
+DROP INDEX idx_email_tokens_user_id_purpose_created_at;
+
+DROP TABLE email_tokens;
+
+DROP TYPE email_token_purpose;
@@ -0,0 +1,16 @@
+CREATE TYPE email_token_purpose AS ENUM ('VERIFY_EMAIL', 'RESET_PASSWORD');
+
+CREATE TABLE
+    email_tokens (
+        id BIGINT PRIMARY KEY,
+        user_id BIGINT NOT NULL,
+        purpose email_token_purpose NOT NULL,
+        email TEXT NOT NULL,
+        expires_at TIMESTAMPTZ NOT NULL,
+        used_at TIMESTAMPTZ,
+        created_at TIMESTAMPTZ NOT NULL DEFAULT NOW ()
+    );
+
+CREATE INDEX idx_email_tokens_user_id_purpose_created_at ON email_tokens (user_id, purpose, created_at);
+
+CREATE INDEX idx_email_tokens_expires_at ON email_tokens (expires_at);
@@ -0,0 +1,7 @@
+DROP INDEX idx_api_tokens_ws_id;
+
+DROP INDEX idx_api_tokens_user_id;
+
+DROP INDEX idx_api_tokens_unique_token_hash;
+
+DROP TABLE api_tokens;
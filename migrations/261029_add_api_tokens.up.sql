@@ -0,0 +1,20 @@
+CREATE TABLE
+    api_tokens (
+        id BIGINT PRIMARY KEY,
+        user_id BIGINT NOT NULL,
+        ws_id BIGINT NOT NULL DEFAULT 0,
+        name TEXT NOT NULL,
+        token_hash BYTEA NOT NULL,
+        prefix TEXT NOT NULL,
+        scopes TEXT[] NOT NULL,
+        expires_at TIMESTAMPTZ,
+        last_used_at TIMESTAMPTZ,
+        revoked_at TIMESTAMPTZ,
+        created_at TIMESTAMPTZ NOT NULL DEFAULT NOW ()
+    );
+
+CREATE UNIQUE INDEX idx_api_tokens_unique_token_hash ON api_tokens (token_hash);
+
+CREATE INDEX idx_api_tokens_user_id ON api_tokens (user_id);
+
+CREATE INDEX idx_api_tokens_ws_id ON api_tokens (ws_id);
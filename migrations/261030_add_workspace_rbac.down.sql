@@ -0,0 +1,12 @@
+ALTER TABLE reprocess_jobs
+    DROP COLUMN created_by;
+
+ALTER TABLE ws_roles
+    DROP COLUMN permissions;
+
+DROP INDEX idx_ws_members_user_id;
+
+ALTER TABLE ws_members
+    DROP COLUMN role;
+
+DROP TYPE ws_member_role;
@@ -0,0 +1,16 @@
+CREATE TYPE ws_member_role AS ENUM ('OWNER', 'ADMIN', 'EDITOR', 'VIEWER');
+
+ALTER TABLE ws_members
+    ADD COLUMN role ws_member_role NOT NULL DEFAULT 'VIEWER';
+
+-- the first member of a workspace is the owner it was created with
+UPDATE ws_members SET role = 'OWNER'
+    WHERE id IN (SELECT DISTINCT ON (ws_id) id FROM ws_members ORDER BY ws_id, created_at, id);
+
+CREATE INDEX idx_ws_members_user_id ON ws_members (user_id);
+
+ALTER TABLE ws_roles
+    ADD COLUMN permissions TEXT[] NOT NULL DEFAULT '{}';
+
+ALTER TABLE reprocess_jobs
+    ADD COLUMN created_by BIGINT NOT NULL DEFAULT 0;
@@ -0,0 +1,5 @@
+DROP INDEX idx_document_assets_blob_key;
+
+DROP INDEX idx_document_assets_version_id;
+
+DROP TABLE document_assets;
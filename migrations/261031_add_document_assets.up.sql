@@ -0,0 +1,17 @@
+CREATE TABLE
+    document_assets (
+        id BIGINT PRIMARY KEY,
+        ws_id BIGINT NOT NULL,
+        document_id BIGINT NOT NULL,
+        version_id BIGINT NOT NULL,
+        source_url TEXT NOT NULL,
+        blob_key TEXT NOT NULL,
+        content_type TEXT NOT NULL,
+        size BIGINT NOT NULL,
+        caption TEXT NOT NULL DEFAULT '',
+        created_at TIMESTAMPTZ NOT NULL DEFAULT NOW ()
+    );
+
+CREATE INDEX idx_document_assets_version_id ON document_assets (version_id);
+
+CREATE INDEX idx_document_assets_blob_key ON document_assets (blob_key);
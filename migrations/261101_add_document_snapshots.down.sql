@@ -0,0 +1,3 @@
+DROP INDEX idx_document_snapshots_unique_version_id_kind;
+
+DROP TABLE document_snapshots;
@@ -0,0 +1,14 @@
+CREATE TABLE
+    document_snapshots (
+        id BIGINT PRIMARY KEY,
+        ws_id BIGINT NOT NULL,
+        document_id BIGINT NOT NULL,
+        version_id BIGINT NOT NULL,
+        kind TEXT NOT NULL,
+        blob_key TEXT NOT NULL,
+        content_type TEXT NOT NULL,
+        size BIGINT NOT NULL,
+        created_at TIMESTAMPTZ NOT NULL DEFAULT NOW ()
+    );
+
+CREATE UNIQUE INDEX idx_document_snapshots_unique_version_id_kind ON document_snapshots (version_id, kind);
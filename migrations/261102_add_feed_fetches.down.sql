@@ -0,0 +1,8 @@
+DROP INDEX idx_feeds_status_next_fetch_at;
+
+ALTER TABLE feeds
+    DROP COLUMN etag,
+    DROP COLUMN last_modified,
+    DROP COLUMN fetch_error,
+    DROP COLUMN last_fetched_at,
+    DROP COLUMN next_fetch_at;
@@ -0,0 +1,8 @@
+ALTER TABLE feeds
+    ADD COLUMN etag TEXT NOT NULL DEFAULT '',
+    ADD COLUMN last_modified TEXT NOT NULL DEFAULT '',
+    ADD COLUMN fetch_error TEXT NOT NULL DEFAULT '',
+    ADD COLUMN last_fetched_at TIMESTAMPTZ,
+    ADD COLUMN next_fetch_at TIMESTAMPTZ NOT NULL DEFAULT NOW ();
+
+CREATE INDEX idx_feeds_status_next_fetch_at ON feeds (status, next_fetch_at);
@@ -0,0 +1 @@
+ALTER TABLE feeds ADD COLUMN subscribed_by BIGINT NOT NULL DEFAULT 0;
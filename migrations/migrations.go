@@ -0,0 +1,9 @@
+// Package migrations embeds the SQL migrations of the database schema.
+package migrations
+
+import "embed"
+
+// FS holds the <version>_<name>.(up|down).sql scripts.
+//
+//go:embed *.sql
+var FS embed.FS
@@ -14,10 +14,16 @@ import (
 	_ "github.com/lemon-mint/coord/provider/vertexai"
 
 	"encoding/json"
+	"errors"
+	"net/url"
 	"os"
 	"strings"
 
 	"github.com/google/go-jsonnet"
+	"gosuda.org/jimin/internal/blob"
+	"gosuda.org/jimin/internal/crawler"
+	"gosuda.org/jimin/internal/pipeline"
+	"gosuda.org/jimin/internal/recrawl"
 
 	"gopkg.eu.org/envloader"
 )
@@ -55,12 +61,105 @@ func LoadConfig(file string) (*Config, error) {
 }
 
 type ModelConfigs struct {
-	ChunkGenerator ModelConfig `json:"chunk_generator"`
+	ChunkGenerator ModelConfig  `json:"chunk_generator"`
+	ImageCaptioner *ModelConfig `json:"image_captioner,omitempty"`
+	// Roles are further models referenced by name, e.g. by pipeline stages.
+	Roles map[string]ModelConfig `json:"roles,omitempty"`
+}
+
+// Role returns the model of a role.
+func (m ModelConfigs) Role(name string) (ModelConfig, bool) {
+	switch name {
+	case "chunk_generator":
+		return m.ChunkGenerator, true
+	case "image_captioner":
+		if m.ImageCaptioner != nil {
+			return *m.ImageCaptioner, true
+		}
+	}
+	mc, ok := m.Roles[name]
+	return mc, ok
 }
 
 type Config struct {
-	ModelConfigs ModelConfigs `json:"model_configs"`
-	Providers    []Providers  `json:"providers"`
+	Database     DatabaseConfig  `json:"database"`
+	Server       ServerConfig    `json:"server"`
+	ModelConfigs ModelConfigs    `json:"model_configs"`
+	Providers    []Providers     `json:"providers"`
+	Sources      []SourceConfig  `json:"sources,omitempty"`
+	Blobs        BlobConfig      `json:"blobs"`
+	Assets       AssetConfig     `json:"assets"`
+	Crawler      CrawlerConfig   `json:"crawler"`
+	LinkCheck    LinkCheckConfig `json:"link_check"`
+	Feeds        FeedConfig      `json:"feeds"`
+	// DuplicateThreshold is the largest SimHash distance between near-duplicate documents, at most 3.
+	DuplicateThreshold int `json:"duplicate_threshold,omitempty"`
+	// Profiles hold the credentials for crawling pages behind logins.
+	Profiles []crawler.Profile `json:"profiles,omitempty"`
+	// Pipelines are the ingestion pipelines by source type.
+	Pipelines map[string]pipeline.Config `json:"pipelines,omitempty"`
+	Search    SearchConfig               `json:"search"`
+	Auth      AuthConfig                 `json:"auth"`
+	Mail      MailConfig                 `json:"mail"`
+}
+
+type DatabaseConfig struct {
+	URL string `json:"url"`
+	// IDSecret is the hex encoded 16 byte key encrypting the generated ids.
+	IDSecret string `json:"id_secret"`
+}
+
+type ServerConfig struct {
+	// Addr is the listen address, :8080 if empty.
+	Addr string `json:"addr,omitempty"`
+	// ShutdownTimeout is the time in seconds to drain the server and the workers on shutdown.
+	ShutdownTimeout int `json:"shutdown_timeout,omitempty"`
+}
+
+type BlobConfig struct {
+	// Dir is the blob store directory. Blobs are not stored if Dir is empty.
+	Dir      string `json:"dir,omitempty"`
+	MaxBytes int64  `json:"max_bytes,omitempty"`
+}
+
+// AssetConfig configures capturing the images of crawled pages into the blob store.
+type AssetConfig struct {
+	Enabled bool `json:"enabled"`
+	// BaseURL is prepended to the blob keys in the captured pages, the asset endpoint /v1/assets/ if empty.
+	BaseURL   string `json:"base_url,omitempty"`
+	MaxBytes  int64  `json:"max_bytes,omitempty"`
+	MaxImages int    `json:"max_images,omitempty"`
+}
+
+type SourceConfig struct {
+	Name string `json:"name"`
+	// Type selects the pipeline of the source, web if empty.
+	Type   string          `json:"type,omitempty"`
+	Hosts  []string        `json:"hosts"`
+	Policy string          `json:"policy,omitempty"`
+	Crawl  crawler.Options `json:"crawl"`
+	// Recrawl is the schedule for crawling the pages of the source again.
+	Recrawl recrawl.Schedule `json:"recrawl"`
+	// AutoSubscribeFeeds subscribes to the feeds discovered on the pages of the source
+	// instead of suggesting them.
+	AutoSubscribeFeeds bool `json:"auto_subscribe_feeds,omitempty"`
+}
+
+// Source returns the first source whose hosts match the host of rawURL.
+func (c *Config) Source(rawURL string) (SourceConfig, bool) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return SourceConfig{}, false
+	}
+
+	for _, s := range c.Sources {
+		for _, pattern := range s.Hosts {
+			if crawler.MatchHost(pattern, u.Hostname()) {
+				return s, true
+			}
+		}
+	}
+	return SourceConfig{}, false
 }
 
 type Parameters struct {
@@ -103,6 +202,37 @@ func Connect(m Providers) (c provider.LLMClient, err error) {
 	return
 }
 
+var ErrNoBlobStore = errors.New("blob store is not configured")
+
+// BlobStore opens the configured blob store.
+func (c *Config) BlobStore() (blob.Store, error) {
+	if c.Blobs.Dir == "" {
+		return nil, ErrNoBlobStore
+	}
+	return blob.NewFSStore(c.Blobs.Dir, c.Blobs.MaxBytes)
+}
+
+var ErrUnknownProvider = errors.New("unknown provider")
+
+// NewModel connects to the provider of mc and returns the configured model.
+func (c *Config) NewModel(mc ModelConfig) (llm.Model, error) {
+	for _, p := range c.Providers {
+		if p.Name != mc.Provider {
+			continue
+		}
+
+		client, err := Connect(p)
+		if err != nil {
+			return nil, err
+		}
+		if client == nil {
+			return nil, ErrUnknownProvider
+		}
+		return GetModel(client, mc.Model, mc.Parameters)
+	}
+	return nil, ErrUnknownProvider
+}
+
 func GetModel(c provider.LLMClient, name string, params Parameters) (m llm.Model, err error) {
 	config := new(llm.Config)
 	config.Temperature = &params.Temperature
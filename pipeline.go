@@ -0,0 +1,200 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/lemon-mint/coord/llm"
+	"gosuda.org/jimin/internal/asset"
+	"gosuda.org/jimin/internal/pipeline"
+)
+
+const (
+	_DEFAULT_SOURCE_TYPE     = "web"
+	_DEFAULT_OPENAI_BASE_URL = "https://api.openai.com/v1"
+)
+
+var (
+	ErrUnknownModelRole    = errors.New("unknown model role")
+	ErrUnsupportedEmbedder = errors.New("provider does not support embeddings")
+)
+
+type llmGenerator struct {
+	model llm.Model
+}
+
+func (g *llmGenerator) Generate(ctx context.Context, instruction, input string) (string, error) {
+	out := g.model.GenerateStream(
+		ctx,
+		&llm.ChatContext{SystemInstruction: instruction},
+		&llm.Content{Role: llm.RoleUser, Parts: []llm.Segment{llm.Text(input)}},
+	)
+
+	var sb strings.Builder
+	for segment := range out.Stream {
+		if text, ok := segment.(llm.Text); ok {
+			sb.WriteString(string(text))
+		}
+	}
+	if out.Err != nil {
+		return "", out.Err
+	}
+
+	return sb.String(), nil
+}
+
+// openAIEmbedder embeds texts with the embeddings endpoint of OpenAI compatible providers.
+type openAIEmbedder struct {
+	client  *http.Client
+	baseURL string
+	apiKey  string
+	model   string
+}
+
+func (e *openAIEmbedder) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	body, err := json.Marshal(map[string]any{"model": e.model, "input": texts})
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, strings.TrimSuffix(e.baseURL, "/")+"/embeddings", bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+e.apiKey)
+
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		err := fmt.Errorf("embeddings request failed: %s", resp.Status)
+		if resp.StatusCode >= 500 || resp.StatusCode == http.StatusTooManyRequests {
+			return nil, err
+		}
+		return nil, pipeline.Permanent(err)
+	}
+
+	var out struct {
+		Data []struct {
+			Index     int       `json:"index"`
+			Embedding []float32 `json:"embedding"`
+		} `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		return nil, err
+	}
+
+	embeddings := make([][]float32, len(texts))
+	for _, d := range out.Data {
+		if d.Index < 0 || d.Index >= len(embeddings) {
+			return nil, pipeline.Permanent(fmt.Errorf("embeddings response has an invalid index %d", d.Index))
+		}
+		embeddings[d.Index] = d.Embedding
+	}
+	return embeddings, nil
+}
+
+// NewEmbedder returns the embedder of the model of a role.
+func (c *Config) NewEmbedder(role string) (pipeline.Embedder, error) {
+	mc, ok := c.ModelConfigs.Role(role)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownModelRole, role)
+	}
+
+	for _, p := range c.Providers {
+		if p.Name != mc.Provider {
+			continue
+		}
+		if p.Type != "openai" {
+			return nil, ErrUnsupportedEmbedder
+		}
+
+		baseURL := p.Baseurl
+		if baseURL == "" {
+			baseURL = _DEFAULT_OPENAI_BASE_URL
+		}
+		return &openAIEmbedder{
+			client:  &http.Client{Timeout: time.Minute},
+			baseURL: baseURL,
+			apiKey:  p.APIKey,
+			model:   mc.Model,
+		}, nil
+	}
+	return nil, ErrUnknownProvider
+}
+
+// pipelines are the ingestion pipelines by source type.
+type pipelines map[string]*pipeline.Pipeline
+
+// NewPipelines builds the declared pipelines and the default pipeline of the other source types.
+// Models are connected once per role and shared by the stages.
+// Pages are fetched with client if it is not nil, and their images are captured with capturer if it is not nil.
+func (c *Config) NewPipelines(client *http.Client, capturer *asset.Capturer) (pipelines, error) {
+	var mu sync.Mutex
+	generators := make(map[string]pipeline.Generator)
+	env := pipeline.Env{
+		Client: client,
+		Generator: func(role string) (pipeline.Generator, error) {
+			mu.Lock()
+			defer mu.Unlock()
+
+			if g, ok := generators[role]; ok {
+				return g, nil
+			}
+			mc, ok := c.ModelConfigs.Role(role)
+			if !ok {
+				return nil, fmt.Errorf("%w: %s", ErrUnknownModelRole, role)
+			}
+			model, err := c.NewModel(mc)
+			if err != nil {
+				return nil, err
+			}
+			generators[role] = &llmGenerator{model: model}
+			return generators[role], nil
+		},
+		Embedder: c.NewEmbedder,
+	}
+	if capturer != nil {
+		env.Capturer = capturer
+	}
+
+	ps := make(pipelines, len(c.Pipelines)+1)
+	for name, cfg := range c.Pipelines {
+		p, err := pipeline.New(cfg, env)
+		if err != nil {
+			return nil, fmt.Errorf("pipeline %s: %w", name, err)
+		}
+		ps[name] = p
+	}
+
+	p, err := pipeline.New(pipeline.Default, env)
+	if err != nil {
+		return nil, err
+	}
+	ps[""] = p
+
+	return ps, nil
+}
+
+// forSource returns the pipeline of the type of source.
+func (ps pipelines) forSource(source SourceConfig) *pipeline.Pipeline {
+	t := source.Type
+	if t == "" {
+		t = _DEFAULT_SOURCE_TYPE
+	}
+	if p, ok := ps[t]; ok {
+		return p
+	}
+	return ps[""]
+}
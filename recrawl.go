@@ -0,0 +1,228 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"mime"
+	"net/http"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/rs/zerolog/log"
+	"gosuda.org/jimin/database"
+	"gosuda.org/jimin/internal/convert"
+	"gosuda.org/jimin/internal/crawler"
+	"gosuda.org/jimin/internal/dedup"
+	"gosuda.org/jimin/internal/feed"
+	"gosuda.org/jimin/internal/indexer"
+	"gosuda.org/jimin/internal/linkcheck"
+	"gosuda.org/jimin/internal/pipeline"
+	"gosuda.org/jimin/internal/recrawl"
+)
+
+var ErrUnsupportedContent = errors.New("unsupported content type")
+
+// pageProcessor runs the pages of documents through the pipelines of their sources
+// and records what they link to.
+// It is shared by the recrawler and the reprocessor, so both build documents alike.
+type pageProcessor struct {
+	c         *Config
+	db        dedup.DB
+	ids       dedup.IDGenerator
+	q         *database.Queries
+	pipelines pipelines
+	feeds     *feed.Registry
+	links     *linkcheck.Checker
+	dups      *dedup.Deduper
+}
+
+func newPageProcessor(c *Config, db dedup.DB, ids dedup.IDGenerator, client *http.Client, cr *crawler.Crawler, links *linkcheck.Checker) (*pageProcessor, error) {
+	capturer, err := NewAssetCapturer(c, cr)
+	if err != nil {
+		return nil, err
+	}
+	ps, err := c.NewPipelines(client, capturer)
+	if err != nil {
+		return nil, err
+	}
+
+	return &pageProcessor{
+		c:         c,
+		db:        db,
+		ids:       ids,
+		q:         database.New(db),
+		pipelines: ps,
+		feeds:     feed.NewRegistry(db, ids),
+		links:     links,
+		dups:      dedup.New(dedup.Config{DB: db, IDs: ids, Threshold: c.DuplicateThreshold}),
+	}, nil
+}
+
+// convert runs the extract and clean stages on the page of doc, capturing its images if assets are enabled.
+// Feeds advertised by the page are suggested to the workspace of the document,
+// and the outlinks of the page are tracked if links are checked.
+func (p *pageProcessor) convert(ctx context.Context, doc database.Document, raw []byte, contentType string) (*recrawl.Content, error) {
+	source, _ := p.c.Source(doc.Url)
+
+	item := &pipeline.Item{URL: doc.Url, Policy: source.Policy, ContentType: contentType, Raw: raw}
+	if _, err := p.pipelines.forSource(source).Select(pipeline.StageExtract, pipeline.StageClean).Run(ctx, item); err != nil {
+		return nil, err
+	}
+
+	if d := item.Document; d != nil {
+		if len(d.Feeds) > 0 {
+			if _, err := p.feeds.Discovered(ctx, doc.WsID, doc.Url, d.Feeds, source.AutoSubscribeFeeds); err != nil {
+				log.Warn().Err(err).Str("url", doc.Url).Msg("Failed to record discovered feeds")
+			}
+		}
+
+		if p.links != nil {
+			if err := p.links.Track(ctx, doc, linkcheck.Outlinks(d)); err != nil {
+				log.Warn().Err(err).Str("url", doc.Url).Msg("Failed to track outlinks")
+			}
+		}
+	}
+
+	return &recrawl.Content{
+		Title:            item.Title,
+		Markdown:         item.Markdown,
+		Raw:              raw,
+		RawContentType:   contentType,
+		ConverterVersion: convert.Version,
+		ChunkerVersion:   indexer.Version,
+		Assets:           item.Assets,
+	}, nil
+}
+
+// changed runs the chunk, embed, summarize and tag stages on a changed document and stores the results.
+// The document is then clustered with its near-duplicates in the workspace.
+func (p *pageProcessor) changed(ctx context.Context, doc database.Document, version database.DocumentVersion) error {
+	source, _ := p.c.Source(doc.Url)
+
+	item := &pipeline.Item{URL: doc.Url, Policy: source.Policy, Title: version.Title, Markdown: version.Content}
+	stages := p.pipelines.forSource(source).Select(pipeline.StageChunk, pipeline.StageEmbed, pipeline.StageSummarize, pipeline.StageTag)
+	if _, err := stages.Run(ctx, item); err != nil {
+		return err
+	}
+
+	if err := p.store(ctx, doc, version, item); err != nil {
+		return err
+	}
+
+	_, err := p.dups.Update(ctx, doc, version.Content)
+	return err
+}
+
+// store replaces the chunks of doc and records the summary and tags of version.
+func (p *pageProcessor) store(ctx context.Context, doc database.Document, version database.DocumentVersion, item *pipeline.Item) error {
+	ids := make([]int64, len(item.Chunks))
+	for i := range ids {
+		id, err := p.ids.Generate(ctx)
+		if err != nil {
+			return err
+		}
+		ids[i] = id
+	}
+
+	return pgx.BeginFunc(ctx, p.db, func(tx pgx.Tx) error {
+		q := p.q.WithTx(tx)
+
+		if err := q.DeleteDocumentChunks(ctx, doc.ID); err != nil {
+			return err
+		}
+		for i, c := range item.Chunks {
+			embedding := []float32{}
+			if i < len(item.Embeddings) && item.Embeddings[i] != nil {
+				embedding = item.Embeddings[i]
+			}
+
+			err := q.CreateDocumentChunk(ctx, database.CreateDocumentChunkParams{
+				ID:          ids[i],
+				WsID:        doc.WsID,
+				DocumentID:  doc.ID,
+				VersionID:   version.ID,
+				Seq:         int32(i),
+				Heading:     c.Heading,
+				Content:     c.Content,
+				StartOffset: int32(c.Start),
+				EndOffset:   int32(c.End),
+				Embedding:   embedding,
+			})
+			if err != nil {
+				return err
+			}
+		}
+
+		if item.Summary == "" && item.Tags == nil {
+			return nil
+		}
+		tags := item.Tags
+		if tags == nil {
+			tags = []string{}
+		}
+		return q.SetDocumentVersionSummary(ctx, database.SetDocumentVersionSummaryParams{ID: version.ID, Summary: item.Summary, Tags: tags})
+	})
+}
+
+// NewRecrawler returns a recrawler that schedules documents by the recrawl config of their source.
+// Modified pages are rendered with cr if it is not nil, and their outlinks are tracked by links if it is not nil.
+// The fetched pages are kept in the blob store if it is configured, so they can be reprocessed.
+func NewRecrawler(c *Config, db recrawl.DB, ids recrawl.IDGenerator, cr *crawler.Crawler, links *linkcheck.Checker) (*recrawl.Recrawler, error) {
+	client := &http.Client{Timeout: time.Second * 30}
+	if cr != nil {
+		client.Transport = cr.Transport(nil)
+	}
+
+	pages, err := newPageProcessor(c, db, ids, client, cr, links)
+	if err != nil {
+		return nil, err
+	}
+	return newRecrawler(c, db, ids, client, cr, pages)
+}
+
+func newRecrawler(c *Config, db recrawl.DB, ids recrawl.IDGenerator, client *http.Client, cr *crawler.Crawler, pages *pageProcessor) (*recrawl.Recrawler, error) {
+	blobs, err := c.BlobStore()
+	if err != nil && !errors.Is(err, ErrNoBlobStore) {
+		return nil, err
+	}
+
+	return recrawl.New(recrawl.Config{
+		DB:     db,
+		IDs:    ids,
+		Client: client,
+		Blobs:  blobs,
+		Extract: func(ctx context.Context, doc database.Document, resp *recrawl.Response) (*recrawl.Content, error) {
+			source, _ := c.Source(doc.Url)
+
+			contentType := resp.Header.Get("Content-Type")
+			mediaType, _, _ := mime.ParseMediaType(contentType)
+			if mediaType != "text/html" && mediaType != "application/xhtml+xml" {
+				return nil, ErrUnsupportedContent
+			}
+
+			raw := resp.Body
+			var snapshots []crawler.Snapshot
+			if cr != nil {
+				page, err := cr.Crawl(ctx, doc.Url, source.Crawl)
+				if err != nil {
+					return nil, err
+				}
+				// the rendered page is kept, reprocessing does not render again
+				raw, contentType = []byte(page.HTML), "text/html; charset=utf-8"
+				snapshots = page.Snapshots
+			}
+
+			content, err := pages.convert(ctx, doc, raw, contentType)
+			if err != nil {
+				return nil, err
+			}
+			content.Snapshots = snapshots
+			return content, nil
+		},
+		Schedule: func(doc database.Document) recrawl.Schedule {
+			source, _ := c.Source(doc.Url)
+			return source.Recrawl
+		},
+		Changed: pages.changed,
+	}), nil
+}
@@ -0,0 +1,35 @@
+package main
+
+import (
+	"gosuda.org/jimin/internal/convert"
+	"gosuda.org/jimin/internal/indexer"
+	"gosuda.org/jimin/internal/linkcheck"
+	"gosuda.org/jimin/internal/rbac"
+	"gosuda.org/jimin/internal/reprocess"
+)
+
+// NewReprocessor returns the reprocessor rebuilding documents from the pages kept in the blob store.
+// Reprocessed documents are converted and handled like recrawled ones.
+// The jobs of users run while their users may still reprocess the workspace.
+func NewReprocessor(c *Config, db reprocess.DB, ids reprocess.IDGenerator, links *linkcheck.Checker) (*reprocess.Reprocessor, error) {
+	store, err := c.BlobStore()
+	if err != nil {
+		return nil, err
+	}
+
+	pages, err := newPageProcessor(c, db, ids, nil, nil, links)
+	if err != nil {
+		return nil, err
+	}
+
+	return reprocess.New(reprocess.Config{
+		DB:               db,
+		IDs:              ids,
+		Blobs:            store,
+		Authz:            rbac.New(db),
+		Convert:          pages.convert,
+		Changed:          pages.changed,
+		ConverterVersion: convert.Version,
+		ChunkerVersion:   indexer.Version,
+	}), nil
+}
@@ -0,0 +1,178 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"gosuda.org/jimin/internal/dedup"
+	"gosuda.org/jimin/internal/search"
+)
+
+type SearchConfig struct {
+	// Mode is the default search mode: keyword, vector or hybrid.
+	Mode string `json:"mode,omitempty"`
+	// Embedder is the model role embedding the queries, the same as the embed stage of the pipelines.
+	// Vector search is disabled if it is empty.
+	Embedder string `json:"embedder,omitempty"`
+	// Generator is the model role answering questions, chunk_generator if empty.
+	Generator string `json:"generator,omitempty"`
+}
+
+// NewSearcher returns the searcher of the documents, collapsing their near-duplicates.
+func NewSearcher(c *Config, db dedup.DB, ids dedup.IDGenerator) (*search.Searcher, error) {
+	cfg := search.Config{
+		DB:   db,
+		Dups: dedup.New(dedup.Config{DB: db, IDs: ids, Threshold: c.DuplicateThreshold}),
+	}
+	if c.Search.Embedder != "" {
+		embedder, err := c.NewEmbedder(c.Search.Embedder)
+		if err != nil {
+			return nil, err
+		}
+		cfg.Embedder = embedder
+	}
+	return search.New(cfg), nil
+}
+
+// NewAnswerer returns the generator answering questions.
+func (c *Config) NewAnswerer() (search.Generator, error) {
+	role := c.Search.Generator
+	if role == "" {
+		role = "chunk_generator"
+	}
+	mc, ok := c.ModelConfigs.Role(role)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownModelRole, role)
+	}
+	model, err := c.NewModel(mc)
+	if err != nil {
+		return nil, err
+	}
+	return &llmGenerator{model: model}, nil
+}
+
+// searchFlags are the flags shared by search and ask.
+type searchFlags struct {
+	ws    *int64
+	mode  *string
+	limit *int
+}
+
+func (c *cli) searchFlags(name, args string) (*searchFlags, func([]string) (string, error)) {
+	fs := c.flags(name, args)
+	f := &searchFlags{
+		ws:    fs.Int64("ws", 0, "workspace id"),
+		mode:  fs.String("mode", "", "search mode: keyword, vector or hybrid (default from the config)"),
+		limit: fs.Int("limit", 10, "maximum number of results"),
+	}
+	parse := func(args []string) (string, error) {
+		pos, err := c.parse(fs, args, 1)
+		if err != nil {
+			return "", err
+		}
+		if *f.ws == 0 {
+			return "", usageErrorf("%s: -ws is required", name)
+		}
+		return pos[0], nil
+	}
+	return f, parse
+}
+
+func (c *cli) searcher(ctx context.Context, f *searchFlags) (*app, *search.Searcher, search.Mode, error) {
+	a, err := c.open(ctx)
+	if err != nil {
+		return nil, nil, "", err
+	}
+
+	mode := *f.mode
+	if mode == "" {
+		mode = a.cfg.Search.Mode
+	}
+	m, err := search.ParseMode(mode)
+	if err != nil {
+		a.Close()
+		return nil, nil, "", withCode(_EXIT_USAGE, err)
+	}
+
+	s, err := NewSearcher(a.cfg, a.pool, a.ids)
+	if err != nil {
+		a.Close()
+		return nil, nil, "", withCode(_EXIT_CONFIG, err)
+	}
+	return a, s, m, nil
+}
+
+func (c *cli) search(ctx context.Context, args []string) error {
+	f, parse := c.searchFlags("search", "<query>")
+	query, err := parse(args)
+	if err != nil {
+		return err
+	}
+
+	a, s, mode, err := c.searcher(ctx, f)
+	if err != nil {
+		return err
+	}
+	defer a.Close()
+
+	results, err := s.Search(ctx, *f.ws, query, mode, *f.limit)
+	if err != nil {
+		return err
+	}
+
+	return c.print(map[string]any{"query": query, "mode": mode, "results": results}, func(w io.Writer) {
+		if len(results) == 0 {
+			fmt.Fprintln(w, "no results")
+		}
+		for i, r := range results {
+			fmt.Fprintf(w, "%d. %s\n   %s\n   %s\n\n", i+1, r.Title, r.URL, snippet(r.Content, 200))
+		}
+	})
+}
+
+func (c *cli) ask(ctx context.Context, args []string) error {
+	f, parse := c.searchFlags("ask", "<question>")
+	question, err := parse(args)
+	if err != nil {
+		return err
+	}
+
+	a, s, mode, err := c.searcher(ctx, f)
+	if err != nil {
+		return err
+	}
+	defer a.Close()
+
+	gen, err := a.cfg.NewAnswerer()
+	if err != nil {
+		return withCode(_EXIT_CONFIG, err)
+	}
+
+	answer, err := s.Ask(ctx, gen, *f.ws, question, mode, *f.limit)
+	if errors.Is(err, search.ErrNoSources) {
+		return withCode(_EXIT_NOT_FOUND, err)
+	}
+	if err != nil {
+		return err
+	}
+
+	return c.print(answer, func(w io.Writer) {
+		fmt.Fprintln(w, answer.Answer)
+		fmt.Fprintln(w)
+		for i, r := range answer.Sources {
+			fmt.Fprintf(w, "[%d] %s %s\n", i+1, r.Title, r.URL)
+		}
+	})
+}
+
+// snippet returns content on one line, shortened to about n bytes.
+func snippet(content string, n int) string {
+	s := strings.Join(strings.Fields(content), " ")
+	if len(s) <= n {
+		return s
+	}
+	return strings.ToValidUTF8(s[:n], "") + "…"
+}
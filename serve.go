@@ -0,0 +1,210 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/rs/zerolog/log"
+	"gosuda.org/jimin/internal/lifecycle"
+	"gosuda.org/jimin/internal/migrate"
+	"gosuda.org/jimin/internal/server"
+)
+
+const (
+	_DEFAULT_ADDR             = ":8080"
+	_DEFAULT_SHUTDOWN_TIMEOUT = time.Second * 30
+)
+
+type serveOptions struct {
+	addr          string
+	migrate       bool
+	migrationsDir string
+}
+
+func (c *cli) serve(ctx context.Context, args []string) error {
+	fs := c.flags("serve", "")
+	var opts serveOptions
+	fs.StringVar(&opts.addr, "addr", "", "listen address (default from the config, or "+_DEFAULT_ADDR+")")
+	fs.BoolVar(&opts.migrate, "migrate", true, "apply the pending migrations before starting")
+	fs.StringVar(&opts.migrationsDir, "migrations", "", "migrations directory (default: the embedded migrations)")
+	timeout := fs.Duration("shutdown-timeout", 0, "time to drain on shutdown (default from the config, or 30s)")
+	if _, err := c.parse(fs, args, 0); err != nil {
+		return err
+	}
+
+	cfg, err := c.loadConfig()
+	if err != nil {
+		return err
+	}
+	if opts.addr == "" {
+		opts.addr = cfg.Server.Addr
+	}
+	if opts.addr == "" {
+		opts.addr = _DEFAULT_ADDR
+	}
+	if *timeout <= 0 {
+		*timeout = time.Duration(cfg.Server.ShutdownTimeout) * time.Second
+	}
+	if *timeout <= 0 {
+		*timeout = _DEFAULT_SHUTDOWN_TIMEOUT
+	}
+
+	g := lifecycle.New()
+	err = start(ctx, g, cfg, opts)
+	if err == nil {
+		select {
+		case <-ctx.Done():
+			log.Info().Msg("Shutting down")
+		case <-g.Failed():
+			err = g.Err()
+			log.Error().Err(err).Msg("Component failed, shutting down")
+		}
+	}
+
+	// the signal context is done, so the shutdown has its own deadline
+	stopCtx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+	if serr := g.Stop(stopCtx); serr != nil {
+		err = errors.Join(err, fmt.Errorf("failed to stop cleanly: %w", serr))
+	} else if err == nil {
+		log.Info().Msg("Stopped")
+	}
+	return err
+}
+
+// start brings up the subsystems in dependency order and adds them to g,
+// so they are stopped in the reverse order: the server, the workers, the crawler,
+// the id generator and the database.
+func start(ctx context.Context, g *lifecycle.Group, cfg *Config, opts serveOptions) error {
+	pool, err := connect(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	g.Add("database", func(context.Context) error {
+		pool.Close()
+		return nil
+	})
+
+	if opts.migrate {
+		if err := applyMigrations(ctx, pool, opts.migrationsDir); err != nil {
+			return err
+		}
+	}
+
+	rf, err := startIDs(pool, cfg)
+	if err != nil {
+		return err
+	}
+	g.Add("randflake", rf.Close)
+	ids, err := rf.NewGenerator(ctx)
+	if err != nil {
+		return withCode(_EXIT_UNAVAILABLE, fmt.Errorf("failed to lease an id range: %w", err))
+	}
+	g.Add("id generator", func(context.Context) error {
+		ids.Close()
+		return nil
+	})
+
+	cr, closeCrawler, err := NewCrawler(cfg)
+	if err != nil {
+		return withCode(_EXIT_CONFIG, err)
+	}
+	g.Add("crawler", func(ctx context.Context) error {
+		done := make(chan struct{})
+		go func() {
+			cr.Wait()
+			close(done)
+		}()
+		select {
+		case <-done:
+		case <-ctx.Done():
+			return fmt.Errorf("crawls did not finish: %w", ctx.Err())
+		}
+		return closeCrawler()
+	})
+
+	links := NewLinkChecker(cfg, pool, ids, cr)
+	recrawler, err := NewRecrawler(cfg, pool, ids, cr, links)
+	if err != nil {
+		return withCode(_EXIT_CONFIG, err)
+	}
+	reprocessor, err := NewReprocessor(cfg, pool, ids, links)
+	if errors.Is(err, ErrNoBlobStore) {
+		log.Info().Msg("Reprocessing is disabled without a blob store")
+		reprocessor = nil
+	} else if err != nil {
+		return withCode(_EXIT_CONFIG, err)
+	}
+	mailer, err := cfg.Mailer()
+	if errors.Is(err, ErrNoMailer) {
+		log.Info().Msg("Email verification and password reset are disabled without a mailer")
+		mailer = nil
+	} else if err != nil {
+		return withCode(_EXIT_CONFIG, err)
+	}
+	au, err := NewAuth(cfg, pool, ids, mailer)
+	if err != nil {
+		return withCode(_EXIT_CONFIG, err)
+	}
+
+	g.Go("sessions", au.Run)
+	g.Go("link checker", links.Run)
+	g.Go("recrawler", recrawler.Run)
+	g.Go("feed poller", NewFeedPoller(cfg, pool, cr, recrawler).Run)
+	if reprocessor != nil {
+		g.Go("reprocessor", reprocessor.Run)
+	}
+
+	v1, err := NewAPI(cfg, pool, ids, au, recrawler, links, reprocessor)
+	if err != nil {
+		return withCode(_EXIT_CONFIG, err)
+	}
+	mux := http.NewServeMux()
+	v1.Register(mux)
+	s := server.New(mux, server.Options{})
+
+	ln, err := net.Listen("tcp", opts.addr)
+	if err != nil {
+		return withCode(_EXIT_UNAVAILABLE, err)
+	}
+	if err := s.Start(ln); err != nil {
+		ln.Close()
+		return err
+	}
+	g.Add("server", s.Stop)
+	go func() {
+		if err := s.Wait(); err != nil {
+			g.Fail(fmt.Errorf("server: %w", err))
+		}
+	}()
+	<-s.Ready()
+	log.Info().Str("addr", ln.Addr().String()).Msg("Server started")
+
+	return nil
+}
+
+// applyMigrations applies the pending migrations of dir, or the embedded ones if dir is empty.
+// Replicas starting at once wait for the first one to migrate.
+func applyMigrations(ctx context.Context, pool *pgxpool.Pool, dir string) error {
+	migrations, err := loadMigrations(dir)
+	if err != nil {
+		return err
+	}
+
+	conn, err := pool.Acquire(ctx)
+	if err != nil {
+		return withCode(_EXIT_UNAVAILABLE, err)
+	}
+	defer conn.Release()
+
+	applied, err := migrate.Up(ctx, conn.Conn(), migrations)
+	for _, m := range applied {
+		log.Info().Int64("version", m.Version).Str("name", m.Name).Msg("Applied migration")
+	}
+	return err
+}
@@ -0,0 +1,157 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/mail"
+	"strconv"
+
+	"github.com/jackc/pgx/v5"
+	"gosuda.org/jimin/database"
+)
+
+func (c *cli) createUser(ctx context.Context, args []string) error {
+	fs := c.flags("user create", "")
+	name := fs.String("name", "", "name of the user")
+	email := fs.String("email", "", "email address of the user")
+	verified := fs.Bool("verified", false, "mark the email address as verified")
+	admin := fs.Bool("admin", false, "let the user administer the server")
+	if _, err := c.parse(fs, args, 0); err != nil {
+		return err
+	}
+	if *name == "" || *email == "" {
+		return usageErrorf("user create: -name and -email are required")
+	}
+	if _, err := mail.ParseAddress(*email); err != nil {
+		return usageErrorf("user create: invalid email address %q", *email)
+	}
+
+	a, err := c.open(ctx)
+	if err != nil {
+		return err
+	}
+	defer a.Close()
+
+	id, err := a.ids.Generate(ctx)
+	if err != nil {
+		return err
+	}
+	var user database.User
+	err = pgx.BeginFunc(ctx, a.pool, func(tx pgx.Tx) error {
+		q := database.New(tx)
+
+		var err error
+		user, err = q.CreateUser(ctx, database.CreateUserParams{
+			ID:            id,
+			Name:          *name,
+			Email:         *email,
+			EmailVerified: *verified,
+		})
+		if err != nil || !*admin {
+			return err
+		}
+		user, err = q.SetUserAdmin(ctx, database.SetUserAdminParams{ID: user.ID, IsAdmin: true})
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	return c.print(user, func(w io.Writer) {
+		fmt.Fprintf(w, "created user %d <%s>\n", user.ID, user.Email)
+	})
+}
+
+func (c *cli) setUserAdmin(ctx context.Context, args []string) error {
+	fs := c.flags("user admin", "<user_id>")
+	revoke := fs.Bool("revoke", false, "stop the user from administering the server")
+	pos, err := c.parse(fs, args, 1)
+	if err != nil {
+		return err
+	}
+	id, err := strconv.ParseInt(pos[0], 10, 64)
+	if err != nil || id <= 0 {
+		return usageErrorf("user admin: invalid user id %q", pos[0])
+	}
+
+	a, err := c.open(ctx)
+	if err != nil {
+		return err
+	}
+	defer a.Close()
+
+	user, err := database.New(a.pool).SetUserAdmin(ctx, database.SetUserAdminParams{ID: id, IsAdmin: !*revoke})
+	if errors.Is(err, pgx.ErrNoRows) {
+		return withCode(_EXIT_NOT_FOUND, fmt.Errorf("user %d not found", id))
+	}
+	if err != nil {
+		return err
+	}
+
+	return c.print(user, func(w io.Writer) {
+		if user.IsAdmin {
+			fmt.Fprintf(w, "user %d <%s> is an admin\n", user.ID, user.Email)
+		} else {
+			fmt.Fprintf(w, "user %d <%s> is not an admin\n", user.ID, user.Email)
+		}
+	})
+}
+
+func (c *cli) createWorkspace(ctx context.Context, args []string) error {
+	fs := c.flags("workspace create", "")
+	name := fs.String("name", "", "name of the workspace")
+	owner := fs.Int64("owner", 0, "id of the user added as the first member, with the owner role")
+	if _, err := c.parse(fs, args, 0); err != nil {
+		return err
+	}
+	if *name == "" {
+		return usageErrorf("workspace create: -name is required")
+	}
+
+	a, err := c.open(ctx)
+	if err != nil {
+		return err
+	}
+	defer a.Close()
+
+	q := database.New(a.pool)
+	if *owner != 0 {
+		if _, err := q.GetUserByID(ctx, *owner); err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				return withCode(_EXIT_NOT_FOUND, fmt.Errorf("user %d not found", *owner))
+			}
+			return err
+		}
+	}
+
+	wsID, err := a.ids.Generate(ctx)
+	if err != nil {
+		return err
+	}
+	memberID, err := a.ids.Generate(ctx)
+	if err != nil {
+		return err
+	}
+
+	var ws database.Wss
+	err = pgx.BeginFunc(ctx, a.pool, func(tx pgx.Tx) error {
+		q := q.WithTx(tx)
+
+		var err error
+		ws, err = q.CreateWorkspace(ctx, database.CreateWorkspaceParams{ID: wsID, Name: *name})
+		if err != nil || *owner == 0 {
+			return err
+		}
+		_, err = q.CreateWorkspaceMember(ctx, database.CreateWorkspaceMemberParams{ID: memberID, WsID: ws.ID, UserID: *owner, Role: database.WsMemberRoleOWNER})
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	return c.print(ws, func(w io.Writer) {
+		fmt.Fprintf(w, "created workspace %d %s\n", ws.ID, ws.Name)
+	})
+}